@@ -0,0 +1,282 @@
+// Package archive reads and writes account archives: ZIP files holding everything a
+// user keeps in geoalbum, for taking the data out or moving it to another instance.
+//
+// An archive of format version 1 contains:
+//
+//	manifest.json      Manifest; identifies the file as an archive and its version
+//	albums.json        []Album
+//	photos.json        []Photo
+//	paths.json         []Path
+//	trips.json         []Trip
+//	tags.json          []Tag
+//	saved_places.json  []SavedPlace
+//	photos/<id><ext>   the original photo files, named by Photo.File
+//
+// Records refer to each other by the IDs they had where the archive was made.
+// Coordinates are WGS-84 degrees, times are RFC 3339 and text is plain, not HTML.
+// Within a version fields may be added but are never removed or changed in meaning;
+// readers ignore fields they do not know and reject archives of a later version.
+package archive
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+)
+
+// FormatName identifies account archives in their manifest
+const FormatName = "geoalbum-archive"
+
+// Version is the format version written, and the latest one read
+const Version = 1
+
+// Names of the documents in an archive
+const (
+	ManifestFile    = "manifest.json"
+	AlbumsFile      = "albums.json"
+	PhotosFile      = "photos.json"
+	PathsFile       = "paths.json"
+	TripsFile       = "trips.json"
+	TagsFile        = "tags.json"
+	SavedPlacesFile = "saved_places.json"
+	PhotosDir       = "photos"
+)
+
+// ErrNotArchive is returned for files that are not account archives
+var ErrNotArchive = errors.New("not a geoalbum account archive")
+
+// Manifest describes an archive
+type Manifest struct {
+	Format      string    `json:"format"`
+	Version     int       `json:"version"`
+	CreatedAt   time.Time `json:"created_at"`
+	Username    string    `json:"username"`
+	Albums      int       `json:"albums"`
+	Photos      int       `json:"photos"`
+	Paths       int       `json:"paths"`
+	Trips       int       `json:"trips"`
+	Tags        int       `json:"tags"`
+	SavedPlaces int       `json:"saved_places"`
+}
+
+// Album is an album; its place name is not kept as it is derived from the location
+type Album struct {
+	ID           string    `json:"id"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	Latitude     float64   `json:"latitude"`
+	Longitude    float64   `json:"longitude"`
+	CreatedAt    time.Time `json:"created_at"`
+	StartAt      time.Time `json:"start_at"`
+	EndAt        time.Time `json:"end_at"`
+	DatesManual  bool      `json:"dates_manual"` // the date range was set by hand rather than from photos
+	Timezone     string    `json:"timezone,omitempty"`
+	ExternalID   string    `json:"external_id,omitempty"`
+	CoverPhotoID string    `json:"cover_photo_id,omitempty"` // set only when chosen by hand
+	Tags         []string  `json:"tags,omitempty"`
+}
+
+// Photo is a photo of an album. File is empty when the original was missing.
+type Photo struct {
+	ID           string     `json:"id"`
+	AlbumID      string     `json:"album_id"`
+	File         string     `json:"file,omitempty"`
+	Filename     string     `json:"filename"`
+	MimeType     string     `json:"mime_type"`
+	Size         int64      `json:"size"`
+	DisplayOrder int        `json:"display_order"`
+	UploadedAt   time.Time  `json:"uploaded_at"`
+	TakenAt      *time.Time `json:"taken_at,omitempty"`
+	Rating       int        `json:"rating"`
+	Favorite     bool       `json:"favorite"`
+	Latitude     *float64   `json:"latitude,omitempty"`
+	Longitude    *float64   `json:"longitude,omitempty"`
+	Description  string     `json:"description,omitempty"`
+	Tags         []string   `json:"tags,omitempty"`
+}
+
+// Path is a journey from one album to another
+type Path struct {
+	ID              string       `json:"id"`
+	FromAlbumID     string       `json:"from_album_id"`
+	ToAlbumID       string       `json:"to_album_id"`
+	CreatedAt       time.Time    `json:"created_at"`
+	TransportMode   string       `json:"transport_mode,omitempty"`
+	DurationMinutes *int         `json:"duration_minutes,omitempty"`
+	DepartAt        *time.Time   `json:"depart_at,omitempty"`
+	ArriveAt        *time.Time   `json:"arrive_at,omitempty"`
+	Notes           string       `json:"notes,omitempty"`
+	Geometry        [][2]float64 `json:"geometry,omitempty"` // [longitude, latitude] points of the route taken
+}
+
+// Trip is an itinerary visiting albums in order
+type Trip struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	StartAt     *time.Time `json:"start_at,omitempty"`
+	EndAt       *time.Time `json:"end_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	AlbumIDs    []string   `json:"album_ids"`
+}
+
+// Tag is a tag, listed so that tags without albums or photos are kept too
+type Tag struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SavedPlace is a named area: a circle or a polygon
+type SavedPlace struct {
+	Name      string       `json:"name"`
+	Kind      string       `json:"kind"`
+	Latitude  *float64     `json:"latitude,omitempty"`
+	Longitude *float64     `json:"longitude,omitempty"`
+	RadiusM   *float64     `json:"radius_m,omitempty"`
+	Polygon   [][2]float64 `json:"polygon,omitempty"` // [longitude, latitude] vertices
+	AutoTag   bool         `json:"auto_tag"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// Archive is the content of an account archive apart from the photo files
+type Archive struct {
+	Manifest    Manifest
+	Albums      []Album
+	Photos      []Photo
+	Paths       []Path
+	Trips       []Trip
+	Tags        []Tag
+	SavedPlaces []SavedPlace
+}
+
+// Writer writes an account archive
+type Writer struct {
+	zw *zip.Writer
+}
+
+// NewWriter starts an archive on w. Photo files are added with WritePhoto and the
+// documents written by Close.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{zw: zip.NewWriter(w)}
+}
+
+// WritePhoto adds the original file of photo to the archive and sets photo.File
+func (w *Writer) WritePhoto(photo *Photo, r io.Reader) error {
+	name := path.Join(PhotosDir, photo.ID+strings.ToLower(path.Ext(photo.Filename)))
+	// Photos are compressed already, so they are stored as they are
+	dst, err := w.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: photo.UploadedAt})
+	if err != nil {
+		return fmt.Errorf("failed to write photo %s: %w", photo.ID, err)
+	}
+	if _, err := io.Copy(dst, r); err != nil {
+		return fmt.Errorf("failed to write photo %s: %w", photo.ID, err)
+	}
+	photo.File = name
+	return nil
+}
+
+// Close writes the documents of archive, filling in its manifest, and finishes the
+// ZIP file. It does not close the underlying writer.
+func (w *Writer) Close(archive *Archive) error {
+	manifest := archive.Manifest
+	manifest.Format, manifest.Version = FormatName, Version
+	manifest.Albums, manifest.Photos, manifest.Paths = len(archive.Albums), len(archive.Photos), len(archive.Paths)
+	manifest.Trips, manifest.Tags, manifest.SavedPlaces = len(archive.Trips), len(archive.Tags), len(archive.SavedPlaces)
+
+	documents := []struct {
+		name  string
+		value interface{}
+	}{
+		{ManifestFile, manifest},
+		{AlbumsFile, nonNil(archive.Albums)},
+		{PhotosFile, nonNil(archive.Photos)},
+		{PathsFile, nonNil(archive.Paths)},
+		{TripsFile, nonNil(archive.Trips)},
+		{TagsFile, nonNil(archive.Tags)},
+		{SavedPlacesFile, nonNil(archive.SavedPlaces)},
+	}
+	for _, document := range documents {
+		dst, err := w.zw.Create(document.name)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", document.name, err)
+		}
+		encoder := json.NewEncoder(dst)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(document.value); err != nil {
+			return fmt.Errorf("failed to write %s: %w", document.name, err)
+		}
+	}
+	if err := w.zw.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+// nonNil makes empty lists encode as [] rather than null
+func nonNil[T any](records []T) []T {
+	if records == nil {
+		return []T{}
+	}
+	return records
+}
+
+// Read reads the documents of an archive opened as a file system, such as a
+// *zip.Reader. Photo files are opened from the same file system by Photo.File.
+func Read(fsys fs.FS) (*Archive, error) {
+	archive := &Archive{}
+	if err := readDocument(fsys, ManifestFile, &archive.Manifest); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotArchive
+		}
+		return nil, err
+	}
+	if archive.Manifest.Format != FormatName {
+		return nil, ErrNotArchive
+	}
+	if archive.Manifest.Version < 1 || archive.Manifest.Version > Version {
+		return nil, fmt.Errorf("unsupported archive version %d: this version of geoalbum reads up to version %d", archive.Manifest.Version, Version)
+	}
+
+	documents := []struct {
+		name  string
+		value interface{}
+	}{
+		{AlbumsFile, &archive.Albums},
+		{PhotosFile, &archive.Photos},
+		{PathsFile, &archive.Paths},
+		{TripsFile, &archive.Trips},
+		{TagsFile, &archive.Tags},
+		{SavedPlacesFile, &archive.SavedPlaces},
+	}
+	for _, document := range documents {
+		if err := readDocument(fsys, document.name, document.value); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	for _, photo := range archive.Photos {
+		if photo.File != "" && (!fs.ValidPath(photo.File) || !strings.HasPrefix(photo.File, PhotosDir+"/")) {
+			return nil, fmt.Errorf("invalid archive: photo %s has file %q outside %s/", photo.ID, photo.File, PhotosDir)
+		}
+	}
+	return archive, nil
+}
+
+func readDocument(fsys fs.FS, name string, value interface{}) error {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := json.NewDecoder(file).Decode(value); err != nil {
+		return fmt.Errorf("invalid archive: %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTrip(t *testing.T) {
+	created := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+	lat, lng := 35.0116, 135.7681
+	original := &Archive{
+		Manifest: Manifest{CreatedAt: created, Username: "tester"},
+		Albums: []Album{{
+			ID: "a1", Title: "Kyoto & Nara", Latitude: lat, Longitude: lng,
+			CreatedAt: created, StartAt: created, EndAt: created, Tags: []string{"japan"},
+		}},
+		Photos: []Photo{{
+			ID: "p1", AlbumID: "a1", Filename: "IMG_0001.JPG", MimeType: "image/jpeg", Size: 4,
+			UploadedAt: created, TakenAt: &created, Rating: 4, Latitude: &lat, Longitude: &lng,
+		}},
+		Paths: []Path{{ID: "path1", FromAlbumID: "a1", ToAlbumID: "a1", CreatedAt: created,
+			Geometry: [][2]float64{{135.7, 35.0}, {135.8, 34.7}}}},
+		Tags: []Tag{{Name: "japan", CreatedAt: created}},
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	require.NoError(t, w.WritePhoto(&original.Photos[0], strings.NewReader("jpeg")))
+	assert.Equal(t, "photos/p1.jpg", original.Photos[0].File)
+	require.NoError(t, w.Close(original))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	read, err := Read(zr)
+	require.NoError(t, err)
+
+	assert.Equal(t, FormatName, read.Manifest.Format)
+	assert.Equal(t, Version, read.Manifest.Version)
+	assert.Equal(t, 1, read.Manifest.Photos)
+	assert.Equal(t, original.Albums, read.Albums)
+	assert.Equal(t, original.Photos, read.Photos)
+	assert.Equal(t, original.Paths, read.Paths)
+	assert.Empty(t, read.Trips)
+	assert.NotNil(t, read.SavedPlaces, "empty documents are written as []")
+
+	file, err := zr.Open(read.Photos[0].File)
+	require.NoError(t, err)
+	content, err := io.ReadAll(file)
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", string(content))
+}
+
+func TestReadRejects(t *testing.T) {
+	_, err := Read(fstest.MapFS{"albums.json": {Data: []byte("[]")}})
+	assert.ErrorIs(t, err, ErrNotArchive)
+
+	_, err = Read(fstest.MapFS{"manifest.json": {Data: []byte(`{"format": "other", "version": 1}`)}})
+	assert.ErrorIs(t, err, ErrNotArchive)
+
+	_, err = Read(fstest.MapFS{"manifest.json": {Data: []byte(`{"format": "geoalbum-archive", "version": 2}`)}})
+	assert.ErrorContains(t, err, "unsupported archive version 2")
+
+	_, err = Read(fstest.MapFS{
+		"manifest.json": {Data: []byte(`{"format": "geoalbum-archive", "version": 1}`)},
+		"photos.json":   {Data: []byte(`[{"id": "p1", "file": "photos/../manifest.json"}]`)},
+	})
+	assert.ErrorContains(t, err, "outside photos/")
+}
@@ -0,0 +1,163 @@
+// Package basemap serves map tiles from local MBTiles files
+// (https://github.com/mapbox/mbtiles-spec) so that the map works without network access.
+package basemap
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+
+	"geoalbum/backend/model"
+)
+
+// Source is an open MBTiles file
+type Source struct {
+	model.Basemap
+	db *sqlx.DB
+}
+
+// Open opens an MBTiles file read-only and reads its metadata. The basemap is named
+// after the file.
+func Open(path string) (*Source, error) {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	db, err := sqlx.Open("sqlite", "file:"+(&url.URL{Path: path}).EscapedPath()+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open basemap %s: %w", name, err)
+	}
+
+	source := &Source{
+		Basemap: model.Basemap{
+			Name:    name,
+			Title:   name,
+			Type:    "raster",
+			Format:  "png",
+			MaxZoom: 22,
+			TileURL: "/api/basemap/" + name + "/{z}/{x}/{y}",
+		},
+		db: db,
+	}
+	if err := source.readMetadata(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to read basemap %s: %w", name, err)
+	}
+	return source, nil
+}
+
+// readMetadata fills in the basemap description from the metadata table
+func (s *Source) readMetadata() error {
+	var rows []struct {
+		Name  string `db:"name"`
+		Value string `db:"value"`
+	}
+	if err := s.db.Select(&rows, `SELECT name, value FROM metadata`); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		value := strings.TrimSpace(row.Value)
+		switch row.Name {
+		case "name":
+			if value != "" {
+				s.Title = value
+			}
+		case "description":
+			s.Description = value
+		case "attribution":
+			s.Attribution = value
+		case "format":
+			s.Format = strings.ToLower(value)
+		case "minzoom":
+			s.MinZoom, _ = strconv.Atoi(value)
+		case "maxzoom":
+			if zoom, err := strconv.Atoi(value); err == nil {
+				s.MaxZoom = zoom
+			}
+		case "bounds":
+			if values := parseFloats(value); len(values) == 4 {
+				s.Bounds = &model.BoundingBox{MinLng: values[0], MinLat: values[1], MaxLng: values[2], MaxLat: values[3]}
+			}
+		case "center":
+			if values := parseFloats(value); len(values) >= 2 {
+				s.Center = values
+			}
+		case "json":
+			// Vector tile sets list their layers here
+			var spec struct {
+				VectorLayers []struct {
+					ID string `json:"id"`
+				} `json:"vector_layers"`
+			}
+			if err := json.Unmarshal([]byte(value), &spec); err == nil {
+				for _, layer := range spec.VectorLayers {
+					s.VectorLayers = append(s.VectorLayers, layer.ID)
+				}
+			}
+		}
+	}
+
+	if s.Format == "pbf" || s.Format == "mvt" {
+		s.Format = "pbf"
+		s.Type = "vector"
+	}
+	return nil
+}
+
+// Tile returns the tile at z/x/y in XYZ numbering, or nil when the tile set has none.
+// MBTiles stores rows in TMS order, counting from the south.
+func (s *Source) Tile(z, x, y int) ([]byte, error) {
+	var data []byte
+	row := (1 << uint(z)) - 1 - y
+	err := s.db.Get(&data, `SELECT tile_data FROM tiles WHERE zoom_level = ? AND tile_column = ? AND tile_row = ?`, z, x, row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read tile %d/%d/%d of basemap %s: %w", z, x, y, s.Name, err)
+	}
+	return data, nil
+}
+
+// ContentType returns the MIME type of the tile set's tiles
+func (s *Source) ContentType() string {
+	switch s.Format {
+	case "jpg", "jpeg":
+		return "image/jpeg"
+	case "webp":
+		return "image/webp"
+	case "pbf":
+		return "application/vnd.mapbox-vector-tile"
+	}
+	return "image/png"
+}
+
+// IsGzipped reports whether tile data is gzip compressed, as vector tiles in
+// MBTiles usually are
+func IsGzipped(data []byte) bool {
+	return bytes.HasPrefix(data, []byte{0x1f, 0x8b})
+}
+
+// Close closes the underlying file
+func (s *Source) Close() error {
+	return s.db.Close()
+}
+
+// parseFloats parses a comma-separated list of numbers, returning nil if any is invalid
+func parseFloats(value string) []float64 {
+	var values []float64
+	for _, part := range strings.Split(value, ",") {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil
+		}
+		values = append(values, f)
+	}
+	return values
+}
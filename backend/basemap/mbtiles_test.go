@@ -0,0 +1,115 @@
+package basemap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"geoalbum/backend/model"
+)
+
+// tileKey is a tile position in XYZ numbering
+type tileKey struct{ z, x, y int }
+
+// writeMBTiles creates dir/name.mbtiles with the given metadata and tiles, storing
+// the tiles in TMS order as MBTiles does
+func writeMBTiles(t *testing.T, dir, name string, metadata map[string]string, tiles map[tileKey][]byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name+".mbtiles")
+	db, err := sqlx.Open("sqlite", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	db.MustExec(`CREATE TABLE metadata (name TEXT, value TEXT)`)
+	db.MustExec(`CREATE TABLE tiles (zoom_level INTEGER, tile_column INTEGER, tile_row INTEGER, tile_data BLOB)`)
+	for key, value := range metadata {
+		db.MustExec(`INSERT INTO metadata (name, value) VALUES (?, ?)`, key, value)
+	}
+	for key, data := range tiles {
+		db.MustExec(`INSERT INTO tiles (zoom_level, tile_column, tile_row, tile_data) VALUES (?, ?, ?, ?)`,
+			key.z, key.x, (1<<uint(key.z))-1-key.y, data)
+	}
+	return path
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		metadata map[string]string
+		want     model.Basemap
+	}{
+		{"defaults", map[string]string{}, model.Basemap{
+			Name: "defaults", Title: "defaults", Type: "raster", Format: "png", MaxZoom: 22,
+			TileURL: "/api/basemap/defaults/{z}/{x}/{y}",
+		}},
+		{"raster", map[string]string{
+			"name": " Alps ", "description": "Topographic", "attribution": "© OpenTopoMap",
+			"format": "JPG", "minzoom": "2", "maxzoom": "14", "bounds": "5.9, 45.8, 10.5, 47.8", "center": "8.2,46.8,9",
+		}, model.Basemap{
+			Name: "raster", Title: "Alps", Description: "Topographic", Attribution: "© OpenTopoMap",
+			Type: "raster", Format: "jpg", MinZoom: 2, MaxZoom: 14,
+			Bounds: &model.BoundingBox{MinLng: 5.9, MinLat: 45.8, MaxLng: 10.5, MaxLat: 47.8},
+			Center: []float64{8.2, 46.8, 9}, TileURL: "/api/basemap/raster/{z}/{x}/{y}",
+		}},
+		{"vector", map[string]string{
+			"format": "pbf", "json": `{"vector_layers": [{"id": "water"}, {"id": "roads"}]}`,
+		}, model.Basemap{
+			Name: "vector", Title: "vector", Type: "vector", Format: "pbf", MaxZoom: 22,
+			VectorLayers: []string{"water", "roads"}, TileURL: "/api/basemap/vector/{z}/{x}/{y}",
+		}},
+		{"mvt", map[string]string{"format": "mvt", "maxzoom": "x", "bounds": "1,2,3", "json": "{"}, model.Basemap{
+			Name: "mvt", Title: "mvt", Type: "vector", Format: "pbf", MaxZoom: 22,
+			TileURL: "/api/basemap/mvt/{z}/{x}/{y}",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := Open(writeMBTiles(t, dir, tt.name, tt.metadata, nil))
+			require.NoError(t, err)
+			defer source.Close()
+			assert.Equal(t, tt.want, source.Basemap)
+		})
+	}
+
+	_, err := Open(filepath.Join(dir, "missing.mbtiles"))
+	assert.Error(t, err)
+	notMBTiles := filepath.Join(dir, "empty.mbtiles")
+	require.NoError(t, os.WriteFile(notMBTiles, nil, 0o644))
+	_, err = Open(notMBTiles)
+	assert.Error(t, err)
+}
+
+func TestTile(t *testing.T) {
+	source, err := Open(writeMBTiles(t, t.TempDir(), "tiles", map[string]string{"format": "webp"}, map[tileKey][]byte{
+		{0, 0, 0}: []byte("world"),
+		{2, 1, 0}: []byte("north"),
+		{2, 1, 3}: []byte("south"),
+	}))
+	require.NoError(t, err)
+	defer source.Close()
+	assert.Equal(t, "image/webp", source.ContentType())
+
+	tests := []struct {
+		z, x, y int
+		want    []byte
+	}{
+		{0, 0, 0, []byte("world")},
+		{2, 1, 0, []byte("north")},
+		{2, 1, 3, []byte("south")},
+		{2, 2, 0, nil},
+		{5, 0, 0, nil},
+	}
+	for _, tt := range tests {
+		data, err := source.Tile(tt.z, tt.x, tt.y)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, data, "tile %d/%d/%d", tt.z, tt.x, tt.y)
+	}
+
+	assert.True(t, IsGzipped([]byte{0x1f, 0x8b, 0x08}))
+	assert.False(t, IsGzipped([]byte("north")))
+}
@@ -0,0 +1,84 @@
+package basemap
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"geoalbum/backend/logging"
+)
+
+var (
+	mu      sync.RWMutex
+	sources = make(map[string]*Source)
+)
+
+// Initialize opens every .mbtiles file in dir. Files that cannot be opened are
+// logged and skipped; an empty dir disables offline basemaps.
+func Initialize(dir string) error {
+	if dir == "" {
+		return nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.mbtiles"))
+	if err != nil {
+		return fmt.Errorf("failed to list basemaps: %w", err)
+	}
+
+	opened := make(map[string]*Source)
+	for _, path := range paths {
+		source, err := Open(path)
+		if err != nil {
+			logging.WithError(err).WithField("path", path).Warn("Skipping basemap")
+			continue
+		}
+		opened[source.Name] = source
+	}
+
+	mu.Lock()
+	previous := sources
+	sources = opened
+	mu.Unlock()
+	for _, source := range previous {
+		source.Close()
+	}
+
+	logging.WithFields(map[string]interface{}{
+		"dir":      dir,
+		"basemaps": len(opened),
+	}).Info("Offline basemaps loaded")
+	return nil
+}
+
+// Get returns the basemap with the given name, or nil
+func Get(name string) *Source {
+	mu.RLock()
+	defer mu.RUnlock()
+	return sources[name]
+}
+
+// All returns the loaded basemaps ordered by name
+func All() []*Source {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	all := make([]*Source, 0, len(sources))
+	for _, source := range sources {
+		all = append(all, source)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Name < all[j].Name
+	})
+	return all
+}
+
+// HasVector reports whether any loaded basemap holds vector tiles
+func HasVector() bool {
+	for _, source := range All() {
+		if source.Type == "vector" {
+			return true
+		}
+	}
+	return false
+}
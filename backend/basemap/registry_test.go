@@ -0,0 +1,109 @@
+package basemap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"geoalbum/backend/middleware"
+)
+
+// resetSources closes the loaded basemaps when a test ends
+func resetSources(t *testing.T) {
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, source := range sources {
+			source.Close()
+		}
+		sources = make(map[string]*Source)
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	resetSources(t)
+	dir := t.TempDir()
+	writeMBTiles(t, dir, "topo", map[string]string{"format": "png"}, nil)
+	writeMBTiles(t, dir, "streets", map[string]string{"format": "pbf"}, nil)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.mbtiles"), []byte("not sqlite"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o644))
+
+	require.NoError(t, Initialize(dir))
+
+	tests := []struct {
+		name  string
+		found bool
+		kind  string
+	}{
+		{"topo", true, "raster"},
+		{"streets", true, "vector"},
+		{"broken", false, ""},
+		{"notes", false, ""},
+		{"", false, ""},
+		{"../topo", false, ""},
+	}
+	for _, tt := range tests {
+		source := Get(tt.name)
+		if !tt.found {
+			assert.Nil(t, source, tt.name)
+			continue
+		}
+		if assert.NotNil(t, source, tt.name) {
+			assert.Equal(t, tt.kind, source.Type, tt.name)
+		}
+	}
+
+	var names []string
+	for _, source := range All() {
+		names = append(names, source.Name)
+	}
+	assert.Equal(t, []string{"streets", "topo"}, names)
+	assert.True(t, HasVector())
+
+	// Loading again replaces the basemaps; an empty dir keeps them
+	other := t.TempDir()
+	writeMBTiles(t, other, "satellite", map[string]string{"format": "jpg"}, nil)
+	require.NoError(t, Initialize(other))
+	assert.Nil(t, Get("topo"))
+	assert.NotNil(t, Get("satellite"))
+	assert.False(t, HasVector())
+	require.NoError(t, Initialize(""))
+	assert.Len(t, All(), 1)
+}
+
+func TestOfflineBasemapPolicy(t *testing.T) {
+	online := middleware.DefaultContentSecurityPolicy()
+	tests := []struct {
+		name        string
+		formats     []string
+		offlineOnly bool
+		imgSources  []string
+		workers     []string
+	}{
+		{"raster", []string{"png"}, false, online.ImgSources, []string{"'none'"}},
+		{"raster offline", []string{"jpg"}, true, []string{"'self'", "data:", "blob:"}, []string{"'none'"}},
+		{"vector", []string{"pbf"}, false, online.ImgSources, []string{"'self'", "blob:"}},
+		{"mixed offline", []string{"png", "pbf"}, true, []string{"'self'", "data:", "blob:"}, []string{"'self'", "blob:"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetSources(t)
+			dir := t.TempDir()
+			for i, format := range tt.formats {
+				writeMBTiles(t, dir, string(rune('a'+i)), map[string]string{"format": format}, nil)
+			}
+			require.NoError(t, Initialize(dir))
+
+			policy := middleware.DefaultContentSecurityPolicy().WithOfflineBasemaps(HasVector(), tt.offlineOnly)
+			assert.Equal(t, tt.imgSources, policy.ImgSources)
+			assert.Equal(t, tt.workers, policy.WorkerSources)
+		})
+	}
+
+	// The policy adjusted is left as it was
+	online.WithOfflineBasemaps(true, true)
+	assert.Equal(t, middleware.DefaultContentSecurityPolicy(), online)
+}
@@ -0,0 +1,85 @@
+package backend
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"geoalbum/backend/dao"
+	"geoalbum/backend/database"
+	"geoalbum/backend/logging"
+	"geoalbum/backend/model"
+	"geoalbum/backend/service"
+)
+
+// RunTakeoutImport runs the import-takeout command, which imports a Google Takeout
+// export from the server's disk into a user's albums, and returns the exit code
+func RunTakeoutImport(args []string) int {
+	flags := flag.NewFlagSet("import-takeout", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: geoalbum import-takeout -user NAME [options] PATH")
+		fmt.Fprintln(flags.Output(), "PATH is a Google Takeout ZIP file or the folder it was extracted to.")
+		flags.PrintDefaults()
+	}
+	username := flags.String("user", "", "user to import the photos for")
+	group := flags.String("group", service.TakeoutGroupFolder, "group photos into albums by Google Photos album (folder) or by place and time (cluster)")
+	radiusKm := flags.Float64("radius-km", service.DefaultTakeoutRadiusKm, "distance from a cluster's centre within which photos join it")
+	maxGap := flags.Duration("max-gap", service.DefaultTakeoutMaxGap, "time without photos after which a cluster ends")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *username == "" || flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	if err := database.Initialize(); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to initialize database:", err)
+		return 1
+	}
+	defer database.Close()
+	if err := service.NewGeocodeService().EnsureGazetteer(); err != nil {
+		logging.WithError(err).Error("Failed to load gazetteer")
+	}
+
+	user, err := dao.NewUserDAO().GetByUsername(*username)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to look up user:", err)
+		return 1
+	}
+	if user == nil {
+		fmt.Fprintf(os.Stderr, "User %q not found\n", *username)
+		return 1
+	}
+
+	lastReport := time.Now()
+	job, err := service.NewTakeoutService().ImportTakeout(user.ID, flags.Arg(0), service.TakeoutImportOptions{
+		Group:    *group,
+		RadiusKm: *radiusKm,
+		MaxGap:   *maxGap,
+	}, func(job model.TakeoutImportJob) {
+		if time.Since(lastReport) >= time.Second || job.Done == job.Total {
+			fmt.Printf("%d/%d photos: %d imported, %d skipped, %d failed\n", job.Done, job.Total, job.Imported, job.Skipped, job.Failed)
+			lastReport = time.Now()
+		}
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Import failed:", err)
+		return 1
+	}
+
+	for _, message := range job.Errors {
+		fmt.Fprintln(os.Stderr, message)
+	}
+	if job.Status == model.ImportJobFailed {
+		fmt.Fprintln(os.Stderr, "Import failed:", job.Error)
+		return 1
+	}
+	fmt.Printf("Imported %d of %d photos: %d skipped, %d failed\n", job.Imported, job.Total, job.Skipped, job.Failed)
+	fmt.Printf("Albums: %d created, %d reused; unsupported files left out: %d\n", job.AlbumsCreated, job.AlbumsReused, job.Unsupported)
+	if job.Failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"geoalbum/backend/archive"
+	"geoalbum/backend/common"
+	"geoalbum/backend/service"
+)
+
+// MaxAccountUploadSize is the largest account archive that can be uploaded for import
+const MaxAccountUploadSize = 8 << 30
+
+type AccountController struct {
+	accountService *service.AccountService
+}
+
+func NewAccountController() *AccountController {
+	return &AccountController{
+		accountService: service.NewAccountService(),
+	}
+}
+
+// ExportAccount starts building an archive of the user's albums, photos, paths, trips,
+// tags and saved places. GetExportJob reports progress and, once done, the link to
+// download the archive from.
+func (ctrl *AccountController) ExportAccount(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	job, err := ctrl.accountService.StartAccountExport(userID)
+	if err != nil {
+		if errors.Is(err, service.ErrExportInProgress) {
+			common.ConflictErrorResponse(c, "EXPORT_IN_PROGRESS", "An account export is already running", nil)
+			return
+		}
+		logrus.WithError(err).Error("Failed to start account export")
+		common.InternalServerErrorResponse(c, "ACCOUNT_EXPORT_FAILED", "Failed to start account export")
+		return
+	}
+
+	common.SuccessResponse(c, http.StatusAccepted, job)
+}
+
+// GetExportJob reports the progress of an account export
+func (ctrl *AccountController) GetExportJob(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	job, err := ctrl.accountService.GetAccountExport(c.Param("id"), userID)
+	if err != nil {
+		if errors.Is(err, service.ErrExportNotFound) {
+			common.NotFoundErrorResponse(c, "EXPORT_JOB_NOT_FOUND", "Export job not found")
+			return
+		}
+		logrus.WithError(err).Error("Failed to get account export job")
+		common.InternalServerErrorResponse(c, "ACCOUNT_EXPORT_FAILED", "Failed to get export job")
+		return
+	}
+
+	common.SuccessResponse(c, http.StatusOK, job)
+}
+
+// DownloadExport serves a finished account archive. It needs no authentication, as
+// the link is signed and expires.
+func (ctrl *AccountController) DownloadExport(c *gin.Context) {
+	path, err := ctrl.accountService.OpenAccountExport(c.Param("id"), c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrExportLinkInvalid):
+			common.ForbiddenErrorResponse(c, "INVALID_DOWNLOAD_LINK", "Invalid download link")
+		case errors.Is(err, service.ErrExportLinkExpired):
+			common.ErrorResponse(c, http.StatusGone, "DOWNLOAD_LINK_EXPIRED", "Download link has expired", nil)
+		case errors.Is(err, service.ErrExportNotFound):
+			common.NotFoundErrorResponse(c, "EXPORT_NOT_FOUND", "Archive is no longer available")
+		default:
+			logrus.WithError(err).Error("Failed to open account export")
+			common.InternalServerErrorResponse(c, "ACCOUNT_EXPORT_FAILED", "Failed to open archive")
+		}
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-store")
+	c.FileAttachment(path, "geoalbum-archive.zip")
+}
+
+// ImportAccount starts importing an uploaded account archive, as made by
+// ExportAccount here or on another instance. Progress is reported by GetImportJob.
+func (ctrl *AccountController) ImportAccount(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		common.ValidationErrorResponse(c, "No archive file provided")
+		return
+	}
+	source, err := saveTakeoutUpload(header)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to save uploaded account archive")
+		common.InternalServerErrorResponse(c, "ACCOUNT_IMPORT_FAILED", "Failed to save uploaded file")
+		return
+	}
+
+	job, err := ctrl.accountService.StartAccountImport(userID, source, true)
+	if err != nil {
+		if !errors.Is(err, archive.ErrNotArchive) {
+			logrus.WithError(err).Warn("Rejected account archive")
+		}
+		common.ErrorResponse(c, http.StatusBadRequest, "ACCOUNT_IMPORT_FAILED", "Failed to import account archive", err.Error())
+		return
+	}
+
+	common.SuccessResponse(c, http.StatusAccepted, job)
+}
+
+// GetImportJob reports the progress of an account import
+func (ctrl *AccountController) GetImportJob(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	job, err := ctrl.accountService.GetAccountImport(c.Param("id"), userID)
+	if err != nil {
+		if errors.Is(err, service.ErrImportJobNotFound) {
+			common.NotFoundErrorResponse(c, "IMPORT_JOB_NOT_FOUND", "Import job not found")
+			return
+		}
+		logrus.WithError(err).Error("Failed to get account import job")
+		common.InternalServerErrorResponse(c, "ACCOUNT_IMPORT_FAILED", "Failed to get import job")
+		return
+	}
+
+	common.SuccessResponse(c, http.StatusOK, job)
+}
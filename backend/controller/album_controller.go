@@ -2,41 +2,66 @@ package controller
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 
 	"geoalbum/backend/common"
+	"geoalbum/backend/datum"
+	"geoalbum/backend/model"
 	"geoalbum/backend/service"
 )
 
 type AlbumController struct {
 	albumService *service.AlbumService
+	tagService   *service.TagService
 }
 
 func NewAlbumController() *AlbumController {
 	return &AlbumController{
 		albumService: service.NewAlbumService(),
+		tagService:   service.NewTagService(),
 	}
 }
 
 type CreateAlbumRequest struct {
-	Title       string    `json:"title" binding:"required,max=200"`
-	Description string    `json:"description" binding:"max=2000"`
-	Latitude    float64   `json:"latitude" binding:"required,min=-90,max=90"`
-	Longitude   float64   `json:"longitude" binding:"required,min=-180,max=180"`
-	CreatedAt   time.Time `json:"created_at"`
+	Title       string     `json:"title" binding:"required,max=200"`
+	Description string     `json:"description" binding:"max=2000"`
+	Latitude    float64    `json:"latitude" binding:"required,min=-90,max=90"`
+	Longitude   float64    `json:"longitude" binding:"required,min=-180,max=180"`
+	CreatedAt   time.Time  `json:"created_at"`
+	StartAt     *time.Time `json:"start_at"`
+	EndAt       *time.Time `json:"end_at"`
+	Timezone    string     `json:"timezone" binding:"max=64"`
+	CRS         string     `json:"crs"` // datum of latitude/longitude; defaults to wgs84
 }
 
+// UpdateAlbumRequest is a partial update: omitted fields are left unchanged
 type UpdateAlbumRequest struct {
-	Title       string `json:"title" binding:"max=200"`
-	Description string `json:"description" binding:"max=2000"`
+	Title        *string    `json:"title" binding:"omitempty,max=200"`
+	Description  *string    `json:"description" binding:"omitempty,max=2000"`
+	Latitude     *float64   `json:"latitude" binding:"omitempty,min=-90,max=90"`
+	Longitude    *float64   `json:"longitude" binding:"omitempty,min=-180,max=180"`
+	CreatedAt    *time.Time `json:"created_at"`
+	StartAt      *time.Time `json:"start_at"`
+	EndAt        *time.Time `json:"end_at"`
+	AutoDates    bool       `json:"auto_dates"`
+	Timezone     *string    `json:"timezone" binding:"omitempty,max=64"`
+	CoverPhotoID *string    `json:"cover_photo_id"`
+	CRS          string     `json:"crs"` // datum of latitude/longitude; defaults to wgs84
 }
 
 type GetAlbumsQuery struct {
 	StartDate *time.Time `form:"start_date" time_format:"2006-01-02T15:04:05Z07:00"`
 	EndDate   *time.Time `form:"end_date" time_format:"2006-01-02T15:04:05Z07:00"`
+	Tags      string     `form:"tags"`
+	TagMode   string     `form:"tag_mode" binding:"omitempty,oneof=and or"`
+	Country   string     `form:"country" binding:"max=100"`
+	Region    string     `form:"region" binding:"max=100"`
+	City      string     `form:"city" binding:"max=100"`
+	Search    string     `form:"q" binding:"max=200"`
 }
 
 // CreateAlbum creates a new album
@@ -52,6 +77,16 @@ func (ctrl *AlbumController) CreateAlbum(c *gin.Context) {
 		common.ValidationErrorResponse(c, err.Error())
 		return
 	}
+	inputCRS, err := datum.Parse(req.CRS)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	crs, err := crsQuery(c)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
 
 	// Use provided created_at or current time
 	createdAt := req.CreatedAt
@@ -59,13 +94,24 @@ func (ctrl *AlbumController) CreateAlbum(c *gin.Context) {
 		createdAt = time.Now()
 	}
 
-	album, err := ctrl.albumService.CreateAlbum(userID, req.Title, req.Description, req.Latitude, req.Longitude, createdAt)
+	album, err := ctrl.albumService.CreateAlbum(userID, service.NewAlbum{
+		Title:       req.Title,
+		Description: req.Description,
+		Latitude:    req.Latitude,
+		Longitude:   req.Longitude,
+		CreatedAt:   createdAt,
+		StartAt:     req.StartAt,
+		EndAt:       req.EndAt,
+		Timezone:    req.Timezone,
+		Datum:       inputCRS,
+	})
 	if err != nil {
 		logrus.WithError(err).Error("Failed to create album")
 		common.InternalServerErrorResponse(c, "ALBUM_CREATION_FAILED", "Failed to create album")
 		return
 	}
 
+	convertAlbum(album, crs)
 	common.SuccessResponse(c, http.StatusCreated, album)
 }
 
@@ -77,38 +123,43 @@ func (ctrl *AlbumController) GetAlbums(c *gin.Context) {
 		return
 	}
 
+	crs, err := crsQuery(c)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
 	var query GetAlbumsQuery
 	if err := c.ShouldBindQuery(&query); err != nil {
 		common.ValidationErrorResponse(c, err.Error())
 		return
 	}
 
-	var albums []interface{}
-
-	if query.StartDate != nil || query.EndDate != nil {
-		albumList, err := ctrl.albumService.GetAlbumsByUserIDAndTimeRange(userID, query.StartDate, query.EndDate)
-		if err != nil {
-			logrus.WithError(err).Error("Failed to get albums by time range")
-			common.InternalServerErrorResponse(c, "ALBUMS_RETRIEVAL_FAILED", "Failed to retrieve albums")
-			return
-		}
-		albums = make([]interface{}, len(albumList))
-		for i, album := range albumList {
-			albums[i] = album
-		}
-	} else {
-		albumList, err := ctrl.albumService.GetAlbumsByUserID(userID)
-		if err != nil {
-			logrus.WithError(err).Error("Failed to get albums")
-			common.InternalServerErrorResponse(c, "ALBUMS_RETRIEVAL_FAILED", "Failed to retrieve albums")
-			return
-		}
-		albums = make([]interface{}, len(albumList))
-		for i, album := range albumList {
-			albums[i] = album
-		}
+	tags, err := ctrl.tagService.ParseTagFilter(query.Tags)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	filter := model.AlbumFilter{
+		StartDate:    query.StartDate,
+		EndDate:      query.EndDate,
+		Tags:         tags,
+		MatchAllTags: query.TagMode != "or",
+		Country:      strings.TrimSpace(query.Country),
+		Region:       strings.TrimSpace(query.Region),
+		City:         strings.TrimSpace(query.City),
+		Search:       strings.TrimSpace(query.Search),
 	}
 
+	albums, err := ctrl.albumService.GetAlbumsFiltered(userID, filter)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get albums")
+		common.InternalServerErrorResponse(c, "ALBUMS_RETRIEVAL_FAILED", "Failed to retrieve albums")
+		return
+	}
+
+	convertAlbums(albums, crs)
 	response := gin.H{
 		"albums": albums,
 		"count":  len(albums),
@@ -125,6 +176,12 @@ func (ctrl *AlbumController) GetAlbum(c *gin.Context) {
 		return
 	}
 
+	crs, err := crsQuery(c)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
 	albumID := c.Param("id")
 	album, err := ctrl.albumService.GetAlbumByID(albumID, userID)
 	if err != nil {
@@ -133,6 +190,7 @@ func (ctrl *AlbumController) GetAlbum(c *gin.Context) {
 		return
 	}
 
+	convertAlbum(album, crs)
 	common.SuccessResponse(c, http.StatusOK, album)
 }
 
@@ -150,14 +208,36 @@ func (ctrl *AlbumController) UpdateAlbum(c *gin.Context) {
 		common.ValidationErrorResponse(c, err.Error())
 		return
 	}
+	inputCRS, err := datum.Parse(req.CRS)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	crs, err := crsQuery(c)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
 
-	album, err := ctrl.albumService.UpdateAlbum(albumID, userID, req.Title, req.Description)
+	album, err := ctrl.albumService.UpdateAlbum(albumID, userID, service.AlbumUpdate{
+		Title:        req.Title,
+		Description:  req.Description,
+		Latitude:     req.Latitude,
+		Longitude:    req.Longitude,
+		CreatedAt:    req.CreatedAt,
+		StartAt:      req.StartAt,
+		EndAt:        req.EndAt,
+		AutoDates:    req.AutoDates,
+		Timezone:     req.Timezone,
+		CoverPhotoID: req.CoverPhotoID,
+		Datum:        inputCRS,
+	})
 	if err != nil {
-		logrus.WithError(err).Error("Failed to update album")
-		common.InternalServerErrorResponse(c, "ALBUM_UPDATE_FAILED", "Failed to update album")
+		serviceErrorResponse(c, err, "ALBUM_UPDATE_FAILED", "Failed to update album")
 		return
 	}
 
+	convertAlbum(album, crs)
 	common.SuccessResponse(c, http.StatusOK, album)
 }
 
@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"geoalbum/backend/common"
+	"geoalbum/backend/service"
+)
+
+type BasemapController struct {
+	basemapService *service.BasemapService
+}
+
+func NewBasemapController() *BasemapController {
+	return &BasemapController{
+		basemapService: service.NewBasemapService(),
+	}
+}
+
+// GetBasemaps lists the offline basemaps with their zoom range and bounds
+func (ctrl *BasemapController) GetBasemaps(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	basemaps := ctrl.basemapService.ListBasemaps()
+	common.SuccessResponse(c, http.StatusOK, gin.H{
+		"basemaps": basemaps,
+		"count":    len(basemaps),
+	})
+}
+
+// GetBasemapTile serves one tile of an offline basemap. The y coordinate may carry a
+// file extension such as .png or .pbf.
+func (ctrl *BasemapController) GetBasemapTile(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	rawY := c.Param("y")
+	if dot := strings.IndexByte(rawY, '.'); dot >= 0 {
+		rawY = rawY[:dot]
+	}
+	z, x, y, err := parseTileCoordinates(c.Param("z"), c.Param("x"), rawY)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	if z > service.MaxTileZoom || x >= 1<<uint(z) || y >= 1<<uint(z) {
+		common.ValidationErrorResponse(c, "tile is out of range")
+		return
+	}
+
+	tile, err := ctrl.basemapService.GetTile(c.Param("name"), z, x, y)
+	if err != nil {
+		if errors.Is(err, service.ErrBasemapNotFound) {
+			common.NotFoundErrorResponse(c, "BASEMAP_NOT_FOUND", "Basemap not found")
+			return
+		}
+		logrus.WithError(err).Error("Failed to get basemap tile")
+		common.InternalServerErrorResponse(c, "TILE_RETRIEVAL_FAILED", "Failed to retrieve tile")
+		return
+	}
+	if tile == nil {
+		common.NotFoundErrorResponse(c, "TILE_NOT_FOUND", "Tile not found")
+		return
+	}
+
+	// Tiles of a file only change when it is replaced, which requires a restart
+	c.Header("Cache-Control", "private, max-age=86400")
+	if tile.Gzipped {
+		c.Header("Content-Encoding", "gzip")
+	}
+	c.Data(http.StatusOK, tile.ContentType, tile.Data)
+}
@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"geoalbum/backend/datum"
+	"geoalbum/backend/model"
+	"geoalbum/backend/polyline"
+)
+
+// Coordinates are stored in WGS-84. Endpoints returning coordinates accept
+// crs=wgs84|gcj02|bd09 and convert them on output, so pins line up on Chinese
+// providers' tiles; bounding boxes given to those endpoints are read in the same datum.
+
+// crsQuery parses the crs query parameter; it defaults to WGS-84
+func crsQuery(c *gin.Context) (datum.Datum, error) {
+	return datum.Parse(c.Query("crs"))
+}
+
+// convertAlbum converts an album's coordinates, and those of its photos, from WGS-84
+func convertAlbum(album *model.Album, d datum.Datum) {
+	if album == nil || d == datum.WGS84 {
+		return
+	}
+	album.Latitude, album.Longitude = datum.FromWGS84(album.Latitude, album.Longitude, d)
+	convertPhotos(album.Photos, d)
+}
+
+func convertAlbums(albums []model.Album, d datum.Datum) {
+	for i := range albums {
+		convertAlbum(&albums[i], d)
+	}
+}
+
+// convertPhoto converts a photo's GPS position from WGS-84
+func convertPhoto(photo *model.Photo, d datum.Datum) {
+	if photo == nil || d == datum.WGS84 || photo.Latitude == nil || photo.Longitude == nil {
+		return
+	}
+	lat, lng := datum.FromWGS84(*photo.Latitude, *photo.Longitude, d)
+	photo.Latitude, photo.Longitude = &lat, &lng
+}
+
+func convertPhotos(photos []model.Photo, d datum.Datum) {
+	for i := range photos {
+		convertPhoto(&photos[i], d)
+	}
+}
+
+// convertPath converts the coordinates of a path's albums and geometry from WGS-84
+func convertPath(path *model.Path, d datum.Datum) {
+	if path == nil {
+		return
+	}
+	convertAlbum(path.FromAlbum, d)
+	convertAlbum(path.ToAlbum, d)
+	if path.Geometry == "" || d == datum.WGS84 {
+		return
+	}
+	if line, err := polyline.Decode(path.Geometry); err == nil {
+		for i := range line {
+			line[i].Lat, line[i].Lng = datum.FromWGS84(line[i].Lat, line[i].Lng, d)
+		}
+		path.Geometry = polyline.Encode(line)
+	}
+}
+
+func convertPaths(paths []model.Path, d datum.Datum) {
+	for i := range paths {
+		convertPath(&paths[i], d)
+	}
+}
+
+// convertTrip converts the coordinates of a trip's stop albums from WGS-84
+func convertTrip(trip *model.Trip, d datum.Datum) {
+	if trip == nil {
+		return
+	}
+	for i := range trip.Stops {
+		convertAlbum(trip.Stops[i].Album, d)
+	}
+}
+
+// convertSavedPlace converts a place's centre or polygon from WGS-84
+func convertSavedPlace(place *model.SavedPlace, d datum.Datum) {
+	if place == nil || d == datum.WGS84 {
+		return
+	}
+	if place.Latitude != nil && place.Longitude != nil {
+		lat, lng := datum.FromWGS84(*place.Latitude, *place.Longitude, d)
+		place.Latitude, place.Longitude = &lat, &lng
+	}
+	polygon := make([][2]float64, len(place.Polygon))
+	for i, vertex := range place.Polygon {
+		lat, lng := datum.FromWGS84(vertex[1], vertex[0], d)
+		polygon[i] = [2]float64{lng, lat}
+	}
+	place.Polygon = polygon
+}
+
+func convertSavedPlaces(places []model.SavedPlace, d datum.Datum) {
+	for i := range places {
+		convertSavedPlace(&places[i], d)
+	}
+}
+
+// convertGeocodeResults converts gazetteer matches from WGS-84
+func convertGeocodeResults(results []model.GeocodeResult, d datum.Datum) {
+	if d == datum.WGS84 {
+		return
+	}
+	for i := range results {
+		results[i].Latitude, results[i].Longitude = datum.FromWGS84(results[i].Latitude, results[i].Longitude, d)
+	}
+}
+
+// convertHeatmap converts heatmap cell centres from WGS-84
+func convertHeatmap(heatmap *model.Heatmap, d datum.Datum) {
+	if heatmap == nil || d == datum.WGS84 {
+		return
+	}
+	for i := range heatmap.Points {
+		point := &heatmap.Points[i]
+		point.Latitude, point.Longitude = datum.FromWGS84(point.Latitude, point.Longitude, d)
+	}
+}
+
+// boundingBoxToWGS84 converts a box given in d to WGS-84 by converting its corners;
+// the offsets change by only metres across a viewport
+func boundingBoxToWGS84(box *model.BoundingBox, d datum.Datum) *model.BoundingBox {
+	if box == nil || d == datum.WGS84 {
+		return box
+	}
+	minLat, minLng := datum.ToWGS84(box.MinLat, box.MinLng, d)
+	maxLat, maxLng := datum.ToWGS84(box.MaxLat, box.MaxLng, d)
+	return &model.BoundingBox{MinLat: minLat, MinLng: minLng, MaxLat: maxLat, MaxLng: maxLng}
+}
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"geoalbum/backend/common"
+	"geoalbum/backend/service"
+)
+
+// serviceErrors are the service errors reported with their own status. Invalid input
+// is reported as a validation error with its description.
+var serviceErrors = []struct {
+	err     error
+	status  int
+	code    string
+	message string
+}{
+	{service.ErrInvalidAlbum, http.StatusBadRequest, "", ""},
+	{service.ErrAlbumNotFound, http.StatusNotFound, "ALBUM_NOT_FOUND", "Album not found"},
+	{service.ErrAlbumAccessDenied, http.StatusForbidden, "ACCESS_DENIED", "Album does not belong to user"},
+	{service.ErrInvalidPhoto, http.StatusBadRequest, "", ""},
+	{service.ErrPhotoNotFound, http.StatusNotFound, "PHOTO_NOT_FOUND", "Photo not found"},
+	{service.ErrInvalidTag, http.StatusBadRequest, "", ""},
+	{service.ErrTagNotFound, http.StatusNotFound, "TAG_NOT_FOUND", "Tag not found"},
+	{service.ErrTagAccessDenied, http.StatusForbidden, "ACCESS_DENIED", "Tag does not belong to user"},
+	{service.ErrTagExists, http.StatusConflict, "TAG_EXISTS", "A tag with this name already exists"},
+	{service.ErrInvalidTrip, http.StatusBadRequest, "", ""},
+	{service.ErrTripNotFound, http.StatusNotFound, "TRIP_NOT_FOUND", "Trip not found"},
+	{service.ErrTripAccessDenied, http.StatusForbidden, "ACCESS_DENIED", "Trip does not belong to user"},
+	{service.ErrInvalidSavedPlace, http.StatusBadRequest, "", ""},
+	{service.ErrSavedPlaceNotFound, http.StatusNotFound, "PLACE_NOT_FOUND", "Saved place not found"},
+	{service.ErrSavedPlaceAccessDenied, http.StatusForbidden, "ACCESS_DENIED", "Saved place does not belong to user"},
+	{service.ErrSavedPlaceExists, http.StatusConflict, "PLACE_EXISTS", "A saved place with this name already exists"},
+	{service.ErrInvalidPlaceFile, http.StatusBadRequest, "", ""},
+}
+
+// serviceErrorResponse reports err by the service error it matches; any other error
+// is logged and reported as an internal error with code and message
+func serviceErrorResponse(c *gin.Context, err error, code, message string) {
+	for _, known := range serviceErrors {
+		if !errors.Is(err, known.err) {
+			continue
+		}
+		if known.status == http.StatusBadRequest {
+			common.ValidationErrorResponse(c, err.Error())
+		} else {
+			common.ErrorResponse(c, known.status, known.code, known.message, nil)
+		}
+		return
+	}
+	logrus.WithError(err).Error(message)
+	common.InternalServerErrorResponse(c, code, message)
+}
@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"geoalbum/backend/common"
+	"geoalbum/backend/service"
+)
+
+type ExportController struct {
+	exportService *service.ExportService
+}
+
+func NewExportController() *ExportController {
+	return &ExportController{
+		exportService: service.NewExportService(),
+	}
+}
+
+type MapExportQuery struct {
+	Format    string     `form:"format" binding:"required,oneof=geojson kml gpx"`
+	StartDate *time.Time `form:"start_date" time_format:"2006-01-02T15:04:05Z07:00"`
+	EndDate   *time.Time `form:"end_date" time_format:"2006-01-02T15:04:05Z07:00"`
+	TripID    string     `form:"trip_id"`
+}
+
+// mapExportTypes maps export formats to their content type and file extension
+var mapExportTypes = map[string][2]string{
+	service.MapFormatGeoJSON: {"application/geo+json", "geojson"},
+	service.MapFormatKML:     {"application/vnd.google-earth.kml+xml", "kml"},
+	service.MapFormatGPX:     {"application/gpx+xml", "gpx"},
+}
+
+// ExportMap downloads the user's albums, paths and trips as a GeoJSON, KML or GPX
+// file, optionally limited to a date range or a single trip
+func (ctrl *ExportController) ExportMap(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var query MapExportQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	if query.StartDate != nil && query.EndDate != nil && query.EndDate.Before(*query.StartDate) {
+		common.ValidationErrorResponse(c, "end_date must not be before start_date")
+		return
+	}
+
+	var buf bytes.Buffer
+	err := ctrl.exportService.ExportMap(userID, service.MapExportOptions{
+		Format:    query.Format,
+		StartDate: query.StartDate,
+		EndDate:   query.EndDate,
+		TripID:    query.TripID,
+		BaseURL:   requestBaseURL(c),
+	}, &buf)
+	if err != nil {
+		if errors.Is(err, service.ErrTripNotFound) {
+			common.NotFoundErrorResponse(c, "TRIP_NOT_FOUND", "Trip not found")
+			return
+		}
+		logrus.WithError(err).Error("Failed to export map")
+		common.InternalServerErrorResponse(c, "MAP_EXPORT_FAILED", "Failed to export map")
+		return
+	}
+
+	exportType := mapExportTypes[query.Format]
+	filename := fmt.Sprintf("geoalbum-%s.%s", time.Now().Format("20060102"), exportType[1])
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, exportType[0], buf.Bytes())
+}
+
+// requestBaseURL returns the scheme and host the request was made to, honouring
+// X-Forwarded-Proto from a reverse proxy
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host
+}
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"geoalbum/backend/common"
+	"geoalbum/backend/model"
+	"geoalbum/backend/service"
+)
+
+type GeocodeController struct {
+	geocodeService *service.GeocodeService
+}
+
+func NewGeocodeController() *GeocodeController {
+	return &GeocodeController{
+		geocodeService: service.NewGeocodeService(),
+	}
+}
+
+type GeocodeQuery struct {
+	Query string `form:"q" binding:"required,max=100"`
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=50"`
+	Lang  string `form:"lang" binding:"omitempty,oneof=en zh"`
+	BBox  string `form:"bbox"`
+}
+
+// Geocode looks up places by name in the offline gazetteer
+func (ctrl *GeocodeController) Geocode(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	crs, err := crsQuery(c)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	var query GeocodeQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	viewport, err := parseBoundingBox(query.BBox)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = 10
+	}
+
+	results, err := ctrl.geocodeService.Search(service.GeocodeQuery{
+		Text:     query.Query,
+		Limit:    limit,
+		Lang:     query.Lang,
+		Viewport: boundingBoxToWGS84(viewport, crs),
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed to geocode")
+		common.InternalServerErrorResponse(c, "GEOCODE_FAILED", "Failed to look up place")
+		return
+	}
+
+	convertGeocodeResults(results, crs)
+	common.SuccessResponse(c, http.StatusOK, gin.H{
+		"results": results,
+		"count":   len(results),
+	})
+}
+
+// parseBoundingBox parses "minLng,minLat,maxLng,maxLat". An empty string yields nil;
+// minLng > maxLng denotes a box crossing the antimeridian.
+func parseBoundingBox(raw string) (*model.BoundingBox, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(raw, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid bbox: expected minLng,minLat,maxLng,maxLat")
+	}
+	values := make([]float64, 4)
+	for i, part := range parts {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bbox: %q is not a number", part)
+		}
+		values[i] = value
+	}
+
+	box := &model.BoundingBox{MinLng: values[0], MinLat: values[1], MaxLng: values[2], MaxLat: values[3]}
+	if box.MinLat < -90 || box.MaxLat > 90 || box.MinLat > box.MaxLat {
+		return nil, fmt.Errorf("invalid bbox: latitudes must be within -90 to 90 and ordered")
+	}
+	if box.MinLng < -180 || box.MinLng > 180 || box.MaxLng < -180 || box.MaxLng > 180 {
+		return nil, fmt.Errorf("invalid bbox: longitudes must be within -180 to 180")
+	}
+	return box, nil
+}
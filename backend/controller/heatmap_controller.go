@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"geoalbum/backend/common"
+	"geoalbum/backend/service"
+)
+
+type HeatmapController struct {
+	heatmapService *service.HeatmapService
+}
+
+func NewHeatmapController() *HeatmapController {
+	return &HeatmapController{
+		heatmapService: service.NewHeatmapService(),
+	}
+}
+
+type HeatmapQuery struct {
+	BBox string `form:"bbox"`
+	Zoom int    `form:"zoom" binding:"min=0,max=22"`
+}
+
+// GetHeatmap returns the density of the user's photo locations as weighted grid points
+func (ctrl *HeatmapController) GetHeatmap(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	crs, err := crsQuery(c)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	var query HeatmapQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	bbox, err := parseBoundingBox(query.BBox)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	heatmap, err := ctrl.heatmapService.GetHeatmap(userID, boundingBoxToWGS84(bbox, crs), query.Zoom)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get heatmap")
+		common.InternalServerErrorResponse(c, "HEATMAP_RETRIEVAL_FAILED", "Failed to retrieve heatmap")
+		return
+	}
+
+	convertHeatmap(heatmap, crs)
+	common.SuccessResponse(c, http.StatusOK, heatmap)
+}
@@ -0,0 +1,277 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"geoalbum/backend/common"
+	"geoalbum/backend/datum"
+	"geoalbum/backend/service"
+)
+
+type ImportController struct {
+	importService  *service.ImportService
+	takeoutService *service.TakeoutService
+}
+
+func NewImportController() *ImportController {
+	return &ImportController{
+		importService:  service.NewImportService(),
+		takeoutService: service.NewTakeoutService(),
+	}
+}
+
+// GPXImportRequest holds the form fields sent with a GPX file
+type GPXImportRequest struct {
+	MatchRadiusM        float64 `form:"match_radius_m" binding:"omitempty,min=10,max=10000"`  // defaults to 200
+	CameraOffsetSeconds int     `form:"camera_offset_seconds" binding:"min=-86400,max=86400"` // camera clock minus GPS time
+	MaxGapMinutes       int     `form:"max_gap_minutes" binding:"omitempty,min=1,max=1440"`   // defaults to 30
+	CreateAlbums        *bool   `form:"create_albums"`                                        // defaults to true
+	Geotag              *bool   `form:"geotag"`                                               // defaults to true
+}
+
+// ImportGPX imports the waypoints, routes and tracks of an uploaded GPX file
+func (ctrl *ImportController) ImportGPX(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var req GPXImportRequest
+	if err := c.ShouldBind(&req); err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	crs, err := crsQuery(c)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		common.ValidationErrorResponse(c, "No GPX file provided")
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to open uploaded GPX file")
+		common.InternalServerErrorResponse(c, "GPX_IMPORT_FAILED", "Failed to read GPX file")
+		return
+	}
+	defer file.Close()
+
+	options := service.GPXImportOptions{
+		MatchRadiusM: 200,
+		CameraOffset: time.Duration(req.CameraOffsetSeconds) * time.Second,
+		MaxGap:       30 * time.Minute,
+		CreateAlbums: req.CreateAlbums == nil || *req.CreateAlbums,
+		Geotag:       req.Geotag == nil || *req.Geotag,
+	}
+	if req.MatchRadiusM > 0 {
+		options.MatchRadiusM = req.MatchRadiusM
+	}
+	if req.MaxGapMinutes > 0 {
+		options.MaxGap = time.Duration(req.MaxGapMinutes) * time.Minute
+	}
+
+	result, err := ctrl.importService.ImportGPX(userID, file, options)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to import GPX file")
+		common.ErrorResponse(c, http.StatusBadRequest, "GPX_IMPORT_FAILED", "Failed to import GPX file", err.Error())
+		return
+	}
+
+	convertAlbums(result.AlbumsCreated, crs)
+	common.SuccessResponse(c, http.StatusOK, result)
+}
+
+// PlaceImportRequest holds the form fields sent with a GeoJSON or KML file
+type PlaceImportRequest struct {
+	Format       string  `form:"format" binding:"omitempty,oneof=geojson kml"`        // detected from the file when omitted
+	CRS          string  `form:"crs"`                                                 // datum of the file's coordinates; defaults to wgs84
+	MatchRadiusM float64 `form:"match_radius_m" binding:"omitempty,min=10,max=10000"` // defaults to 200
+	CreatePaths  *bool   `form:"create_paths"`                                        // defaults to true
+}
+
+// ImportPlaces imports the points of an uploaded GeoJSON or KML file as albums and
+// its lines as paths, reporting the outcome of each feature. Features imported before
+// one that fails are kept.
+func (ctrl *ImportController) ImportPlaces(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var req PlaceImportRequest
+	if err := c.ShouldBind(&req); err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	inputCRS, err := datum.Parse(req.CRS)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		common.ValidationErrorResponse(c, "No GeoJSON or KML file provided")
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to open uploaded place file")
+		common.InternalServerErrorResponse(c, "PLACE_IMPORT_FAILED", "Failed to read file")
+		return
+	}
+	defer file.Close()
+
+	options := service.PlaceImportOptions{
+		Format:       req.Format,
+		Datum:        inputCRS,
+		MatchRadiusM: 200,
+		CreatePaths:  req.CreatePaths == nil || *req.CreatePaths,
+	}
+	if options.Format == "" {
+		switch strings.ToLower(filepath.Ext(header.Filename)) {
+		case ".geojson", ".json":
+			options.Format = service.PlaceFormatGeoJSON
+		case ".kml":
+			options.Format = service.PlaceFormatKML
+		}
+	}
+	if req.MatchRadiusM > 0 {
+		options.MatchRadiusM = req.MatchRadiusM
+	}
+
+	result, err := ctrl.importService.ImportPlaces(userID, file, options)
+	if err != nil {
+		serviceErrorResponse(c, err, "PLACE_IMPORT_FAILED", "Failed to import places")
+		return
+	}
+
+	common.SuccessResponse(c, http.StatusOK, result)
+}
+
+// MaxTakeoutUploadSize bounds the size of a Google Takeout ZIP file uploaded for import
+const MaxTakeoutUploadSize = 8 << 30
+
+// TakeoutImportRequest holds the form fields of a Google Takeout import. The export is
+// either uploaded as a ZIP file or, when TAKEOUT_IMPORT_DIR is set, named by its path
+// within that directory on the server.
+type TakeoutImportRequest struct {
+	Path            string  `form:"path" json:"path"`
+	Group           string  `form:"group" json:"group" binding:"omitempty,oneof=folder cluster"`                    // defaults to folder
+	ClusterRadiusKm float64 `form:"cluster_radius_km" json:"cluster_radius_km" binding:"omitempty,min=0.1,max=500"` // defaults to 2
+	ClusterGapHours float64 `form:"cluster_gap_hours" json:"cluster_gap_hours" binding:"omitempty,min=1,max=720"`   // defaults to 24
+}
+
+// ImportTakeout starts importing a Google Takeout export of Google Photos. Progress
+// is reported by GetTakeoutJob.
+func (ctrl *ImportController) ImportTakeout(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var req TakeoutImportRequest
+	if err := c.ShouldBind(&req); err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	options := service.TakeoutImportOptions{
+		Group:    req.Group,
+		RadiusKm: req.ClusterRadiusKm,
+		MaxGap:   time.Duration(req.ClusterGapHours * float64(time.Hour)),
+	}
+	var source string
+	if header, err := c.FormFile("file"); err == nil {
+		source, err = saveTakeoutUpload(header)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to save uploaded Takeout export")
+			common.InternalServerErrorResponse(c, "TAKEOUT_IMPORT_FAILED", "Failed to save uploaded file")
+			return
+		}
+		options.RemoveSource = true
+	} else if req.Path != "" {
+		root := os.Getenv("TAKEOUT_IMPORT_DIR")
+		if root == "" {
+			common.ValidationErrorResponse(c, "Importing from a server path is not enabled")
+			return
+		}
+		// Cleaning the path as an absolute one keeps it inside the import directory
+		source = filepath.Join(root, filepath.Clean("/"+req.Path))
+	} else {
+		common.ValidationErrorResponse(c, "No Takeout ZIP file or path provided")
+		return
+	}
+
+	job, err := ctrl.takeoutService.StartTakeoutImport(userID, source, options)
+	if err != nil {
+		common.ErrorResponse(c, http.StatusBadRequest, "TAKEOUT_IMPORT_FAILED", "Failed to import Takeout export", err.Error())
+		return
+	}
+
+	common.SuccessResponse(c, http.StatusAccepted, job)
+}
+
+// GetTakeoutJob reports the progress of a Google Takeout import
+func (ctrl *ImportController) GetTakeoutJob(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	job, err := ctrl.takeoutService.GetTakeoutJob(c.Param("id"), userID)
+	if err != nil {
+		if errors.Is(err, service.ErrImportJobNotFound) {
+			common.NotFoundErrorResponse(c, "IMPORT_JOB_NOT_FOUND", "Import job not found")
+			return
+		}
+		logrus.WithError(err).Error("Failed to get Takeout import job")
+		common.InternalServerErrorResponse(c, "TAKEOUT_IMPORT_FAILED", "Failed to get import job")
+		return
+	}
+
+	common.SuccessResponse(c, http.StatusOK, job)
+}
+
+// saveTakeoutUpload copies an uploaded export to a temporary file that outlives the
+// request, for the import job to read
+func saveTakeoutUpload(header *multipart.FileHeader) (string, error) {
+	src, err := header.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	dst, err := os.CreateTemp("", "takeout-*.zip")
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return "", err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(dst.Name())
+		return "", err
+	}
+	return dst.Name(), nil
+}
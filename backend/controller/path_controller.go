@@ -1,11 +1,17 @@
 package controller
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 
+	"geoalbum/backend/datum"
+	"geoalbum/backend/model"
+	"geoalbum/backend/polyline"
 	"geoalbum/backend/service"
 )
 
@@ -22,10 +28,91 @@ func NewPathController() *PathController {
 type CreatePathRequest struct {
 	FromAlbumID string `json:"from_album_id" binding:"required"`
 	ToAlbumID   string `json:"to_album_id" binding:"required"`
+	ChainPolicy string `json:"chain_policy" binding:"omitempty,oneof=warn reject"` // defaults to warn
 }
 
 type SetNextDestinationRequest struct {
-	ToAlbumID string `json:"to_album_id" binding:"required"`
+	ToAlbumID   string `json:"to_album_id" binding:"required"`
+	ChainPolicy string `json:"chain_policy" binding:"omitempty,oneof=warn reject"` // defaults to warn
+}
+
+// UpdatePathRequest is a partial update of a path's travel details: omitted fields
+// are left unchanged and an empty transport_mode clears the mode
+type UpdatePathRequest struct {
+	TransportMode   *string    `json:"transport_mode" binding:"omitempty,max=20"`
+	DurationMinutes *int       `json:"duration_minutes" binding:"omitempty,min=0"`
+	DepartAt        *time.Time `json:"depart_at"`
+	ArriveAt        *time.Time `json:"arrive_at"`
+	Notes           *string    `json:"notes" binding:"omitempty,max=2000"`
+	ClearSchedule   bool       `json:"clear_schedule"`
+}
+
+// PathGeometryRequest sets a path's geometry, either as an encoded polyline or as
+// [latitude, longitude] pairs
+type PathGeometryRequest struct {
+	Polyline    string       `json:"polyline"`
+	Coordinates [][2]float64 `json:"coordinates" binding:"max=50000"`
+	CRS         string       `json:"crs"` // datum of the coordinates; defaults to wgs84
+}
+
+// points returns the requested geometry and the datum it is given in
+func (req *PathGeometryRequest) points() ([]polyline.Point, datum.Datum, error) {
+	inputCRS, err := datum.Parse(req.CRS)
+	if err != nil {
+		return nil, "", err
+	}
+	if (req.Polyline == "") == (len(req.Coordinates) == 0) {
+		return nil, "", fmt.Errorf("give either polyline or coordinates")
+	}
+	if req.Polyline != "" {
+		points, err := polyline.Decode(req.Polyline)
+		return points, inputCRS, err
+	}
+	points := make([]polyline.Point, len(req.Coordinates))
+	for i, coordinate := range req.Coordinates {
+		points[i] = polyline.Point{Lat: coordinate[0], Lng: coordinate[1]}
+	}
+	return points, inputCRS, nil
+}
+
+// PathGeometryQuery simplifies returned geometry for display at zoom
+type PathGeometryQuery struct {
+	Zoom *int `form:"zoom" binding:"omitempty,min=0,max=22"`
+}
+
+// AutoPathRequest selects albums by album_ids or by start_date/end_date
+type AutoPathRequest struct {
+	AlbumIDs        []string   `json:"album_ids" binding:"max=1000"`
+	StartDate       *time.Time `json:"start_date"`
+	EndDate         *time.Time `json:"end_date"`
+	MaxGapHours     float64    `json:"max_gap_hours" binding:"min=0"` // 0 never splits chains
+	DryRun          bool       `json:"dry_run"`
+	ReplaceExisting bool       `json:"replace_existing"`
+	ChainPolicy     string     `json:"chain_policy" binding:"omitempty,oneof=warn reject"` // defaults to warn
+}
+
+type GetRouteQuery struct {
+	Direction string `form:"direction" binding:"omitempty,oneof=forward backward both"`
+	MaxDepth  int    `form:"max_depth" binding:"omitempty,min=1,max=1000"`
+}
+
+// chainPolicy returns the requested chain policy, warning by default
+func chainPolicy(raw string) service.ChainPolicy {
+	if raw == "" {
+		return service.ChainPolicyWarn
+	}
+	return service.ChainPolicy(raw)
+}
+
+// chainConflictResponse reports a path refused under the reject chain policy
+func chainConflictResponse(c *gin.Context, err error) {
+	c.JSON(http.StatusConflict, gin.H{
+		"error": map[string]interface{}{
+			"code":    "PATH_CHAIN_CONFLICT",
+			"message": "Path would close a loop or fork a chain",
+			"details": err.Error(),
+		},
+	})
 }
 
 // CreatePath creates a new path between two albums
@@ -41,6 +128,18 @@ func (ctrl *PathController) CreatePath(c *gin.Context) {
 		return
 	}
 
+	crs, err := crsQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
 	var req CreatePathRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -64,9 +163,13 @@ func (ctrl *PathController) CreatePath(c *gin.Context) {
 		return
 	}
 
-	path, err := ctrl.pathService.CreatePath(userID, req.FromAlbumID, req.ToAlbumID)
+	path, err := ctrl.pathService.CreatePath(userID, req.FromAlbumID, req.ToAlbumID, chainPolicy(req.ChainPolicy))
 	if err != nil {
 		logrus.WithError(err).Error("Failed to create path")
+		if errors.Is(err, service.ErrChainConflict) {
+			chainConflictResponse(c, err)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": map[string]interface{}{
 				"code":    "PATH_CREATION_FAILED",
@@ -77,6 +180,7 @@ func (ctrl *PathController) CreatePath(c *gin.Context) {
 		return
 	}
 
+	convertPath(path, crs)
 	c.JSON(http.StatusCreated, path)
 }
 
@@ -93,6 +197,30 @@ func (ctrl *PathController) GetPaths(c *gin.Context) {
 		return
 	}
 
+	crs, err := crsQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	var query PathGeometryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
 	paths, err := ctrl.pathService.GetPathsByUserID(userID)
 	if err != nil {
 		logrus.WithError(err).Error("Failed to get paths")
@@ -105,6 +233,12 @@ func (ctrl *PathController) GetPaths(c *gin.Context) {
 		return
 	}
 
+	if query.Zoom != nil {
+		for i := range paths {
+			service.SimplifyPathGeometry(&paths[i], *query.Zoom)
+		}
+	}
+	convertPaths(paths, crs)
 	c.JSON(http.StatusOK, gin.H{
 		"paths": paths,
 	})
@@ -123,6 +257,30 @@ func (ctrl *PathController) GetPath(c *gin.Context) {
 		return
 	}
 
+	crs, err := crsQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	var query PathGeometryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
 	pathID := c.Param("id")
 	path, err := ctrl.pathService.GetPathByID(pathID, userID)
 	if err != nil {
@@ -136,6 +294,177 @@ func (ctrl *PathController) GetPath(c *gin.Context) {
 		return
 	}
 
+	if query.Zoom != nil {
+		service.SimplifyPathGeometry(path, *query.Zoom)
+	}
+	convertPath(path, crs)
+	c.JSON(http.StatusOK, path)
+}
+
+// UpdatePath updates a path's transport mode, schedule and notes
+func (ctrl *PathController) UpdatePath(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	crs, err := crsQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	var req UpdatePathRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	path, err := ctrl.pathService.UpdatePath(c.Param("id"), userID, service.PathUpdate{
+		TransportMode:   req.TransportMode,
+		DurationMinutes: req.DurationMinutes,
+		DepartAt:        req.DepartAt,
+		ArriveAt:        req.ArriveAt,
+		Notes:           req.Notes,
+		ClearSchedule:   req.ClearSchedule,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed to update path")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "PATH_UPDATE_FAILED",
+				"message": "Failed to update path",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	convertPath(path, crs)
+	c.JSON(http.StatusOK, path)
+}
+
+// SetPathGeometry replaces the line a path is drawn along
+func (ctrl *PathController) SetPathGeometry(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	crs, err := crsQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	var req PathGeometryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+	points, inputCRS, err := req.points()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	path, err := ctrl.pathService.SetPathGeometry(c.Param("id"), userID, points, inputCRS)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to set path geometry")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "PATH_GEOMETRY_UPDATE_FAILED",
+				"message": "Failed to update path geometry",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	convertPath(path, crs)
+	c.JSON(http.StatusOK, path)
+}
+
+// ClearPathGeometry removes a path's geometry
+func (ctrl *PathController) ClearPathGeometry(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	crs, err := crsQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	path, err := ctrl.pathService.SetPathGeometry(c.Param("id"), userID, nil, datum.WGS84)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to clear path geometry")
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": map[string]interface{}{
+				"code":    "PATH_NOT_FOUND",
+				"message": "Path not found",
+			},
+		})
+		return
+	}
+
+	convertPath(path, crs)
 	c.JSON(http.StatusOK, path)
 }
 
@@ -182,6 +511,18 @@ func (ctrl *PathController) SetNextDestination(c *gin.Context) {
 		return
 	}
 
+	crs, err := crsQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
 	fromAlbumID := c.Param("id")
 	var req SetNextDestinationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -206,9 +547,13 @@ func (ctrl *PathController) SetNextDestination(c *gin.Context) {
 		return
 	}
 
-	path, err := ctrl.pathService.SetNextDestination(userID, fromAlbumID, req.ToAlbumID)
+	path, err := ctrl.pathService.SetNextDestination(userID, fromAlbumID, req.ToAlbumID, chainPolicy(req.ChainPolicy))
 	if err != nil {
 		logrus.WithError(err).Error("Failed to set next destination")
+		if errors.Is(err, service.ErrChainConflict) {
+			chainConflictResponse(c, err)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": map[string]interface{}{
 				"code":    "NEXT_DESTINATION_FAILED",
@@ -219,6 +564,7 @@ func (ctrl *PathController) SetNextDestination(c *gin.Context) {
 		return
 	}
 
+	convertPath(path, crs)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data":    path,
@@ -238,6 +584,18 @@ func (ctrl *PathController) GetNextDestination(c *gin.Context) {
 		return
 	}
 
+	crs, err := crsQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
 	fromAlbumID := c.Param("id")
 	album, err := ctrl.pathService.GetNextDestination(fromAlbumID, userID)
 	if err != nil {
@@ -251,6 +609,7 @@ func (ctrl *PathController) GetNextDestination(c *gin.Context) {
 		return
 	}
 
+	convertAlbum(album, crs)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": gin.H{
@@ -287,4 +646,128 @@ func (ctrl *PathController) RemoveNextDestination(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Next destination removed successfully",
 	})
-}
\ No newline at end of file
+}
+
+// GetRoute returns the albums reached by following paths from an album
+func (ctrl *PathController) GetRoute(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	var query GetRouteQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+	crs, err := crsQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	direction := query.Direction
+	if direction == "" {
+		direction = model.RouteForward
+	}
+	maxDepth := query.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = service.DefaultRouteDepth
+	}
+
+	route, err := ctrl.pathService.GetRoute(c.Param("id"), userID, direction, maxDepth)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get route")
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": map[string]interface{}{
+				"code":    "ALBUM_NOT_FOUND",
+				"message": "Album not found",
+			},
+		})
+		return
+	}
+
+	convertAlbums(route.Albums, crs)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    route,
+	})
+}
+
+// AutoCreatePaths links albums in date order, optionally as a dry run
+func (ctrl *PathController) AutoCreatePaths(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	var req AutoPathRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	result, err := ctrl.pathService.AutoLink(userID, service.AutoLinkInput{
+		AlbumIDs:        req.AlbumIDs,
+		StartDate:       req.StartDate,
+		EndDate:         req.EndDate,
+		MaxGap:          time.Duration(req.MaxGapHours * float64(time.Hour)),
+		DryRun:          req.DryRun,
+		ReplaceExisting: req.ReplaceExisting,
+		ChainPolicy:     chainPolicy(req.ChainPolicy),
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed to auto-create paths")
+		if errors.Is(err, service.ErrChainConflict) {
+			chainConflictResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "AUTO_PATH_FAILED",
+				"message": "Failed to create paths",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	status := http.StatusCreated
+	if req.DryRun {
+		status = http.StatusOK
+	}
+	c.JSON(status, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
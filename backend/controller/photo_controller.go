@@ -7,16 +7,19 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 
+	"geoalbum/backend/model"
 	"geoalbum/backend/service"
 )
 
 type PhotoController struct {
 	photoService *service.PhotoService
+	tagService   *service.TagService
 }
 
 func NewPhotoController() *PhotoController {
 	return &PhotoController{
 		photoService: service.NewPhotoService(),
+		tagService:   service.NewTagService(),
 	}
 }
 
@@ -24,6 +27,34 @@ type UpdatePhotoOrderRequest struct {
 	Order int `json:"order" binding:"required,min=0"`
 }
 
+type GetPhotosQuery struct {
+	Tags      string `form:"tags"`
+	TagMode   string `form:"tag_mode" binding:"omitempty,oneof=and or"`
+	Favorite  *bool  `form:"favorite"`
+	MinRating int    `form:"min_rating" binding:"omitempty,min=0,max=5"`
+}
+
+// UpdatePhotoRatingsRequest sets the rating and/or favorite flag of several photos
+type UpdatePhotoRatingsRequest struct {
+	PhotoIDs []string `json:"photo_ids" binding:"required,min=1,max=500"`
+	Rating   *int     `json:"rating" binding:"omitempty,min=0,max=5"`
+	Favorite *bool    `json:"favorite"`
+}
+
+// photoFilter converts photo listing query parameters into a filter
+func (ctrl *PhotoController) photoFilter(query GetPhotosQuery) (model.PhotoFilter, error) {
+	tags, err := ctrl.tagService.ParseTagFilter(query.Tags)
+	if err != nil {
+		return model.PhotoFilter{}, err
+	}
+	return model.PhotoFilter{
+		Tags:         tags,
+		MatchAllTags: query.TagMode != "or",
+		Favorite:     query.Favorite,
+		MinRating:    query.MinRating,
+	}, nil
+}
+
 // UploadPhoto uploads a photo to an album
 func (ctrl *PhotoController) UploadPhoto(c *gin.Context) {
 	userID := c.GetString("user_id")
@@ -37,6 +68,18 @@ func (ctrl *PhotoController) UploadPhoto(c *gin.Context) {
 		return
 	}
 
+	crs, err := crsQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
 	albumID := c.Param("id")
 	if albumID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -74,6 +117,7 @@ func (ctrl *PhotoController) UploadPhoto(c *gin.Context) {
 		return
 	}
 
+	convertPhoto(photo, crs)
 	c.JSON(http.StatusCreated, photo)
 }
 
@@ -90,8 +134,43 @@ func (ctrl *PhotoController) GetAlbumPhotos(c *gin.Context) {
 		return
 	}
 
+	crs, err := crsQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	var query GetPhotosQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+	filter, err := ctrl.photoFilter(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
 	albumID := c.Param("id")
-	photos, err := ctrl.photoService.GetPhotosByAlbumID(albumID, userID)
+	photos, err := ctrl.photoService.GetPhotosByAlbumIDFiltered(albumID, userID, filter)
 	if err != nil {
 		logrus.WithError(err).Error("Failed to get album photos")
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -103,11 +182,116 @@ func (ctrl *PhotoController) GetAlbumPhotos(c *gin.Context) {
 		return
 	}
 
+	convertPhotos(photos, crs)
 	c.JSON(http.StatusOK, gin.H{
 		"photos": photos,
 	})
 }
 
+// GetPhotos retrieves photos across all of the user's albums, e.g. favorites or
+// photos rated at least min_rating stars
+func (ctrl *PhotoController) GetPhotos(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	crs, err := crsQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	var query GetPhotosQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+	filter, err := ctrl.photoFilter(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	photos, err := ctrl.photoService.GetPhotosByUserIDFiltered(userID, filter)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get photos")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": map[string]interface{}{
+				"code":    "PHOTOS_RETRIEVAL_FAILED",
+				"message": "Failed to retrieve photos",
+			},
+		})
+		return
+	}
+
+	convertPhotos(photos, crs)
+	c.JSON(http.StatusOK, gin.H{
+		"photos": photos,
+		"count":  len(photos),
+	})
+}
+
+// UpdatePhotoRatings sets the star rating and/or favorite flag of several photos
+func (ctrl *PhotoController) UpdatePhotoRatings(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": map[string]interface{}{
+				"code":    "UNAUTHORIZED",
+				"message": "User not authenticated",
+			},
+		})
+		return
+	}
+
+	var req UpdatePhotoRatingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
+	updated, err := ctrl.photoService.UpdatePhotoRatings(userID, req.PhotoIDs, req.Rating, req.Favorite)
+	if err != nil {
+		serviceErrorResponse(c, err, "PHOTO_RATING_UPDATE_FAILED", "Failed to update photo ratings")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"updated_count": updated,
+	})
+}
+
 // DeletePhoto deletes a photo
 func (ctrl *PhotoController) DeletePhoto(c *gin.Context) {
 	userID := c.GetString("user_id")
@@ -236,6 +420,18 @@ func (ctrl *PhotoController) GetPhoto(c *gin.Context) {
 		return
 	}
 
+	crs, err := crsQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
 	photoID := c.Param("id")
 	photo, err := ctrl.photoService.GetPhotoByID(photoID, userID)
 	if err != nil {
@@ -249,6 +445,7 @@ func (ctrl *PhotoController) GetPhoto(c *gin.Context) {
 		return
 	}
 
+	convertPhoto(photo, crs)
 	c.JSON(http.StatusOK, photo)
 }
 
@@ -265,6 +462,18 @@ func (ctrl *PhotoController) UploadMultiplePhotos(c *gin.Context) {
 		return
 	}
 
+	crs, err := crsQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]interface{}{
+				"code":    "VALIDATION_ERROR",
+				"message": "Invalid request data",
+				"details": err.Error(),
+			},
+		})
+		return
+	}
+
 	albumID := c.Param("id")
 	if albumID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -310,6 +519,7 @@ func (ctrl *PhotoController) UploadMultiplePhotos(c *gin.Context) {
 			errors = append(errors, fmt.Sprintf("Failed to upload %s: %s", file.Filename, err.Error()))
 			continue
 		}
+		convertPhoto(photo, crs)
 		uploadedPhotos = append(uploadedPhotos, photo)
 	}
 
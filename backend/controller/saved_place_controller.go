@@ -0,0 +1,227 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"geoalbum/backend/common"
+	"geoalbum/backend/datum"
+	"geoalbum/backend/service"
+)
+
+type SavedPlaceController struct {
+	savedPlaceService *service.SavedPlaceService
+}
+
+func NewSavedPlaceController() *SavedPlaceController {
+	return &SavedPlaceController{
+		savedPlaceService: service.NewSavedPlaceService(),
+	}
+}
+
+// SavedPlaceRequest describes a place as a centre and radius, or as a polygon of
+// [longitude, latitude] vertices
+type SavedPlaceRequest struct {
+	Name      string       `json:"name" binding:"required,max=200"`
+	Latitude  *float64     `json:"latitude"`
+	Longitude *float64     `json:"longitude"`
+	RadiusM   *float64     `json:"radius_m"`
+	Polygon   [][2]float64 `json:"polygon"`
+	AutoTag   *bool        `json:"auto_tag"` // defaults to true
+	CRS       string       `json:"crs"`      // datum of the coordinates; defaults to wgs84
+}
+
+func (req *SavedPlaceRequest) input() (service.SavedPlaceInput, error) {
+	autoTag := true
+	if req.AutoTag != nil {
+		autoTag = *req.AutoTag
+	}
+	inputCRS, err := datum.Parse(req.CRS)
+	if err != nil {
+		return service.SavedPlaceInput{}, err
+	}
+	return service.SavedPlaceInput{
+		Name:      req.Name,
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+		RadiusM:   req.RadiusM,
+		Polygon:   req.Polygon,
+		AutoTag:   autoTag,
+		Datum:     inputCRS,
+	}, nil
+}
+
+// GetSavedPlaces retrieves all saved places for the authenticated user
+func (ctrl *SavedPlaceController) GetSavedPlaces(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	crs, err := crsQuery(c)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	places, err := ctrl.savedPlaceService.GetSavedPlaces(userID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get saved places")
+		common.InternalServerErrorResponse(c, "PLACES_RETRIEVAL_FAILED", "Failed to retrieve saved places")
+		return
+	}
+
+	convertSavedPlaces(places, crs)
+	response := gin.H{
+		"places": places,
+		"count":  len(places),
+	}
+
+	common.SuccessResponse(c, http.StatusOK, response)
+}
+
+// GetSavedPlace retrieves a single saved place
+func (ctrl *SavedPlaceController) GetSavedPlace(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	crs, err := crsQuery(c)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	place, err := ctrl.savedPlaceService.GetSavedPlaceByID(c.Param("id"), userID)
+	if err != nil {
+		serviceErrorResponse(c, err, "PLACE_RETRIEVAL_FAILED", "Failed to retrieve saved place")
+		return
+	}
+
+	convertSavedPlace(place, crs)
+	common.SuccessResponse(c, http.StatusOK, place)
+}
+
+// CreateSavedPlace creates a new saved place
+func (ctrl *SavedPlaceController) CreateSavedPlace(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var req SavedPlaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	input, err := req.input()
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	crs, err := crsQuery(c)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	place, err := ctrl.savedPlaceService.CreateSavedPlace(userID, input)
+	if err != nil {
+		serviceErrorResponse(c, err, "PLACE_CREATION_FAILED", "Failed to create saved place")
+		return
+	}
+
+	convertSavedPlace(place, crs)
+	common.SuccessResponse(c, http.StatusCreated, place)
+}
+
+// UpdateSavedPlace replaces a saved place's name, shape and tagging
+func (ctrl *SavedPlaceController) UpdateSavedPlace(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var req SavedPlaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	input, err := req.input()
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	crs, err := crsQuery(c)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	place, err := ctrl.savedPlaceService.UpdateSavedPlace(c.Param("id"), userID, input)
+	if err != nil {
+		serviceErrorResponse(c, err, "PLACE_UPDATE_FAILED", "Failed to update saved place")
+		return
+	}
+
+	convertSavedPlace(place, crs)
+	common.SuccessResponse(c, http.StatusOK, place)
+}
+
+// DeleteSavedPlace deletes a saved place
+func (ctrl *SavedPlaceController) DeleteSavedPlace(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	placeID := c.Param("id")
+	if err := ctrl.savedPlaceService.DeleteSavedPlace(placeID, userID); err != nil {
+		serviceErrorResponse(c, err, "PLACE_DELETION_FAILED", "Failed to delete saved place")
+		return
+	}
+
+	response := gin.H{
+		"message":  "Saved place deleted successfully",
+		"place_id": placeID,
+	}
+
+	common.SuccessResponse(c, http.StatusOK, response)
+}
+
+// GetAlbumsInPlace retrieves the albums located inside a saved place
+func (ctrl *SavedPlaceController) GetAlbumsInPlace(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	crs, err := crsQuery(c)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	albums, err := ctrl.savedPlaceService.GetAlbumsInPlace(c.Param("id"), userID)
+	if err != nil {
+		serviceErrorResponse(c, err, "PLACE_ALBUMS_RETRIEVAL_FAILED", "Failed to retrieve albums in saved place")
+		return
+	}
+
+	convertAlbums(albums, crs)
+	response := gin.H{
+		"albums": albums,
+		"count":  len(albums),
+	}
+
+	common.SuccessResponse(c, http.StatusOK, response)
+}
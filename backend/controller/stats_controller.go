@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"geoalbum/backend/common"
+	"geoalbum/backend/service"
+)
+
+type StatsController struct {
+	statsService *service.StatsService
+}
+
+func NewStatsController() *StatsController {
+	return &StatsController{
+		statsService: service.NewStatsService(),
+	}
+}
+
+// GetStats returns travel statistics for the current user
+func (ctrl *StatsController) GetStats(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	stats, err := ctrl.statsService.GetStats(userID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get stats")
+		common.InternalServerErrorResponse(c, "STATS_RETRIEVAL_FAILED", "Failed to retrieve statistics")
+		return
+	}
+
+	common.SuccessResponse(c, http.StatusOK, stats)
+}
@@ -0,0 +1,225 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"geoalbum/backend/common"
+	"geoalbum/backend/service"
+)
+
+type TagController struct {
+	tagService *service.TagService
+}
+
+func NewTagController() *TagController {
+	return &TagController{
+		tagService: service.NewTagService(),
+	}
+}
+
+type TagNameRequest struct {
+	Name string `json:"name" binding:"required,max=200"`
+}
+
+type MergeTagsRequest struct {
+	SourceTagIDs []string `json:"source_tag_ids" binding:"required,min=1"`
+}
+
+type AttachTagsRequest struct {
+	Tags []string `json:"tags" binding:"required,min=1,max=50"`
+}
+
+// GetTags retrieves all tags for the authenticated user
+func (ctrl *TagController) GetTags(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	tags, err := ctrl.tagService.GetTagsByUserID(userID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get tags")
+		common.InternalServerErrorResponse(c, "TAGS_RETRIEVAL_FAILED", "Failed to retrieve tags")
+		return
+	}
+
+	response := gin.H{
+		"tags":  tags,
+		"count": len(tags),
+	}
+
+	common.SuccessResponse(c, http.StatusOK, response)
+}
+
+// CreateTag creates a new tag
+func (ctrl *TagController) CreateTag(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var req TagNameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	tag, err := ctrl.tagService.CreateTag(userID, req.Name)
+	if err != nil {
+		serviceErrorResponse(c, err, "TAG_CREATION_FAILED", "Failed to create tag")
+		return
+	}
+
+	common.SuccessResponse(c, http.StatusCreated, tag)
+}
+
+// RenameTag renames a tag
+func (ctrl *TagController) RenameTag(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var req TagNameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	tag, err := ctrl.tagService.RenameTag(c.Param("id"), userID, req.Name)
+	if err != nil {
+		serviceErrorResponse(c, err, "TAG_RENAME_FAILED", "Failed to rename tag")
+		return
+	}
+
+	common.SuccessResponse(c, http.StatusOK, tag)
+}
+
+// MergeTags merges other tags into the tag given in the URL
+func (ctrl *TagController) MergeTags(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var req MergeTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	tag, err := ctrl.tagService.MergeTags(c.Param("id"), userID, req.SourceTagIDs)
+	if err != nil {
+		serviceErrorResponse(c, err, "TAG_MERGE_FAILED", "Failed to merge tags")
+		return
+	}
+
+	common.SuccessResponse(c, http.StatusOK, tag)
+}
+
+// DeleteTag deletes a tag
+func (ctrl *TagController) DeleteTag(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	tagID := c.Param("id")
+	if err := ctrl.tagService.DeleteTag(tagID, userID); err != nil {
+		serviceErrorResponse(c, err, "TAG_DELETION_FAILED", "Failed to delete tag")
+		return
+	}
+
+	response := gin.H{
+		"message": "Tag deleted successfully",
+		"tag_id":  tagID,
+	}
+
+	common.SuccessResponse(c, http.StatusOK, response)
+}
+
+// AttachAlbumTags attaches tags by name to an album
+func (ctrl *TagController) AttachAlbumTags(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var req AttachTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	tags, err := ctrl.tagService.AttachTagsToAlbum(c.Param("id"), userID, req.Tags)
+	if err != nil {
+		serviceErrorResponse(c, err, "TAG_ATTACH_FAILED", "Failed to attach tags")
+		return
+	}
+
+	common.SuccessResponse(c, http.StatusOK, gin.H{"tags": tags})
+}
+
+// DetachAlbumTag removes a tag from an album
+func (ctrl *TagController) DetachAlbumTag(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	if err := ctrl.tagService.DetachTagFromAlbum(c.Param("id"), userID, c.Param("tagId")); err != nil {
+		serviceErrorResponse(c, err, "TAG_DETACH_FAILED", "Failed to detach tag")
+		return
+	}
+
+	common.SuccessResponse(c, http.StatusOK, gin.H{"message": "Tag detached successfully"})
+}
+
+// AttachPhotoTags attaches tags by name to a photo
+func (ctrl *TagController) AttachPhotoTags(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var req AttachTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	photo, err := ctrl.tagService.AttachTagsToPhoto(c.Param("id"), userID, req.Tags)
+	if err != nil {
+		serviceErrorResponse(c, err, "TAG_ATTACH_FAILED", "Failed to attach tags")
+		return
+	}
+
+	common.SuccessResponse(c, http.StatusOK, photo)
+}
+
+// DetachPhotoTag removes a tag from a photo
+func (ctrl *TagController) DetachPhotoTag(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	if err := ctrl.tagService.DetachTagFromPhoto(c.Param("id"), userID, c.Param("tagId")); err != nil {
+		serviceErrorResponse(c, err, "TAG_DETACH_FAILED", "Failed to detach tag")
+		return
+	}
+
+	common.SuccessResponse(c, http.StatusOK, gin.H{"message": "Tag detached successfully"})
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"geoalbum/backend/common"
+	"geoalbum/backend/service"
+)
+
+// TileRequestsPerMinute is the rate limit per client of each tile route; a map
+// requests a screenful of tiles at every pan or zoom
+const TileRequestsPerMinute = 3000
+
+type TileController struct {
+	tileService *service.TileService
+}
+
+func NewTileController() *TileController {
+	return &TileController{
+		tileService: service.NewTileService(),
+	}
+}
+
+type AlbumTileQuery struct {
+	Paths bool `form:"paths"`
+}
+
+// GetAlbumTile serves the user's albums (and paths with ?paths=true) as a Mapbox
+// Vector Tile, in the datum given by crs. Its ETag follows the user's data version.
+func (ctrl *TileController) GetAlbumTile(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var query AlbumTileQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	crs, err := crsQuery(c)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	z, x, y, err := parseTileCoordinates(c.Param("z"), c.Param("x"), strings.TrimSuffix(c.Param("y"), ".mvt"))
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	if z > service.MaxTileZoom || x >= 1<<uint(z) || y >= 1<<uint(z) {
+		common.ValidationErrorResponse(c, fmt.Sprintf("tile %d/%d/%d is out of range", z, x, y))
+		return
+	}
+
+	data, version, err := ctrl.tileService.GetAlbumTile(userID, z, x, y, query.Paths, crs)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get album tile")
+		common.InternalServerErrorResponse(c, "TILE_RETRIEVAL_FAILED", "Failed to retrieve tile")
+		return
+	}
+
+	// Data versions of different users can coincide, so the user is part of the tag
+	user := sha256.Sum256([]byte(userID))
+	etag := fmt.Sprintf(`"%x-%d-%t-%s"`, user[:8], version, query.Paths, crs)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/vnd.mapbox-vector-tile", data)
+}
+
+// parseTileCoordinates parses non-negative z/x/y tile coordinates
+func parseTileCoordinates(rawZ, rawX, rawY string) (int, int, int, error) {
+	var coordinates [3]int
+	for i, raw := range []string{rawZ, rawX, rawY} {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			return 0, 0, 0, fmt.Errorf("invalid tile coordinate %q", raw)
+		}
+		coordinates[i] = value
+	}
+	return coordinates[0], coordinates[1], coordinates[2], nil
+}
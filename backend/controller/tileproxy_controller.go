@@ -0,0 +1,192 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"geoalbum/backend/common"
+	"geoalbum/backend/service"
+	"geoalbum/backend/tileproxy"
+)
+
+type TileProxyController struct {
+	tileProxyService *service.TileProxyService
+}
+
+func NewTileProxyController() *TileProxyController {
+	return &TileProxyController{
+		tileProxyService: service.NewTileProxyService(),
+	}
+}
+
+type SeedTilesRequest struct {
+	Provider string `json:"provider" binding:"required"`
+	BBox     string `json:"bbox" binding:"required"`
+	MinZoom  int    `json:"min_zoom" binding:"min=0,max=22"`
+	MaxZoom  int    `json:"max_zoom" binding:"min=0,max=22,gtefield=MinZoom"`
+}
+
+// GetProviders lists the tile providers available through the proxy
+func (ctrl *TileProxyController) GetProviders(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	providers := ctrl.tileProxyService.ListProviders()
+	common.SuccessResponse(c, http.StatusOK, gin.H{
+		"providers": providers,
+		"count":     len(providers),
+	})
+}
+
+// GetTile serves a remote basemap tile through the caching proxy. The y coordinate
+// may carry a file extension such as .png.
+func (ctrl *TileProxyController) GetTile(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	rawY := c.Param("y")
+	if dot := strings.IndexByte(rawY, '.'); dot >= 0 {
+		rawY = rawY[:dot]
+	}
+	z, x, y, err := parseTileCoordinates(c.Param("z"), c.Param("x"), rawY)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	if z > tileproxy.MaxZoom || x >= 1<<uint(z) || y >= 1<<uint(z) {
+		common.ValidationErrorResponse(c, "tile is out of range")
+		return
+	}
+
+	tile, err := ctrl.tileProxyService.GetTile(c.Request.Context(), c.Param("provider"), z, x, y)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrTileProxyDisabled):
+			common.NotFoundErrorResponse(c, "TILE_PROXY_DISABLED", "Tile proxy is not enabled")
+		case errors.Is(err, tileproxy.ErrUnknownProvider):
+			common.NotFoundErrorResponse(c, "PROVIDER_NOT_FOUND", "Tile provider not found")
+		case errors.Is(err, tileproxy.ErrTileNotFound):
+			common.NotFoundErrorResponse(c, "TILE_NOT_FOUND", "Tile not found")
+		default:
+			logrus.WithError(err).Warn("Failed to proxy tile")
+			common.ErrorResponse(c, http.StatusBadGateway, "TILE_FETCH_FAILED", "Failed to fetch tile", nil)
+		}
+		return
+	}
+
+	// Let the browser keep the tile for as long as the proxy considers it fresh
+	if maxAge := int(time.Until(tile.Expires).Seconds()); maxAge > 0 {
+		c.Header("Cache-Control", "private, max-age="+strconv.Itoa(maxAge))
+	} else {
+		c.Header("Cache-Control", "private, no-cache")
+	}
+	c.Header("X-Cache", tile.Cache)
+	contentType := tile.ContentType
+	if contentType == "" {
+		contentType = http.DetectContentType(tile.Data)
+	}
+	c.Data(http.StatusOK, contentType, tile.Data)
+}
+
+// SeedTiles starts caching the tiles of a bounding box and zoom range for offline use
+func (ctrl *TileProxyController) SeedTiles(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var req SeedTilesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	bbox, err := parseBoundingBox(req.BBox)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	job, err := ctrl.tileProxyService.SeedTiles(userID, req.Provider, *bbox, req.MinZoom, req.MaxZoom)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrTileProxyDisabled):
+			common.NotFoundErrorResponse(c, "TILE_PROXY_DISABLED", "Tile proxy is not enabled")
+		case errors.Is(err, tileproxy.ErrUnknownProvider):
+			common.NotFoundErrorResponse(c, "PROVIDER_NOT_FOUND", "Tile provider not found")
+		case errors.Is(err, tileproxy.ErrSeedInProgress):
+			common.ConflictErrorResponse(c, "SEED_IN_PROGRESS", "A seed job is already running", nil)
+		case errors.Is(err, tileproxy.ErrSeedCapacity):
+			common.ErrorResponse(c, http.StatusServiceUnavailable, "SEED_CAPACITY_REACHED", "Too many seed jobs are running, try again later", nil)
+		default:
+			common.ValidationErrorResponse(c, err.Error())
+		}
+		return
+	}
+
+	common.SuccessResponse(c, http.StatusAccepted, job)
+}
+
+// GetSeedJob reports the progress of a seed job
+func (ctrl *TileProxyController) GetSeedJob(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	job, err := ctrl.tileProxyService.GetSeedJob(c.Param("id"), userID)
+	if err != nil {
+		seedJobErrorResponse(c, err)
+		return
+	}
+	if job == nil {
+		common.NotFoundErrorResponse(c, "SEED_JOB_NOT_FOUND", "Seed job not found")
+		return
+	}
+
+	common.SuccessResponse(c, http.StatusOK, job)
+}
+
+// CancelSeedJob stops a seed job; tiles fetched so far stay cached
+func (ctrl *TileProxyController) CancelSeedJob(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	found, err := ctrl.tileProxyService.CancelSeedJob(c.Param("id"), userID)
+	if err != nil {
+		seedJobErrorResponse(c, err)
+		return
+	}
+	if !found {
+		common.NotFoundErrorResponse(c, "SEED_JOB_NOT_FOUND", "Seed job not found")
+		return
+	}
+
+	common.SuccessResponse(c, http.StatusOK, gin.H{"message": "Seed job cancelled"})
+}
+
+// seedJobErrorResponse maps errors looking up a seed job to responses
+func seedJobErrorResponse(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrTileProxyDisabled) {
+		common.NotFoundErrorResponse(c, "TILE_PROXY_DISABLED", "Tile proxy is not enabled")
+		return
+	}
+	logrus.WithError(err).Error("Failed to get seed job")
+	common.InternalServerErrorResponse(c, "SEED_JOB_FAILED", "Failed to get seed job")
+}
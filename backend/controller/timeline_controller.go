@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"geoalbum/backend/common"
+	"geoalbum/backend/service"
+)
+
+type TimelineController struct {
+	timelineService *service.TimelineService
+}
+
+func NewTimelineController() *TimelineController {
+	return &TimelineController{
+		timelineService: service.NewTimelineService(),
+	}
+}
+
+type TimelineQuery struct {
+	Granularity     string `form:"granularity" binding:"omitempty,oneof=year month day"`
+	Timezone        string `form:"tz" binding:"max=64"`
+	IncludeAlbumIDs bool   `form:"include_album_ids"`
+}
+
+// GetTimeline returns album and photo counts per year, month or day
+func (ctrl *TimelineController) GetTimeline(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var query TimelineQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	if query.Granularity == "" {
+		query.Granularity = service.GranularityMonth
+	}
+	if query.Timezone == "" {
+		query.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(query.Timezone); err != nil || query.Timezone == "Local" {
+		common.ValidationErrorResponse(c, "invalid timezone: "+query.Timezone)
+		return
+	}
+
+	buckets, err := ctrl.timelineService.GetTimeline(userID, query.Granularity, query.Timezone, query.IncludeAlbumIDs)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get timeline")
+		common.InternalServerErrorResponse(c, "TIMELINE_RETRIEVAL_FAILED", "Failed to retrieve timeline")
+		return
+	}
+
+	common.SuccessResponse(c, http.StatusOK, gin.H{
+		"granularity": query.Granularity,
+		"timezone":    query.Timezone,
+		"buckets":     buckets,
+	})
+}
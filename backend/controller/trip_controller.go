@@ -0,0 +1,192 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"geoalbum/backend/common"
+	"geoalbum/backend/service"
+)
+
+type TripController struct {
+	tripService *service.TripService
+}
+
+func NewTripController() *TripController {
+	return &TripController{
+		tripService: service.NewTripService(),
+	}
+}
+
+type CreateTripRequest struct {
+	Title       string     `json:"title" binding:"required,max=200"`
+	Description string     `json:"description" binding:"max=2000"`
+	StartAt     *time.Time `json:"start_at"`
+	EndAt       *time.Time `json:"end_at"`
+	AlbumIDs    []string   `json:"album_ids" binding:"max=500"`
+}
+
+// UpdateTripRequest is a partial update: omitted fields are left unchanged and
+// album_ids, when given, replaces the stops
+type UpdateTripRequest struct {
+	Title       *string    `json:"title" binding:"omitempty,max=200"`
+	Description *string    `json:"description" binding:"omitempty,max=2000"`
+	StartAt     *time.Time `json:"start_at"`
+	EndAt       *time.Time `json:"end_at"`
+	ClearDates  bool       `json:"clear_dates"`
+	AlbumIDs    []string   `json:"album_ids" binding:"omitempty,max=500"`
+}
+
+type GetTripsQuery struct {
+	AlbumID string `form:"album_id"`
+}
+
+// CreateTrip creates a new trip
+func (ctrl *TripController) CreateTrip(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var req CreateTripRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	crs, err := crsQuery(c)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	trip, err := ctrl.tripService.CreateTrip(userID, service.TripInput{
+		Title:       &req.Title,
+		Description: &req.Description,
+		StartAt:     req.StartAt,
+		EndAt:       req.EndAt,
+		AlbumIDs:    req.AlbumIDs,
+	})
+	if err != nil {
+		serviceErrorResponse(c, err, "TRIP_CREATION_FAILED", "Failed to create trip")
+		return
+	}
+
+	convertTrip(trip, crs)
+	common.SuccessResponse(c, http.StatusCreated, trip)
+}
+
+// GetTrips retrieves the authenticated user's trips, optionally only those stopping
+// at album_id
+func (ctrl *TripController) GetTrips(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var query GetTripsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	trips, err := ctrl.tripService.GetTripsByUserID(userID, query.AlbumID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get trips")
+		common.InternalServerErrorResponse(c, "TRIPS_RETRIEVAL_FAILED", "Failed to retrieve trips")
+		return
+	}
+
+	response := gin.H{
+		"trips": trips,
+		"count": len(trips),
+	}
+
+	common.SuccessResponse(c, http.StatusOK, response)
+}
+
+// GetTrip retrieves a trip with its stops and legs
+func (ctrl *TripController) GetTrip(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	crs, err := crsQuery(c)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	trip, err := ctrl.tripService.GetTripByID(c.Param("id"), userID)
+	if err != nil {
+		serviceErrorResponse(c, err, "TRIP_RETRIEVAL_FAILED", "Failed to retrieve trip")
+		return
+	}
+
+	convertTrip(trip, crs)
+	common.SuccessResponse(c, http.StatusOK, trip)
+}
+
+// UpdateTrip updates a trip's details and stops
+func (ctrl *TripController) UpdateTrip(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var req UpdateTripRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	crs, err := crsQuery(c)
+	if err != nil {
+		common.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	trip, err := ctrl.tripService.UpdateTrip(c.Param("id"), userID, service.TripInput{
+		Title:       req.Title,
+		Description: req.Description,
+		StartAt:     req.StartAt,
+		EndAt:       req.EndAt,
+		ClearDates:  req.ClearDates,
+		AlbumIDs:    req.AlbumIDs,
+	})
+	if err != nil {
+		serviceErrorResponse(c, err, "TRIP_UPDATE_FAILED", "Failed to update trip")
+		return
+	}
+
+	convertTrip(trip, crs)
+	common.SuccessResponse(c, http.StatusOK, trip)
+}
+
+// DeleteTrip deletes a trip
+func (ctrl *TripController) DeleteTrip(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		common.UnauthorizedErrorResponse(c, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	tripID := c.Param("id")
+	if err := ctrl.tripService.DeleteTrip(tripID, userID); err != nil {
+		serviceErrorResponse(c, err, "TRIP_DELETION_FAILED", "Failed to delete trip")
+		return
+	}
+
+	response := gin.H{
+		"message": "Trip deleted successfully",
+		"trip_id": tripID,
+	}
+
+	common.SuccessResponse(c, http.StatusOK, response)
+}
@@ -3,6 +3,7 @@ package dao
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"geoalbum/backend/database"
@@ -18,106 +19,224 @@ func NewAlbumDAO() *AlbumDAO {
 // Create creates a new album in the database
 func (dao *AlbumDAO) Create(album *model.Album) error {
 	query := `
-		INSERT INTO albums (id, user_id, title, description, latitude, longitude, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO albums (id, user_id, title, description, latitude, longitude, created_at, updated_at,
+			start_at, end_at, dates_manual, timezone, country_code, country, region, city, external_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	_, err := database.DB.Exec(query, album.ID, album.UserID, album.Title, album.Description, 
-		album.Latitude, album.Longitude, album.CreatedAt, album.UpdatedAt)
+		album.Latitude, album.Longitude, album.CreatedAt.UTC(), album.UpdatedAt,
+		album.StartAt.UTC(), album.EndAt.UTC(), album.DatesManual, album.Timezone,
+		album.CountryCode, album.Country, album.Region, album.City, album.ExternalID)
 	if err != nil {
 		return fmt.Errorf("failed to create album: %w", err)
 	}
 	return nil
 }
 
-// GetByUserID retrieves all albums for a specific user
+// albumSummarySelect selects albums together with their photo count, cover photo and tags.
+// Callers append a WHERE clause followed by albumSummaryGroupBy.
+const albumSummarySelect = `
+	SELECT a.id, a.user_id, a.title, a.description, a.latitude, a.longitude, a.created_at, a.updated_at,
+		a.start_at, a.end_at, a.dates_manual, a.timezone, a.country_code, a.country, a.region, a.city, a.external_id,
+		COUNT(p.id) AS photo_count,
+		COALESCE(SUM(p.file_size), 0) AS storage_bytes,
+		COALESCE(a.cover_photo_id, (
+			SELECT cp.id FROM photos cp
+			WHERE cp.album_id = a.id
+			ORDER BY cp.rating DESC, cp.favorite DESC, cp.display_order ASC, cp.uploaded_at ASC
+			LIMIT 1
+		), '') AS cover_photo_id,
+		COALESCE((
+			SELECT GROUP_CONCAT(t.name, char(31)) FROM album_tags at
+			JOIN tags t ON t.id = at.tag_id
+			WHERE at.album_id = a.id
+		), '') AS tag_names
+	FROM albums a
+	LEFT JOIN photos p ON p.album_id = a.id
+`
+
+const albumSummaryGroupBy = `
+	GROUP BY a.id
+	ORDER BY a.start_at DESC
+`
+
+// albumSummaryRow is an album listing row with its tag names concatenated
+type albumSummaryRow struct {
+	model.Album
+	TagNames string `db:"tag_names"`
+}
+
+// GetByUserID retrieves all albums for a specific user with photo counts and cover photos
 func (dao *AlbumDAO) GetByUserID(userID string) ([]model.Album, error) {
-	var albums []model.Album
-	query := `
-		SELECT id, user_id, title, description, latitude, longitude, created_at, updated_at
-		FROM albums 
-		WHERE user_id = ? 
-		ORDER BY created_at DESC
-	`
-	err := database.DB.Select(&albums, query, userID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get albums by user ID: %w", err)
-	}
-	return albums, nil
+	return dao.GetByUserIDFiltered(userID, model.AlbumFilter{})
 }
 
-// GetByUserIDAndTimeRange retrieves albums for a user within a time range
+// GetByUserIDAndTimeRange retrieves albums for a user whose date range overlaps the given window
 func (dao *AlbumDAO) GetByUserIDAndTimeRange(userID string, startDate, endDate *time.Time) ([]model.Album, error) {
-	var albums []model.Album
-	var query string
-	var args []interface{}
-
-	if startDate != nil && endDate != nil {
-		query = `
-			SELECT id, user_id, title, description, latitude, longitude, created_at, updated_at
-			FROM albums 
-			WHERE user_id = ? AND created_at >= ? AND created_at <= ?
-			ORDER BY created_at DESC
-		`
-		args = []interface{}{userID, startDate, endDate}
-	} else if startDate != nil {
-		query = `
-			SELECT id, user_id, title, description, latitude, longitude, created_at, updated_at
-			FROM albums 
-			WHERE user_id = ? AND created_at >= ?
-			ORDER BY created_at DESC
-		`
-		args = []interface{}{userID, startDate}
-	} else if endDate != nil {
-		query = `
-			SELECT id, user_id, title, description, latitude, longitude, created_at, updated_at
-			FROM albums 
-			WHERE user_id = ? AND created_at <= ?
-			ORDER BY created_at DESC
-		`
-		args = []interface{}{userID, endDate}
-	} else {
-		return dao.GetByUserID(userID)
+	return dao.GetByUserIDFiltered(userID, model.AlbumFilter{StartDate: startDate, EndDate: endDate})
+}
+
+// GetByUserIDFiltered retrieves a user's albums matching filter in a single query
+func (dao *AlbumDAO) GetByUserIDFiltered(userID string, filter model.AlbumFilter) ([]model.Album, error) {
+	conditions := []string{"a.user_id = ?"}
+	args := []interface{}{userID}
+	if filter.StartDate != nil {
+		conditions = append(conditions, "a.end_at >= ?")
+		args = append(args, filter.StartDate.UTC())
+	}
+	if filter.EndDate != nil {
+		conditions = append(conditions, "a.start_at <= ?")
+		args = append(args, filter.EndDate.UTC())
+	}
+	if len(filter.Tags) > 0 {
+		clause, tagArgs := tagFilterClause("album_tags", "album_id", "a.id", userID, filter.Tags, filter.MatchAllTags)
+		conditions = append(conditions, clause)
+		args = append(args, tagArgs...)
+	}
+	if filter.Country != "" {
+		conditions = append(conditions, "(a.country_code = ? COLLATE NOCASE OR a.country = ? COLLATE NOCASE)")
+		args = append(args, filter.Country, filter.Country)
+	}
+	if filter.Region != "" {
+		conditions = append(conditions, "a.region = ? COLLATE NOCASE")
+		args = append(args, filter.Region)
+	}
+	if filter.City != "" {
+		conditions = append(conditions, "a.city = ? COLLATE NOCASE")
+		args = append(args, filter.City)
+	}
+	if filter.Search != "" {
+		pattern := "%" + escapeLike(filter.Search) + "%"
+		conditions = append(conditions, `(a.title LIKE ? ESCAPE '\' OR a.description LIKE ? ESCAPE '\'
+			OR a.country LIKE ? ESCAPE '\' OR a.region LIKE ? ESCAPE '\' OR a.city LIKE ? ESCAPE '\')`)
+		args = append(args, pattern, pattern, pattern, pattern, pattern)
+	}
+	if filter.BBox != nil {
+		clause, boxArgs := boundingBoxClause("a.latitude", "a.longitude", filter.BBox)
+		conditions = append(conditions, clause)
+		args = append(args, boxArgs...)
 	}
 
-	err := database.DB.Select(&albums, query, args...)
+	var rows []albumSummaryRow
+	query := albumSummarySelect + `WHERE ` + strings.Join(conditions, " AND ") + albumSummaryGroupBy
+	err := database.DB.Select(&rows, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get albums by time range: %w", err)
+		return nil, fmt.Errorf("failed to get albums by user ID: %w", err)
+	}
+
+	albums := make([]model.Album, len(rows))
+	for i, row := range rows {
+		albums[i] = row.Album
+		albums[i].Tags = splitTagNames(row.TagNames)
 	}
 	return albums, nil
 }
 
-// GetByID retrieves an album by ID
+// GetByID retrieves an album by ID. CoverPhotoID is the explicitly chosen cover, if any.
 func (dao *AlbumDAO) GetByID(id string) (*model.Album, error) {
-	var album model.Album
+	var row albumSummaryRow
 	query := `
-		SELECT id, user_id, title, description, latitude, longitude, created_at, updated_at
-		FROM albums 
-		WHERE id = ?
+		SELECT a.id, a.user_id, a.title, a.description, a.latitude, a.longitude, a.created_at, a.updated_at,
+			a.start_at, a.end_at, a.dates_manual, a.timezone, a.country_code, a.country, a.region, a.city, a.external_id,
+			COALESCE(a.cover_photo_id, '') AS cover_photo_id,
+			COALESCE((
+				SELECT GROUP_CONCAT(t.name, char(31)) FROM album_tags at
+				JOIN tags t ON t.id = at.tag_id
+				WHERE at.album_id = a.id
+			), '') AS tag_names
+		FROM albums a
+		WHERE a.id = ?
 	`
-	err := database.DB.Get(&album, query, id)
+	err := database.DB.Get(&row, query, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("failed to get album by ID: %w", err)
 	}
+	album := row.Album
+	album.Tags = splitTagNames(row.TagNames)
 	return &album, nil
 }
 
-// Update updates an album in the database
+// Update updates an album's editable fields in the database.
+// An empty CoverPhotoID clears the explicit cover so the first photo is used again.
 func (dao *AlbumDAO) Update(album *model.Album) error {
 	query := `
 		UPDATE albums 
-		SET title = ?, description = ?, updated_at = ?
+		SET title = ?, description = ?, latitude = ?, longitude = ?, created_at = ?,
+			start_at = ?, end_at = ?, dates_manual = ?, timezone = ?,
+			country_code = ?, country = ?, region = ?, city = ?,
+			cover_photo_id = ?, updated_at = ?
 		WHERE id = ? AND user_id = ?
 	`
-	_, err := database.DB.Exec(query, album.Title, album.Description, album.UpdatedAt, album.ID, album.UserID)
+	var coverPhotoID interface{}
+	if album.CoverPhotoID != "" {
+		coverPhotoID = album.CoverPhotoID
+	}
+	_, err := database.DB.Exec(query, album.Title, album.Description, album.Latitude, album.Longitude,
+		album.CreatedAt.UTC(), album.StartAt.UTC(), album.EndAt.UTC(), album.DatesManual, album.Timezone,
+		album.CountryCode, album.Country, album.Region, album.City, coverPhotoID, album.UpdatedAt, album.ID, album.UserID)
 	if err != nil {
 		return fmt.Errorf("failed to update album: %w", err)
 	}
 	return nil
 }
 
+// RefreshDateRange re-derives an album's start/end from its photos' capture times,
+// falling back to created_at. Albums with manually set dates are left untouched.
+func (dao *AlbumDAO) RefreshDateRange(id string) error {
+	query := `
+		UPDATE albums
+		SET start_at = COALESCE((SELECT MIN(taken_at) FROM photos WHERE album_id = albums.id), created_at),
+			end_at = COALESCE((SELECT MAX(taken_at) FROM photos WHERE album_id = albums.id), created_at)
+		WHERE id = ? AND dates_manual = 0
+	`
+	_, err := database.DB.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to refresh album date range: %w", err)
+	}
+	return nil
+}
+
+// GetAllLocations retrieves the ID, coordinates and time zone of every album
+func (dao *AlbumDAO) GetAllLocations() ([]model.Album, error) {
+	var albums []model.Album
+	query := `SELECT id, latitude, longitude, timezone FROM albums`
+	err := database.DB.Select(&albums, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get album locations: %w", err)
+	}
+	return albums, nil
+}
+
+// UpdatePlace stores an album's resolved place names and time zone
+func (dao *AlbumDAO) UpdatePlace(album *model.Album) error {
+	query := `UPDATE albums SET country_code = ?, country = ?, region = ?, city = ?, timezone = ? WHERE id = ?`
+	_, err := database.DB.Exec(query, album.CountryCode, album.Country, album.Region, album.City, album.Timezone, album.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update album place: %w", err)
+	}
+	return nil
+}
+
+// boundingBoxClause restricts the given latitude and longitude columns to box,
+// handling boxes that cross the antimeridian
+func boundingBoxClause(latColumn, lngColumn string, box *model.BoundingBox) (string, []interface{}) {
+	clause := latColumn + " BETWEEN ? AND ? AND "
+	if box.MinLng <= box.MaxLng {
+		clause += lngColumn + " BETWEEN ? AND ?"
+	} else {
+		clause += "(" + lngColumn + " >= ? OR " + lngColumn + " <= ?)"
+	}
+	return clause, []interface{}{box.MinLat, box.MaxLat, box.MinLng, box.MaxLng}
+}
+
+// escapeLike escapes the LIKE wildcards in a search term
+func escapeLike(term string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(term)
+}
+
 // Delete deletes an album from the database
 func (dao *AlbumDAO) Delete(id, userID string) error {
 	query := `DELETE FROM albums WHERE id = ? AND user_id = ?`
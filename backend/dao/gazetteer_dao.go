@@ -0,0 +1,197 @@
+package dao
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"geoalbum/backend/database"
+	"geoalbum/backend/model"
+)
+
+type GazetteerDAO struct{}
+
+func NewGazetteerDAO() *GazetteerDAO {
+	return &GazetteerDAO{}
+}
+
+// gazetteerVersionKey is the gazetteer_meta key holding the loaded dataset version
+const gazetteerVersionKey = "dataset_version"
+
+// GetVersion returns the version of the loaded dataset, or "" when none is loaded
+func (dao *GazetteerDAO) GetVersion() (string, error) {
+	var version string
+	err := database.DB.Get(&version, `SELECT value FROM gazetteer_meta WHERE key = ?`, gazetteerVersionKey)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get gazetteer version: %w", err)
+	}
+	return version, nil
+}
+
+// ReplaceAll replaces the gazetteer contents and records version, in one transaction
+func (dao *GazetteerDAO) ReplaceAll(places []model.GazetteerPlace, version string) error {
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin gazetteer load: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM gazetteer_names`); err != nil {
+		return fmt.Errorf("failed to clear gazetteer: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM gazetteer_places`); err != nil {
+		return fmt.Errorf("failed to clear gazetteer: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO gazetteer_places (id, name, name_zh, latitude, longitude, country_code, country, country_zh,
+			region, population, timezone)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare gazetteer insert: %w", err)
+	}
+	defer stmt.Close()
+
+	nameStmt, err := tx.Prepare(`INSERT INTO gazetteer_names (place_id, name, lang) VALUES (?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare gazetteer name insert: %w", err)
+	}
+	defer nameStmt.Close()
+
+	for i := range places {
+		place := &places[i]
+		// Places without a source ID are numbered in load order
+		var id interface{}
+		if place.ID != 0 {
+			id = place.ID
+		}
+		result, err := stmt.Exec(id, place.Name, place.NameZh, place.Latitude, place.Longitude, place.CountryCode,
+			place.Country, place.CountryZh, place.Region, place.Population, place.Timezone)
+		if err != nil {
+			return fmt.Errorf("failed to insert gazetteer place %q: %w", place.Name, err)
+		}
+		if place.ID == 0 {
+			if place.ID, err = result.LastInsertId(); err != nil {
+				return fmt.Errorf("failed to insert gazetteer place %q: %w", place.Name, err)
+			}
+		}
+
+		for _, name := range placeNames(place) {
+			if _, err := nameStmt.Exec(place.ID, name[0], name[1]); err != nil {
+				return fmt.Errorf("failed to insert gazetteer name %q: %w", name[0], err)
+			}
+		}
+	}
+
+	_, err = tx.Exec(`INSERT OR REPLACE INTO gazetteer_meta (key, value) VALUES (?, ?)`, gazetteerVersionKey, version)
+	if err != nil {
+		return fmt.Errorf("failed to record gazetteer version: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit gazetteer load: %w", err)
+	}
+	return nil
+}
+
+// placeNames lists the distinct names of a place with their language: "en" for
+// the primary name, "zh" for the Chinese name and "" for alternate names
+func placeNames(place *model.GazetteerPlace) [][2]string {
+	var names [][2]string
+	seen := make(map[string]bool)
+	add := func(name, lang string) {
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		names = append(names, [2]string{name, lang})
+	}
+
+	add(place.Name, "en")
+	add(place.NameZh, "zh")
+	for _, name := range place.AlternateNames {
+		add(name, "")
+	}
+	return names
+}
+
+// gazetteerNameMatch is a place matched by one of its names
+type gazetteerNameMatch struct {
+	model.GazetteerPlace
+	MatchedName string `db:"matched_name"`
+}
+
+// SearchByName retrieves places with a name starting with prefix, case-insensitively,
+// those with a name equal to prefix first and then the most populous. Each place
+// appears once with its best matching name: an exact match, else its primary or
+// Chinese name, else its shortest alternate name. When viewport is given only
+// places inside it are retrieved.
+func (dao *GazetteerDAO) SearchByName(prefix string, viewport *model.BoundingBox, limit int) ([]model.GeocodeResult, error) {
+	query := `
+		WITH matches AS (
+			SELECT place_id, name,
+				ROW_NUMBER() OVER (
+					PARTITION BY place_id
+					ORDER BY name = ? DESC, lang = '' ASC, length(name) ASC, name ASC
+				) AS rank
+			FROM gazetteer_names
+			-- A range scan rather than LIKE so that the name index is used
+			WHERE name >= ? AND name < ?
+		)
+		SELECT g.id, g.name, g.name_zh, g.latitude, g.longitude, g.country_code, g.country, g.country_zh,
+			g.region, g.population, g.timezone, m.name AS matched_name
+		FROM matches m
+		JOIN gazetteer_places g ON g.id = m.place_id
+		WHERE m.rank = 1`
+	args := []interface{}{prefix, prefix, prefix + "\U0010FFFF"}
+	if viewport != nil {
+		query += ` AND g.latitude BETWEEN ? AND ?`
+		if viewport.MinLng <= viewport.MaxLng {
+			query += ` AND g.longitude BETWEEN ? AND ?`
+		} else {
+			query += ` AND (g.longitude >= ? OR g.longitude <= ?)`
+		}
+		args = append(args, viewport.MinLat, viewport.MaxLat, viewport.MinLng, viewport.MaxLng)
+	}
+	query += `
+		ORDER BY m.name = ? COLLATE NOCASE DESC, g.population DESC
+		LIMIT ?`
+	args = append(args, prefix, limit)
+
+	var matches []gazetteerNameMatch
+	if err := database.DB.Select(&matches, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to search gazetteer: %w", err)
+	}
+
+	results := make([]model.GeocodeResult, len(matches))
+	for i, match := range matches {
+		results[i] = model.GeocodeResult{GazetteerPlace: match.GazetteerPlace, MatchedName: match.MatchedName}
+	}
+	return results, nil
+}
+
+// GetWithin retrieves the places inside a latitude/longitude box. A box crossing the
+// antimeridian is given with minLng > maxLng.
+func (dao *GazetteerDAO) GetWithin(minLat, maxLat, minLng, maxLng float64) ([]model.GazetteerPlace, error) {
+	query := `
+		SELECT id, name, name_zh, latitude, longitude, country_code, country, country_zh, region, population, timezone
+		FROM gazetteer_places
+		WHERE latitude BETWEEN ? AND ? AND `
+	if minLng <= maxLng {
+		query += `longitude BETWEEN ? AND ?`
+	} else {
+		query += `(longitude >= ? OR longitude <= ?)`
+	}
+
+	var places []model.GazetteerPlace
+	err := database.DB.Select(&places, query, minLat, maxLat, minLng, maxLng)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gazetteer places: %w", err)
+	}
+	return places, nil
+}
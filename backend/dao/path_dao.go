@@ -17,10 +17,12 @@ func NewPathDAO() *PathDAO {
 // Create creates a new path in the database
 func (dao *PathDAO) Create(path *model.Path) error {
 	query := `
-		INSERT INTO paths (id, user_id, from_album_id, to_album_id, created_at)
-		VALUES (?, ?, ?, ?, ?)
+		INSERT INTO paths (id, user_id, from_album_id, to_album_id, created_at,
+			transport_mode, duration_minutes, depart_at, arrive_at, notes, geometry)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	_, err := database.DB.Exec(query, path.ID, path.UserID, path.FromAlbumID, path.ToAlbumID, path.CreatedAt)
+	_, err := database.DB.Exec(query, path.ID, path.UserID, path.FromAlbumID, path.ToAlbumID, path.CreatedAt,
+		path.TransportMode, path.DurationMinutes, path.DepartAt, path.ArriveAt, path.Notes, path.Geometry)
 	if err != nil {
 		return fmt.Errorf("failed to create path: %w", err)
 	}
@@ -31,7 +33,8 @@ func (dao *PathDAO) Create(path *model.Path) error {
 func (dao *PathDAO) GetByUserID(userID string) ([]model.Path, error) {
 	var paths []model.Path
 	query := `
-		SELECT id, user_id, from_album_id, to_album_id, created_at
+		SELECT id, user_id, from_album_id, to_album_id, created_at,
+			transport_mode, duration_minutes, depart_at, arrive_at, notes, geometry
 		FROM paths 
 		WHERE user_id = ? 
 		ORDER BY created_at DESC
@@ -43,11 +46,64 @@ func (dao *PathDAO) GetByUserID(userID string) ([]model.Path, error) {
 	return paths, nil
 }
 
+// pathWithAlbumsSelect selects paths joined with their from/to albums so that
+// a listing is served in a single round trip
+const pathWithAlbumsSelect = `
+	SELECT p.id, p.user_id, p.from_album_id, p.to_album_id, p.created_at,
+		p.transport_mode, p.duration_minutes, p.depart_at, p.arrive_at, p.notes, p.geometry,
+		fa.id AS "from_album.id", fa.user_id AS "from_album.user_id", fa.title AS "from_album.title",
+		fa.description AS "from_album.description", fa.latitude AS "from_album.latitude",
+		fa.longitude AS "from_album.longitude", fa.created_at AS "from_album.created_at",
+		fa.updated_at AS "from_album.updated_at", fa.start_at AS "from_album.start_at", fa.end_at AS "from_album.end_at",
+		fa.dates_manual AS "from_album.dates_manual", fa.timezone AS "from_album.timezone",
+		fa.country_code AS "from_album.country_code", fa.country AS "from_album.country", fa.region AS "from_album.region",
+		fa.city AS "from_album.city",
+		ta.id AS "to_album.id", ta.user_id AS "to_album.user_id", ta.title AS "to_album.title",
+		ta.description AS "to_album.description", ta.latitude AS "to_album.latitude",
+		ta.longitude AS "to_album.longitude", ta.created_at AS "to_album.created_at",
+		ta.updated_at AS "to_album.updated_at", ta.start_at AS "to_album.start_at", ta.end_at AS "to_album.end_at",
+		ta.dates_manual AS "to_album.dates_manual", ta.timezone AS "to_album.timezone",
+		ta.country_code AS "to_album.country_code", ta.country AS "to_album.country", ta.region AS "to_album.region",
+		ta.city AS "to_album.city"
+	FROM paths p
+	JOIN albums fa ON fa.id = p.from_album_id
+	JOIN albums ta ON ta.id = p.to_album_id
+`
+
+// GetByUserIDWithAlbums retrieves all paths for a user with their from/to albums populated
+func (dao *PathDAO) GetByUserIDWithAlbums(userID string) ([]model.Path, error) {
+	var paths []model.Path
+	query := pathWithAlbumsSelect + `
+		WHERE p.user_id = ?
+		ORDER BY p.created_at DESC
+	`
+	err := database.DB.Select(&paths, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get paths with albums by user ID: %w", err)
+	}
+	return paths, nil
+}
+
+// GetByIDWithAlbums retrieves a path by ID with its from/to albums populated
+func (dao *PathDAO) GetByIDWithAlbums(id string) (*model.Path, error) {
+	var path model.Path
+	query := pathWithAlbumsSelect + `WHERE p.id = ?`
+	err := database.DB.Get(&path, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get path with albums by ID: %w", err)
+	}
+	return &path, nil
+}
+
 // GetByID retrieves a path by ID
 func (dao *PathDAO) GetByID(id string) (*model.Path, error) {
 	var path model.Path
 	query := `
-		SELECT id, user_id, from_album_id, to_album_id, created_at
+		SELECT id, user_id, from_album_id, to_album_id, created_at,
+			transport_mode, duration_minutes, depart_at, arrive_at, notes, geometry
 		FROM paths 
 		WHERE id = ?
 	`
@@ -65,7 +121,8 @@ func (dao *PathDAO) GetByID(id string) (*model.Path, error) {
 func (dao *PathDAO) GetByFromAlbumID(fromAlbumID string) ([]model.Path, error) {
 	var paths []model.Path
 	query := `
-		SELECT id, user_id, from_album_id, to_album_id, created_at
+		SELECT id, user_id, from_album_id, to_album_id, created_at,
+			transport_mode, duration_minutes, depart_at, arrive_at, notes, geometry
 		FROM paths 
 		WHERE from_album_id = ?
 		ORDER BY created_at DESC
@@ -77,6 +134,21 @@ func (dao *PathDAO) GetByFromAlbumID(fromAlbumID string) ([]model.Path, error) {
 	return paths, nil
 }
 
+// Update updates a path's travel details and geometry
+func (dao *PathDAO) Update(path *model.Path) error {
+	query := `
+		UPDATE paths
+		SET transport_mode = ?, duration_minutes = ?, depart_at = ?, arrive_at = ?, notes = ?, geometry = ?
+		WHERE id = ? AND user_id = ?
+	`
+	_, err := database.DB.Exec(query, path.TransportMode, path.DurationMinutes, path.DepartAt, path.ArriveAt,
+		path.Notes, path.Geometry, path.ID, path.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to update path: %w", err)
+	}
+	return nil
+}
+
 // Delete deletes a path from the database
 func (dao *PathDAO) Delete(id, userID string) error {
 	query := `DELETE FROM paths WHERE id = ? AND user_id = ?`
@@ -97,6 +169,36 @@ func (dao *PathDAO) DeleteByFromAlbumID(fromAlbumID, userID string) error {
 	return nil
 }
 
+// ReplaceFromAlbum deletes all paths starting from the path's from album and creates
+// the path in their place, in one transaction
+func (dao *PathDAO) ReplaceFromAlbum(path *model.Path) error {
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin path replacement: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM paths WHERE from_album_id = ? AND user_id = ?`
+	if _, err := tx.Exec(query, path.FromAlbumID, path.UserID); err != nil {
+		return fmt.Errorf("failed to delete paths by from_album_id: %w", err)
+	}
+	query = `
+		INSERT INTO paths (id, user_id, from_album_id, to_album_id, created_at,
+			transport_mode, duration_minutes, depart_at, arrive_at, notes, geometry)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err = tx.Exec(query, path.ID, path.UserID, path.FromAlbumID, path.ToAlbumID, path.CreatedAt,
+		path.TransportMode, path.DurationMinutes, path.DepartAt, path.ArriveAt, path.Notes, path.Geometry)
+	if err != nil {
+		return fmt.Errorf("failed to create path: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit path replacement: %w", err)
+	}
+	return nil
+}
+
 // CheckPathExists checks if a path already exists between two albums
 func (dao *PathDAO) CheckPathExists(fromAlbumID, toAlbumID, userID string) (bool, error) {
 	var count int
@@ -106,4 +208,40 @@ func (dao *PathDAO) CheckPathExists(fromAlbumID, toAlbumID, userID string) (bool
 		return false, fmt.Errorf("failed to check path existence: %w", err)
 	}
 	return count > 0, nil
-}
\ No newline at end of file
+}
+
+// CreateLinks creates paths in one transaction, keeping any that already exist. With
+// replace set, other paths leaving each path's from album are deleted first, as
+// setting a next destination does. It returns how many paths were replaced.
+func (dao *PathDAO) CreateLinks(paths []model.Path, replace bool) (int64, error) {
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin path creation: %w", err)
+	}
+	defer tx.Rollback()
+
+	var replaced int64
+	for _, path := range paths {
+		if replace {
+			query := `DELETE FROM paths WHERE from_album_id = ? AND to_album_id != ? AND user_id = ?`
+			result, err := tx.Exec(query, path.FromAlbumID, path.ToAlbumID, path.UserID)
+			if err != nil {
+				return 0, fmt.Errorf("failed to replace paths: %w", err)
+			}
+			deleted, _ := result.RowsAffected()
+			replaced += deleted
+		}
+		query := `
+			INSERT OR IGNORE INTO paths (id, user_id, from_album_id, to_album_id, created_at)
+			VALUES (?, ?, ?, ?, ?)
+		`
+		if _, err := tx.Exec(query, path.ID, path.UserID, path.FromAlbumID, path.ToAlbumID, path.CreatedAt); err != nil {
+			return 0, fmt.Errorf("failed to create path: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit path creation: %w", err)
+	}
+	return replaced, nil
+}
@@ -3,6 +3,7 @@ package dao
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"geoalbum/backend/database"
 	"geoalbum/backend/model"
@@ -17,48 +18,209 @@ func NewPhotoDAO() *PhotoDAO {
 // Create creates a new photo in the database
 func (dao *PhotoDAO) Create(photo *model.Photo) error {
 	query := `
-		INSERT INTO photos (id, album_id, filename, file_path, file_size, mime_type, display_order, uploaded_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO photos (id, album_id, filename, file_path, file_size, mime_type, display_order, uploaded_at,
+			taken_at, rating, favorite, latitude, longitude, description)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
+	var takenAt interface{}
+	if photo.TakenAt != nil {
+		takenAt = photo.TakenAt.UTC()
+	}
 	_, err := database.DB.Exec(query, photo.ID, photo.AlbumID, photo.Filename, photo.FilePath,
-		photo.FileSize, photo.MimeType, photo.DisplayOrder, photo.UploadedAt)
+		photo.FileSize, photo.MimeType, photo.DisplayOrder, photo.UploadedAt, takenAt, photo.Rating, photo.Favorite,
+		photo.Latitude, photo.Longitude, photo.Description)
 	if err != nil {
 		return fmt.Errorf("failed to create photo: %w", err)
 	}
 	return nil
 }
 
+// photoSelect selects photos together with their tag names
+const photoSelect = `
+	SELECT p.id, p.album_id, p.filename, p.file_path, p.file_size, p.mime_type, p.display_order,
+		p.uploaded_at, p.taken_at, p.rating, p.favorite, p.latitude, p.longitude, p.description,
+		COALESCE((
+			SELECT GROUP_CONCAT(t.name, char(31)) FROM photo_tags pt
+			JOIN tags t ON t.id = pt.tag_id
+			WHERE pt.photo_id = p.id
+		), '') AS tag_names
+	FROM photos p
+`
+
+// photoRow is a photo row with its tag names concatenated
+type photoRow struct {
+	model.Photo
+	TagNames string `db:"tag_names"`
+}
+
+// toPhotos converts photo rows into photos with their tags split
+func toPhotos(rows []photoRow) []model.Photo {
+	photos := make([]model.Photo, len(rows))
+	for i, row := range rows {
+		photos[i] = row.Photo
+		photos[i].Tags = splitTagNames(row.TagNames)
+	}
+	return photos
+}
+
 // GetByAlbumID retrieves all photos for a specific album
 func (dao *PhotoDAO) GetByAlbumID(albumID string) ([]model.Photo, error) {
+	return dao.GetByAlbumIDFiltered(albumID, "", model.PhotoFilter{})
+}
+
+// GetByAlbumIDFiltered retrieves the photos of an album matching filter; userID scopes tag names
+func (dao *PhotoDAO) GetByAlbumIDFiltered(albumID, userID string, filter model.PhotoFilter) ([]model.Photo, error) {
+	conditions, args := photoFilterConditions(userID, filter)
+	conditions = append([]string{"p.album_id = ?"}, conditions...)
+	args = append([]interface{}{albumID}, args...)
+
+	var rows []photoRow
+	query := photoSelect + `
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY p.display_order ASC, p.uploaded_at ASC
+	`
+	err := database.DB.Select(&rows, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get photos by album ID: %w", err)
+	}
+	return toPhotos(rows), nil
+}
+
+// GetByUserIDFiltered retrieves photos across all of a user's albums matching filter,
+// best rated first
+func (dao *PhotoDAO) GetByUserIDFiltered(userID string, filter model.PhotoFilter) ([]model.Photo, error) {
+	conditions, args := photoFilterConditions(userID, filter)
+	conditions = append([]string{"p.album_id IN (SELECT id FROM albums WHERE user_id = ?)"}, conditions...)
+	args = append([]interface{}{userID}, args...)
+
+	var rows []photoRow
+	query := photoSelect + `
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY p.rating DESC, p.favorite DESC, COALESCE(p.taken_at, p.uploaded_at) DESC
+	`
+	err := database.DB.Select(&rows, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get photos by user ID: %w", err)
+	}
+	return toPhotos(rows), nil
+}
+
+// GetCaptureTimesByUserID retrieves the ID, album and capture time of every photo a user owns
+func (dao *PhotoDAO) GetCaptureTimesByUserID(userID string) ([]model.Photo, error) {
 	var photos []model.Photo
 	query := `
-		SELECT id, album_id, filename, file_path, file_size, mime_type, display_order, uploaded_at
-		FROM photos 
-		WHERE album_id = ? 
-		ORDER BY display_order ASC, uploaded_at ASC
+		SELECT p.id, p.album_id, p.taken_at
+		FROM photos p
+		JOIN albums a ON a.id = p.album_id
+		WHERE a.user_id = ?
 	`
-	err := database.DB.Select(&photos, query, albumID)
+	err := database.DB.Select(&photos, query, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get photos by album ID: %w", err)
+		return nil, fmt.Errorf("failed to get photo capture times: %w", err)
 	}
 	return photos, nil
 }
 
-// GetByID retrieves a photo by ID
-func (dao *PhotoDAO) GetByID(id string) (*model.Photo, error) {
-	var photo model.Photo
+// GetUnlocatedByUserID retrieves the ID, album and capture time of a user's photos
+// that have a capture time but no GPS position
+func (dao *PhotoDAO) GetUnlocatedByUserID(userID string) ([]model.Photo, error) {
+	var photos []model.Photo
 	query := `
-		SELECT id, album_id, filename, file_path, file_size, mime_type, display_order, uploaded_at
-		FROM photos 
-		WHERE id = ?
+		SELECT p.id, p.album_id, p.taken_at
+		FROM photos p
+		JOIN albums a ON a.id = p.album_id
+		WHERE a.user_id = ? AND p.taken_at IS NOT NULL AND (p.latitude IS NULL OR p.longitude IS NULL)
+		ORDER BY p.taken_at
 	`
-	err := database.DB.Get(&photo, query, id)
+	err := database.DB.Select(&photos, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unlocated photos: %w", err)
+	}
+	return photos, nil
+}
+
+// UpdateLocations sets the GPS position of photos in one transaction
+func (dao *PhotoDAO) UpdateLocations(photos []model.Photo) error {
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin photo location update: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE photos SET latitude = ?, longitude = ? WHERE id = ?`
+	for _, photo := range photos {
+		if _, err := tx.Exec(query, photo.Latitude, photo.Longitude, photo.ID); err != nil {
+			return fmt.Errorf("failed to update photo location: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit photo location update: %w", err)
+	}
+	return nil
+}
+
+// GetLocationWeights counts a user's photos per location, using a photo's own GPS
+// position when known and its album's location otherwise. bbox optionally restricts
+// the locations; a box with MinLng > MaxLng crosses the antimeridian.
+func (dao *PhotoDAO) GetLocationWeights(userID string, bbox *model.BoundingBox) ([]model.HeatmapPoint, error) {
+	query := `
+		SELECT latitude, longitude, COUNT(*) AS weight
+		FROM (
+			SELECT COALESCE(p.latitude, a.latitude) AS latitude, COALESCE(p.longitude, a.longitude) AS longitude
+			FROM photos p
+			JOIN albums a ON a.id = p.album_id
+			WHERE a.user_id = ?
+		)
+	`
+	args := []interface{}{userID}
+	if bbox != nil {
+		clause, boxArgs := boundingBoxClause("latitude", "longitude", bbox)
+		query += `WHERE ` + clause
+		args = append(args, boxArgs...)
+	}
+	query += ` GROUP BY latitude, longitude`
+
+	var points []model.HeatmapPoint
+	err := database.DB.Select(&points, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get photo location weights: %w", err)
+	}
+	return points, nil
+}
+
+// photoFilterConditions builds the WHERE conditions for a photo filter
+func photoFilterConditions(userID string, filter model.PhotoFilter) ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if len(filter.Tags) > 0 {
+		clause, tagArgs := tagFilterClause("photo_tags", "photo_id", "p.id", userID, filter.Tags, filter.MatchAllTags)
+		conditions = append(conditions, clause)
+		args = append(args, tagArgs...)
+	}
+	if filter.Favorite != nil {
+		conditions = append(conditions, "p.favorite = ?")
+		args = append(args, *filter.Favorite)
+	}
+	if filter.MinRating > 0 {
+		conditions = append(conditions, "p.rating >= ?")
+		args = append(args, filter.MinRating)
+	}
+	return conditions, args
+}
+
+// GetByID retrieves a photo by ID
+func (dao *PhotoDAO) GetByID(id string) (*model.Photo, error) {
+	var row photoRow
+	query := photoSelect + `WHERE p.id = ?`
+	err := database.DB.Get(&row, query, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("failed to get photo by ID: %w", err)
 	}
+	photo := toPhotos([]photoRow{row})[0]
 	return &photo, nil
 }
 
@@ -72,6 +234,34 @@ func (dao *PhotoDAO) UpdateOrder(id string, order int) error {
 	return nil
 }
 
+// UpdateRatings sets the rating and/or favorite flag of a user's photos in one statement.
+// Nil values are left unchanged; photos of other users are ignored.
+func (dao *PhotoDAO) UpdateRatings(userID string, photoIDs []string, rating *int, favorite *bool) (int64, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(photoIDs)), ", ")
+	query := `
+		UPDATE photos
+		SET rating = COALESCE(?, rating), favorite = COALESCE(?, favorite)
+		WHERE id IN (` + placeholders + `)
+			AND album_id IN (SELECT id FROM albums WHERE user_id = ?)
+	`
+
+	args := []interface{}{rating, favorite}
+	for _, id := range photoIDs {
+		args = append(args, id)
+	}
+	args = append(args, userID)
+
+	result, err := database.DB.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update photo ratings: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to update photo ratings: %w", err)
+	}
+	return updated, nil
+}
+
 // Delete deletes a photo from the database
 func (dao *PhotoDAO) Delete(id string) error {
 	query := `DELETE FROM photos WHERE id = ?`
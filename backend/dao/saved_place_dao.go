@@ -0,0 +1,145 @@
+package dao
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"geoalbum/backend/database"
+	"geoalbum/backend/model"
+)
+
+type SavedPlaceDAO struct{}
+
+func NewSavedPlaceDAO() *SavedPlaceDAO {
+	return &SavedPlaceDAO{}
+}
+
+// savedPlaceRow is a saved place with its polygon still JSON encoded
+type savedPlaceRow struct {
+	model.SavedPlace
+	PolygonJSON string `db:"polygon"`
+}
+
+const savedPlaceSelect = `
+	SELECT id, user_id, name, kind, latitude, longitude, radius_m, polygon, auto_tag, created_at, updated_at
+	FROM saved_places
+`
+
+// Create creates a new saved place in the database
+func (dao *SavedPlaceDAO) Create(place *model.SavedPlace) error {
+	polygon, err := encodePolygon(place.Polygon)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO saved_places (id, user_id, name, kind, latitude, longitude, radius_m, polygon, auto_tag,
+			created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err = database.DB.Exec(query, place.ID, place.UserID, place.Name, place.Kind, place.Latitude, place.Longitude,
+		place.RadiusM, polygon, place.AutoTag, place.CreatedAt.UTC(), place.UpdatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to create saved place: %w", err)
+	}
+	return nil
+}
+
+// GetByUserID retrieves all saved places of a user ordered by name
+func (dao *SavedPlaceDAO) GetByUserID(userID string) ([]model.SavedPlace, error) {
+	var rows []savedPlaceRow
+	err := database.DB.Select(&rows, savedPlaceSelect+`WHERE user_id = ? ORDER BY name ASC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved places by user ID: %w", err)
+	}
+
+	places := make([]model.SavedPlace, len(rows))
+	for i := range rows {
+		if err := rows[i].decode(); err != nil {
+			return nil, err
+		}
+		places[i] = rows[i].SavedPlace
+	}
+	return places, nil
+}
+
+// GetByID retrieves a saved place by ID
+func (dao *SavedPlaceDAO) GetByID(id string) (*model.SavedPlace, error) {
+	var row savedPlaceRow
+	err := database.DB.Get(&row, savedPlaceSelect+`WHERE id = ?`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get saved place by ID: %w", err)
+	}
+	if err := row.decode(); err != nil {
+		return nil, err
+	}
+	return &row.SavedPlace, nil
+}
+
+// GetByName retrieves a user's saved place by name, case-insensitively
+func (dao *SavedPlaceDAO) GetByName(userID, name string) (*model.SavedPlace, error) {
+	var row savedPlaceRow
+	err := database.DB.Get(&row, savedPlaceSelect+`WHERE user_id = ? AND name = ?`, userID, name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get saved place by name: %w", err)
+	}
+	if err := row.decode(); err != nil {
+		return nil, err
+	}
+	return &row.SavedPlace, nil
+}
+
+// Update updates a saved place's name, shape and tagging
+func (dao *SavedPlaceDAO) Update(place *model.SavedPlace) error {
+	polygon, err := encodePolygon(place.Polygon)
+	if err != nil {
+		return err
+	}
+	query := `
+		UPDATE saved_places
+		SET name = ?, kind = ?, latitude = ?, longitude = ?, radius_m = ?, polygon = ?, auto_tag = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?
+	`
+	_, err = database.DB.Exec(query, place.Name, place.Kind, place.Latitude, place.Longitude, place.RadiusM,
+		polygon, place.AutoTag, place.UpdatedAt.UTC(), place.ID, place.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to update saved place: %w", err)
+	}
+	return nil
+}
+
+// Delete deletes a saved place
+func (dao *SavedPlaceDAO) Delete(id, userID string) error {
+	_, err := database.DB.Exec(`DELETE FROM saved_places WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete saved place: %w", err)
+	}
+	return nil
+}
+
+func (row *savedPlaceRow) decode() error {
+	if row.PolygonJSON == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(row.PolygonJSON), &row.Polygon); err != nil {
+		return fmt.Errorf("failed to decode polygon of saved place %s: %w", row.ID, err)
+	}
+	return nil
+}
+
+func encodePolygon(polygon [][2]float64) (string, error) {
+	if len(polygon) == 0 {
+		return "", nil
+	}
+	encoded, err := json.Marshal(polygon)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode polygon: %w", err)
+	}
+	return string(encoded), nil
+}
@@ -0,0 +1,212 @@
+package dao
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"geoalbum/backend/database"
+	"geoalbum/backend/model"
+)
+
+type TagDAO struct{}
+
+func NewTagDAO() *TagDAO {
+	return &TagDAO{}
+}
+
+// tagSelect selects tags together with how many albums and photos carry them
+const tagSelect = `
+	SELECT t.id, t.user_id, t.name, t.created_at,
+		(SELECT COUNT(*) FROM album_tags at WHERE at.tag_id = t.id) AS album_count,
+		(SELECT COUNT(*) FROM photo_tags pt WHERE pt.tag_id = t.id) AS photo_count
+	FROM tags t
+`
+
+// Create creates a new tag in the database
+func (dao *TagDAO) Create(tag *model.Tag) error {
+	query := `INSERT INTO tags (id, user_id, name, created_at) VALUES (?, ?, ?, ?)`
+	_, err := database.DB.Exec(query, tag.ID, tag.UserID, tag.Name, tag.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create tag: %w", err)
+	}
+	return nil
+}
+
+// GetByUserID retrieves all tags for a specific user
+func (dao *TagDAO) GetByUserID(userID string) ([]model.Tag, error) {
+	var tags []model.Tag
+	query := tagSelect + `WHERE t.user_id = ? ORDER BY t.name ASC`
+	err := database.DB.Select(&tags, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags by user ID: %w", err)
+	}
+	return tags, nil
+}
+
+// GetByID retrieves a tag by ID
+func (dao *TagDAO) GetByID(id string) (*model.Tag, error) {
+	var tag model.Tag
+	query := tagSelect + `WHERE t.id = ?`
+	err := database.DB.Get(&tag, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get tag by ID: %w", err)
+	}
+	return &tag, nil
+}
+
+// GetByName retrieves a user's tag by name, case-insensitively
+func (dao *TagDAO) GetByName(userID, name string) (*model.Tag, error) {
+	var tag model.Tag
+	query := tagSelect + `WHERE t.user_id = ? AND t.name = ?`
+	err := database.DB.Get(&tag, query, userID, name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get tag by name: %w", err)
+	}
+	return &tag, nil
+}
+
+// GetByAlbumID retrieves the tags attached to an album
+func (dao *TagDAO) GetByAlbumID(albumID string) ([]model.Tag, error) {
+	var tags []model.Tag
+	query := tagSelect + `
+		WHERE t.id IN (SELECT tag_id FROM album_tags WHERE album_id = ?)
+		ORDER BY t.name ASC
+	`
+	err := database.DB.Select(&tags, query, albumID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags by album ID: %w", err)
+	}
+	return tags, nil
+}
+
+// Rename changes a tag's name
+func (dao *TagDAO) Rename(id, userID, name string) error {
+	query := `UPDATE tags SET name = ? WHERE id = ? AND user_id = ?`
+	_, err := database.DB.Exec(query, name, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to rename tag: %w", err)
+	}
+	return nil
+}
+
+// Merge moves every album and photo link from the source tags to the target tag
+// and deletes the source tags, all in one transaction
+func (dao *TagDAO) Merge(targetID string, sourceIDs []string, userID string) error {
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin tag merge: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, sourceID := range sourceIDs {
+		statements := []string{
+			`INSERT OR IGNORE INTO album_tags (album_id, tag_id) SELECT album_id, ? FROM album_tags WHERE tag_id = ?`,
+			`INSERT OR IGNORE INTO photo_tags (photo_id, tag_id) SELECT photo_id, ? FROM photo_tags WHERE tag_id = ?`,
+		}
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement, targetID, sourceID); err != nil {
+				return fmt.Errorf("failed to merge tag links: %w", err)
+			}
+		}
+		if _, err := tx.Exec(`DELETE FROM tags WHERE id = ? AND user_id = ?`, sourceID, userID); err != nil {
+			return fmt.Errorf("failed to delete merged tag: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tag merge: %w", err)
+	}
+	return nil
+}
+
+// Delete deletes a tag and, through cascading, its links
+func (dao *TagDAO) Delete(id, userID string) error {
+	query := `DELETE FROM tags WHERE id = ? AND user_id = ?`
+	_, err := database.DB.Exec(query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+	return nil
+}
+
+// AttachToAlbum links a tag to an album; attaching twice is a no-op
+func (dao *TagDAO) AttachToAlbum(albumID, tagID string) error {
+	query := `INSERT OR IGNORE INTO album_tags (album_id, tag_id) VALUES (?, ?)`
+	_, err := database.DB.Exec(query, albumID, tagID)
+	if err != nil {
+		return fmt.Errorf("failed to attach tag to album: %w", err)
+	}
+	return nil
+}
+
+// DetachFromAlbum removes a tag from an album
+func (dao *TagDAO) DetachFromAlbum(albumID, tagID string) error {
+	query := `DELETE FROM album_tags WHERE album_id = ? AND tag_id = ?`
+	_, err := database.DB.Exec(query, albumID, tagID)
+	if err != nil {
+		return fmt.Errorf("failed to detach tag from album: %w", err)
+	}
+	return nil
+}
+
+// AttachToPhoto links a tag to a photo; attaching twice is a no-op
+func (dao *TagDAO) AttachToPhoto(photoID, tagID string) error {
+	query := `INSERT OR IGNORE INTO photo_tags (photo_id, tag_id) VALUES (?, ?)`
+	_, err := database.DB.Exec(query, photoID, tagID)
+	if err != nil {
+		return fmt.Errorf("failed to attach tag to photo: %w", err)
+	}
+	return nil
+}
+
+// DetachFromPhoto removes a tag from a photo
+func (dao *TagDAO) DetachFromPhoto(photoID, tagID string) error {
+	query := `DELETE FROM photo_tags WHERE photo_id = ? AND tag_id = ?`
+	_, err := database.DB.Exec(query, photoID, tagID)
+	if err != nil {
+		return fmt.Errorf("failed to detach tag from photo: %w", err)
+	}
+	return nil
+}
+
+// tagNameSeparator separates names in GROUP_CONCAT(..., char(31)) results
+const tagNameSeparator = "\x1f"
+
+// splitTagNames splits a concatenated tag name list
+func splitTagNames(names string) []string {
+	if names == "" {
+		return nil
+	}
+	return strings.Split(names, tagNameSeparator)
+}
+
+// tagFilterClause builds a condition matching rows of idExpr linked through
+// linkTable to the named tags of a user. With matchAll every tag must be
+// present, otherwise any one of them is enough.
+func tagFilterClause(linkTable, linkColumn, idExpr, userID string, names []string, matchAll bool) (string, []interface{}) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", ")
+	args := []interface{}{userID}
+	for _, name := range names {
+		args = append(args, name)
+	}
+
+	clause := fmt.Sprintf(`%s IN (
+		SELECT l.%s FROM %s l
+		JOIN tags t ON t.id = l.tag_id
+		WHERE t.user_id = ? AND t.name IN (%s)
+		GROUP BY l.%s`, idExpr, linkColumn, linkTable, placeholders, linkColumn)
+	if matchAll {
+		clause += ` HAVING COUNT(DISTINCT t.id) = ?`
+		args = append(args, len(names))
+	}
+	clause += `)`
+
+	return clause, args
+}
@@ -0,0 +1,154 @@
+package dao
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"geoalbum/backend/database"
+	"geoalbum/backend/model"
+)
+
+type TripDAO struct{}
+
+func NewTripDAO() *TripDAO {
+	return &TripDAO{}
+}
+
+// tripSelect selects trips together with their number of stops
+const tripSelect = `
+	SELECT t.id, t.user_id, t.title, t.description, t.start_at, t.end_at, t.created_at, t.updated_at,
+		(SELECT COUNT(*) FROM trip_stops s WHERE s.trip_id = t.id) AS stop_count
+	FROM trips t
+`
+
+// Create stores a trip with its stops, plus any missing legs, in one transaction
+func (dao *TripDAO) Create(trip *model.Trip, legs []model.Path) error {
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin trip creation: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO trips (id, user_id, title, description, start_at, end_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err = tx.Exec(query, trip.ID, trip.UserID, trip.Title, trip.Description, trip.StartAt, trip.EndAt,
+		trip.CreatedAt, trip.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create trip: %w", err)
+	}
+	if err := writeStops(tx, trip.ID, trip.Stops, legs); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit trip creation: %w", err)
+	}
+	return nil
+}
+
+// GetByUserID retrieves a user's trips, most recent first. When albumID is set only
+// trips stopping at that album are returned.
+func (dao *TripDAO) GetByUserID(userID, albumID string) ([]model.Trip, error) {
+	query := tripSelect + `WHERE t.user_id = ?`
+	args := []interface{}{userID}
+	if albumID != "" {
+		query += ` AND t.id IN (SELECT trip_id FROM trip_stops WHERE album_id = ?)`
+		args = append(args, albumID)
+	}
+	query += ` ORDER BY COALESCE(t.start_at, t.created_at) DESC`
+
+	var trips []model.Trip
+	if err := database.DB.Select(&trips, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get trips by user ID: %w", err)
+	}
+	return trips, nil
+}
+
+// GetByID retrieves a trip by ID
+func (dao *TripDAO) GetByID(id string) (*model.Trip, error) {
+	var trip model.Trip
+	err := database.DB.Get(&trip, tripSelect+`WHERE t.id = ?`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get trip by ID: %w", err)
+	}
+	return &trip, nil
+}
+
+// GetStops retrieves a trip's stops in order
+func (dao *TripDAO) GetStops(tripID string) ([]model.TripStop, error) {
+	var stops []model.TripStop
+	query := `SELECT position, album_id FROM trip_stops WHERE trip_id = ? ORDER BY position ASC`
+	if err := database.DB.Select(&stops, query, tripID); err != nil {
+		return nil, fmt.Errorf("failed to get trip stops: %w", err)
+	}
+	return stops, nil
+}
+
+// Update stores a trip's details. When stops is not nil the stops are replaced and
+// any missing legs created, in the same transaction.
+func (dao *TripDAO) Update(trip *model.Trip, stops []model.TripStop, legs []model.Path) error {
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin trip update: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE trips SET title = ?, description = ?, start_at = ?, end_at = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?
+	`
+	_, err = tx.Exec(query, trip.Title, trip.Description, trip.StartAt, trip.EndAt, trip.UpdatedAt, trip.ID, trip.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to update trip: %w", err)
+	}
+	if stops != nil {
+		if _, err := tx.Exec(`DELETE FROM trip_stops WHERE trip_id = ?`, trip.ID); err != nil {
+			return fmt.Errorf("failed to replace trip stops: %w", err)
+		}
+		if err := writeStops(tx, trip.ID, stops, legs); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit trip update: %w", err)
+	}
+	return nil
+}
+
+// Delete deletes a trip and its stops; the paths between its albums are kept
+func (dao *TripDAO) Delete(id, userID string) error {
+	query := `DELETE FROM trips WHERE id = ? AND user_id = ?`
+	_, err := database.DB.Exec(query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete trip: %w", err)
+	}
+	return nil
+}
+
+// writeStops inserts a trip's stops and the paths joining them that do not exist yet
+func writeStops(tx *sqlx.Tx, tripID string, stops []model.TripStop, legs []model.Path) error {
+	for _, stop := range stops {
+		query := `INSERT INTO trip_stops (trip_id, position, album_id) VALUES (?, ?, ?)`
+		if _, err := tx.Exec(query, tripID, stop.Position, stop.AlbumID); err != nil {
+			return fmt.Errorf("failed to create trip stop: %w", err)
+		}
+	}
+	for _, leg := range legs {
+		query := `
+			INSERT OR IGNORE INTO paths (id, user_id, from_album_id, to_album_id, created_at)
+			VALUES (?, ?, ?, ?, ?)
+		`
+		if _, err := tx.Exec(query, leg.ID, leg.UserID, leg.FromAlbumID, leg.ToAlbumID, leg.CreatedAt); err != nil {
+			return fmt.Errorf("failed to create trip leg: %w", err)
+		}
+	}
+	return nil
+}
@@ -63,4 +63,18 @@ func (dao *UserDAO) Delete(id string) error {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
 	return nil
+}
+
+// GetDataVersion returns a counter that changes whenever the user's albums, photos
+// or paths change
+func (dao *UserDAO) GetDataVersion(id string) (int64, error) {
+	var version int64
+	err := database.DB.Get(&version, `SELECT data_version FROM users WHERE id = ?`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get user data version: %w", err)
+	}
+	return version, nil
 }
\ No newline at end of file
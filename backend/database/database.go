@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -45,7 +46,6 @@ func Initialize() error {
 		return fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	DB = db
 	logging.WithFields(map[string]interface{}{
 		"max_open_conns":     25,
 		"max_idle_conns":     5,
@@ -55,6 +55,14 @@ func Initialize() error {
 		"foreign_keys":      true,
 	}).Info("Database connection established with optimized settings")
 
+	return Setup(db)
+}
+
+// Setup installs db as the shared connection, applies pragmas and creates the schema.
+// It is used by Initialize and by tests that need an isolated database.
+func Setup(db *sqlx.DB) error {
+	DB = db
+
 	// Apply additional performance optimizations
 	if err := optimizeDatabase(); err != nil {
 		return fmt.Errorf("failed to optimize database: %w", err)
@@ -77,6 +85,7 @@ func createTables() error {
 		id TEXT PRIMARY KEY,
 		username TEXT UNIQUE NOT NULL,
 		password_hash TEXT NOT NULL,
+		data_version INTEGER NOT NULL DEFAULT 0,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);`
@@ -92,6 +101,16 @@ func createTables() error {
 		longitude REAL NOT NULL,
 		created_at DATETIME NOT NULL,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		cover_photo_id TEXT REFERENCES photos(id) ON DELETE SET NULL,
+		start_at DATETIME,
+		end_at DATETIME,
+		dates_manual INTEGER NOT NULL DEFAULT 0,
+		timezone TEXT NOT NULL DEFAULT '',
+		country_code TEXT NOT NULL DEFAULT '',
+		country TEXT NOT NULL DEFAULT '',
+		region TEXT NOT NULL DEFAULT '',
+		city TEXT NOT NULL DEFAULT '',
+		external_id TEXT NOT NULL DEFAULT '',
 		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 	);`
 
@@ -106,6 +125,12 @@ func createTables() error {
 		mime_type TEXT NOT NULL,
 		display_order INTEGER NOT NULL DEFAULT 0,
 		uploaded_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		taken_at DATETIME,
+		rating INTEGER NOT NULL DEFAULT 0 CHECK (rating BETWEEN 0 AND 5),
+		favorite INTEGER NOT NULL DEFAULT 0,
+		latitude REAL,
+		longitude REAL,
+		description TEXT NOT NULL DEFAULT '',
 		FOREIGN KEY (album_id) REFERENCES albums(id) ON DELETE CASCADE
 	);`
 
@@ -117,28 +142,295 @@ func createTables() error {
 		from_album_id TEXT NOT NULL,
 		to_album_id TEXT NOT NULL,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		transport_mode TEXT NOT NULL DEFAULT '',
+		duration_minutes INTEGER,
+		depart_at DATETIME,
+		arrive_at DATETIME,
+		notes TEXT NOT NULL DEFAULT '',
+		geometry TEXT NOT NULL DEFAULT '',
 		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
 		FOREIGN KEY (from_album_id) REFERENCES albums(id) ON DELETE CASCADE,
 		FOREIGN KEY (to_album_id) REFERENCES albums(id) ON DELETE CASCADE,
 		UNIQUE(from_album_id, to_album_id)
 	);`
 
+	// Tags table (names are unique per user, case-insensitively)
+	tagsTable := `
+	CREATE TABLE IF NOT EXISTS tags (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		name TEXT NOT NULL COLLATE NOCASE,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+		UNIQUE(user_id, name)
+	);`
+
+	// Album-tag links
+	albumTagsTable := `
+	CREATE TABLE IF NOT EXISTS album_tags (
+		album_id TEXT NOT NULL,
+		tag_id TEXT NOT NULL,
+		PRIMARY KEY (album_id, tag_id),
+		FOREIGN KEY (album_id) REFERENCES albums(id) ON DELETE CASCADE,
+		FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+	);`
+
+	// Photo-tag links
+	photoTagsTable := `
+	CREATE TABLE IF NOT EXISTS photo_tags (
+		photo_id TEXT NOT NULL,
+		tag_id TEXT NOT NULL,
+		PRIMARY KEY (photo_id, tag_id),
+		FOREIGN KEY (photo_id) REFERENCES photos(id) ON DELETE CASCADE,
+		FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+	);`
+
+	// Offline gazetteer used for geocoding; reloaded whenever the dataset changes
+	gazetteerTable := `
+	CREATE TABLE IF NOT EXISTS gazetteer_places (
+		id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		name_zh TEXT NOT NULL DEFAULT '',
+		latitude REAL NOT NULL,
+		longitude REAL NOT NULL,
+		country_code TEXT NOT NULL,
+		country TEXT NOT NULL DEFAULT '',
+		country_zh TEXT NOT NULL DEFAULT '',
+		region TEXT NOT NULL DEFAULT '',
+		population INTEGER NOT NULL DEFAULT 0,
+		timezone TEXT NOT NULL DEFAULT ''
+	);`
+
+	// Searchable gazetteer names in every language a place is known by
+	gazetteerNamesTable := `
+	CREATE TABLE IF NOT EXISTS gazetteer_names (
+		place_id INTEGER NOT NULL,
+		name TEXT NOT NULL COLLATE NOCASE,
+		lang TEXT NOT NULL DEFAULT '',
+		FOREIGN KEY (place_id) REFERENCES gazetteer_places(id) ON DELETE CASCADE
+	);`
+
+	// Gazetteer bookkeeping such as the loaded dataset version
+	gazetteerMetaTable := `
+	CREATE TABLE IF NOT EXISTS gazetteer_meta (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);`
+
+	// User-defined places: a centre with a radius, or a polygon stored as a JSON
+	// array of [longitude, latitude] vertices
+	savedPlacesTable := `
+	CREATE TABLE IF NOT EXISTS saved_places (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		name TEXT NOT NULL COLLATE NOCASE,
+		kind TEXT NOT NULL CHECK (kind IN ('circle', 'polygon')),
+		latitude REAL,
+		longitude REAL,
+		radius_m REAL,
+		polygon TEXT NOT NULL DEFAULT '',
+		auto_tag INTEGER NOT NULL DEFAULT 1,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+		UNIQUE(user_id, name)
+	);`
+
+	// Trips group albums into an ordered itinerary
+	tripsTable := `
+	CREATE TABLE IF NOT EXISTS trips (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		title TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		start_at DATETIME,
+		end_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+
+	// Trip stops in visiting order; an album may be a stop of several trips
+	tripStopsTable := `
+	CREATE TABLE IF NOT EXISTS trip_stops (
+		trip_id TEXT NOT NULL,
+		position INTEGER NOT NULL,
+		album_id TEXT NOT NULL,
+		PRIMARY KEY (trip_id, position),
+		FOREIGN KEY (trip_id) REFERENCES trips(id) ON DELETE CASCADE,
+		FOREIGN KEY (album_id) REFERENCES albums(id) ON DELETE CASCADE
+	);`
+
 	// Execute table creation
-	tables := []string{usersTable, albumsTable, photosTable, pathsTable}
+	tables := []string{usersTable, albumsTable, photosTable, pathsTable, tagsTable, albumTagsTable, photoTagsTable,
+		gazetteerTable, gazetteerNamesTable, gazetteerMetaTable, savedPlacesTable, tripsTable, tripStopsTable}
 	for _, table := range tables {
 		if _, err := DB.Exec(table); err != nil {
 			return fmt.Errorf("failed to create table: %w", err)
 		}
 	}
 
+	// Bring tables created by older versions up to date
+	if err := migrateTables(); err != nil {
+		return fmt.Errorf("failed to migrate tables: %w", err)
+	}
+
 	// Create indexes
 	if err := createIndexes(); err != nil {
 		return fmt.Errorf("failed to create indexes: %w", err)
 	}
 
+	if err := createTriggers(); err != nil {
+		return fmt.Errorf("failed to create triggers: %w", err)
+	}
+
+	return nil
+}
+
+// createTriggers installs triggers that bump users.data_version whenever a user's
+// albums, photos or paths change, so derived data can be cached per version
+func createTriggers() error {
+	bump := func(userExpr string) string {
+		return "UPDATE users SET data_version = data_version + 1 WHERE id = " + userExpr + ";"
+	}
+	albumOwner := func(row string) string {
+		return "(SELECT user_id FROM albums WHERE id = " + row + ".album_id)"
+	}
+
+	var triggers []string
+	for _, event := range []string{"INSERT", "UPDATE", "DELETE"} {
+		row := "NEW"
+		if event == "DELETE" {
+			row = "OLD"
+		}
+		name := strings.ToLower(event)
+		triggers = append(triggers,
+			fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS trg_albums_%s_version AFTER %s ON albums BEGIN %s END;",
+				name, event, bump(row+".user_id")),
+			fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS trg_photos_%s_version AFTER %s ON photos BEGIN %s END;",
+				name, event, bump(albumOwner(row))),
+			fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS trg_paths_%s_version AFTER %s ON paths BEGIN %s END;",
+				name, event, bump(row+".user_id")),
+		)
+	}
+
+	for _, trigger := range triggers {
+		if _, err := DB.Exec(trigger); err != nil {
+			return fmt.Errorf("failed to create trigger: %w", err)
+		}
+	}
 	return nil
 }
 
+// columnMigration describes a column added to an existing table after its initial release
+type columnMigration struct {
+	table      string
+	column     string
+	definition string
+}
+
+// migrateTables adds columns that databases created by older versions are missing.
+// Fresh databases already get these columns from createTables.
+func migrateTables() error {
+	migrations := []columnMigration{
+		{"users", "data_version", "INTEGER NOT NULL DEFAULT 0"},
+		{"albums", "cover_photo_id", "TEXT REFERENCES photos(id) ON DELETE SET NULL"},
+		{"albums", "start_at", "DATETIME"},
+		{"albums", "end_at", "DATETIME"},
+		{"albums", "dates_manual", "INTEGER NOT NULL DEFAULT 0"},
+		{"albums", "timezone", "TEXT NOT NULL DEFAULT ''"},
+		{"albums", "country_code", "TEXT NOT NULL DEFAULT ''"},
+		{"albums", "country", "TEXT NOT NULL DEFAULT ''"},
+		{"albums", "region", "TEXT NOT NULL DEFAULT ''"},
+		{"albums", "city", "TEXT NOT NULL DEFAULT ''"},
+		{"albums", "external_id", "TEXT NOT NULL DEFAULT ''"},
+		{"photos", "taken_at", "DATETIME"},
+		{"photos", "rating", "INTEGER NOT NULL DEFAULT 0 CHECK (rating BETWEEN 0 AND 5)"},
+		{"photos", "favorite", "INTEGER NOT NULL DEFAULT 0"},
+		{"photos", "latitude", "REAL"},
+		{"photos", "longitude", "REAL"},
+		{"photos", "description", "TEXT NOT NULL DEFAULT ''"},
+		{"paths", "transport_mode", "TEXT NOT NULL DEFAULT ''"},
+		{"paths", "duration_minutes", "INTEGER"},
+		{"paths", "depart_at", "DATETIME"},
+		{"paths", "arrive_at", "DATETIME"},
+		{"paths", "notes", "TEXT NOT NULL DEFAULT ''"},
+		{"paths", "geometry", "TEXT NOT NULL DEFAULT ''"},
+	}
+
+	for _, m := range migrations {
+		exists, err := columnExists(m.table, m.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", m.table, m.column, m.definition)
+		if _, err := DB.Exec(query); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", m.table, m.column, err)
+		}
+		logging.WithFields(map[string]interface{}{
+			"table":  m.table,
+			"column": m.column,
+		}).Info("Database column added")
+	}
+
+	return backfillAlbumDates()
+}
+
+// backfillAlbumDates gives albums that predate their date range one starting and ending
+// at their creation time. Albums used to be created with times in the server's zone;
+// their times are rewritten in UTC, as all times are now stored, so that the stored
+// strings sort and compare in time order.
+func backfillAlbumDates() error {
+	var albums []struct {
+		ID        string     `db:"id"`
+		CreatedAt time.Time  `db:"created_at"`
+		StartAt   *time.Time `db:"start_at"`
+		EndAt     *time.Time `db:"end_at"`
+	}
+	query := `
+		SELECT id, created_at, start_at, end_at FROM albums
+		WHERE start_at IS NULL OR end_at IS NULL
+			OR created_at NOT LIKE '% UTC' OR start_at NOT LIKE '% UTC' OR end_at NOT LIKE '% UTC'
+	`
+	if err := DB.Select(&albums, query); err != nil {
+		return fmt.Errorf("failed to get albums to backfill: %w", err)
+	}
+
+	for _, album := range albums {
+		createdAt := album.CreatedAt.UTC()
+		startAt := createdAt
+		if album.StartAt != nil {
+			startAt = album.StartAt.UTC()
+		}
+		endAt := startAt
+		if album.EndAt != nil {
+			endAt = album.EndAt.UTC()
+		}
+		update := `UPDATE albums SET created_at = ?, start_at = ?, end_at = ? WHERE id = ?`
+		if _, err := DB.Exec(update, createdAt, startAt, endAt, album.ID); err != nil {
+			return fmt.Errorf("failed to backfill album dates: %w", err)
+		}
+	}
+	if len(albums) > 0 {
+		logging.WithField("album_count", len(albums)).Info("Album dates backfilled")
+	}
+	return nil
+}
+
+// columnExists reports whether table already has the named column
+func columnExists(table, column string) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`
+	if err := DB.Get(&count, query, table, column); err != nil {
+		return false, fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	return count > 0, nil
+}
+
 // optimizeDatabase applies performance optimizations to the database
 func optimizeDatabase() error {
 	optimizations := []string{
@@ -183,12 +475,17 @@ func createIndexes() error {
 		"CREATE INDEX IF NOT EXISTS idx_albums_location ON albums(latitude, longitude);",
 		"CREATE INDEX IF NOT EXISTS idx_albums_user_created ON albums(user_id, created_at);",
 		"CREATE INDEX IF NOT EXISTS idx_albums_user_location ON albums(user_id, latitude, longitude);",
+		"CREATE INDEX IF NOT EXISTS idx_albums_user_range ON albums(user_id, start_at, end_at);",
+		"CREATE INDEX IF NOT EXISTS idx_albums_user_country ON albums(user_id, country_code);",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_albums_user_external ON albums(user_id, external_id) WHERE external_id != '';",
 		
 		// Photo table indexes
 		"CREATE INDEX IF NOT EXISTS idx_photos_album_id ON photos(album_id);",
 		"CREATE INDEX IF NOT EXISTS idx_photos_order ON photos(album_id, display_order);",
 		"CREATE INDEX IF NOT EXISTS idx_photos_uploaded_at ON photos(uploaded_at);",
 		"CREATE INDEX IF NOT EXISTS idx_photos_album_order ON photos(album_id, display_order, uploaded_at);",
+		"CREATE INDEX IF NOT EXISTS idx_photos_album_taken ON photos(album_id, taken_at);",
+		"CREATE INDEX IF NOT EXISTS idx_photos_album_rating ON photos(album_id, rating, favorite);",
 		
 		// Path table indexes
 		"CREATE INDEX IF NOT EXISTS idx_paths_user_id ON paths(user_id);",
@@ -196,6 +493,20 @@ func createIndexes() error {
 		"CREATE INDEX IF NOT EXISTS idx_paths_to_album ON paths(to_album_id);",
 		"CREATE INDEX IF NOT EXISTS idx_paths_user_from ON paths(user_id, from_album_id);",
 		"CREATE INDEX IF NOT EXISTS idx_paths_created_at ON paths(created_at);",
+
+		// Tag table indexes
+		"CREATE INDEX IF NOT EXISTS idx_tags_user_id ON tags(user_id);",
+		"CREATE INDEX IF NOT EXISTS idx_album_tags_tag ON album_tags(tag_id);",
+		"CREATE INDEX IF NOT EXISTS idx_photo_tags_tag ON photo_tags(tag_id);",
+
+		// Trip indexes
+		"CREATE INDEX IF NOT EXISTS idx_trips_user_id ON trips(user_id);",
+		"CREATE INDEX IF NOT EXISTS idx_trip_stops_album ON trip_stops(album_id);",
+
+		// Gazetteer indexes
+		"CREATE INDEX IF NOT EXISTS idx_gazetteer_places_location ON gazetteer_places(latitude, longitude);",
+		"CREATE INDEX IF NOT EXISTS idx_gazetteer_names_name ON gazetteer_names(name);",
+		"CREATE INDEX IF NOT EXISTS idx_gazetteer_names_place ON gazetteer_names(place_id);",
 	}
 
 	for i, index := range indexes {
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"geoalbum/backend/logging"
+)
+
+func TestBackfillAlbumDates(t *testing.T) {
+	require.NoError(t, logging.InitializeGlobalLogger(&logging.LogConfig{Level: logging.ErrorLevel, Format: "text", Output: "stdout"}))
+	db, err := sqlx.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, Setup(db))
+
+	_, err = db.Exec(`INSERT INTO users (id, username, password_hash) VALUES ('user', 'traveller', 'x')`)
+	require.NoError(t, err)
+
+	// Albums as older versions left them, created in the server's zone
+	tokyo := time.FixedZone("JST", 9*3600)
+	insert := `INSERT INTO albums (id, user_id, title, latitude, longitude, created_at, start_at, end_at) VALUES (?, 'user', ?, 0, 0, ?, ?, ?)`
+	early := time.Date(2024, 5, 1, 8, 30, 0, 0, tokyo) // 23:30 UTC the day before
+	late := time.Date(2024, 4, 30, 23, 45, 0, 0, time.UTC)
+	_, err = db.Exec(insert, "early", "early", early, nil, nil)
+	require.NoError(t, err)
+	_, err = db.Exec(insert, "late", "late", late, late, nil)
+	require.NoError(t, err)
+	_, err = db.Exec(insert, "ranged", "ranged", early, early, early.Add(time.Hour))
+	require.NoError(t, err)
+
+	require.NoError(t, backfillAlbumDates())
+
+	var stored []struct {
+		ID        string `db:"id"`
+		CreatedAt string `db:"created_at"`
+		StartAt   string `db:"start_at"`
+		EndAt     string `db:"end_at"`
+	}
+	query := `SELECT id, CAST(created_at AS TEXT) AS created_at, CAST(start_at AS TEXT) AS start_at, CAST(end_at AS TEXT) AS end_at FROM albums ORDER BY start_at`
+	require.NoError(t, db.Select(&stored, query))
+	require.Len(t, stored, 3)
+	// The stored strings sort in time order
+	assert.Equal(t, "early", stored[0].ID)
+	assert.Equal(t, "ranged", stored[1].ID)
+	assert.Equal(t, "late", stored[2].ID)
+	assert.Equal(t, "2024-04-30 23:30:00 +0000 UTC", stored[0].StartAt)
+	assert.Equal(t, stored[0].CreatedAt, stored[0].EndAt)
+	assert.Equal(t, "2024-05-01 00:30:00 +0000 UTC", stored[1].EndAt)
+	assert.Equal(t, stored[2].StartAt, stored[2].EndAt)
+}
@@ -0,0 +1,218 @@
+// Package datum converts coordinates between WGS-84, the GPS datum stored by the
+// backend, and the obfuscated GCJ-02 and BD-09 systems required by map providers in
+// mainland China.
+//
+// The offsets are only applied inside mainland China; elsewhere all three systems
+// coincide, as they do on Chinese providers' own maps.
+package datum
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Datum names a coordinate reference system
+type Datum string
+
+const (
+	WGS84 Datum = "wgs84"
+	GCJ02 Datum = "gcj02"
+	BD09  Datum = "bd09"
+)
+
+// Krasovsky 1940 ellipsoid used by GCJ-02
+const (
+	krasovskyA  = 6378245.0
+	krasovskyEE = 0.00669342162296594323
+)
+
+const bdXPi = math.Pi * 3000.0 / 180.0
+
+// Parse parses a datum name case-insensitively; an empty name is WGS-84
+func Parse(name string) (Datum, error) {
+	switch Datum(strings.ToLower(strings.TrimSpace(name))) {
+	case "", WGS84:
+		return WGS84, nil
+	case GCJ02:
+		return GCJ02, nil
+	case BD09:
+		return BD09, nil
+	}
+	return "", fmt.Errorf("invalid crs: %q must be wgs84, gcj02 or bd09", name)
+}
+
+// Convert converts a point from one datum to another
+func Convert(lat, lng float64, from, to Datum) (float64, float64) {
+	if from == to || !InChina(lat, lng) {
+		return lat, lng
+	}
+
+	switch from {
+	case GCJ02:
+		lat, lng = GCJ02ToWGS84(lat, lng)
+	case BD09:
+		lat, lng = BD09ToWGS84(lat, lng)
+	}
+	switch to {
+	case GCJ02:
+		return WGS84ToGCJ02(lat, lng)
+	case BD09:
+		return WGS84ToBD09(lat, lng)
+	}
+	return lat, lng
+}
+
+// FromWGS84 converts a stored WGS-84 point for output in d
+func FromWGS84(lat, lng float64, d Datum) (float64, float64) {
+	return Convert(lat, lng, WGS84, d)
+}
+
+// ToWGS84 converts a point given in d to WGS-84 for storage
+func ToWGS84(lat, lng float64, d Datum) (float64, float64) {
+	return Convert(lat, lng, d, WGS84)
+}
+
+// box is a latitude/longitude rectangle
+type box struct {
+	north, west, south, east float64
+}
+
+func (b box) contains(lat, lng float64) bool {
+	return lat <= b.north && lat >= b.south && lng >= b.west && lng <= b.east
+}
+
+// chinaRegions approximate mainland China; chinaExclusions cut out Taiwan and the
+// neighbouring parts of Vietnam, Russia and North Korea the regions overlap
+var (
+	chinaRegions = []box{
+		{49.220400, 79.446200, 42.889900, 96.330000},
+		{54.141500, 109.687200, 39.374200, 135.000200},
+		{42.889900, 73.124600, 29.529700, 124.143255},
+		{29.529700, 82.968400, 26.718600, 97.035200},
+		{29.529700, 97.025300, 20.414096, 124.367395},
+		{20.414096, 107.975793, 17.871542, 111.744104},
+	}
+	chinaExclusions = []box{
+		{25.398623, 119.921265, 21.785006, 122.497559},
+		{22.284000, 101.865200, 20.098800, 106.665000},
+		{21.542200, 106.452500, 20.487800, 108.051000},
+		{55.817500, 109.032300, 50.325700, 119.127000},
+		{55.817500, 127.456800, 49.557400, 137.022700},
+		{44.892200, 131.266200, 42.569200, 137.022700},
+	}
+)
+
+// InChina reports whether a point lies in the area where GCJ-02 is applied
+func InChina(lat, lng float64) bool {
+	inside := false
+	for _, region := range chinaRegions {
+		if region.contains(lat, lng) {
+			inside = true
+			break
+		}
+	}
+	if !inside {
+		return false
+	}
+	for _, exclusion := range chinaExclusions {
+		if exclusion.contains(lat, lng) {
+			return false
+		}
+	}
+	return true
+}
+
+// WGS84ToGCJ02 applies the GCJ-02 offset
+func WGS84ToGCJ02(lat, lng float64) (float64, float64) {
+	if !InChina(lat, lng) {
+		return lat, lng
+	}
+	dLat, dLng := gcjOffset(lat, lng)
+	return lat + dLat, lng + dLng
+}
+
+// GCJ02ToWGS84 removes the GCJ-02 offset. The offset has no closed-form inverse, so
+// the point is refined until it maps back to within a fraction of a millimetre.
+func GCJ02ToWGS84(lat, lng float64) (float64, float64) {
+	if !InChina(lat, lng) {
+		return lat, lng
+	}
+	return invert(lat, lng, lat, lng, WGS84ToGCJ02)
+}
+
+// GCJ02ToBD09 applies Baidu's additional offset to a GCJ-02 point
+func GCJ02ToBD09(lat, lng float64) (float64, float64) {
+	z := math.Hypot(lng, lat) + 0.00002*math.Sin(lat*bdXPi)
+	theta := math.Atan2(lat, lng) + 0.000003*math.Cos(lng*bdXPi)
+	return z*math.Sin(theta) + 0.006, z*math.Cos(theta) + 0.0065
+}
+
+// BD09ToGCJ02 removes Baidu's offset from a BD-09 point. Baidu's published inverse
+// is off by up to a decimetre, so it only seeds the same refinement as GCJ02ToWGS84.
+func BD09ToGCJ02(lat, lng float64) (float64, float64) {
+	x, y := lng-0.0065, lat-0.006
+	z := math.Hypot(x, y) - 0.00002*math.Sin(y*bdXPi)
+	theta := math.Atan2(y, x) - 0.000003*math.Cos(x*bdXPi)
+	return invert(lat, lng, z*math.Sin(theta), z*math.Cos(theta), GCJ02ToBD09)
+}
+
+// WGS84ToBD09 converts a WGS-84 point to BD-09
+func WGS84ToBD09(lat, lng float64) (float64, float64) {
+	if !InChina(lat, lng) {
+		return lat, lng
+	}
+	return GCJ02ToBD09(WGS84ToGCJ02(lat, lng))
+}
+
+// BD09ToWGS84 converts a BD-09 point to WGS-84
+func BD09ToWGS84(lat, lng float64) (float64, float64) {
+	if !InChina(lat, lng) {
+		return lat, lng
+	}
+	return GCJ02ToWGS84(BD09ToGCJ02(lat, lng))
+}
+
+// invert finds the point that forward maps to (lat, lng), starting from a guess
+func invert(lat, lng, guessLat, guessLng float64, forward func(lat, lng float64) (float64, float64)) (float64, float64) {
+	for i := 0; i < 30; i++ {
+		gotLat, gotLng := forward(guessLat, guessLng)
+		errLat, errLng := gotLat-lat, gotLng-lng
+		guessLat -= errLat
+		guessLng -= errLng
+		if math.Abs(errLat) < 1e-10 && math.Abs(errLng) < 1e-10 {
+			break
+		}
+	}
+	return guessLat, guessLng
+}
+
+// gcjOffset returns the GCJ-02 shift in degrees at a WGS-84 point
+func gcjOffset(lat, lng float64) (float64, float64) {
+	x, y := lng-105.0, lat-35.0
+	dLat := transformLat(x, y)
+	dLng := transformLng(x, y)
+
+	radLat := lat / 180.0 * math.Pi
+	magic := 1 - krasovskyEE*math.Sin(radLat)*math.Sin(radLat)
+	sqrtMagic := math.Sqrt(magic)
+	dLat = dLat * 180.0 / ((krasovskyA * (1 - krasovskyEE)) / (magic * sqrtMagic) * math.Pi)
+	dLng = dLng * 180.0 / (krasovskyA / sqrtMagic * math.Cos(radLat) * math.Pi)
+	return dLat, dLng
+}
+
+func transformLat(x, y float64) float64 {
+	ret := -100.0 + 2.0*x + 3.0*y + 0.2*y*y + 0.1*x*y + 0.2*math.Sqrt(math.Abs(x))
+	ret += (20.0*math.Sin(6.0*x*math.Pi) + 20.0*math.Sin(2.0*x*math.Pi)) * 2.0 / 3.0
+	ret += (20.0*math.Sin(y*math.Pi) + 40.0*math.Sin(y/3.0*math.Pi)) * 2.0 / 3.0
+	ret += (160.0*math.Sin(y/12.0*math.Pi) + 320*math.Sin(y*math.Pi/30.0)) * 2.0 / 3.0
+	return ret
+}
+
+func transformLng(x, y float64) float64 {
+	ret := 300.0 + x + 2.0*y + 0.1*x*x + 0.1*x*y + 0.1*math.Sqrt(math.Abs(x))
+	ret += (20.0*math.Sin(6.0*x*math.Pi) + 20.0*math.Sin(2.0*x*math.Pi)) * 2.0 / 3.0
+	ret += (20.0*math.Sin(x*math.Pi) + 40.0*math.Sin(x/3.0*math.Pi)) * 2.0 / 3.0
+	ret += (150.0*math.Sin(x/12.0*math.Pi) + 300.0*math.Sin(x/30.0*math.Pi)) * 2.0 / 3.0
+	return ret
+}
@@ -0,0 +1,108 @@
+package datum
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const tolerance = 1e-6
+
+func TestWGS84ToGCJ02(t *testing.T) {
+	lat, lng := WGS84ToGCJ02(39.915, 116.404)
+	assert.InDelta(t, 39.91640428150164, lat, tolerance)
+	assert.InDelta(t, 116.41024449916938, lng, tolerance)
+}
+
+func TestGCJ02AndBD09(t *testing.T) {
+	lat, lng := GCJ02ToBD09(39.915, 116.404)
+	assert.InDelta(t, 39.92133699351021, lat, tolerance)
+	assert.InDelta(t, 116.41036949371029, lng, tolerance)
+
+	// Baidu's closed-form inverse gives 39.90865673957631, 116.39762729119315; the
+	// refined result differs from it by well under a metre
+	lat, lng = BD09ToGCJ02(39.915, 116.404)
+	assert.InDelta(t, 39.90865673957631, lat, 1e-5)
+	assert.InDelta(t, 116.39762729119315, lng, 1e-5)
+
+	bdLat, bdLng := GCJ02ToBD09(lat, lng)
+	assert.InDelta(t, 39.915, bdLat, 1e-9)
+	assert.InDelta(t, 116.404, bdLng, 1e-9)
+}
+
+func TestRoundTripsInsideChina(t *testing.T) {
+	points := [][2]float64{
+		{39.9075, 116.3972}, // Beijing
+		{31.2304, 121.4737}, // Shanghai
+		{22.5431, 114.0579}, // Shenzhen
+		{43.8256, 87.6168},  // Urumqi
+		{29.6520, 91.1721},  // Lhasa
+		{18.2528, 109.5119}, // Sanya
+		{53.4833, 122.3667}, // Mohe
+	}
+	for _, p := range points {
+		for _, d := range []Datum{GCJ02, BD09} {
+			lat, lng := FromWGS84(p[0], p[1], d)
+			shift := math.Hypot(lat-p[0], lng-p[1])
+			assert.Greater(t, shift, 1e-4, "%v should move in %s", p, d)
+			assert.Less(t, shift, 0.02, "%v should move by at most a couple of kilometres in %s", p, d)
+
+			backLat, backLng := ToWGS84(lat, lng, d)
+			assert.InDelta(t, p[0], backLat, 1e-8, "%v lat round trip via %s", p, d)
+			assert.InDelta(t, p[1], backLng, 1e-8, "%v lng round trip via %s", p, d)
+		}
+	}
+}
+
+func TestConvertBetweenOffsetDatums(t *testing.T) {
+	gcjLat, gcjLng := WGS84ToGCJ02(31.2304, 121.4737)
+	bdLat, bdLng := Convert(gcjLat, gcjLng, GCJ02, BD09)
+	wantLat, wantLng := GCJ02ToBD09(gcjLat, gcjLng)
+	assert.InDelta(t, wantLat, bdLat, 1e-8)
+	assert.InDelta(t, wantLng, bdLng, 1e-8)
+
+	lat, lng := Convert(bdLat, bdLng, BD09, GCJ02)
+	assert.InDelta(t, gcjLat, lat, 1e-8)
+	assert.InDelta(t, gcjLng, lng, 1e-8)
+}
+
+func TestOutsideChinaIsUnchanged(t *testing.T) {
+	points := [][2]float64{
+		{35.0116, 135.7681},  // Kyoto
+		{48.8566, 2.3522},    // Paris
+		{-33.8688, 151.2093}, // Sydney
+		{37.5665, 126.9780},  // Seoul
+		{25.0330, 121.5654},  // Taipei
+		{21.0278, 105.8342},  // Hanoi
+		{0, 0},
+	}
+	for _, p := range points {
+		for _, d := range []Datum{WGS84, GCJ02, BD09} {
+			lat, lng := FromWGS84(p[0], p[1], d)
+			assert.Equal(t, p[0], lat, "%v in %s", p, d)
+			assert.Equal(t, p[1], lng, "%v in %s", p, d)
+			lat, lng = ToWGS84(p[0], p[1], d)
+			assert.Equal(t, p[0], lat, "%v from %s", p, d)
+			assert.Equal(t, p[1], lng, "%v from %s", p, d)
+		}
+	}
+}
+
+func TestWGS84IsIdentity(t *testing.T) {
+	lat, lng := Convert(39.9075, 116.3972, WGS84, WGS84)
+	assert.Equal(t, 39.9075, lat)
+	assert.Equal(t, 116.3972, lng)
+}
+
+func TestParse(t *testing.T) {
+	for name, want := range map[string]Datum{"": WGS84, "wgs84": WGS84, "GCJ02": GCJ02, " bd09 ": BD09} {
+		got, err := Parse(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, got, name)
+	}
+
+	_, err := Parse("epsg:3857")
+	assert.Error(t, err)
+}
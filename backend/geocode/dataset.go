@@ -0,0 +1,232 @@
+package geocode
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"geoalbum/backend/model"
+)
+
+// formatVersion changes whenever the way places are derived from the source files
+// changes, so that an unchanged dataset is still reloaded after an upgrade
+const formatVersion = "2"
+
+// bundled is a small gazetteer of major cities shipped with the binary. It is
+// used unless a full GeoNames dump is configured.
+//
+//go:embed data/cities.tsv data/countries.tsv
+var bundled embed.FS
+
+// Dataset is a set of gazetteer places together with a version identifying its contents
+type Dataset struct {
+	Version string
+	Places  []model.GazetteerPlace
+}
+
+// Load returns the GeoNames dump in dir, or the bundled gazetteer when dir is empty
+func Load(dir string) (*Dataset, error) {
+	if dir == "" {
+		return Bundled()
+	}
+	return LoadGeoNames(dir)
+}
+
+// Bundled returns the gazetteer embedded in the binary
+func Bundled() (*Dataset, error) {
+	cities, err := bundled.ReadFile("data/cities.tsv")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundled cities: %w", err)
+	}
+	countries, err := bundled.ReadFile("data/countries.tsv")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundled countries: %w", err)
+	}
+
+	countryNames := make(map[string][2]string)
+	for _, fields := range readTSV(countries) {
+		if len(fields) < 3 {
+			continue
+		}
+		countryNames[fields[0]] = [2]string{fields[1], fields[2]}
+	}
+
+	var places []model.GazetteerPlace
+	for _, fields := range readTSV(cities) {
+		// name, name_zh, alternate_names, latitude, longitude, country_code, region, population, timezone
+		if len(fields) < 9 {
+			continue
+		}
+		lat, latErr := strconv.ParseFloat(fields[3], 64)
+		lng, lngErr := strconv.ParseFloat(fields[4], 64)
+		if latErr != nil || lngErr != nil {
+			continue
+		}
+		population, _ := strconv.ParseInt(fields[7], 10, 64)
+		country := countryNames[fields[5]]
+
+		places = append(places, model.GazetteerPlace{
+			Name:           fields[0],
+			NameZh:         fields[1],
+			AlternateNames: splitNames(fields[2]),
+			Latitude:       lat,
+			Longitude:      lng,
+			CountryCode:    fields[5],
+			Country:        country[0],
+			CountryZh:      country[1],
+			Region:         fields[6],
+			Population:     population,
+			Timezone:       fields[8],
+		})
+	}
+
+	return &Dataset{Version: datasetVersion("bundled", cities, countries), Places: places}, nil
+}
+
+// LoadGeoNames reads a GeoNames dump from dir: a cities file (cities500.txt,
+// cities1000.txt, cities5000.txt or cities15000.txt), and optionally
+// admin1CodesASCII.txt and countryInfo.txt for region and country names.
+// See https://download.geonames.org/export/dump/ for the file formats.
+func LoadGeoNames(dir string) (*Dataset, error) {
+	var cities []byte
+	for _, name := range []string{"cities500.txt", "cities1000.txt", "cities5000.txt", "cities15000.txt"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err == nil {
+			cities = data
+			break
+		}
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+	}
+	if cities == nil {
+		return nil, fmt.Errorf("no GeoNames cities file found in %s", dir)
+	}
+
+	admin1, err := readOptional(filepath.Join(dir, "admin1CodesASCII.txt"))
+	if err != nil {
+		return nil, err
+	}
+	countryInfo, err := readOptional(filepath.Join(dir, "countryInfo.txt"))
+	if err != nil {
+		return nil, err
+	}
+
+	// admin1CodesASCII: "JP.26", name, ascii name, geonameid
+	regions := make(map[string]string)
+	for _, fields := range readTSV(admin1) {
+		if len(fields) >= 2 {
+			regions[fields[0]] = fields[1]
+		}
+	}
+	// countryInfo: ISO, ISO3, ISO-numeric, fips, country name, ...
+	countryNames := make(map[string]string)
+	for _, fields := range readTSV(countryInfo) {
+		if len(fields) >= 5 {
+			countryNames[fields[0]] = fields[4]
+		}
+	}
+
+	var places []model.GazetteerPlace
+	for _, fields := range readTSV(cities) {
+		// geonameid, name, asciiname, alternatenames, latitude, longitude, feature class,
+		// feature code, country code, cc2, admin1 code, admin2, admin3, admin4,
+		// population, elevation, dem, timezone, modification date
+		if len(fields) < 18 {
+			continue
+		}
+		id, idErr := strconv.ParseInt(fields[0], 10, 64)
+		lat, latErr := strconv.ParseFloat(fields[4], 64)
+		lng, lngErr := strconv.ParseFloat(fields[5], 64)
+		if idErr != nil || latErr != nil || lngErr != nil {
+			continue
+		}
+		population, _ := strconv.ParseInt(fields[14], 10, 64)
+		alternates := splitNames(fields[3])
+		if fields[2] != fields[1] {
+			alternates = append(alternates, fields[2])
+		}
+
+		places = append(places, model.GazetteerPlace{
+			ID:             id,
+			Name:           fields[1],
+			NameZh:         firstHanName(alternates),
+			AlternateNames: alternates,
+			Latitude:       lat,
+			Longitude:      lng,
+			CountryCode:    fields[8],
+			Country:        countryNames[fields[8]],
+			Region:         regions[fields[8]+"."+fields[10]],
+			Population:     population,
+			Timezone:       fields[17],
+		})
+	}
+
+	return &Dataset{Version: datasetVersion("geonames", cities, admin1, countryInfo), Places: places}, nil
+}
+
+// readOptional reads a file, treating a missing file as empty
+func readOptional(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	return data, nil
+}
+
+// readTSV splits tab-separated data into rows, skipping blank and '#' comment lines
+func readTSV(data []byte) [][]string {
+	var rows [][]string
+	reader := bufio.NewReader(bytes.NewReader(data))
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if line != "" && !strings.HasPrefix(line, "#") {
+			rows = append(rows, strings.Split(line, "\t"))
+		}
+		if err == io.EOF {
+			return rows
+		}
+	}
+}
+
+// splitNames splits a comma-separated list of names
+func splitNames(names string) []string {
+	var result []string
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// firstHanName returns the first name written in Chinese characters
+func firstHanName(names []string) string {
+	for _, name := range names {
+		for _, r := range name {
+			if unicode.Is(unicode.Han, r) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// datasetVersion fingerprints the source files of a dataset
+func datasetVersion(source string, files ...[]byte) string {
+	hash := sha256.New()
+	for _, file := range files {
+		hash.Write(file)
+	}
+	return source + ":" + formatVersion + ":" + hex.EncodeToString(hash.Sum(nil))[:16]
+}
@@ -0,0 +1,82 @@
+package geocode
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"geoalbum/backend/model"
+)
+
+func findPlace(places []model.GazetteerPlace, name string) *model.GazetteerPlace {
+	for i := range places {
+		if places[i].Name == name {
+			return &places[i]
+		}
+	}
+	return nil
+}
+
+func TestBundled(t *testing.T) {
+	dataset, err := Bundled()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(dataset.Version, "bundled:"))
+
+	suva := findPlace(dataset.Places, "Suva")
+	require.NotNil(t, suva)
+	assert.Equal(t, "苏瓦", suva.NameZh)
+	assert.Equal(t, "FJ", suva.CountryCode)
+	assert.NotEmpty(t, suva.Country)
+	assert.InDelta(t, 178.4419, suva.Longitude, 1e-9)
+	assert.Equal(t, "Pacific/Fiji", suva.Timezone)
+
+	beijing := findPlace(dataset.Places, "Beijing")
+	require.NotNil(t, beijing)
+	assert.Equal(t, []string{"Peking", "Pekin", "Beijing Shi"}, beijing.AlternateNames)
+}
+
+func TestLoadGeoNames(t *testing.T) {
+	dir := t.TempDir()
+	row := func(fields ...string) string { return strings.Join(fields, "\t") + "\n" }
+	cities := "# comment\n\n" +
+		row("2198148", "Waiyevo", "Waiyevo", "Somosomo,瓦伊耶沃", "-16.79", "-179.98", "P", "PPL", "FJ", "", "03",
+			"", "", "", "1000", "", "5", "Pacific/Fiji", "2020-01-01") +
+		row("1850147", "Tōkyō", "Tokyo", "Edo", "35.6895", "139.69171", "P", "PPLC", "JP", "", "40",
+			"", "", "", "8336599", "", "44", "Asia/Tokyo", "2020-01-01") +
+		row("x", "Broken", "Broken", "", "0", "0", "P", "PPL", "XX", "", "", "", "", "", "0", "", "0", "", "") +
+		row("1", "Short", "Short")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cities1000.txt"), []byte(cities), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "admin1CodesASCII.txt"),
+		[]byte(row("FJ.03", "Northern", "Northern", "1")+row("JP.40", "Tokyo", "Tokyo", "2")), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "countryInfo.txt"),
+		[]byte("#ISO\tISO3\n"+row("FJ", "FJI", "242", "FJ", "Fiji")+row("JP", "JPN", "392", "JA", "Japan")), 0644))
+
+	dataset, err := LoadGeoNames(dir)
+	require.NoError(t, err)
+	require.Len(t, dataset.Places, 2, "malformed rows are skipped")
+	assert.True(t, strings.HasPrefix(dataset.Version, "geonames:"))
+
+	waiyevo := dataset.Places[0]
+	assert.Equal(t, int64(2198148), waiyevo.ID)
+	assert.Equal(t, -179.98, waiyevo.Longitude)
+	assert.Equal(t, "瓦伊耶沃", waiyevo.NameZh)
+	assert.Equal(t, "Northern", waiyevo.Region)
+	assert.Equal(t, "Fiji", waiyevo.Country)
+
+	tokyo := dataset.Places[1]
+	assert.Equal(t, []string{"Edo", "Tokyo"}, tokyo.AlternateNames, "the ASCII name is an alternate")
+	assert.Equal(t, "Japan", tokyo.Country)
+
+	// Changing a source file changes the version
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "countryInfo.txt"), []byte(row("FJ", "FJI", "242", "FJ", "Fidji")), 0644))
+	changed, err := LoadGeoNames(dir)
+	require.NoError(t, err)
+	assert.NotEqual(t, dataset.Version, changed.Version)
+
+	_, err = LoadGeoNames(t.TempDir())
+	assert.ErrorContains(t, err, "no GeoNames cities file")
+}
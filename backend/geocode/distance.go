@@ -0,0 +1,36 @@
+package geocode
+
+import "math"
+
+// earthRadiusKm is the mean Earth radius used for great-circle distances
+const earthRadiusKm = 6371.0088
+
+// DistanceKm returns the great-circle (haversine) distance between two points in kilometres
+func DistanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	dPhi := (lat2 - lat1) * math.Pi / 180
+	dLambda := (lng2 - lng1) * math.Pi / 180
+
+	a := math.Sin(dPhi/2)*math.Sin(dPhi/2) +
+		math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// BoundingBox returns the latitude/longitude deltas that enclose a circle of radiusKm
+// around a point at lat. The longitude delta grows towards the poles, where the
+// circle's widest point lies poleward of lat, and is 180 once the circle reaches a pole.
+func BoundingBox(lat, radiusKm float64) (latDelta, lngDelta float64) {
+	angle := radiusKm / earthRadiusKm
+	latDelta = angle * 180 / math.Pi
+	if math.Abs(lat)+latDelta >= 90 {
+		return latDelta, 180
+	}
+	// The meridians tangent to the circle are asin(sin r / cos lat) away
+	ratio := math.Sin(angle) / math.Cos(lat*math.Pi/180)
+	if ratio >= 1 {
+		return latDelta, 180
+	}
+	lngDelta = math.Asin(ratio) * 180 / math.Pi
+	return latDelta, lngDelta
+}
@@ -0,0 +1,55 @@
+package geocode
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistanceKm(t *testing.T) {
+	assert.InDelta(t, 343.9, DistanceKm(51.5074, -0.1278, 48.8566, 2.3522), 0.5) // London to Paris
+	assert.InDelta(t, 0, DistanceKm(35, 135, 35, 135), 1e-9)
+	// Across the antimeridian the short way round
+	assert.InDelta(t, 22.2, DistanceKm(0, 179.9, 0, -179.9), 0.1)
+	assert.InDelta(t, DistanceKm(-16.8, 179.97, -16.79, -179.98), DistanceKm(-16.8, -180.03, -16.79, -179.98), 1e-6)
+	// Antipodes are half the circumference apart
+	assert.InDelta(t, math.Pi*earthRadiusKm, DistanceKm(10, 20, -10, -160), 1e-6)
+}
+
+func TestBoundingBox(t *testing.T) {
+	latDelta, lngDelta := BoundingBox(0, 250)
+	assert.InDelta(t, 2.248, latDelta, 1e-3)
+	assert.InDelta(t, latDelta, lngDelta, 1e-9)
+
+	// Longitude degrees shrink towards the poles
+	latDelta, lngDelta = BoundingBox(60, 250)
+	assert.InDelta(t, 2*latDelta, lngDelta, 0.01)
+	_, south := BoundingBox(-60, 250)
+	assert.Equal(t, lngDelta, south)
+
+	// and the whole circle of longitude is covered once the circle reaches one
+	for _, lat := range []float64{88, 89.9, -90} {
+		_, lngDelta = BoundingBox(lat, 250)
+		assert.Equal(t, 180.0, lngDelta, "lat %v", lat)
+	}
+}
+
+func TestBoundingBoxEnclosesCircle(t *testing.T) {
+	for _, lat := range []float64{0, 45, 70, 80, 85, -85} {
+		latDelta, lngDelta := BoundingBox(lat, 250)
+		// Points on the circle, found by bisecting the longitude offset at each latitude
+		for dLat := -latDelta * 0.999; dLat <= latDelta; dLat += latDelta / 50 {
+			low, high := 0.0, 180.0
+			for i := 0; i < 60; i++ {
+				mid := (low + high) / 2
+				if DistanceKm(lat, 0, lat+dLat, mid) < 250 {
+					low = mid
+				} else {
+					high = mid
+				}
+			}
+			assert.LessOrEqual(t, low, lngDelta+1e-6, "lat %v, point at lat %v", lat, lat+dLat)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Package geofence tests whether points lie inside circles and polygons on the globe.
+package geofence
+
+import (
+	"math"
+
+	"geoalbum/backend/geocode"
+)
+
+// Circle is the area within RadiusM metres of a centre point
+type Circle struct {
+	Latitude  float64
+	Longitude float64
+	RadiusM   float64
+}
+
+// Contains reports whether a point lies within the circle's great-circle radius
+func (c Circle) Contains(lat, lng float64) bool {
+	return geocode.DistanceKm(c.Latitude, c.Longitude, lat, lng)*1000 <= c.RadiusM
+}
+
+// Bounds returns a box enclosing the circle; MinLng > MaxLng when it crosses the
+// antimeridian
+func (c Circle) Bounds() (minLat, minLng, maxLat, maxLng float64) {
+	latDelta, lngDelta := geocode.BoundingBox(c.Latitude, c.RadiusM/1000)
+	minLat = math.Max(-90, c.Latitude-latDelta)
+	maxLat = math.Min(90, c.Latitude+latDelta)
+	if lngDelta >= 180 || maxLat == 90 || minLat == -90 {
+		return minLat, -180, maxLat, 180
+	}
+	return minLat, wrap(c.Longitude - lngDelta), maxLat, wrap(c.Longitude + lngDelta)
+}
+
+// Polygon is a closed ring of [longitude, latitude] vertices, in GeoJSON order. The
+// closing vertex may be repeated or left out. Edges are straight in longitude and
+// latitude and always take the shorter way around, so a polygon may span the
+// antimeridian but not enclose a pole.
+type Polygon [][2]float64
+
+// Contains reports whether a point lies inside the polygon, using the even-odd rule
+func (p Polygon) Contains(lat, lng float64) bool {
+	ring := p.unwrapped()
+	if len(ring) < 3 {
+		return false
+	}
+	// The unwrapped ring may extend past ±180, so try the point's equivalent longitudes
+	for _, offset := range []float64{0, 360, -360} {
+		if ringContains(ring, lat, lng+offset) {
+			return true
+		}
+	}
+	return false
+}
+
+// Bounds returns the box enclosing the polygon; MinLng > MaxLng when it crosses the
+// antimeridian
+func (p Polygon) Bounds() (minLat, minLng, maxLat, maxLng float64) {
+	ring := p.unwrapped()
+	if len(ring) == 0 {
+		return 0, 0, 0, 0
+	}
+	minLat, maxLat = ring[0][1], ring[0][1]
+	minLng, maxLng = ring[0][0], ring[0][0]
+	for _, vertex := range ring[1:] {
+		minLng, maxLng = math.Min(minLng, vertex[0]), math.Max(maxLng, vertex[0])
+		minLat, maxLat = math.Min(minLat, vertex[1]), math.Max(maxLat, vertex[1])
+	}
+	if maxLng-minLng >= 360 {
+		return minLat, -180, maxLat, 180
+	}
+	return minLat, wrap(minLng), maxLat, wrap(maxLng)
+}
+
+// unwrapped returns the ring with longitudes shifted by multiples of 360 so that no
+// edge spans more than 180 degrees of longitude, without the closing vertex
+func (p Polygon) unwrapped() [][2]float64 {
+	ring := make([][2]float64, 0, len(p))
+	for i, vertex := range p {
+		if i == len(p)-1 && i > 0 && vertex == p[0] {
+			break
+		}
+		if len(ring) > 0 {
+			previous := ring[len(ring)-1][0]
+			for vertex[0]-previous > 180 {
+				vertex[0] -= 360
+			}
+			for vertex[0]-previous < -180 {
+				vertex[0] += 360
+			}
+		}
+		ring = append(ring, vertex)
+	}
+	return ring
+}
+
+// ringContains is the even-odd ray casting test on a planar ring
+func ringContains(ring [][2]float64, lat, lng float64) bool {
+	inside := false
+	j := len(ring) - 1
+	for i := range ring {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > lat) != (yj > lat) && lng < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+		j = i
+	}
+	return inside
+}
+
+// wrap maps a longitude onto [-180, 180]
+func wrap(lng float64) float64 {
+	for lng > 180 {
+		lng -= 360
+	}
+	for lng < -180 {
+		lng += 360
+	}
+	return lng
+}
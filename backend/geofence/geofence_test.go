@@ -0,0 +1,76 @@
+package geofence
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolygonContainsConcaveShape(t *testing.T) {
+	// A "U" opening north: the notch between the arms is outside
+	u := Polygon{{0, 0}, {3, 0}, {3, 3}, {2, 3}, {2, 1}, {1, 1}, {1, 3}, {0, 3}, {0, 0}}
+
+	assert.True(t, u.Contains(0.5, 1.5), "base")
+	assert.True(t, u.Contains(2, 0.5), "left arm")
+	assert.True(t, u.Contains(2, 2.5), "right arm")
+	assert.False(t, u.Contains(2, 1.5), "notch")
+	assert.False(t, u.Contains(3.5, 1.5), "above the notch")
+	assert.False(t, u.Contains(-0.5, 1.5), "below")
+	assert.False(t, u.Contains(1.5, 4), "east")
+}
+
+func TestPolygonContainsRingWithoutClosingVertex(t *testing.T) {
+	// An "L" whose closing vertex is left out
+	l := Polygon{{0, 0}, {2, 0}, {2, 1}, {1, 1}, {1, 2}, {0, 2}}
+
+	assert.True(t, l.Contains(1.5, 0.5))
+	assert.True(t, l.Contains(0.5, 1.5))
+	assert.False(t, l.Contains(1.5, 1.5))
+}
+
+func TestPolygonContainsAcrossAntimeridian(t *testing.T) {
+	// Fiji, from 177°E to 178°W
+	fiji := Polygon{{177, -19}, {-178, -19}, {-178, -16}, {177, -16}, {177, -19}}
+
+	assert.True(t, fiji.Contains(-17.7, 178.4), "east of 180")
+	assert.True(t, fiji.Contains(-17, -179.5), "west of 180")
+	assert.True(t, fiji.Contains(-17, 180))
+	assert.True(t, fiji.Contains(-17, -180))
+	assert.False(t, fiji.Contains(-17, 0), "opposite side of the globe")
+	assert.False(t, fiji.Contains(-17, 176))
+	assert.False(t, fiji.Contains(-17, -177))
+	assert.False(t, fiji.Contains(-20, 179))
+
+	minLat, minLng, maxLat, maxLng := fiji.Bounds()
+	assert.Equal(t, []float64{-19, 177, -16, -178}, []float64{minLat, minLng, maxLat, maxLng})
+}
+
+func TestPolygonContainsConcaveShapeAcrossAntimeridian(t *testing.T) {
+	// A "C" opening east, drawn with vertices on both sides of 180
+	c := Polygon{{178, 0}, {-178, 0}, {-178, 1}, {179, 1}, {179, 3}, {-178, 3}, {-178, 4}, {178, 4}}
+
+	assert.True(t, c.Contains(0.5, -179), "lower arm west of 180")
+	assert.True(t, c.Contains(3.5, 179.5), "upper arm east of 180")
+	assert.True(t, c.Contains(2, 178.5), "spine")
+	assert.False(t, c.Contains(2, -179), "mouth")
+	assert.False(t, c.Contains(2, 179.5), "mouth east of 180")
+}
+
+func TestPolygonContainsDegenerateRing(t *testing.T) {
+	assert.False(t, Polygon{}.Contains(0, 0))
+	assert.False(t, Polygon{{0, 0}, {1, 1}, {0, 0}}.Contains(0.5, 0.5))
+}
+
+func TestCircleContains(t *testing.T) {
+	// Kyoto station, 5 km
+	kyoto := Circle{Latitude: 34.9858, Longitude: 135.7588, RadiusM: 5000}
+	assert.True(t, kyoto.Contains(35.0116, 135.7681), "Kyoto city hall, ~3 km")
+	assert.False(t, kyoto.Contains(34.6937, 135.5023), "Osaka")
+
+	dateline := Circle{Latitude: 0, Longitude: 179.99, RadiusM: 10000}
+	assert.True(t, dateline.Contains(0, -179.99))
+	minLat, minLng, maxLat, maxLng := dateline.Bounds()
+	assert.Greater(t, minLng, maxLng, "bounds cross the antimeridian")
+	assert.Less(t, minLat, 0.0)
+	assert.Greater(t, maxLat, 0.0)
+}
@@ -0,0 +1,184 @@
+// Package geojson reads and builds GeoJSON documents (RFC 7946). Positions are
+// WGS-84 [longitude, latitude] pairs.
+package geojson
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+
+	"geoalbum/backend/polyline"
+)
+
+// FeatureCollection is a list of features
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// Feature is a geometry with properties
+type Feature struct {
+	Type       string                 `json:"type"`
+	ID         FeatureID              `json:"id,omitempty"`
+	Geometry   *Geometry              `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// FeatureID is a feature identifier. RFC 7946 allows strings and numbers; numbers
+// are kept as their JSON text.
+type FeatureID string
+
+func (id *FeatureID) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*id = FeatureID(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("feature id must be a string or number")
+	}
+	*id = FeatureID(n.String())
+	return nil
+}
+
+// Geometry holds coordinates whose nesting depends on Type
+type Geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// NewFeatureCollection returns an empty feature collection
+func NewFeatureCollection() *FeatureCollection {
+	return &FeatureCollection{Type: "FeatureCollection", Features: []Feature{}}
+}
+
+// Add appends a feature with the given ID, geometry and properties
+func (fc *FeatureCollection) Add(id string, geometry *Geometry, properties map[string]interface{}) {
+	fc.Features = append(fc.Features, Feature{Type: "Feature", ID: FeatureID(id), Geometry: geometry, Properties: properties})
+}
+
+// NewPoint returns a Point geometry
+func NewPoint(lat, lng float64) *Geometry {
+	return newGeometry("Point", [2]float64{lng, lat})
+}
+
+// NewLine returns a LineString geometry, or a MultiLineString when the line crosses
+// the antimeridian and has to be split
+func NewLine(points []polyline.Point) *Geometry {
+	parts := polyline.SplitAntimeridian(points)
+	lines := make([][][2]float64, len(parts))
+	for i, part := range parts {
+		lines[i] = positions(part)
+	}
+	if len(lines) == 1 {
+		return newGeometry("LineString", lines[0])
+	}
+	return newGeometry("MultiLineString", lines)
+}
+
+func positions(points []polyline.Point) [][2]float64 {
+	coordinates := make([][2]float64, len(points))
+	for i, p := range points {
+		coordinates[i] = [2]float64{p.Lng, p.Lat}
+	}
+	return coordinates
+}
+
+func newGeometry(geometryType string, coordinates interface{}) *Geometry {
+	raw, _ := json.Marshal(coordinates)
+	return &Geometry{Type: geometryType, Coordinates: raw}
+}
+
+// Decode reads a FeatureCollection, a single Feature or a bare geometry, which is
+// returned as a feature without properties
+func Decode(r io.Reader) ([]Feature, error) {
+	var doc struct {
+		Type        string                 `json:"type"`
+		Features    []Feature              `json:"features"`
+		ID          FeatureID              `json:"id"`
+		Geometry    *Geometry              `json:"geometry"`
+		Properties  map[string]interface{} `json:"properties"`
+		Coordinates json.RawMessage        `json:"coordinates"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid GeoJSON: %w", err)
+	}
+
+	switch doc.Type {
+	case "FeatureCollection":
+		for i, feature := range doc.Features {
+			if feature.Type != "Feature" {
+				return nil, fmt.Errorf("invalid GeoJSON: feature %d has type %q", i, feature.Type)
+			}
+		}
+		return doc.Features, nil
+	case "Feature":
+		return []Feature{{Type: doc.Type, ID: doc.ID, Geometry: doc.Geometry, Properties: doc.Properties}}, nil
+	case "Point", "MultiPoint", "LineString", "MultiLineString", "Polygon", "MultiPolygon", "GeometryCollection":
+		geometry := &Geometry{Type: doc.Type, Coordinates: doc.Coordinates}
+		return []Feature{{Type: "Feature", Geometry: geometry}}, nil
+	default:
+		return nil, fmt.Errorf("invalid GeoJSON: unsupported type %q", doc.Type)
+	}
+}
+
+// Point returns the position of a Point geometry
+func (g *Geometry) Point() (polyline.Point, error) {
+	if g.Type != "Point" {
+		return polyline.Point{}, fmt.Errorf("not a Point geometry: %s", g.Type)
+	}
+	var position []float64
+	if err := json.Unmarshal(g.Coordinates, &position); err != nil {
+		return polyline.Point{}, fmt.Errorf("invalid Point coordinates: %w", err)
+	}
+	return toPoint(position)
+}
+
+// Lines returns the lines of a LineString or MultiLineString geometry
+func (g *Geometry) Lines() ([][]polyline.Point, error) {
+	var lines [][][]float64
+	switch g.Type {
+	case "LineString":
+		var line [][]float64
+		if err := json.Unmarshal(g.Coordinates, &line); err != nil {
+			return nil, fmt.Errorf("invalid LineString coordinates: %w", err)
+		}
+		lines = [][][]float64{line}
+	case "MultiLineString":
+		if err := json.Unmarshal(g.Coordinates, &lines); err != nil {
+			return nil, fmt.Errorf("invalid MultiLineString coordinates: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("not a line geometry: %s", g.Type)
+	}
+
+	result := make([][]polyline.Point, len(lines))
+	for i, line := range lines {
+		if len(line) < 2 {
+			return nil, fmt.Errorf("invalid %s: a line needs at least 2 positions", g.Type)
+		}
+		result[i] = make([]polyline.Point, len(line))
+		for j, position := range line {
+			point, err := toPoint(position)
+			if err != nil {
+				return nil, err
+			}
+			result[i][j] = point
+		}
+	}
+	return result, nil
+}
+
+// toPoint reads a [longitude, latitude] position, ignoring any altitude
+func toPoint(position []float64) (polyline.Point, error) {
+	if len(position) < 2 {
+		return polyline.Point{}, fmt.Errorf("invalid position: needs longitude and latitude")
+	}
+	lng, lat := position[0], position[1]
+	if math.IsNaN(lat) || math.IsNaN(lng) || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		return polyline.Point{}, fmt.Errorf("invalid position: %g, %g out of range", lng, lat)
+	}
+	return polyline.Point{Lat: lat, Lng: lng}, nil
+}
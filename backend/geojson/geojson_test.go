@@ -0,0 +1,87 @@
+package geojson
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"geoalbum/backend/polyline"
+)
+
+func TestDecodeFeatureCollection(t *testing.T) {
+	features, err := Decode(strings.NewReader(`{
+		"type": "FeatureCollection",
+		"features": [
+			{"type": "Feature", "id": "kyoto", "geometry": {"type": "Point", "coordinates": [135.7681, 35.0116, 50]}, "properties": {"name": "Kyoto"}},
+			{"type": "Feature", "id": 7, "geometry": {"type": "LineString", "coordinates": [[139.7, 35.6], [135.7, 35.0]]}, "properties": null},
+			{"type": "Feature", "geometry": null, "properties": {}}
+		]
+	}`))
+	require.NoError(t, err)
+	require.Len(t, features, 3)
+
+	assert.Equal(t, FeatureID("kyoto"), features[0].ID)
+	assert.Equal(t, "Kyoto", features[0].Properties["name"])
+	point, err := features[0].Geometry.Point()
+	require.NoError(t, err)
+	assert.Equal(t, polyline.Point{Lat: 35.0116, Lng: 135.7681}, point)
+
+	assert.Equal(t, FeatureID("7"), features[1].ID)
+	lines, err := features[1].Geometry.Lines()
+	require.NoError(t, err)
+	assert.Equal(t, [][]polyline.Point{{{Lat: 35.6, Lng: 139.7}, {Lat: 35.0, Lng: 135.7}}}, lines)
+
+	assert.Nil(t, features[2].Geometry)
+}
+
+func TestDecodeSingle(t *testing.T) {
+	features, err := Decode(strings.NewReader(`{"type": "Feature", "id": "a", "geometry": {"type": "Point", "coordinates": [1, 2]}, "properties": {}}`))
+	require.NoError(t, err)
+	require.Len(t, features, 1)
+	assert.Equal(t, FeatureID("a"), features[0].ID)
+
+	features, err = Decode(strings.NewReader(`{"type": "MultiLineString", "coordinates": [[[170, 0], [180, 1]], [[-180, 1], [-170, 2]]]}`))
+	require.NoError(t, err)
+	require.Len(t, features, 1)
+	lines, err := features[0].Geometry.Lines()
+	require.NoError(t, err)
+	assert.Len(t, lines, 2)
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	for _, input := range []string{
+		`not json`,
+		`{"type": "Topology"}`,
+		`{"type": "FeatureCollection", "features": [{"type": "Point"}]}`,
+		`{"type": "Feature", "id": true}`,
+	} {
+		_, err := Decode(strings.NewReader(input))
+		assert.Error(t, err, input)
+	}
+
+	for _, geometry := range []string{
+		`{"type": "Point", "coordinates": [200, 0]}`,
+		`{"type": "Point", "coordinates": [1]}`,
+		`{"type": "LineString", "coordinates": [[1, 2]]}`,
+		`{"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [0, 1], [0, 0]]]}`,
+	} {
+		var g Geometry
+		require.NoError(t, json.Unmarshal([]byte(geometry), &g))
+		_, pointErr := g.Point()
+		_, linesErr := g.Lines()
+		assert.True(t, pointErr != nil && linesErr != nil, geometry)
+	}
+}
+
+func TestNewLineSplitsAtAntimeridian(t *testing.T) {
+	line := NewLine([]polyline.Point{{Lat: 0, Lng: 170}, {Lat: 10, Lng: 190}})
+	assert.Equal(t, "MultiLineString", line.Type)
+	assert.JSONEq(t, `[[[170,0],[180,5]],[[-180,5],[-170,10]]]`, string(line.Coordinates))
+
+	line = NewLine([]polyline.Point{{Lat: 0, Lng: 10}, {Lat: 1, Lng: 11}})
+	assert.Equal(t, "LineString", line.Type)
+	assert.JSONEq(t, `[[10,0],[11,1]]`, string(line.Coordinates))
+}
@@ -0,0 +1,240 @@
+// Package gpx reads and writes waypoints, routes and tracks in GPX 1.1 files
+// (https://www.topografix.com/GPX/1/1/) and locates moments in time along tracks.
+package gpx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// Point is a waypoint, route point or track point
+type Point struct {
+	Lat         float64
+	Lng         float64
+	Elevation   *float64
+	Time        *time.Time
+	Name        string
+	Description string
+}
+
+// Line is a route, or a track with its segments joined in order
+type Line struct {
+	Name   string
+	Points []Point
+}
+
+// File holds the contents of a GPX file
+type File struct {
+	Waypoints []Point
+	Routes    []Line
+	Tracks    []Line
+}
+
+// xmlPoint mirrors wptType, the schema type of waypoints, route points and track points
+type xmlPoint struct {
+	Lat         float64  `xml:"lat,attr"`
+	Lon         float64  `xml:"lon,attr"`
+	Ele         *float64 `xml:"ele,omitempty"`
+	Time        string   `xml:"time,omitempty"`
+	Name        string   `xml:"name,omitempty"`
+	Description string   `xml:"desc,omitempty"`
+}
+
+type xmlRoute struct {
+	Name   string     `xml:"name,omitempty"`
+	Points []xmlPoint `xml:"rtept"`
+}
+
+type xmlTrack struct {
+	Name     string       `xml:"name,omitempty"`
+	Segments []xmlSegment `xml:"trkseg"`
+}
+
+type xmlSegment struct {
+	Points []xmlPoint `xml:"trkpt"`
+}
+
+type xmlFile struct {
+	XMLName   xml.Name   `xml:"gpx"`
+	Xmlns     string     `xml:"xmlns,attr,omitempty"`
+	Version   string     `xml:"version,attr,omitempty"`
+	Creator   string     `xml:"creator,attr,omitempty"`
+	Waypoints []xmlPoint `xml:"wpt"`
+	Routes    []xmlRoute `xml:"rte"`
+	Tracks    []xmlTrack `xml:"trk"`
+}
+
+// Parse reads a GPX document. Points with coordinates out of range are rejected;
+// unparseable times are ignored.
+func Parse(r io.Reader) (*File, error) {
+	var doc xmlFile
+	decoder := xml.NewDecoder(r)
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid GPX: %w", err)
+	}
+
+	file := &File{}
+	for _, p := range doc.Waypoints {
+		point, err := p.point()
+		if err != nil {
+			return nil, err
+		}
+		file.Waypoints = append(file.Waypoints, point)
+	}
+	for _, route := range doc.Routes {
+		line := Line{Name: route.Name}
+		for _, p := range route.Points {
+			point, err := p.point()
+			if err != nil {
+				return nil, err
+			}
+			line.Points = append(line.Points, point)
+		}
+		file.Routes = append(file.Routes, line)
+	}
+	for _, track := range doc.Tracks {
+		line := Line{Name: track.Name}
+		for _, segment := range track.Segments {
+			for _, p := range segment.Points {
+				point, err := p.point()
+				if err != nil {
+					return nil, err
+				}
+				line.Points = append(line.Points, point)
+			}
+		}
+		file.Tracks = append(file.Tracks, line)
+	}
+	return file, nil
+}
+
+func (p xmlPoint) point() (Point, error) {
+	if p.Lat < -90 || p.Lat > 90 || p.Lon < -180 || p.Lon > 180 || math.IsNaN(p.Lat) || math.IsNaN(p.Lon) {
+		return Point{}, fmt.Errorf("invalid GPX: coordinates %g, %g out of range", p.Lat, p.Lon)
+	}
+	point := Point{Lat: p.Lat, Lng: p.Lon, Elevation: p.Ele, Name: p.Name, Description: p.Description}
+	if t, err := time.Parse(time.RFC3339Nano, p.Time); err == nil {
+		t = t.UTC()
+		point.Time = &t
+	}
+	return point, nil
+}
+
+// Timeline is a time-ordered list of timestamped track points
+type Timeline []Point
+
+// NewTimeline collects the timestamped points of tracks in time order
+func NewTimeline(tracks []Line) Timeline {
+	var timeline Timeline
+	for _, track := range tracks {
+		for _, p := range track.Points {
+			if p.Time != nil {
+				timeline = append(timeline, p)
+			}
+		}
+	}
+	sort.SliceStable(timeline, func(i, j int) bool { return timeline[i].Time.Before(*timeline[j].Time) })
+	return timeline
+}
+
+// Locate returns the position at time t, interpolated linearly between the points
+// recorded just before and after it. Positions are only given where those points are
+// at most maxGap apart, or within maxGap of the first or last point.
+func (timeline Timeline) Locate(t time.Time, maxGap time.Duration) (float64, float64, bool) {
+	if len(timeline) == 0 {
+		return 0, 0, false
+	}
+	// i is the first point at or after t
+	i := sort.Search(len(timeline), func(i int) bool { return !timeline[i].Time.Before(t) })
+	switch {
+	case i == len(timeline):
+		last := timeline[i-1]
+		if t.Sub(*last.Time) > maxGap {
+			return 0, 0, false
+		}
+		return last.Lat, last.Lng, true
+	case timeline[i].Time.Equal(t):
+		return timeline[i].Lat, timeline[i].Lng, true
+	case i == 0:
+		first := timeline[0]
+		if first.Time.Sub(t) > maxGap {
+			return 0, 0, false
+		}
+		return first.Lat, first.Lng, true
+	}
+
+	before, after := timeline[i-1], timeline[i]
+	span := after.Time.Sub(*before.Time)
+	if span > maxGap {
+		return 0, 0, false
+	}
+	f := float64(t.Sub(*before.Time)) / float64(span)
+	lngDelta := after.Lng - before.Lng
+	// Take the short way across the antimeridian
+	if lngDelta > 180 {
+		lngDelta -= 360
+	} else if lngDelta < -180 {
+		lngDelta += 360
+	}
+	lng := before.Lng + f*lngDelta
+	if lng > 180 {
+		lng -= 360
+	} else if lng < -180 {
+		lng += 360
+	}
+	return before.Lat + f*(after.Lat-before.Lat), lng, true
+}
+
+// Namespace is the GPX 1.1 XML namespace
+const Namespace = "http://www.topografix.com/GPX/1/1"
+
+// Encode writes file as an indented GPX 1.1 document. Longitudes are wrapped into
+// [-180, 180] and each track is written as a single segment.
+func Encode(w io.Writer, file *File, creator string) error {
+	doc := xmlFile{Xmlns: Namespace, Version: "1.1", Creator: creator}
+	for _, p := range file.Waypoints {
+		doc.Waypoints = append(doc.Waypoints, newXMLPoint(p))
+	}
+	for _, route := range file.Routes {
+		r := xmlRoute{Name: route.Name}
+		for _, p := range route.Points {
+			r.Points = append(r.Points, newXMLPoint(p))
+		}
+		doc.Routes = append(doc.Routes, r)
+	}
+	for _, track := range file.Tracks {
+		var segment xmlSegment
+		for _, p := range track.Points {
+			segment.Points = append(segment.Points, newXMLPoint(p))
+		}
+		doc.Tracks = append(doc.Tracks, xmlTrack{Name: track.Name, Segments: []xmlSegment{segment}})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write GPX: %w", err)
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write GPX: %w", err)
+	}
+	return encoder.Flush()
+}
+
+func newXMLPoint(p Point) xmlPoint {
+	point := xmlPoint{
+		Lat:         p.Lat,
+		Lon:         p.Lng - 360*math.Floor((p.Lng+180)/360),
+		Ele:         p.Elevation,
+		Name:        p.Name,
+		Description: p.Description,
+	}
+	if p.Time != nil {
+		point.Time = p.Time.UTC().Format(time.RFC3339)
+	}
+	return point
+}
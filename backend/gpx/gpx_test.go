@@ -0,0 +1,124 @@
+package gpx
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sample = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <wpt lat="35.6812" lon="139.7671"><name>Tokyo Station</name><desc>Start</desc></wpt>
+  <wpt lat="35.0116" lon="135.7681"><ele>50</ele><time>2025-05-02T08:00:00Z</time><name>Kyoto</name></wpt>
+  <rte><name>Plan</name>
+    <rtept lat="35.6812" lon="139.7671"/><rtept lat="35.0116" lon="135.7681"/>
+  </rte>
+  <trk><name>Day 1</name>
+    <trkseg>
+      <trkpt lat="35.0" lon="139.0"><time>2025-05-01T10:00:00Z</time></trkpt>
+      <trkpt lat="35.1" lon="139.2"><time>2025-05-01T10:10:00Z</time></trkpt>
+    </trkseg>
+    <trkseg>
+      <trkpt lat="35.2" lon="139.4"><time>2025-05-01T12:00:00+02:00</time></trkpt>
+      <trkpt lat="35.3" lon="139.5"/>
+    </trkseg>
+  </trk>
+</gpx>`
+
+func TestParse(t *testing.T) {
+	file, err := Parse(strings.NewReader(sample))
+	require.NoError(t, err)
+
+	require.Len(t, file.Waypoints, 2)
+	assert.Equal(t, "Tokyo Station", file.Waypoints[0].Name)
+	assert.Equal(t, "Start", file.Waypoints[0].Description)
+	assert.Nil(t, file.Waypoints[0].Time)
+	require.NotNil(t, file.Waypoints[1].Elevation)
+	assert.Equal(t, 50.0, *file.Waypoints[1].Elevation)
+
+	require.Len(t, file.Routes, 1)
+	assert.Equal(t, "Plan", file.Routes[0].Name)
+	assert.Len(t, file.Routes[0].Points, 2)
+
+	require.Len(t, file.Tracks, 1)
+	track := file.Tracks[0]
+	require.Len(t, track.Points, 4)
+	assert.Equal(t, 139.4, track.Points[2].Lng)
+	assert.Equal(t, time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC), *track.Points[2].Time)
+	assert.Nil(t, track.Points[3].Time)
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := Parse(strings.NewReader("not xml"))
+	assert.Error(t, err)
+	_, err = Parse(strings.NewReader(`<gpx><wpt lat="91" lon="0"/></gpx>`))
+	assert.Error(t, err)
+}
+
+func TestLocate(t *testing.T) {
+	at := func(minute int) *time.Time {
+		t := time.Date(2025, 5, 1, 10, minute, 0, 0, time.UTC)
+		return &t
+	}
+	timeline := NewTimeline([]Line{
+		{Points: []Point{{Lat: 1, Lng: 179, Time: at(40)}, {Lat: 1, Lng: -179, Time: at(50)}}},
+		{Points: []Point{{Lat: 0, Lng: 0, Time: at(0)}, {Lat: 1, Lng: 2, Time: at(10)}, {Lat: 9, Lng: 9}}},
+	})
+	require.Len(t, timeline, 4)
+	gap := 15 * time.Minute
+
+	lat, lng, ok := timeline.Locate(*at(5), gap)
+	require.True(t, ok)
+	assert.InDelta(t, 0.5, lat, 1e-9)
+	assert.InDelta(t, 1, lng, 1e-9)
+
+	lat, lng, ok = timeline.Locate(*at(10), gap)
+	require.True(t, ok)
+	assert.Equal(t, 1.0, lat)
+	assert.Equal(t, 2.0, lng)
+
+	// Points 30 minutes apart are too far apart to interpolate between
+	_, _, ok = timeline.Locate(*at(25), gap)
+	assert.False(t, ok)
+
+	// Interpolation crosses the antimeridian the short way
+	_, lng, ok = timeline.Locate(at(47).Add(30*time.Second), gap)
+	require.True(t, ok)
+	assert.InDelta(t, -179.5, lng, 1e-9)
+
+	// Just outside the track the nearest end is used
+	_, lng, ok = timeline.Locate(at(0).Add(-10*time.Minute), gap)
+	require.True(t, ok)
+	assert.Equal(t, 0.0, lng)
+	_, _, ok = timeline.Locate(at(50).Add(20*time.Minute), gap)
+	assert.False(t, ok)
+	_, _, ok = Timeline(nil).Locate(*at(0), gap)
+	assert.False(t, ok)
+}
+
+func TestEncode(t *testing.T) {
+	when := time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)
+	file := &File{
+		Waypoints: []Point{{Lat: 35.68, Lng: 139.77, Time: &when, Name: "Tokyo & Co", Description: "<start>"}},
+		Routes:    []Line{{Name: "Plan", Points: []Point{{Lat: 1, Lng: 2}, {Lat: 3, Lng: 4}}}},
+		Tracks:    []Line{{Name: "Pacific", Points: []Point{{Lat: 30, Lng: 179}, {Lat: 31, Lng: 181}}}},
+	}
+	var buf strings.Builder
+	require.NoError(t, Encode(&buf, file, "test"))
+	assert.Contains(t, buf.String(), `xmlns="http://www.topografix.com/GPX/1/1"`)
+
+	decoded, err := Parse(strings.NewReader(buf.String()))
+	require.NoError(t, err)
+	require.Len(t, decoded.Waypoints, 1)
+	assert.Equal(t, "Tokyo & Co", decoded.Waypoints[0].Name)
+	assert.Equal(t, "<start>", decoded.Waypoints[0].Description)
+	assert.Equal(t, when, *decoded.Waypoints[0].Time)
+	require.Len(t, decoded.Routes, 1)
+	assert.Len(t, decoded.Routes[0].Points, 2)
+	require.Len(t, decoded.Tracks, 1)
+	// Unwrapped longitudes are written back into range
+	assert.Equal(t, -179.0, decoded.Tracks[0].Points[1].Lng)
+}
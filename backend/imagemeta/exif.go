@@ -0,0 +1,418 @@
+package imagemeta
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxHeaderBytes bounds how much of a file is scanned for metadata
+const maxHeaderBytes = 1 << 20
+
+// EXIF tag identifiers used by GeoAlbum
+const (
+	tagDateTime            = 0x0132 // when the file was last changed, so never the capture time
+	tagRating              = 0x4746
+	tagExifIFDPointer      = 0x8769
+	tagGPSIFDPointer       = 0x8825
+	tagDateTimeOriginal    = 0x9003
+	tagDateTimeDigitized   = 0x9004
+	tagOffsetTimeOriginal  = 0x9011
+	tagOffsetTimeDigitized = 0x9012
+)
+
+// GPS IFD tag identifiers
+const (
+	tagGPSLatitudeRef  = 0x0001
+	tagGPSLatitude     = 0x0002
+	tagGPSLongitudeRef = 0x0003
+	tagGPSLongitude    = 0x0004
+)
+
+// EXIF field types
+const (
+	typeByte      = 1
+	typeASCII     = 2
+	typeShort     = 3
+	typeLong      = 4
+	typeRational  = 5
+	typeUndefined = 7
+	typeSLong     = 9
+	typeSRational = 10
+)
+
+// Metadata holds the fields GeoAlbum reads from an image's embedded metadata
+type Metadata struct {
+	// DateTimeOriginal is the capture time from the camera clock, e.g. "2024:05:01 14:03:22",
+	// or else the time the image was digitized
+	DateTimeOriginal string
+	// OffsetTimeOriginal is the camera's UTC offset at capture, e.g. "+09:00", when recorded
+	OffsetTimeOriginal string
+	// Rating is the 0-5 star rating from EXIF or XMP; 0 when not recorded
+	Rating int
+	// Latitude and Longitude are the GPS position in decimal degrees, when recorded
+	Latitude  *float64
+	Longitude *float64
+}
+
+// TakenAt returns the capture time. When the image does not record its UTC offset
+// the camera clock is interpreted in fallback (UTC if nil).
+func (m *Metadata) TakenAt(fallback *time.Location) (time.Time, bool) {
+	if m == nil || m.DateTimeOriginal == "" {
+		return time.Time{}, false
+	}
+
+	loc := fallback
+	if loc == nil {
+		loc = time.UTC
+	}
+	if offset, ok := parseOffset(m.OffsetTimeOriginal); ok {
+		loc = offset
+	}
+
+	t, err := time.ParseInLocation("2006:01:02 15:04:05", strings.TrimSpace(m.DateTimeOriginal), loc)
+	if err != nil || t.Year() < 1800 {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// Extract reads metadata from a JPEG or PNG image. Images without metadata
+// yield an empty Metadata rather than an error.
+func Extract(r io.Reader) (*Metadata, error) {
+	br := bufio.NewReader(io.LimitReader(r, maxHeaderBytes))
+	magic, err := br.Peek(8)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read image header: %w", err)
+	}
+
+	var segments *metadataSegments
+	switch {
+	case len(magic) >= 2 && magic[0] == 0xFF && magic[1] == 0xD8:
+		segments = scanJPEG(br)
+	case bytes.HasPrefix(magic, []byte("\x89PNG\r\n\x1a\n")):
+		segments = scanPNG(br)
+	default:
+		return &Metadata{}, nil
+	}
+
+	meta := parseTIFF(segments.exif)
+	if rating, ok := parseXMPRating(segments.xmp); ok {
+		meta.Rating = rating
+	}
+	return meta, nil
+}
+
+// metadataSegments holds the raw metadata blocks found in an image
+type metadataSegments struct {
+	exif []byte
+	xmp  []byte
+}
+
+// xmpHeader prefixes XMP packets in JPEG APP1 segments
+var xmpHeader = []byte("http://ns.adobe.com/xap/1.0/\x00")
+
+// scanJPEG walks JPEG segments collecting the APP1 Exif and XMP blocks
+func scanJPEG(r *bufio.Reader) *metadataSegments {
+	segments := &metadataSegments{}
+	if _, err := r.Discard(2); err != nil {
+		return segments
+	}
+
+	for {
+		marker := make([]byte, 2)
+		if _, err := io.ReadFull(r, marker); err != nil || marker[0] != 0xFF {
+			return segments
+		}
+		// Start of scan or end of image: no metadata follows
+		if marker[1] == 0xDA || marker[1] == 0xD9 {
+			return segments
+		}
+
+		var length uint16
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil || length < 2 {
+			return segments
+		}
+		segment := make([]byte, int(length)-2)
+		if _, err := io.ReadFull(r, segment); err != nil {
+			return segments
+		}
+
+		if marker[1] == 0xE1 {
+			switch {
+			case bytes.HasPrefix(segment, []byte("Exif\x00\x00")):
+				segments.exif = segment[6:]
+			case bytes.HasPrefix(segment, xmpHeader):
+				segments.xmp = segment[len(xmpHeader):]
+			}
+		}
+	}
+}
+
+// scanPNG walks PNG chunks collecting the eXIf chunk and the iTXt XMP packet
+func scanPNG(r *bufio.Reader) *metadataSegments {
+	segments := &metadataSegments{}
+	if _, err := r.Discard(8); err != nil {
+		return segments
+	}
+
+	for {
+		var length uint32
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+			return segments
+		}
+		chunkType := make([]byte, 4)
+		if _, err := io.ReadFull(r, chunkType); err != nil {
+			return segments
+		}
+		if string(chunkType) == "IDAT" || string(chunkType) == "IEND" || length > maxHeaderBytes {
+			return segments
+		}
+
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return segments
+		}
+		// Skip CRC
+		if _, err := r.Discard(4); err != nil {
+			return segments
+		}
+
+		switch string(chunkType) {
+		case "eXIf":
+			segments.exif = data
+		case "iTXt":
+			if bytes.HasPrefix(data, []byte("XML:com.adobe.xmp\x00")) {
+				segments.xmp = data
+			}
+		}
+	}
+}
+
+// parseXMPRating reads xmp:Rating from an XMP packet, in attribute or element form
+func parseXMPRating(xmp []byte) (int, bool) {
+	if len(xmp) == 0 {
+		return 0, false
+	}
+
+	for _, match := range xmpRatingPattern.FindAllSubmatch(xmp, -1) {
+		value := match[1]
+		if len(value) == 0 {
+			value = match[2]
+		}
+		rating, err := strconv.ParseFloat(string(value), 64)
+		if err != nil {
+			continue
+		}
+		return clampRating(int(rating)), true
+	}
+	return 0, false
+}
+
+var xmpRatingPattern = regexp.MustCompile(`xmp:Rating\s*=\s*["']\s*(-?[0-9.]+)\s*["']|<xmp:Rating>\s*(-?[0-9.]+)\s*</xmp:Rating>`)
+
+// clampRating maps a rating onto 0-5 stars; negative "rejected" ratings become 0
+func clampRating(rating int) int {
+	if rating < 0 {
+		return 0
+	}
+	if rating > 5 {
+		return 5
+	}
+	return rating
+}
+
+// tiffReader decodes IFD entries from a TIFF structure
+type tiffReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+// ifdEntry is a single raw IFD field
+type ifdEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+// parseTIFF extracts the metadata fields from a TIFF/EXIF block
+func parseTIFF(data []byte) *Metadata {
+	if len(data) < 8 {
+		return &Metadata{}
+	}
+
+	t := &tiffReader{data: data}
+	switch string(data[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return &Metadata{}
+	}
+
+	ifd0, err := t.readIFD(t.order.Uint32(data[4:8]))
+	if err != nil {
+		return &Metadata{}
+	}
+
+	meta := &Metadata{}
+	if entry, ok := ifd0[tagRating]; ok {
+		meta.Rating = clampRating(int(int16(t.uint32(entry))))
+	}
+
+	if entry, ok := ifd0[tagExifIFDPointer]; ok {
+		if exifIFD, err := t.readIFD(t.uint32(entry)); err == nil {
+			// Scanners and some phones only record when the image was digitized
+			var dateTag, offsetTag uint16 = tagDateTimeOriginal, tagOffsetTimeOriginal
+			if _, ok := exifIFD[dateTag]; !ok {
+				dateTag, offsetTag = tagDateTimeDigitized, tagOffsetTimeDigitized
+			}
+			if entry, ok := exifIFD[dateTag]; ok {
+				meta.DateTimeOriginal = t.ascii(entry)
+			}
+			if entry, ok := exifIFD[offsetTag]; ok {
+				meta.OffsetTimeOriginal = t.ascii(entry)
+			}
+		}
+	}
+
+	if entry, ok := ifd0[tagGPSIFDPointer]; ok {
+		if gpsIFD, err := t.readIFD(t.uint32(entry)); err == nil {
+			meta.Latitude, meta.Longitude = t.gpsPosition(gpsIFD)
+		}
+	}
+
+	return meta
+}
+
+// gpsPosition decodes the GPS latitude and longitude, or nils when absent or invalid
+func (t *tiffReader) gpsPosition(gps map[uint16]ifdEntry) (*float64, *float64) {
+	lat, latOK := t.degrees(gps[tagGPSLatitude])
+	lng, lngOK := t.degrees(gps[tagGPSLongitude])
+	if !latOK || !lngOK {
+		return nil, nil
+	}
+	if strings.EqualFold(t.ascii(gps[tagGPSLatitudeRef]), "S") {
+		lat = -lat
+	}
+	if strings.EqualFold(t.ascii(gps[tagGPSLongitudeRef]), "W") {
+		lng = -lng
+	}
+	if lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		return nil, nil
+	}
+	return &lat, &lng
+}
+
+// degrees decodes a degrees/minutes/seconds RATIONAL triple into decimal degrees
+func (t *tiffReader) degrees(entry ifdEntry) (float64, bool) {
+	if entry.typ != typeRational || len(entry.value) < 24 {
+		return 0, false
+	}
+	var parts [3]float64
+	for i := range parts {
+		numerator := t.order.Uint32(entry.value[i*8:])
+		denominator := t.order.Uint32(entry.value[i*8+4:])
+		if denominator == 0 {
+			if numerator != 0 {
+				return 0, false
+			}
+			continue
+		}
+		parts[i] = float64(numerator) / float64(denominator)
+	}
+	return parts[0] + parts[1]/60 + parts[2]/3600, true
+}
+
+// readIFD reads the entries of the IFD at offset
+func (t *tiffReader) readIFD(offset uint32) (map[uint16]ifdEntry, error) {
+	if int(offset)+2 > len(t.data) {
+		return nil, fmt.Errorf("ifd offset out of range")
+	}
+
+	count := int(t.order.Uint16(t.data[offset:]))
+	entries := make(map[uint16]ifdEntry, count)
+	for i := 0; i < count; i++ {
+		start := int(offset) + 2 + i*12
+		if start+12 > len(t.data) {
+			return nil, fmt.Errorf("ifd entry out of range")
+		}
+		raw := t.data[start : start+12]
+
+		entry := ifdEntry{
+			tag:   t.order.Uint16(raw[0:2]),
+			typ:   t.order.Uint16(raw[2:4]),
+			count: t.order.Uint32(raw[4:8]),
+		}
+
+		size := typeSize(entry.typ) * int(entry.count)
+		if size <= 0 {
+			continue
+		}
+		if size <= 4 {
+			entry.value = raw[8 : 8+size]
+		} else {
+			valueOffset := int(t.order.Uint32(raw[8:12]))
+			if valueOffset < 0 || valueOffset+size > len(t.data) {
+				continue
+			}
+			entry.value = t.data[valueOffset : valueOffset+size]
+		}
+		entries[entry.tag] = entry
+	}
+
+	return entries, nil
+}
+
+// ascii decodes an ASCII field, dropping the NUL terminator
+func (t *tiffReader) ascii(entry ifdEntry) string {
+	if entry.typ != typeASCII {
+		return ""
+	}
+	return strings.TrimRight(string(entry.value), "\x00 ")
+}
+
+// uint32 decodes a SHORT or LONG field
+func (t *tiffReader) uint32(entry ifdEntry) uint32 {
+	switch entry.typ {
+	case typeShort:
+		return uint32(t.order.Uint16(entry.value))
+	case typeLong:
+		return t.order.Uint32(entry.value)
+	}
+	return 0
+}
+
+// typeSize returns the byte size of one value of an EXIF field type
+func typeSize(typ uint16) int {
+	switch typ {
+	case typeByte, typeASCII, typeUndefined:
+		return 1
+	case typeShort:
+		return 2
+	case typeLong, typeSLong:
+		return 4
+	case typeRational, typeSRational:
+		return 8
+	}
+	return 0
+}
+
+// parseOffset parses an EXIF offset string such as "+09:00"
+func parseOffset(value string) (*time.Location, bool) {
+	value = strings.TrimSpace(value)
+	t, err := time.Parse("-07:00", value)
+	if err != nil {
+		return nil, false
+	}
+	_, offset := t.Zone()
+	return time.FixedZone(value, offset), true
+}
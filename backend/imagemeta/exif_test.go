@@ -0,0 +1,375 @@
+package imagemeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// byteOrder reads and appends in a TIFF byte order
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+// testEntry is an IFD field for buildTIFF; sub, when set, is written as an IFD of
+// its own and the field holds its offset
+type testEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte
+	sub   []testEntry
+}
+
+func asciiEntry(tag uint16, value string) testEntry {
+	return testEntry{tag: tag, typ: typeASCII, count: uint32(len(value) + 1), value: append([]byte(value), 0)}
+}
+
+func shortEntry(order byteOrder, tag uint16, value uint16) testEntry {
+	return testEntry{tag: tag, typ: typeShort, count: 1, value: order.AppendUint16(nil, value)}
+}
+
+func rationalsEntry(order byteOrder, tag uint16, values ...uint32) testEntry {
+	var value []byte
+	for _, v := range values {
+		value = order.AppendUint32(value, v)
+	}
+	return testEntry{tag: tag, typ: typeRational, count: uint32(len(values) / 2), value: value}
+}
+
+// buildTIFF lays out a TIFF structure with IFD0 holding entries
+func buildTIFF(order byteOrder, entries []testEntry) []byte {
+	data := []byte("II*\x00")
+	if order == binary.BigEndian {
+		data = []byte("MM\x00*")
+	}
+	data = order.AppendUint32(data, 8)
+
+	var writeIFD func(entries []testEntry) uint32
+	writeIFD = func(entries []testEntry) uint32 {
+		offset := uint32(len(data))
+		data = order.AppendUint16(data, uint16(len(entries)))
+		fields := len(data)
+		data = append(data, make([]byte, 12*len(entries)+4)...)
+		for i, entry := range entries {
+			// Values are appended first, as appending may move data
+			var value []byte
+			switch {
+			case entry.sub != nil:
+				value = order.AppendUint32(nil, writeIFD(entry.sub))
+			case len(entry.value) <= 4:
+				value = entry.value
+			default:
+				value = order.AppendUint32(nil, uint32(len(data)))
+				data = append(data, entry.value...)
+			}
+			raw := data[fields+12*i:]
+			order.PutUint16(raw[0:], entry.tag)
+			order.PutUint16(raw[2:], entry.typ)
+			order.PutUint32(raw[4:], entry.count)
+			copy(raw[8:12], value)
+		}
+		return offset
+	}
+	writeIFD(entries)
+	return data
+}
+
+// sampleEntries is IFD0 of a photo with a capture time, offset, rating and position
+// (35°0'41.76"N 135°46'5.16"E)
+func sampleEntries(order byteOrder) []testEntry {
+	return []testEntry{
+		asciiEntry(tagDateTime, "2024:05:01 15:00:00"),
+		shortEntry(order, tagRating, 4),
+		{tag: tagExifIFDPointer, typ: typeLong, count: 1, sub: []testEntry{
+			asciiEntry(tagDateTimeOriginal, "2024:05:01 14:03:22"),
+			asciiEntry(tagOffsetTimeOriginal, "+09:00"),
+		}},
+		{tag: tagGPSIFDPointer, typ: typeLong, count: 1, sub: []testEntry{
+			asciiEntry(tagGPSLatitudeRef, "N"),
+			rationalsEntry(order, tagGPSLatitude, 35, 1, 0, 1, 417600, 10000),
+			asciiEntry(tagGPSLongitudeRef, "E"),
+			rationalsEntry(order, tagGPSLongitude, 135, 1, 46, 1, 516, 100),
+		}},
+	}
+}
+
+var byteOrders = map[string]byteOrder{"little endian": binary.LittleEndian, "big endian": binary.BigEndian}
+
+func TestParseTIFF(t *testing.T) {
+	for name, order := range byteOrders {
+		t.Run(name, func(t *testing.T) {
+			meta := parseTIFF(buildTIFF(order, sampleEntries(order)))
+			assert.Equal(t, "2024:05:01 14:03:22", meta.DateTimeOriginal, "DateTimeOriginal wins over DateTime")
+			assert.Equal(t, "+09:00", meta.OffsetTimeOriginal)
+			assert.Equal(t, 4, meta.Rating)
+			require.NotNil(t, meta.Latitude)
+			require.NotNil(t, meta.Longitude)
+			assert.InDelta(t, 35.0116, *meta.Latitude, 1e-9)
+			assert.InDelta(t, 135.7681, *meta.Longitude, 1e-9)
+		})
+	}
+}
+
+func TestParseTIFFCaptureTime(t *testing.T) {
+	order := binary.LittleEndian
+	exifIFD := func(entries ...testEntry) []testEntry {
+		return []testEntry{
+			asciiEntry(tagDateTime, "2024:06:10 08:00:00"),
+			{tag: tagExifIFDPointer, typ: typeLong, count: 1, sub: entries},
+		}
+	}
+
+	// The time the image was digitized stands in for a missing capture time
+	meta := parseTIFF(buildTIFF(order, exifIFD(
+		asciiEntry(tagDateTimeDigitized, "2024:05:01 14:05:00"),
+		asciiEntry(tagOffsetTimeOriginal, "+02:00"),
+		asciiEntry(tagOffsetTimeDigitized, "+09:00"),
+	)))
+	assert.Equal(t, "2024:05:01 14:05:00", meta.DateTimeOriginal)
+	assert.Equal(t, "+09:00", meta.OffsetTimeOriginal)
+
+	// The modification time of an edited photo is never taken for the capture time
+	meta = parseTIFF(buildTIFF(order, exifIFD(asciiEntry(tagOffsetTimeOriginal, "+02:00"))))
+	assert.Empty(t, meta.DateTimeOriginal)
+	meta = parseTIFF(buildTIFF(order, []testEntry{asciiEntry(tagDateTime, "2024:06:10 08:00:00")}))
+	assert.Empty(t, meta.DateTimeOriginal)
+	_, ok := meta.TakenAt(nil)
+	assert.False(t, ok)
+}
+
+func TestParseTIFFGPS(t *testing.T) {
+	order := binary.LittleEndian
+	gps := func(latRef, lngRef string, lat, lng testEntry) *Metadata {
+		return parseTIFF(buildTIFF(order, []testEntry{{tag: tagGPSIFDPointer, typ: typeLong, count: 1, sub: []testEntry{
+			asciiEntry(tagGPSLatitudeRef, latRef), lat, asciiEntry(tagGPSLongitudeRef, lngRef), lng,
+		}}}))
+	}
+
+	meta := gps("S", "W", rationalsEntry(order, tagGPSLatitude, 33, 1, 52, 1, 0, 1), rationalsEntry(order, tagGPSLongitude, 151, 1, 12, 1, 0, 1))
+	require.NotNil(t, meta.Latitude)
+	assert.InDelta(t, -33.8667, *meta.Latitude, 1e-4)
+	assert.InDelta(t, -151.2, *meta.Longitude, 1e-4)
+
+	// 0/0 is an unset part rather than an error
+	meta = gps("N", "E", rationalsEntry(order, tagGPSLatitude, 10, 1, 30, 1, 0, 0), rationalsEntry(order, tagGPSLongitude, 20, 1, 0, 0, 0, 0))
+	require.NotNil(t, meta.Latitude)
+	assert.InDelta(t, 10.5, *meta.Latitude, 1e-9)
+	assert.InDelta(t, 20, *meta.Longitude, 1e-9)
+
+	tests := map[string]*Metadata{
+		"zero denominator": gps("N", "E", rationalsEntry(order, tagGPSLatitude, 10, 0, 0, 1, 0, 1), rationalsEntry(order, tagGPSLongitude, 20, 1, 0, 1, 0, 1)),
+		"out of range":     gps("N", "E", rationalsEntry(order, tagGPSLatitude, 91, 1, 0, 1, 0, 1), rationalsEntry(order, tagGPSLongitude, 20, 1, 0, 1, 0, 1)),
+		"too few parts":    gps("N", "E", rationalsEntry(order, tagGPSLatitude, 10, 1), rationalsEntry(order, tagGPSLongitude, 20, 1, 0, 1, 0, 1)),
+		"wrong type": gps("N", "E", testEntry{tag: tagGPSLatitude, typ: typeLong, count: 6, value: make([]byte, 24)},
+			rationalsEntry(order, tagGPSLongitude, 20, 1, 0, 1, 0, 1)),
+	}
+	for name, meta := range tests {
+		assert.Nil(t, meta.Latitude, name)
+		assert.Nil(t, meta.Longitude, name)
+	}
+}
+
+func TestParseTIFFRating(t *testing.T) {
+	order := binary.BigEndian
+	for value, want := range map[uint16]int{0: 0, 3: 3, 5: 5, 99: 5, 0xFFFF: 0} { // 0xFFFF is -1, "rejected"
+		meta := parseTIFF(buildTIFF(order, []testEntry{shortEntry(order, tagRating, value)}))
+		assert.Equal(t, want, meta.Rating, "rating %d", value)
+	}
+}
+
+func TestParseTIFFMalformed(t *testing.T) {
+	for name, order := range byteOrders {
+		valid := buildTIFF(order, sampleEntries(order))
+		pointing := func(offset uint32) []byte {
+			data := append([]byte(nil), valid...)
+			order.PutUint32(data[4:], offset)
+			return data
+		}
+		tests := map[string][]byte{
+			"empty":                nil,
+			"short header":         valid[:6],
+			"unknown byte order":   append([]byte("XX"), valid[2:]...),
+			"ifd offset past end":  pointing(uint32(len(valid))),
+			"ifd offset overflows": pointing(0xFFFFFFFF),
+			"ifd cut off":          valid[:8+2+12],
+			"entry count too big": func() []byte {
+				data := append([]byte(nil), valid...)
+				order.PutUint16(data[8:], 0xFFFF)
+				return data
+			}(),
+			"value offset past end": buildTIFF(order, []testEntry{
+				{tag: tagDateTime, typ: typeASCII, count: 20, value: nil},
+			}),
+			"value count overflows": func() []byte {
+				data := buildTIFF(order, []testEntry{asciiEntry(tagDateTime, "2024:05:01 15:00:00")})
+				order.PutUint32(data[8+2+4:], 0xFFFFFFFF)
+				return data
+			}(),
+			"sub ifd out of range": buildTIFF(order, []testEntry{
+				{tag: tagExifIFDPointer, typ: typeLong, count: 1, value: order.AppendUint32(nil, 0x7FFFFFFF)},
+				{tag: tagGPSIFDPointer, typ: typeShort, count: 1, value: order.AppendUint16(nil, 0xFFFF)},
+			}),
+			"pointer without value": buildTIFF(order, []testEntry{
+				{tag: tagExifIFDPointer, typ: typeLong, count: 0},
+			}),
+		}
+		for caseName, data := range tests {
+			t.Run(name+"/"+caseName, func(t *testing.T) {
+				var meta *Metadata
+				require.NotPanics(t, func() { meta = parseTIFF(data) })
+				require.NotNil(t, meta)
+				assert.Empty(t, meta.OffsetTimeOriginal)
+				assert.Nil(t, meta.Latitude)
+			})
+		}
+	}
+}
+
+func TestTakenAt(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*3600)
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		meta     *Metadata
+		fallback *time.Location
+		want     time.Time
+		ok       bool
+	}{
+		{"recorded offset", &Metadata{DateTimeOriginal: "2024:05:01 14:03:22", OffsetTimeOriginal: "+09:00"}, paris,
+			time.Date(2024, 5, 1, 5, 3, 22, 0, time.UTC), true},
+		{"negative offset", &Metadata{DateTimeOriginal: "2024:05:01 14:03:22", OffsetTimeOriginal: "-03:30"}, nil,
+			time.Date(2024, 5, 1, 17, 33, 22, 0, time.UTC), true},
+		{"offset with padding", &Metadata{DateTimeOriginal: "2024:05:01 14:03:22 ", OffsetTimeOriginal: " +09:00 "}, nil,
+			time.Date(2024, 5, 1, 5, 3, 22, 0, time.UTC), true},
+		{"no offset uses fallback", &Metadata{DateTimeOriginal: "2024:05:01 14:03:22"}, tokyo,
+			time.Date(2024, 5, 1, 5, 3, 22, 0, time.UTC), true},
+		{"fallback with daylight saving", &Metadata{DateTimeOriginal: "2024:07:01 12:00:00"}, paris,
+			time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC), true},
+		{"invalid offset uses fallback", &Metadata{DateTimeOriginal: "2024:05:01 14:03:22", OffsetTimeOriginal: "   :  "}, tokyo,
+			time.Date(2024, 5, 1, 5, 3, 22, 0, time.UTC), true},
+		{"no offset or fallback is UTC", &Metadata{DateTimeOriginal: "2024:05:01 14:03:22"}, nil,
+			time.Date(2024, 5, 1, 14, 3, 22, 0, time.UTC), true},
+		{"camera default date", &Metadata{DateTimeOriginal: "0000:00:00 00:00:00"}, nil, time.Time{}, false},
+		{"garbage", &Metadata{DateTimeOriginal: "yesterday"}, nil, time.Time{}, false},
+		{"empty", &Metadata{}, nil, time.Time{}, false},
+		{"nil", nil, nil, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.meta.TakenAt(tt.fallback)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.True(t, tt.want.Equal(got), "got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// jpegWith wraps APP1 segments into a minimal JPEG
+func jpegWith(segments ...[]byte) []byte {
+	data := []byte{0xFF, 0xD8}
+	for _, segment := range segments {
+		data = append(data, 0xFF, 0xE1)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(segment)+2))
+		data = append(data, segment...)
+	}
+	return append(data, 0xFF, 0xDA, 0x00, 0x02, 0xFF, 0xD9)
+}
+
+// pngWith wraps chunks into a minimal PNG; CRCs are not checked by the reader
+func pngWith(chunks map[string][]byte) []byte {
+	data := []byte("\x89PNG\r\n\x1a\n")
+	for _, chunkType := range []string{"eXIf", "iTXt"} {
+		if chunk, ok := chunks[chunkType]; ok {
+			data = binary.BigEndian.AppendUint32(data, uint32(len(chunk)))
+			data = append(data, chunkType...)
+			data = append(data, chunk...)
+			data = append(data, 0, 0, 0, 0)
+		}
+	}
+	return append(data, 0, 0, 0, 0, 'I', 'E', 'N', 'D', 0, 0, 0, 0)
+}
+
+func TestExtract(t *testing.T) {
+	order := binary.LittleEndian
+	exif := buildTIFF(order, sampleEntries(order))
+	xmp := []byte(`<x:xmpmeta><rdf:Description xmp:Rating="2"/></x:xmpmeta>`)
+
+	meta, err := Extract(bytes.NewReader(jpegWith(append([]byte("Exif\x00\x00"), exif...), append(append([]byte(nil), xmpHeader...), xmp...))))
+	require.NoError(t, err)
+	assert.Equal(t, "2024:05:01 14:03:22", meta.DateTimeOriginal)
+	assert.Equal(t, 2, meta.Rating, "XMP rating wins over EXIF")
+	require.NotNil(t, meta.Latitude)
+
+	meta, err = Extract(bytes.NewReader(pngWith(map[string][]byte{
+		"eXIf": exif,
+		"iTXt": []byte("XML:com.adobe.xmp\x00\x00\x00\x00\x00<xmp:Rating>5</xmp:Rating>"),
+	})))
+	require.NoError(t, err)
+	assert.Equal(t, "+09:00", meta.OffsetTimeOriginal)
+	assert.Equal(t, 5, meta.Rating)
+
+	for name, data := range map[string][]byte{
+		"not an image":       []byte("GIF89a"),
+		"empty":              nil,
+		"truncated jpeg":     jpegWith(append([]byte("Exif\x00\x00"), exif...))[:20],
+		"bad segment length": {0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x01},
+		"huge png chunk":     append([]byte("\x89PNG\r\n\x1a\n\xFF\xFF\xFF\xFFeXIf"), exif...),
+	} {
+		meta, err := Extract(bytes.NewReader(data))
+		require.NoError(t, err, name)
+		assert.Empty(t, meta.DateTimeOriginal, name)
+	}
+}
+
+func TestParseXMPRating(t *testing.T) {
+	for xmp, want := range map[string]int{
+		`xmp:Rating="3"`:                 3,
+		`xmp:Rating = ' 4 '`:             4,
+		`<xmp:Rating>5</xmp:Rating>`:     5,
+		`<xmp:Rating> 2.0 </xmp:Rating>`: 2,
+		`xmp:Rating="-1"`:                0,
+		`xmp:Rating="7"`:                 5,
+		`xmp:Rating="x" xmp:Rating="1"`:  1,
+		`<xmp:Rating></xmp:Rating>`:      -1,
+		`photoshop:Rating="3"`:           -1,
+		``:                               -1,
+	} {
+		rating, ok := parseXMPRating([]byte(xmp))
+		if want < 0 {
+			assert.False(t, ok, xmp)
+			continue
+		}
+		assert.True(t, ok, xmp)
+		assert.Equal(t, want, rating, xmp)
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, order := range byteOrders {
+		exif := buildTIFF(order, sampleEntries(order))
+		f.Add(exif)
+		f.Add(jpegWith(append([]byte("Exif\x00\x00"), exif...)))
+		f.Add(pngWith(map[string][]byte{"eXIf": exif}))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		meta := parseTIFF(data)
+		if meta.Latitude != nil && (*meta.Latitude < -90 || *meta.Latitude > 90) {
+			t.Fatalf("latitude %v out of range", *meta.Latitude)
+		}
+		if meta.Rating < 0 || meta.Rating > 5 {
+			t.Fatalf("rating %d out of range", meta.Rating)
+		}
+		if _, err := Extract(bytes.NewReader(data)); err != nil {
+			t.Fatalf("Extract: %v", err)
+		}
+	})
+}
@@ -0,0 +1,298 @@
+// Package kml reads and builds KML 2.2 documents
+// (https://developers.google.com/kml/documentation/kmlreference) for Google Earth and
+// other viewers.
+package kml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"geoalbum/backend/polyline"
+)
+
+// Namespace is the KML 2.2 XML namespace
+const Namespace = "http://www.opengis.net/kml/2.2"
+
+// KML is the root element of a KML file
+type KML struct {
+	XMLName  xml.Name `xml:"kml"`
+	Xmlns    string   `xml:"xmlns,attr,omitempty"`
+	Document Document `xml:"Document"`
+}
+
+// Document is the top-level container of styles, folders and placemarks
+type Document struct {
+	Name        string      `xml:"name,omitempty"`
+	Description string      `xml:"description,omitempty"`
+	Styles      []Style     `xml:"Style"`
+	Folders     []Folder    `xml:"Folder"`
+	Placemarks  []Placemark `xml:"Placemark"`
+}
+
+// Folder groups placemarks and nested folders
+type Folder struct {
+	Name        string      `xml:"name,omitempty"`
+	Description string      `xml:"description,omitempty"`
+	Folders     []Folder    `xml:"Folder"`
+	Placemarks  []Placemark `xml:"Placemark"`
+}
+
+// Style is a shared style referenced from placemarks as "#ID"
+type Style struct {
+	ID        string     `xml:"id,attr"`
+	IconStyle *IconStyle `xml:"IconStyle,omitempty"`
+	LineStyle *LineStyle `xml:"LineStyle,omitempty"`
+}
+
+// IconStyle styles point placemarks; Color is aabbggrr hex
+type IconStyle struct {
+	Color string  `xml:"color,omitempty"`
+	Scale float64 `xml:"scale,omitempty"`
+	Icon  *Icon   `xml:"Icon,omitempty"`
+}
+
+type Icon struct {
+	Href string `xml:"href"`
+}
+
+// LineStyle styles line placemarks; Color is aabbggrr hex
+type LineStyle struct {
+	Color string  `xml:"color,omitempty"`
+	Width float64 `xml:"width,omitempty"`
+}
+
+// Placemark is a named feature with one geometry
+type Placemark struct {
+	ID            string         `xml:"id,attr,omitempty"`
+	Name          string         `xml:"name,omitempty"`
+	Description   string         `xml:"description,omitempty"`
+	TimeStamp     *TimeStamp     `xml:"TimeStamp,omitempty"`
+	TimeSpan      *TimeSpan      `xml:"TimeSpan,omitempty"`
+	StyleURL      string         `xml:"styleUrl,omitempty"`
+	ExtendedData  *ExtendedData  `xml:"ExtendedData,omitempty"`
+	Point         *Point         `xml:"Point,omitempty"`
+	LineString    *LineString    `xml:"LineString,omitempty"`
+	MultiGeometry *MultiGeometry `xml:"MultiGeometry,omitempty"`
+}
+
+// TimeStamp places a placemark at one xsd:dateTime
+type TimeStamp struct {
+	When string `xml:"when"`
+}
+
+// TimeSpan bounds a placemark in time with xsd:dateTime values
+type TimeSpan struct {
+	Begin string `xml:"begin,omitempty"`
+	End   string `xml:"end,omitempty"`
+}
+
+// ExtendedData carries untyped name/value pairs, or typed ones in SchemaData as
+// written by GIS tools
+type ExtendedData struct {
+	Data       []Data       `xml:"Data"`
+	SchemaData []SchemaData `xml:"SchemaData,omitempty"`
+}
+
+type Data struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type SchemaData struct {
+	SimpleData []SimpleData `xml:"SimpleData"`
+}
+
+type SimpleData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Point holds one "lng,lat" coordinate tuple
+type Point struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+// LineString holds space-separated "lng,lat" tuples
+type LineString struct {
+	Tessellate  int    `xml:"tessellate,omitempty"`
+	Coordinates string `xml:"coordinates"`
+}
+
+// MultiGeometry combines several geometries in one placemark
+type MultiGeometry struct {
+	Points          []Point         `xml:"Point"`
+	LineStrings     []LineString    `xml:"LineString"`
+	MultiGeometries []MultiGeometry `xml:"MultiGeometry,omitempty"`
+}
+
+// NewPoint returns a Point at lat, lng
+func NewPoint(lat, lng float64) *Point {
+	return &Point{Coordinates: coordinate(lat, lng)}
+}
+
+// SetLine gives a placemark a line geometry, split into a MultiGeometry where it
+// crosses the antimeridian. Lines are tessellated so they follow the globe.
+func (p *Placemark) SetLine(points []polyline.Point) {
+	parts := polyline.SplitAntimeridian(points)
+	lines := make([]LineString, len(parts))
+	for i, part := range parts {
+		tuples := make([]string, len(part))
+		for j, point := range part {
+			tuples[j] = coordinate(point.Lat, point.Lng)
+		}
+		lines[i] = LineString{Tessellate: 1, Coordinates: strings.Join(tuples, " ")}
+	}
+	if len(lines) == 1 {
+		p.LineString = &lines[0]
+		return
+	}
+	p.MultiGeometry = &MultiGeometry{LineStrings: lines}
+}
+
+// AddData appends a name/value pair to the placemark's extended data
+func (p *Placemark) AddData(name, value string) {
+	if p.ExtendedData == nil {
+		p.ExtendedData = &ExtendedData{}
+	}
+	p.ExtendedData.Data = append(p.ExtendedData.Data, Data{Name: name, Value: value})
+}
+
+func coordinate(lat, lng float64) string {
+	return strconv.FormatFloat(lng, 'f', -1, 64) + "," + strconv.FormatFloat(lat, 'f', -1, 64)
+}
+
+// Encode writes doc as an indented KML file
+func Encode(w io.Writer, doc *KML) error {
+	doc.Xmlns = Namespace
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write KML: %w", err)
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write KML: %w", err)
+	}
+	return encoder.Flush()
+}
+
+// Decode reads the placemarks of a KML file in document order, descending into
+// folders
+func Decode(r io.Reader) ([]Placemark, error) {
+	var doc struct {
+		XMLName    xml.Name    `xml:"kml"`
+		Document   Document    `xml:"Document"`
+		Folders    []Folder    `xml:"Folder"`
+		Placemarks []Placemark `xml:"Placemark"`
+	}
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid KML: %w", err)
+	}
+
+	placemarks := append(doc.Placemarks, doc.Document.Placemarks...)
+	folders := append(doc.Folders, doc.Document.Folders...)
+	for len(folders) > 0 {
+		folder := folders[0]
+		folders = append(folders[1:], folder.Folders...)
+		placemarks = append(placemarks, folder.Placemarks...)
+	}
+	return placemarks, nil
+}
+
+// Data returns the value of the named extended data field, from Data or SimpleData
+func (p *Placemark) Data(name string) string {
+	if p.ExtendedData == nil {
+		return ""
+	}
+	for _, data := range p.ExtendedData.Data {
+		if data.Name == name {
+			return strings.TrimSpace(data.Value)
+		}
+	}
+	for _, schemaData := range p.ExtendedData.SchemaData {
+		for _, data := range schemaData.SimpleData {
+			if data.Name == name {
+				return strings.TrimSpace(data.Value)
+			}
+		}
+	}
+	return ""
+}
+
+// Position returns the position of a placemark with a single point, looking
+// inside a MultiGeometry that holds nothing else
+func (p *Placemark) Position() (polyline.Point, bool, error) {
+	point := p.Point
+	if point == nil && p.MultiGeometry != nil && len(p.MultiGeometry.Points) == 1 &&
+		len(p.MultiGeometry.LineStrings) == 0 && len(p.MultiGeometry.MultiGeometries) == 0 {
+		point = &p.MultiGeometry.Points[0]
+	}
+	if point == nil {
+		return polyline.Point{}, false, nil
+	}
+	points, err := parseCoordinates(point.Coordinates)
+	if err != nil {
+		return polyline.Point{}, false, err
+	}
+	if len(points) != 1 {
+		return polyline.Point{}, false, fmt.Errorf("invalid KML Point: %d coordinates", len(points))
+	}
+	return points[0], true, nil
+}
+
+// Lines returns the line strings of a placemark, including those nested in
+// MultiGeometry
+func (p *Placemark) Lines() ([][]polyline.Point, error) {
+	var lineStrings []LineString
+	if p.LineString != nil {
+		lineStrings = append(lineStrings, *p.LineString)
+	}
+	if p.MultiGeometry != nil {
+		geometries := []MultiGeometry{*p.MultiGeometry}
+		for len(geometries) > 0 {
+			geometry := geometries[0]
+			geometries = append(geometries[1:], geometry.MultiGeometries...)
+			lineStrings = append(lineStrings, geometry.LineStrings...)
+		}
+	}
+
+	var lines [][]polyline.Point
+	for _, line := range lineStrings {
+		points, err := parseCoordinates(line.Coordinates)
+		if err != nil {
+			return nil, err
+		}
+		if len(points) < 2 {
+			return nil, fmt.Errorf("invalid KML LineString: a line needs at least 2 coordinates")
+		}
+		lines = append(lines, points)
+	}
+	return lines, nil
+}
+
+// parseCoordinates reads whitespace-separated "lng,lat[,alt]" tuples
+func parseCoordinates(coordinates string) ([]polyline.Point, error) {
+	var points []polyline.Point
+	for _, tuple := range strings.Fields(coordinates) {
+		values := strings.Split(tuple, ",")
+		if len(values) < 2 || len(values) > 3 {
+			return nil, fmt.Errorf("invalid KML coordinates %q", tuple)
+		}
+		lng, err := strconv.ParseFloat(values[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KML coordinates %q", tuple)
+		}
+		lat, err := strconv.ParseFloat(values[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KML coordinates %q", tuple)
+		}
+		if math.IsNaN(lat) || math.IsNaN(lng) || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+			return nil, fmt.Errorf("invalid KML coordinates %q: out of range", tuple)
+		}
+		points = append(points, polyline.Point{Lat: lat, Lng: lng})
+	}
+	return points, nil
+}
@@ -0,0 +1,109 @@
+package kml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"geoalbum/backend/polyline"
+)
+
+const sample = `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+  <Document>
+    <name>Places</name>
+    <Placemark id="tokyo">
+      <name>Tokyo</name>
+      <TimeStamp><when>2025-05-01</when></TimeStamp>
+      <Point><coordinates> 139.7671,35.6812,0 </coordinates></Point>
+    </Placemark>
+    <Folder>
+      <name>Outer</name>
+      <Folder>
+        <Placemark>
+          <name>Route</name>
+          <MultiGeometry>
+            <LineString><coordinates>139.7,35.6 138,35.3
+              135.7,35.0</coordinates></LineString>
+            <MultiGeometry><LineString><coordinates>1,2 3,4</coordinates></LineString></MultiGeometry>
+          </MultiGeometry>
+        </Placemark>
+      </Folder>
+      <Placemark>
+        <name>Kyoto</name>
+        <ExtendedData>
+          <SchemaData schemaUrl="#s"><SimpleData name="external_id">k-1</SimpleData></SchemaData>
+        </ExtendedData>
+        <MultiGeometry><Point><coordinates>135.7681,35.0116</coordinates></Point></MultiGeometry>
+      </Placemark>
+    </Folder>
+  </Document>
+</kml>`
+
+func TestDecode(t *testing.T) {
+	placemarks, err := Decode(strings.NewReader(sample))
+	require.NoError(t, err)
+	require.Len(t, placemarks, 3)
+
+	assert.Equal(t, "tokyo", placemarks[0].ID)
+	assert.Equal(t, "2025-05-01", placemarks[0].TimeStamp.When)
+	point, ok, err := placemarks[0].Position()
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, polyline.Point{Lat: 35.6812, Lng: 139.7671}, point)
+
+	// Folders are read after the document's own placemarks, outermost first
+	assert.Equal(t, "Kyoto", placemarks[1].Name)
+	assert.Equal(t, "k-1", placemarks[1].Data("external_id"))
+	_, ok, err = placemarks[1].Position()
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	assert.Equal(t, "Route", placemarks[2].Name)
+	_, ok, err = placemarks[2].Position()
+	require.NoError(t, err)
+	assert.False(t, ok)
+	lines, err := placemarks[2].Lines()
+	require.NoError(t, err)
+	require.Len(t, lines, 2)
+	assert.Len(t, lines[0], 3)
+	assert.Equal(t, []polyline.Point{{Lat: 2, Lng: 1}, {Lat: 4, Lng: 3}}, lines[1])
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	_, err := Decode(strings.NewReader(`<gpx></gpx>`))
+	assert.Error(t, err)
+
+	for _, coordinates := range []string{"1", "a,b", "200,0", "1,2,3,4"} {
+		p := Placemark{Point: &Point{Coordinates: coordinates}}
+		_, _, err := p.Position()
+		assert.Error(t, err, coordinates)
+	}
+	p := Placemark{LineString: &LineString{Coordinates: "1,2"}}
+	_, err = p.Lines()
+	assert.Error(t, err)
+}
+
+func TestRoundTrip(t *testing.T) {
+	placemark := Placemark{ID: "line", Name: "Pacific & back"}
+	placemark.SetLine([]polyline.Point{{Lat: 30, Lng: 170}, {Lat: 31, Lng: 190}})
+	placemark.AddData("kind", "path")
+	doc := &KML{Document: Document{Folders: []Folder{{Name: "Paths", Placemarks: []Placemark{placemark}}}}}
+
+	var buf strings.Builder
+	require.NoError(t, Encode(&buf, doc))
+	assert.Contains(t, buf.String(), `<kml xmlns="http://www.opengis.net/kml/2.2">`)
+
+	placemarks, err := Decode(strings.NewReader(buf.String()))
+	require.NoError(t, err)
+	require.Len(t, placemarks, 1)
+	assert.Equal(t, "Pacific & back", placemarks[0].Name)
+	assert.Equal(t, "path", placemarks[0].Data("kind"))
+	lines, err := placemarks[0].Lines()
+	require.NoError(t, err)
+	require.Len(t, lines, 2)
+	assert.Equal(t, 180.0, lines[0][1].Lng)
+	assert.Equal(t, -180.0, lines[1][0].Lng)
+}
@@ -24,7 +24,7 @@ func CORSMiddleware() gin.HandlerFunc {
 		}
 
 		// Set other CORS headers
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Requested-With")
 		c.Header("Access-Control-Expose-Headers", "Content-Length, X-Total-Count")
 		c.Header("Access-Control-Allow-Credentials", "true")
@@ -64,21 +64,31 @@ var (
 
 // RateLimitMiddleware implements rate limiting based on client IP
 func RateLimitMiddleware(maxRequests int, window time.Duration) gin.HandlerFunc {
-	refillRate := window / time.Duration(maxRequests)
+	return RouteRateLimitMiddleware(maxRequests, window, nil)
+}
 
+// RouteRateLimitMiddleware implements rate limiting based on client IP, allowing the
+// routes in routeLimits, keyed by their full path, their own number of requests per
+// window. Those routes are counted apart from the rest of the API.
+func RouteRateLimitMiddleware(maxRequests int, window time.Duration, routeLimits map[string]int) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		clientIP := c.ClientIP()
+		key := c.ClientIP()
+		limit := maxRequests
+		if routeLimit, ok := routeLimits[c.FullPath()]; ok {
+			key += " " + c.FullPath()
+			limit = routeLimit
+		}
 
 		rateLimiterMutex.RLock()
-		limiter, exists := rateLimiters[clientIP]
+		limiter, exists := rateLimiters[key]
 		rateLimiterMutex.RUnlock()
 
 		if !exists {
 			rateLimiterMutex.Lock()
 			// Double-check pattern
-			if limiter, exists = rateLimiters[clientIP]; !exists {
-				limiter = NewRateLimiter(maxRequests, refillRate)
-				rateLimiters[clientIP] = limiter
+			if limiter, exists = rateLimiters[key]; !exists {
+				limiter = NewRateLimiter(limit, window/time.Duration(limit))
+				rateLimiters[key] = limiter
 			}
 			rateLimiterMutex.Unlock()
 		}
@@ -103,11 +113,11 @@ func CleanupRateLimiters() {
 		for range ticker.C {
 			rateLimiterMutex.Lock()
 			now := time.Now()
-			for ip, limiter := range rateLimiters {
+			for key, limiter := range rateLimiters {
 				limiter.mutex.Lock()
 				// Remove limiters that haven't been used for more than 2 hours
 				if now.Sub(limiter.lastRefill) > 2*time.Hour {
-					delete(rateLimiters, ip)
+					delete(rateLimiters, key)
 				}
 				limiter.mutex.Unlock()
 			}
@@ -7,13 +7,54 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// remoteTileHosts are the online map tile providers used by the frontend
+var remoteTileHosts = []string{
+	"https://*.tile.openstreetmap.org",
+	"https://*.openstreetmap.org",
+	"https://*.is.autonavi.com",
+	"https://webrd04.is.autonavi.com",
+	"https://webst01.is.autonavi.com",
+}
+
+// ContentSecurityPolicy holds the CSP source lists that depend on how the map is served
+type ContentSecurityPolicy struct {
+	ImgSources    []string
+	WorkerSources []string
+}
+
+// DefaultContentSecurityPolicy allows images from the online tile providers and no workers
+func DefaultContentSecurityPolicy() ContentSecurityPolicy {
+	return ContentSecurityPolicy{
+		ImgSources:    append([]string{"'self'", "data:", "blob:"}, remoteTileHosts...),
+		WorkerSources: []string{"'none'"},
+	}
+}
+
+// WithOfflineBasemaps adjusts the policy for basemaps served by this server. Their
+// tiles are same-origin already; vector basemaps additionally need web workers, which
+// map renderers create from blob URLs. With offlineOnly the online tile providers are
+// no longer allowed.
+func (p ContentSecurityPolicy) WithOfflineBasemaps(vector, offlineOnly bool) ContentSecurityPolicy {
+	if offlineOnly {
+		p.ImgSources = []string{"'self'", "data:", "blob:"}
+	}
+	if vector {
+		p.WorkerSources = []string{"'self'", "blob:"}
+	}
+	return p
+}
+
 // SecurityHeadersMiddleware adds security headers to responses
-func SecurityHeadersMiddleware() gin.HandlerFunc {
+func SecurityHeadersMiddleware(policy ContentSecurityPolicy) gin.HandlerFunc {
+	imgSources := strings.Join(policy.ImgSources, " ")
+	workerSources := strings.Join(policy.WorkerSources, " ")
+
 	return func(c *gin.Context) {
 		// Generate a nonce for CSP
 		nonce := generateNonce()
@@ -30,13 +71,13 @@ func SecurityHeadersMiddleware() gin.HandlerFunc {
 		csp := "default-src 'self'; " +
 			"script-src 'self' 'nonce-" + nonce + "'; " +
 			"style-src 'self' 'unsafe-inline'; " +
-			"img-src 'self' data: blob: https://*.tile.openstreetmap.org https://*.openstreetmap.org https://*.is.autonavi.com https://webrd04.is.autonavi.com https://webst01.is.autonavi.com; " +
+			"img-src " + imgSources + "; " +
 			"font-src 'self'; " +
 			"connect-src 'self'; " +
 			"media-src 'self'; " +
 			"object-src 'none'; " +
 			"child-src 'none'; " +
-			"worker-src 'none'; " +
+			"worker-src " + workerSources + "; " +
 			"frame-ancestors 'none'; " +
 			"form-action 'self'; " +
 			"base-uri 'self'"
@@ -53,13 +94,23 @@ func SecurityHeadersMiddleware() gin.HandlerFunc {
 
 // RequestSizeMiddleware limits request body size
 func RequestSizeMiddleware(maxSize int64) gin.HandlerFunc {
+	return RouteRequestSizeMiddleware(maxSize, nil)
+}
+
+// RouteRequestSizeMiddleware limits request body size, allowing the routes in
+// routeLimits, keyed by their full path, their own limit
+func RouteRequestSizeMiddleware(maxSize int64, routeLimits map[string]int64) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Set max request size (default 10MB for photo uploads)
 		if maxSize == 0 {
 			maxSize = 10 << 20 // 10MB
 		}
-		
-		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
+
+		limit := maxSize
+		if routeLimit, ok := routeLimits[c.FullPath()]; ok {
+			limit = routeLimit
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
 		c.Next()
 	}
 }
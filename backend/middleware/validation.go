@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 
@@ -62,6 +64,21 @@ func (s *InputSanitizer) ValidateAlbumDescription(description string) bool {
 	return len(description) <= 2000
 }
 
+// ValidateTagName validates a tag name
+func (s *InputSanitizer) ValidateTagName(name string) bool {
+	// Tag names should be 1-50 characters; commas are reserved as the filter separator
+	length := utf8.RuneCountInString(name)
+	if length < 1 || length > 50 || strings.Contains(name, ",") {
+		return false
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
 // ValidateCoordinates validates latitude and longitude
 func (s *InputSanitizer) ValidateCoordinates(lat, lng float64) bool {
 	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
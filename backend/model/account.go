@@ -0,0 +1,56 @@
+package model
+
+import (
+	"time"
+)
+
+// Account export job states
+const (
+	ExportJobRunning   = "running"
+	ExportJobCompleted = "completed"
+	ExportJobFailed    = "failed"
+)
+
+// AccountExportJob tracks the building of an archive of a user's account
+type AccountExportJob struct {
+	ID           string     `json:"id"`
+	UserID       string     `json:"-"`
+	Status       string     `json:"status"`
+	Albums       int        `json:"albums"`
+	Photos       int        `json:"photos"`
+	Done         int        `json:"done"`          // photo files written so far
+	MissingFiles int        `json:"missing_files"` // photos whose original could not be read
+	Size         int64      `json:"size,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+	DownloadURL  string     `json:"download_url,omitempty"` // signed link, valid until ExpiresAt
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+}
+
+// ImportCounts counts the records of one kind an account import created, found
+// already present, or failed to import
+type ImportCounts struct {
+	Created  int `json:"created"`
+	Existing int `json:"existing"`
+	Failed   int `json:"failed"`
+}
+
+// AccountImportJob tracks the import of an account archive
+type AccountImportJob struct {
+	ID          string       `json:"id"`
+	UserID      string       `json:"-"`
+	Status      string       `json:"status"` // ImportJobRunning, ImportJobCompleted or ImportJobFailed
+	Total       int          `json:"total"`  // photos in the archive
+	Done        int          `json:"done"`
+	Albums      ImportCounts `json:"albums"`
+	Photos      ImportCounts `json:"photos"`
+	Paths       ImportCounts `json:"paths"`
+	Trips       ImportCounts `json:"trips"`
+	Tags        ImportCounts `json:"tags"`
+	SavedPlaces ImportCounts `json:"saved_places"`
+	Errors      []string     `json:"errors,omitempty"`
+	Error       string       `json:"error,omitempty"`
+	StartedAt   time.Time    `json:"started_at"`
+	FinishedAt  *time.Time   `json:"finished_at,omitempty"`
+}
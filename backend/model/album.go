@@ -5,14 +5,26 @@ import (
 )
 
 type Album struct {
-	ID          string    `db:"id" json:"id"`
-	UserID      string    `db:"user_id" json:"user_id"`
-	Title       string    `db:"title" json:"title"`
-	Description string    `db:"description" json:"description"`
-	Latitude    float64   `db:"latitude" json:"latitude"`
-	Longitude   float64   `db:"longitude" json:"longitude"`
-	CreatedAt   time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
-	PhotoCount  int       `json:"photo_count,omitempty"`
-	Photos      []Photo   `json:"photos,omitempty"`
+	ID           string    `db:"id" json:"id"`
+	UserID       string    `db:"user_id" json:"user_id"`
+	Title        string    `db:"title" json:"title"`
+	Description  string    `db:"description" json:"description"`
+	Latitude     float64   `db:"latitude" json:"latitude"`
+	Longitude    float64   `db:"longitude" json:"longitude"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at" json:"updated_at"`
+	StartAt      time.Time `db:"start_at" json:"start_at"`
+	EndAt        time.Time `db:"end_at" json:"end_at"`
+	DatesManual  bool      `db:"dates_manual" json:"dates_manual"`
+	Timezone     string    `db:"timezone" json:"timezone,omitempty"`
+	CountryCode  string    `db:"country_code" json:"country_code,omitempty"`
+	Country      string    `db:"country" json:"country,omitempty"`
+	Region       string    `db:"region" json:"region,omitempty"`
+	City         string    `db:"city" json:"city,omitempty"`
+	PhotoCount   int       `db:"photo_count" json:"photo_count,omitempty"`
+	StorageBytes int64     `db:"storage_bytes" json:"storage_bytes,omitempty"`
+	CoverPhotoID string    `db:"cover_photo_id" json:"cover_photo_id,omitempty"`
+	ExternalID   string    `db:"external_id" json:"external_id,omitempty"` // ID of the place an album was imported from
+	Tags         []string  `json:"tags,omitempty"`
+	Photos       []Photo   `json:"photos,omitempty"`
 }
\ No newline at end of file
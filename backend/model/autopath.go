@@ -0,0 +1,21 @@
+package model
+
+// AutoPath is a path proposed between two albums that follow each other in time
+type AutoPath struct {
+	FromAlbumID string   `json:"from_album_id"`
+	ToAlbumID   string   `json:"to_album_id"`
+	PathID      string   `json:"path_id,omitempty"`  // set once the path exists
+	Existing    bool     `json:"existing"`           // the path was already there
+	GapHours    float64  `json:"gap_hours"`          // time from the end of one album to the start of the next; 0 when they overlap
+	Warnings    []string `json:"warnings,omitempty"` // loops or forks the path introduces
+}
+
+// AutoPathResult reports the chains built from chronologically ordered albums
+type AutoPathResult struct {
+	DryRun   bool       `json:"dry_run"`
+	Chains   [][]string `json:"chains"` // album IDs of each chain in date order
+	Paths    []AutoPath `json:"paths"`
+	Created  int        `json:"created"`
+	Existing int        `json:"existing"`
+	Replaced int64      `json:"replaced"` // other outgoing paths removed with replace_existing
+}
@@ -0,0 +1,17 @@
+package model
+
+// Basemap describes a locally served tile set
+type Basemap struct {
+	Name         string       `json:"name"`
+	Title        string       `json:"title"`
+	Description  string       `json:"description,omitempty"`
+	Attribution  string       `json:"attribution,omitempty"`
+	Type         string       `json:"type"`   // "raster" or "vector"
+	Format       string       `json:"format"` // png, jpg, webp or pbf
+	MinZoom      int          `json:"min_zoom"`
+	MaxZoom      int          `json:"max_zoom"`
+	Bounds       *BoundingBox `json:"bounds,omitempty"`
+	Center       []float64    `json:"center,omitempty"` // longitude, latitude, zoom
+	VectorLayers []string     `json:"vector_layers,omitempty"`
+	TileURL      string       `json:"tile_url"`
+}
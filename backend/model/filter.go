@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+)
+
+// AlbumFilter narrows an album listing; zero values do not filter
+type AlbumFilter struct {
+	StartDate    *time.Time
+	EndDate      *time.Time
+	Tags         []string
+	MatchAllTags bool // AND semantics for Tags; otherwise any tag matches
+	Country      string // country code or name
+	Region       string
+	City         string
+	Search       string // matched against title, description and place names
+	BBox         *BoundingBox // albums located inside; MinLng > MaxLng crosses the antimeridian
+}
+
+// PhotoFilter narrows a photo listing; zero values do not filter
+type PhotoFilter struct {
+	Tags         []string
+	MatchAllTags bool // AND semantics for Tags; otherwise any tag matches
+	Favorite     *bool
+	MinRating    int
+}
\ No newline at end of file
@@ -0,0 +1,60 @@
+package model
+
+// GazetteerPlace is a populated place from the offline gazetteer used for geocoding
+type GazetteerPlace struct {
+	ID          int64   `db:"id" json:"id"`
+	Name        string  `db:"name" json:"name"`
+	NameZh      string  `db:"name_zh" json:"name_zh,omitempty"`
+	Latitude    float64 `db:"latitude" json:"latitude"`
+	Longitude   float64 `db:"longitude" json:"longitude"`
+	CountryCode string  `db:"country_code" json:"country_code"`
+	Country     string  `db:"country" json:"country"`
+	CountryZh   string  `db:"country_zh" json:"country_zh,omitempty"`
+	Region      string  `db:"region" json:"region,omitempty"`
+	Population  int64   `db:"population" json:"population"`
+	Timezone    string  `db:"timezone" json:"timezone,omitempty"`
+	// AlternateNames are other names and spellings the place is known by
+	AlternateNames []string `db:"-" json:"-"`
+}
+
+// GeocodeResult is a gazetteer place matching a forward geocoding query
+type GeocodeResult struct {
+	GazetteerPlace
+	DisplayName string  `json:"display_name"`
+	MatchedName string  `json:"matched_name"`
+	Score       float64 `json:"score"`
+}
+
+// BoundingBox is a latitude/longitude rectangle. MinLng > MaxLng denotes a box
+// crossing the antimeridian.
+type BoundingBox struct {
+	MinLat float64 `json:"min_lat"`
+	MinLng float64 `json:"min_lng"`
+	MaxLat float64 `json:"max_lat"`
+	MaxLng float64 `json:"max_lng"`
+}
+
+// Contains reports whether a point lies inside the box
+func (b BoundingBox) Contains(lat, lng float64) bool {
+	if lat < b.MinLat || lat > b.MaxLat {
+		return false
+	}
+	if b.MinLng <= b.MaxLng {
+		return lng >= b.MinLng && lng <= b.MaxLng
+	}
+	return lng >= b.MinLng || lng <= b.MaxLng
+}
+
+// Center returns the midpoint of the box
+func (b BoundingBox) Center() (lat, lng float64) {
+	lat = (b.MinLat + b.MaxLat) / 2
+	maxLng := b.MaxLng
+	if b.MinLng > maxLng {
+		maxLng += 360
+	}
+	lng = (b.MinLng + maxLng) / 2
+	if lng > 180 {
+		lng -= 360
+	}
+	return lat, lng
+}
@@ -0,0 +1,16 @@
+package model
+
+// HeatmapPoint is a weighted location; Weight is the number of photos it stands for
+type HeatmapPoint struct {
+	Latitude  float64 `db:"latitude" json:"latitude"`
+	Longitude float64 `db:"longitude" json:"longitude"`
+	Weight    int     `db:"weight" json:"weight"`
+}
+
+// Heatmap is a user's photo density aggregated into grid cells for one zoom level
+type Heatmap struct {
+	Zoom       int            `json:"zoom"`
+	CellSizePx int            `json:"cell_size_px"`
+	MaxWeight  int            `json:"max_weight"`
+	Points     []HeatmapPoint `json:"points"`
+}
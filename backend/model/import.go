@@ -0,0 +1,109 @@
+package model
+
+import (
+	"time"
+)
+
+// GPXImportResult summarises what a GPX import created and matched
+type GPXImportResult struct {
+	Waypoints       int              `json:"waypoints"`
+	Routes          int              `json:"routes"`
+	Tracks          int              `json:"tracks"`
+	TrackPoints     int              `json:"track_points"`
+	AlbumsCreated   []Album          `json:"albums_created"`
+	AlbumsMatched   []ImportMatch    `json:"albums_matched"` // waypoints that fell on an existing album
+	Paths           []ImportedPath   `json:"paths"`
+	PhotosUnlocated int              `json:"photos_unlocated"` // photos with a capture time but no GPS position
+	PhotosGeotagged []GeotaggedPhoto `json:"photos_geotagged"`
+	Warnings        []string         `json:"warnings,omitempty"`
+}
+
+// ImportMatch pairs an imported waypoint with the album it was matched to
+type ImportMatch struct {
+	Name      string  `json:"name"`
+	AlbumID   string  `json:"album_id"`
+	DistanceM float64 `json:"distance_m"`
+}
+
+// ImportedPath is a path whose geometry was set from a GPX track or route
+type ImportedPath struct {
+	PathID      string `json:"path_id"`
+	FromAlbumID string `json:"from_album_id"`
+	ToAlbumID   string `json:"to_album_id"`
+	Source      string `json:"source"` // "track" or "route"
+	Name        string `json:"name,omitempty"`
+	Points      int    `json:"points"`
+	Created     bool   `json:"created"` // false when an existing path got the geometry
+}
+
+// GeotaggedPhoto is a photo given a position from a GPX track
+type GeotaggedPhoto struct {
+	PhotoID   string  `json:"photo_id"`
+	AlbumID   string  `json:"album_id"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Outcomes of an imported place feature
+const (
+	ImportStatusCreated  = "created"
+	ImportStatusExisting = "existing" // imported before, or a path that already existed
+	ImportStatusSkipped  = "skipped"
+	ImportStatusFailed   = "failed"
+)
+
+// PlaceImportResult reports what a GeoJSON or KML import did with each feature
+type PlaceImportResult struct {
+	Format         string               `json:"format"`
+	Features       int                  `json:"features"`
+	AlbumsCreated  int                  `json:"albums_created"`
+	AlbumsExisting int                  `json:"albums_existing"`
+	PathsCreated   int                  `json:"paths_created"`
+	PathsExisting  int                  `json:"paths_existing"`
+	Skipped        int                  `json:"skipped"`
+	Failed         int                  `json:"failed"`
+	Results        []PlaceFeatureResult `json:"results"`
+	Warnings       []string             `json:"warnings,omitempty"`
+}
+
+// PlaceFeatureResult is the outcome of one feature, in file order
+type PlaceFeatureResult struct {
+	Index      int    `json:"index"`
+	ExternalID string `json:"external_id,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Geometry   string `json:"geometry,omitempty"`
+	Status     string `json:"status"`
+	AlbumID    string `json:"album_id,omitempty"`
+	PathID     string `json:"path_id,omitempty"`
+	Error      string `json:"error,omitempty"` // why the feature failed or was skipped
+}
+
+// Takeout import job states
+const (
+	ImportJobRunning   = "running"
+	ImportJobCompleted = "completed"
+	ImportJobFailed    = "failed"
+)
+
+// MaxImportJobErrors bounds the per-photo errors kept on an import job
+const MaxImportJobErrors = 100
+
+// TakeoutImportJob tracks the import of a Google Takeout export of Google Photos
+type TakeoutImportJob struct {
+	ID            string     `json:"id"`
+	UserID        string     `json:"-"`
+	Status        string     `json:"status"`
+	Group         string     `json:"group"` // "folder" or "cluster"
+	Total         int        `json:"total"` // photos to import, known once the export is read
+	Done          int        `json:"done"`
+	Imported      int        `json:"imported"`
+	Skipped       int        `json:"skipped"` // already imported, or without a location to place them
+	Failed        int        `json:"failed"`
+	Unsupported   int        `json:"unsupported"` // videos and other media that are not imported
+	AlbumsCreated int        `json:"albums_created"`
+	AlbumsReused  int        `json:"albums_reused"`
+	Errors        []string   `json:"errors,omitempty"`
+	Error         string     `json:"error,omitempty"` // why the job failed
+	StartedAt     time.Time  `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+}
@@ -4,12 +4,33 @@ import (
 	"time"
 )
 
+// Transport modes a path can be travelled by; an empty mode is unspecified
+const (
+	TransportWalk   = "walk"
+	TransportCar    = "car"
+	TransportTrain  = "train"
+	TransportFlight = "flight"
+	TransportFerry  = "ferry"
+)
+
+// TransportModes lists the valid transport modes
+var TransportModes = []string{TransportWalk, TransportCar, TransportTrain, TransportFlight, TransportFerry}
+
 type Path struct {
-	ID          string    `db:"id" json:"id"`
-	UserID      string    `db:"user_id" json:"user_id"`
-	FromAlbumID string    `db:"from_album_id" json:"from_album_id"`
-	ToAlbumID   string    `db:"to_album_id" json:"to_album_id"`
-	CreatedAt   time.Time `db:"created_at" json:"created_at"`
-	FromAlbum   *Album    `json:"from_album,omitempty"`
-	ToAlbum     *Album    `json:"to_album,omitempty"`
-}
\ No newline at end of file
+	ID              string     `db:"id" json:"id"`
+	UserID          string     `db:"user_id" json:"user_id"`
+	FromAlbumID     string     `db:"from_album_id" json:"from_album_id"`
+	ToAlbumID       string     `db:"to_album_id" json:"to_album_id"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+	TransportMode   string     `db:"transport_mode" json:"transport_mode"`
+	DistanceKm      float64    `db:"-" json:"distance_km"` // great-circle distance between the albums
+	DurationMinutes *int       `db:"duration_minutes" json:"duration_minutes,omitempty"`
+	DepartAt        *time.Time `db:"depart_at" json:"depart_at,omitempty"`
+	ArriveAt        *time.Time `db:"arrive_at" json:"arrive_at,omitempty"`
+	Notes           string     `db:"notes" json:"notes"`
+	Geometry        string     `db:"geometry" json:"geometry,omitempty"` // encoded polyline; see package polyline
+	Interpolated    bool       `db:"-" json:"interpolated,omitempty"`    // geometry is a great circle, not stored
+	FromAlbum       *Album     `db:"from_album" json:"from_album,omitempty"`
+	ToAlbum         *Album     `db:"to_album" json:"to_album,omitempty"`
+	Warnings        []string   `db:"-" json:"warnings,omitempty"` // loops or forks the path introduced
+}
@@ -5,13 +5,20 @@ import (
 )
 
 type Photo struct {
-	ID           string    `db:"id" json:"id"`
-	AlbumID      string    `db:"album_id" json:"album_id"`
-	Filename     string    `db:"filename" json:"filename"`
-	FilePath     string    `db:"file_path" json:"-"`
-	FileSize     int64     `db:"file_size" json:"file_size"`
-	MimeType     string    `db:"mime_type" json:"mime_type"`
-	DisplayOrder int       `db:"display_order" json:"display_order"`
-	UploadedAt   time.Time `db:"uploaded_at" json:"uploaded_at"`
-	URL          string    `json:"url"`
+	ID           string     `db:"id" json:"id"`
+	AlbumID      string     `db:"album_id" json:"album_id"`
+	Filename     string     `db:"filename" json:"filename"`
+	FilePath     string     `db:"file_path" json:"-"`
+	FileSize     int64      `db:"file_size" json:"file_size"`
+	MimeType     string     `db:"mime_type" json:"mime_type"`
+	DisplayOrder int        `db:"display_order" json:"display_order"`
+	UploadedAt   time.Time  `db:"uploaded_at" json:"uploaded_at"`
+	TakenAt      *time.Time `db:"taken_at" json:"taken_at,omitempty"`
+	Rating       int        `db:"rating" json:"rating"`
+	Favorite     bool       `db:"favorite" json:"favorite"`
+	Latitude     *float64   `db:"latitude" json:"latitude,omitempty"`
+	Longitude    *float64   `db:"longitude" json:"longitude,omitempty"`
+	Description  string     `db:"description" json:"description,omitempty"`
+	Tags         []string   `json:"tags,omitempty"`
+	URL          string     `json:"url"`
 }
\ No newline at end of file
@@ -0,0 +1,19 @@
+package model
+
+// Route directions
+const (
+	RouteForward  = "forward"
+	RouteBackward = "backward"
+	RouteBoth     = "both"
+)
+
+// Route is the chain of albums reached by following paths from an album
+type Route struct {
+	AlbumID    string   `json:"album_id"`
+	Direction  string   `json:"direction"`
+	Albums     []Album  `json:"albums"`
+	StartIndex int      `json:"start_index"`                // position of AlbumID in Albums
+	Cycle      bool     `json:"cycle"`                      // the walk reached an album already on the route and stopped
+	Truncated  bool     `json:"truncated"`                  // the walk stopped at the depth cap
+	Branches   []string `json:"branch_album_ids,omitempty"` // albums with several paths in the walked direction; the oldest was followed
+}
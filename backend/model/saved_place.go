@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+)
+
+// Saved place kinds
+const (
+	SavedPlaceCircle  = "circle"
+	SavedPlacePolygon = "polygon"
+)
+
+// SavedPlace is a user-defined named area: a centre with a radius, or a polygon
+type SavedPlace struct {
+	ID         string       `db:"id" json:"id"`
+	UserID     string       `db:"user_id" json:"user_id"`
+	Name       string       `db:"name" json:"name"`
+	Kind       string       `db:"kind" json:"kind"`
+	Latitude   *float64     `db:"latitude" json:"latitude,omitempty"`
+	Longitude  *float64     `db:"longitude" json:"longitude,omitempty"`
+	RadiusM    *float64     `db:"radius_m" json:"radius_m,omitempty"`
+	Polygon    [][2]float64 `db:"-" json:"polygon,omitempty"` // [longitude, latitude] vertices
+	AutoTag    bool         `db:"auto_tag" json:"auto_tag"`
+	CreatedAt  time.Time    `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time    `db:"updated_at" json:"updated_at"`
+	AlbumCount int          `db:"-" json:"album_count"`
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+)
+
+// Stats summarises a user's travel library
+type Stats struct {
+	Albums         int                `json:"albums"`
+	Photos         int                `json:"photos"`
+	StorageBytes   int64              `json:"storage_bytes"`
+	Countries      int                `json:"countries"`
+	Cities         int                `json:"cities"`
+	CountryCodes   []string           `json:"country_codes"`
+	Paths          int                `json:"paths"`
+	DistanceKm     float64            `json:"distance_km"`
+	DistanceByMode map[string]float64 `json:"distance_by_mode"` // keyed by transport mode, "unspecified" when unset
+	FirstTripAt    *time.Time         `json:"first_trip_at,omitempty"`
+	LastTripAt     *time.Time         `json:"last_trip_at,omitempty"`
+	BusiestMonth   *MonthStats        `json:"busiest_month,omitempty"`
+	Years          []YearStats        `json:"years"`
+	GeneratedAt    time.Time          `json:"generated_at"`
+}
+
+// YearStats is the part of a user's library that falls in one calendar year
+type YearStats struct {
+	Year         int     `json:"year"`
+	Albums       int     `json:"albums"`
+	Photos       int     `json:"photos"`
+	StorageBytes int64   `json:"storage_bytes"`
+	Countries    int     `json:"countries"`
+	Cities       int     `json:"cities"`
+	DistanceKm   float64 `json:"distance_km"`
+}
+
+// MonthStats counts the albums started and photos taken in one month ("2006-01")
+type MonthStats struct {
+	Month  string `json:"month"`
+	Albums int    `json:"albums"`
+	Photos int    `json:"photos"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import (
+	"time"
+)
+
+type Tag struct {
+	ID         string    `db:"id" json:"id"`
+	UserID     string    `db:"user_id" json:"user_id"`
+	Name       string    `db:"name" json:"name"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+	AlbumCount int       `db:"album_count" json:"album_count"`
+	PhotoCount int       `db:"photo_count" json:"photo_count"`
+}
\ No newline at end of file
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+)
+
+// Tile seed job states
+const (
+	SeedJobRunning   = "running"
+	SeedJobCompleted = "completed"
+	SeedJobCancelled = "cancelled"
+)
+
+// TileSeedJob tracks the pre-fetching of a tile range into the tile proxy cache
+type TileSeedJob struct {
+	ID         string      `json:"id"`
+	UserID     string      `json:"-"`
+	Provider   string      `json:"provider"`
+	BBox       BoundingBox `json:"bbox"`
+	MinZoom    int         `json:"min_zoom"`
+	MaxZoom    int         `json:"max_zoom"`
+	Status     string      `json:"status"`
+	Total      int         `json:"total"`
+	Done       int         `json:"done"`
+	Failed     int         `json:"failed"`
+	StartedAt  time.Time   `json:"started_at"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+}
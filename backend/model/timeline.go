@@ -0,0 +1,15 @@
+package model
+
+import (
+	"time"
+)
+
+// TimelineBucket counts the albums and photos falling in one year, month or day
+type TimelineBucket struct {
+	Key      string    `json:"key"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Albums   int       `json:"albums"`
+	Photos   int       `json:"photos"`
+	AlbumIDs []string  `json:"album_ids,omitempty"`
+}
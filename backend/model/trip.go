@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+)
+
+// Trip is an ordered itinerary of albums. Consecutive stops are joined by legs,
+// which are the paths between their albums.
+type Trip struct {
+	ID          string     `db:"id" json:"id"`
+	UserID      string     `db:"user_id" json:"user_id"`
+	Title       string     `db:"title" json:"title"`
+	Description string     `db:"description" json:"description"`
+	StartAt     *time.Time `db:"start_at" json:"start_at,omitempty"`
+	EndAt       *time.Time `db:"end_at" json:"end_at,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+	StopCount   int        `db:"stop_count" json:"stop_count"`
+	DistanceKm  float64    `db:"-" json:"distance_km,omitempty"`
+	Stops       []TripStop `db:"-" json:"stops,omitempty"`
+	Legs        []TripLeg  `db:"-" json:"legs,omitempty"`
+}
+
+// TripStop is an album visited at a position of a trip, counted from 0
+type TripStop struct {
+	Position int    `db:"position" json:"position"`
+	AlbumID  string `db:"album_id" json:"album_id"`
+	Album    *Album `db:"-" json:"album,omitempty"`
+}
+
+// TripLeg connects two consecutive stops of a trip
+type TripLeg struct {
+	FromPosition int     `json:"from_position"`
+	ToPosition   int     `json:"to_position"`
+	FromAlbumID  string  `json:"from_album_id"`
+	ToAlbumID    string  `json:"to_album_id"`
+	PathID       string  `json:"path_id,omitempty"` // empty when the path was deleted since
+	DistanceKm   float64 `json:"distance_km"`
+}
@@ -0,0 +1,248 @@
+// Package mvt encodes Mapbox Vector Tiles (version 2.1 of the specification,
+// https://github.com/mapbox/vector-tile-spec) without a protobuf dependency.
+package mvt
+
+import (
+	"math"
+	"sort"
+)
+
+// DefaultExtent is the number of integer units across a tile
+const DefaultExtent = 4096
+
+// Geometry types from the vector tile schema
+const (
+	geomTypePoint      = 1
+	geomTypeLineString = 2
+)
+
+// Geometry command IDs
+const (
+	commandMoveTo = 1
+	commandLineTo = 2
+)
+
+// Protobuf wire types
+const (
+	wireVarint = 0
+	wire64Bit  = 1
+	wireBytes  = 2
+)
+
+// Point is a position in tile coordinates, with the origin at the tile's top left corner
+type Point struct {
+	X, Y int
+}
+
+// Tile is a set of named layers
+type Tile struct {
+	Layers []*Layer
+}
+
+// Layer is a named set of features sharing one key and value table
+type Layer struct {
+	Name   string
+	Extent uint32
+
+	features   []feature
+	keys       []string
+	keyIndex   map[string]uint32
+	values     []interface{}
+	valueIndex map[interface{}]uint32
+}
+
+type feature struct {
+	geomType uint32
+	tags     []uint32
+	geometry []uint32
+}
+
+// NewLayer creates an empty layer
+func NewLayer(name string, extent uint32) *Layer {
+	return &Layer{
+		Name:       name,
+		Extent:     extent,
+		keyIndex:   make(map[string]uint32),
+		valueIndex: make(map[interface{}]uint32),
+	}
+}
+
+// Len returns the number of features in the layer
+func (l *Layer) Len() int {
+	return len(l.features)
+}
+
+// AddPoint adds a point feature. Property values may be strings, bools, integers or
+// floats; others are skipped.
+func (l *Layer) AddPoint(p Point, properties map[string]interface{}) {
+	l.features = append(l.features, feature{
+		geomType: geomTypePoint,
+		tags:     l.tags(properties),
+		geometry: []uint32{command(commandMoveTo, 1), zigzag(p.X), zigzag(p.Y)},
+	})
+}
+
+// AddLineString adds a line feature; lines with fewer than two points are ignored
+func (l *Layer) AddLineString(points []Point, properties map[string]interface{}) {
+	if len(points) < 2 {
+		return
+	}
+
+	geometry := []uint32{command(commandMoveTo, 1), zigzag(points[0].X), zigzag(points[0].Y),
+		command(commandLineTo, len(points)-1)}
+	for i := 1; i < len(points); i++ {
+		geometry = append(geometry, zigzag(points[i].X-points[i-1].X), zigzag(points[i].Y-points[i-1].Y))
+	}
+
+	l.features = append(l.features, feature{
+		geomType: geomTypeLineString,
+		tags:     l.tags(properties),
+		geometry: geometry,
+	})
+}
+
+// tags converts properties into key/value index pairs, adding new keys and values to
+// the layer's tables. Keys are sorted so that the encoding is deterministic.
+func (l *Layer) tags(properties map[string]interface{}) []uint32 {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var tags []uint32
+	for _, key := range keys {
+		value, ok := normalizeValue(properties[key])
+		if !ok {
+			continue
+		}
+
+		keyIndex, ok := l.keyIndex[key]
+		if !ok {
+			keyIndex = uint32(len(l.keys))
+			l.keys = append(l.keys, key)
+			l.keyIndex[key] = keyIndex
+		}
+		valueIndex, ok := l.valueIndex[value]
+		if !ok {
+			valueIndex = uint32(len(l.values))
+			l.values = append(l.values, value)
+			l.valueIndex[value] = valueIndex
+		}
+		tags = append(tags, keyIndex, valueIndex)
+	}
+	return tags
+}
+
+// normalizeValue maps a property value onto string, bool, int64 or float64
+func normalizeValue(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case string, bool, int64, float64:
+		return v, true
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case float32:
+		return float64(v), true
+	}
+	return nil, false
+}
+
+// Encode serializes the tile. Layers without features are left out.
+func (t *Tile) Encode() []byte {
+	var out []byte
+	for _, layer := range t.Layers {
+		if len(layer.features) == 0 {
+			continue
+		}
+		out = appendBytes(out, 3, layer.encode())
+	}
+	return out
+}
+
+func (l *Layer) encode() []byte {
+	out := appendVarintField(nil, 15, 2) // version
+	out = appendBytes(out, 1, []byte(l.Name))
+	for _, f := range l.features {
+		var encoded []byte
+		if len(f.tags) > 0 {
+			encoded = appendPacked(encoded, 2, f.tags)
+		}
+		encoded = appendVarintField(encoded, 3, uint64(f.geomType))
+		encoded = appendPacked(encoded, 4, f.geometry)
+		out = appendBytes(out, 2, encoded)
+	}
+	for _, key := range l.keys {
+		out = appendBytes(out, 3, []byte(key))
+	}
+	for _, value := range l.values {
+		out = appendBytes(out, 4, encodeValue(value))
+	}
+	return appendVarintField(out, 5, uint64(l.Extent))
+}
+
+// encodeValue encodes a Value message: strings as field 1, floats as doubles
+// (field 3), integers as sint64 (field 6) and bools as field 7
+func encodeValue(value interface{}) []byte {
+	switch v := value.(type) {
+	case string:
+		return appendBytes(nil, 1, []byte(v))
+	case float64:
+		out := appendKey(nil, 3, wire64Bit)
+		bits := math.Float64bits(v)
+		for i := 0; i < 8; i++ {
+			out = append(out, byte(bits>>(8*i)))
+		}
+		return out
+	case int64:
+		return appendVarintField(nil, 6, uint64((v<<1)^(v>>63)))
+	case bool:
+		var b uint64
+		if v {
+			b = 1
+		}
+		return appendVarintField(nil, 7, b)
+	}
+	return nil
+}
+
+func command(id, count int) uint32 {
+	return uint32(id&0x7) | uint32(count)<<3
+}
+
+func zigzag(n int) uint32 {
+	return uint32(int32(n)<<1) ^ uint32(int32(n)>>31)
+}
+
+func appendVarint(out []byte, v uint64) []byte {
+	for v >= 0x80 {
+		out = append(out, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(out, byte(v))
+}
+
+func appendKey(out []byte, field, wireType int) []byte {
+	return appendVarint(out, uint64(field<<3|wireType))
+}
+
+func appendVarintField(out []byte, field int, v uint64) []byte {
+	return appendVarint(appendKey(out, field, wireVarint), v)
+}
+
+func appendBytes(out []byte, field int, data []byte) []byte {
+	out = appendKey(out, field, wireBytes)
+	out = appendVarint(out, uint64(len(data)))
+	return append(out, data...)
+}
+
+func appendPacked(out []byte, field int, values []uint32) []byte {
+	var packed []byte
+	for _, v := range values {
+		packed = appendVarint(packed, uint64(v))
+	}
+	return appendBytes(out, field, packed)
+}
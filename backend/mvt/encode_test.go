@@ -0,0 +1,181 @@
+package mvt
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// field is a decoded protobuf field; varints and fixed64 values are in value and
+// length-delimited ones in data
+type field struct {
+	number int
+	value  uint64
+	data   []byte
+}
+
+// decodeMessage splits a protobuf message into its fields
+func decodeMessage(t *testing.T, buf []byte) []field {
+	t.Helper()
+	var fields []field
+	for len(buf) > 0 {
+		key, n := binary.Uvarint(buf)
+		require.Greater(t, n, 0, "truncated key")
+		buf = buf[n:]
+		f := field{number: int(key >> 3)}
+		switch key & 0x7 {
+		case wireVarint:
+			f.value, n = binary.Uvarint(buf)
+			require.Greater(t, n, 0, "truncated varint")
+			buf = buf[n:]
+		case wire64Bit:
+			require.GreaterOrEqual(t, len(buf), 8, "truncated fixed64")
+			f.value = binary.LittleEndian.Uint64(buf)
+			buf = buf[8:]
+		case wireBytes:
+			length, n := binary.Uvarint(buf)
+			require.Greater(t, n, 0, "truncated length")
+			buf = buf[n:]
+			require.GreaterOrEqual(t, uint64(len(buf)), length, "truncated bytes")
+			f.data, buf = buf[:length], buf[length:]
+		default:
+			t.Fatalf("unexpected wire type %d", key&0x7)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func decodePacked(t *testing.T, data []byte) []uint32 {
+	t.Helper()
+	var values []uint32
+	for len(data) > 0 {
+		v, n := binary.Uvarint(data)
+		require.Greater(t, n, 0, "truncated packed varint")
+		values = append(values, uint32(v))
+		data = data[n:]
+	}
+	return values
+}
+
+func TestZigzag(t *testing.T) {
+	for n, want := range map[int]uint32{0: 0, -1: 1, 1: 2, -2: 3, 2: 4, 25: 50, -4096: 8191, math.MaxInt32: math.MaxUint32 - 1, math.MinInt32: math.MaxUint32} {
+		assert.Equal(t, want, zigzag(n), "zigzag(%d)", n)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	// Examples from section 4.3.1 of the specification
+	assert.Equal(t, uint32(9), command(commandMoveTo, 1))
+	assert.Equal(t, uint32(26), command(commandLineTo, 3))
+	assert.Equal(t, uint32(15), command(7, 1)) // ClosePath
+	assert.Equal(t, uint32(8*120+2), command(commandLineTo, 120))
+}
+
+func TestGeometryEncoding(t *testing.T) {
+	// Examples from section 4.3.5 of the specification
+	layer := NewLayer("test", DefaultExtent)
+	layer.AddPoint(Point{25, 17}, nil)
+	layer.AddLineString([]Point{{2, 2}, {2, 10}, {10, 10}}, nil)
+	layer.AddLineString([]Point{{5, 5}}, nil)
+
+	require.Equal(t, 2, layer.Len(), "single-point lines are dropped")
+	assert.Equal(t, []uint32{9, 50, 34}, layer.features[0].geometry)
+	assert.Equal(t, []uint32{9, 4, 4, 18, 0, 16, 16, 0}, layer.features[1].geometry)
+}
+
+func TestTileRoundTrip(t *testing.T) {
+	albums := NewLayer("albums", DefaultExtent)
+	albums.AddPoint(Point{100, 200}, map[string]interface{}{"title": "Kyoto", "photos": 12, "rating": 4.5, "cover": true, "skipped": []int{1}})
+	albums.AddPoint(Point{-3, 4100}, map[string]interface{}{"title": "Kyoto", "photos": int64(-7)})
+	empty := NewLayer("empty", DefaultExtent)
+	paths := NewLayer("paths", 512)
+	paths.AddLineString([]Point{{0, 0}, {10, -5}}, map[string]interface{}{"mode": "train"})
+
+	encoded := (&Tile{Layers: []*Layer{albums, empty, paths}}).Encode()
+	tile := decodeMessage(t, encoded)
+	require.Len(t, tile, 2, "empty layers are left out")
+
+	type decodedFeature struct {
+		tags     []uint32
+		geomType uint64
+		geometry []uint32
+	}
+	type decodedLayer struct {
+		version  uint64
+		name     string
+		extent   uint64
+		keys     []string
+		values   []interface{}
+		features []decodedFeature
+	}
+	var layers []decodedLayer
+	for _, f := range tile {
+		require.Equal(t, 3, f.number)
+		var layer decodedLayer
+		for _, lf := range decodeMessage(t, f.data) {
+			switch lf.number {
+			case 15:
+				layer.version = lf.value
+			case 1:
+				layer.name = string(lf.data)
+			case 5:
+				layer.extent = lf.value
+			case 3:
+				layer.keys = append(layer.keys, string(lf.data))
+			case 4:
+				value := decodeMessage(t, lf.data)
+				require.Len(t, value, 1)
+				switch value[0].number {
+				case 1:
+					layer.values = append(layer.values, string(value[0].data))
+				case 3:
+					layer.values = append(layer.values, math.Float64frombits(value[0].value))
+				case 6:
+					v := value[0].value
+					layer.values = append(layer.values, int64(v>>1)^-int64(v&1))
+				case 7:
+					layer.values = append(layer.values, value[0].value == 1)
+				default:
+					t.Fatalf("unexpected value field %d", value[0].number)
+				}
+			case 2:
+				var feature decodedFeature
+				for _, ff := range decodeMessage(t, lf.data) {
+					switch ff.number {
+					case 2:
+						feature.tags = decodePacked(t, ff.data)
+					case 3:
+						feature.geomType = ff.value
+					case 4:
+						feature.geometry = decodePacked(t, ff.data)
+					}
+				}
+				layer.features = append(layer.features, feature)
+			}
+		}
+		layers = append(layers, layer)
+	}
+
+	require.Len(t, layers, 2)
+	assert.Equal(t, uint64(2), layers[0].version)
+	assert.Equal(t, "albums", layers[0].name)
+	assert.Equal(t, uint64(DefaultExtent), layers[0].extent)
+	// Keys are sorted and shared values are stored once
+	assert.Equal(t, []string{"cover", "photos", "rating", "title"}, layers[0].keys)
+	assert.Equal(t, []interface{}{true, int64(12), 4.5, "Kyoto", int64(-7)}, layers[0].values)
+	require.Len(t, layers[0].features, 2)
+	assert.Equal(t, []uint32{0, 0, 1, 1, 2, 2, 3, 3}, layers[0].features[0].tags)
+	assert.Equal(t, []uint32{1, 4, 3, 3}, layers[0].features[1].tags)
+	assert.Equal(t, uint64(geomTypePoint), layers[0].features[0].geomType)
+	assert.Equal(t, []uint32{9, 200, 400}, layers[0].features[0].geometry)
+	assert.Equal(t, []uint32{9, 5, 8200}, layers[0].features[1].geometry)
+
+	assert.Equal(t, "paths", layers[1].name)
+	assert.Equal(t, uint64(512), layers[1].extent)
+	assert.Equal(t, uint64(geomTypeLineString), layers[1].features[0].geomType)
+	assert.Equal(t, []uint32{9, 0, 0, 10, 20, 9}, layers[1].features[0].geometry)
+}
@@ -0,0 +1,97 @@
+package mvt
+
+import "math"
+
+// maxLatitude is the latitude limit of the Web Mercator projection
+const maxLatitude = 85.05112878
+
+// TileCount returns the number of tiles along each axis at zoom z
+func TileCount(z int) int {
+	return 1 << uint(z)
+}
+
+// worldCoordinates projects a point onto the unit square, x growing east and y south
+func worldCoordinates(lat, lng float64) (float64, float64) {
+	lat = math.Max(-maxLatitude, math.Min(maxLatitude, lat))
+	sinLat := math.Sin(lat * math.Pi / 180)
+	x := (lng + 180) / 360
+	y := 0.5 - math.Log((1+sinLat)/(1-sinLat))/(4*math.Pi)
+	return x, y
+}
+
+// TileProjection maps geographic coordinates into the coordinate space of one tile
+type TileProjection struct {
+	Z, X, Y int
+	Extent  int
+}
+
+// Project returns a point's position in tile coordinates as floats. Points outside
+// the tile fall outside [0, Extent].
+func (p TileProjection) Project(lat, lng float64) (float64, float64) {
+	wx, wy := worldCoordinates(lat, lng)
+	scale := float64(TileCount(p.Z))
+	extent := float64(p.Extent)
+	return (wx*scale - float64(p.X)) * extent, (wy*scale - float64(p.Y)) * extent
+}
+
+// Bounds returns the geographic bounds of the tile grown by buffer tile units on
+// every side. Longitudes are clamped to [-180, 180] rather than wrapped.
+func (p TileProjection) Bounds(buffer int) (minLat, minLng, maxLat, maxLng float64) {
+	scale := float64(TileCount(p.Z))
+	pad := float64(buffer) / float64(p.Extent)
+
+	west := (float64(p.X) - pad) / scale
+	east := (float64(p.X) + 1 + pad) / scale
+	north := (float64(p.Y) - pad) / scale
+	south := (float64(p.Y) + 1 + pad) / scale
+
+	minLng = math.Max(-180, west*360-180)
+	maxLng = math.Min(180, east*360-180)
+	maxLat = worldLatitude(math.Max(0, north))
+	minLat = worldLatitude(math.Min(1, south))
+	return minLat, minLng, maxLat, maxLng
+}
+
+// worldLatitude inverts the y of worldCoordinates
+func worldLatitude(y float64) float64 {
+	return math.Atan(math.Sinh(math.Pi*(1-2*y))) * 180 / math.Pi
+}
+
+// ClipSegment clips the segment a–b to the square [min, max]², returning false when
+// it lies entirely outside (Liang–Barsky)
+func ClipSegment(ax, ay, bx, by, min, max float64) (float64, float64, float64, float64, bool) {
+	t0, t1 := 0.0, 1.0
+	dx, dy := bx-ax, by-ay
+	edges := [4][2]float64{
+		{-dx, ax - min},
+		{dx, max - ax},
+		{-dy, ay - min},
+		{dy, max - ay},
+	}
+	for _, edge := range edges {
+		p, q := edge[0], edge[1]
+		if p == 0 {
+			if q < 0 {
+				return 0, 0, 0, 0, false
+			}
+			continue
+		}
+		t := q / p
+		if p < 0 {
+			if t > t1 {
+				return 0, 0, 0, 0, false
+			}
+			if t > t0 {
+				t0 = t
+			}
+		} else {
+			if t < t0 {
+				return 0, 0, 0, 0, false
+			}
+			if t < t1 {
+				t1 = t
+			}
+		}
+	}
+	return ax + t0*dx, ay + t0*dy, ax + t1*dx, ay + t1*dy, true
+}
@@ -0,0 +1,104 @@
+package mvt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjectAtZoomZero(t *testing.T) {
+	p := TileProjection{Z: 0, X: 0, Y: 0, Extent: DefaultExtent}
+
+	x, y := p.Project(0, 0)
+	assert.InDelta(t, 2048, x, 1e-9)
+	assert.InDelta(t, 2048, y, 1e-9)
+
+	x, y = p.Project(maxLatitude, -180)
+	assert.InDelta(t, 0, x, 1e-9)
+	assert.InDelta(t, 0, y, 1e-3)
+
+	// Latitudes beyond the projection's limit are clamped to the tile edge
+	x, y = p.Project(-90, 180)
+	assert.InDelta(t, 4096, x, 1e-9)
+	assert.InDelta(t, 4096, y, 1e-3)
+
+	minLat, minLng, maxLat, maxLng := p.Bounds(0)
+	assert.InDelta(t, -maxLatitude, minLat, 1e-6)
+	assert.Equal(t, -180.0, minLng)
+	assert.InDelta(t, maxLatitude, maxLat, 1e-6)
+	assert.Equal(t, 180.0, maxLng)
+
+	// Buffers never reach past the edges of the world
+	minLat, minLng, maxLat, maxLng = p.Bounds(256)
+	assert.InDelta(t, -maxLatitude, minLat, 1e-6)
+	assert.Equal(t, -180.0, minLng)
+	assert.InDelta(t, maxLatitude, maxLat, 1e-6)
+	assert.Equal(t, 180.0, maxLng)
+}
+
+func TestProjectAtHighZoom(t *testing.T) {
+	// The z18 tile holding Kyoto Station
+	lat, lng := 34.98580, 135.75880
+	z := 18
+	wx, wy := worldCoordinates(lat, lng)
+	p := TileProjection{Z: z, X: int(wx * float64(TileCount(z))), Y: int(wy * float64(TileCount(z))), Extent: DefaultExtent}
+	assert.Equal(t, 229928, p.X)
+	assert.Equal(t, 103847, p.Y)
+
+	x, y := p.Project(lat, lng)
+	assert.True(t, x >= 0 && x <= DefaultExtent, "x %v inside the tile", x)
+	assert.True(t, y >= 0 && y <= DefaultExtent, "y %v inside the tile", y)
+
+	minLat, minLng, maxLat, maxLng := p.Bounds(0)
+	assert.True(t, minLat < lat && lat < maxLat)
+	assert.True(t, minLng < lng && lng < maxLng)
+	// A z18 tile is 360/2^18 degrees wide
+	assert.InDelta(t, 360.0/float64(TileCount(z)), maxLng-minLng, 1e-9)
+
+	// The tile's corners project onto the corners of the extent
+	x, y = p.Project(maxLat, minLng)
+	assert.InDelta(t, 0, x, 1e-6)
+	assert.InDelta(t, 0, y, 1e-6)
+	x, y = p.Project(minLat, maxLng)
+	assert.InDelta(t, DefaultExtent, x, 1e-6)
+	assert.InDelta(t, DefaultExtent, y, 1e-6)
+
+	// A buffer grows the bounds by the same fraction of the tile on every side
+	bMinLat, bMinLng, bMaxLat, bMaxLng := p.Bounds(DefaultExtent / 16)
+	width := maxLng - minLng
+	assert.InDelta(t, width/16, minLng-bMinLng, 1e-9)
+	assert.InDelta(t, width/16, bMaxLng-maxLng, 1e-9)
+	assert.Greater(t, bMaxLat, maxLat)
+	assert.Less(t, bMinLat, minLat)
+}
+
+func TestClipSegment(t *testing.T) {
+	tests := []struct {
+		name           string
+		ax, ay, bx, by float64
+		want           [4]float64
+		inside         bool
+	}{
+		{"inside", 10, 10, 20, 30, [4]float64{10, 10, 20, 30}, true},
+		{"crossing the left edge", -10, 50, 10, 50, [4]float64{0, 50, 10, 50}, true},
+		{"crossing the right edge", 50, 50, 150, 50, [4]float64{50, 50, 100, 50}, true},
+		{"crossing the top and bottom edges", 50, -50, 50, 150, [4]float64{50, 0, 50, 100}, true},
+		{"through a corner", -10, -10, 110, 110, [4]float64{0, 0, 100, 100}, true},
+		{"along an edge", 0, -20, 0, 120, [4]float64{0, 0, 0, 100}, true},
+		{"touching a corner", -10, 10, 10, -10, [4]float64{0, 0, 0, 0}, true},
+		{"outside to one side", 110, 10, 120, 90, [4]float64{}, false},
+		{"outside past a corner", -10, 5, 5, -10, [4]float64{}, false},
+		{"parallel outside", -5, 0, -5, 100, [4]float64{}, false},
+		{"a point inside", 40, 40, 40, 40, [4]float64{40, 40, 40, 40}, true},
+		{"a point outside", 140, 40, 140, 40, [4]float64{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ax, ay, bx, by, ok := ClipSegment(tt.ax, tt.ay, tt.bx, tt.by, 0, 100)
+			assert.Equal(t, tt.inside, ok)
+			if ok {
+				assert.InDeltaSlice(t, tt.want[:], []float64{ax, ay, bx, by}, 1e-9)
+			}
+		})
+	}
+}
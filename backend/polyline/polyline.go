@@ -0,0 +1,267 @@
+// Package polyline handles path geometry: the Encoded Polyline Algorithm Format
+// (https://developers.google.com/maps/documentation/utilities/polylinealgorithm) used
+// on the wire, Douglas–Peucker simplification for a zoom level and great-circle
+// interpolation.
+package polyline
+
+import (
+	"fmt"
+	"math"
+
+	"geoalbum/backend/geocode"
+)
+
+// precision is the coordinate scale of encoded polylines (five decimal places)
+const precision = 1e5
+
+// Point is a position in degrees
+type Point struct {
+	Lat, Lng float64
+}
+
+// Encode encodes points as a polyline string
+func Encode(points []Point) string {
+	var buf []byte
+	var prevLat, prevLng int64
+	for _, p := range points {
+		lat := int64(math.Round(p.Lat * precision))
+		lng := int64(math.Round(p.Lng * precision))
+		buf = appendValue(buf, lat-prevLat)
+		buf = appendValue(buf, lng-prevLng)
+		prevLat, prevLng = lat, lng
+	}
+	return string(buf)
+}
+
+// appendValue appends one signed delta in 5-bit chunks, lowest first
+func appendValue(buf []byte, value int64) []byte {
+	u := uint64(value) << 1
+	if value < 0 {
+		u = ^u
+	}
+	for u >= 0x20 {
+		buf = append(buf, byte(0x20|u&0x1f)+63)
+		u >>= 5
+	}
+	return append(buf, byte(u)+63)
+}
+
+// Decode decodes a polyline string
+func Decode(encoded string) ([]Point, error) {
+	var points []Point
+	var lat, lng int64
+	for i := 0; i < len(encoded); {
+		dLat, next, err := readValue(encoded, i)
+		if err != nil {
+			return nil, err
+		}
+		dLng, next, err := readValue(encoded, next)
+		if err != nil {
+			return nil, err
+		}
+		i = next
+		lat += dLat
+		lng += dLng
+		points = append(points, Point{Lat: float64(lat) / precision, Lng: float64(lng) / precision})
+	}
+	return points, nil
+}
+
+// readValue reads the delta starting at offset i and returns it with the next offset
+func readValue(encoded string, i int) (int64, int, error) {
+	var u uint64
+	for shift := uint(0); ; shift += 5 {
+		if i >= len(encoded) {
+			return 0, 0, fmt.Errorf("invalid polyline: truncated at offset %d", i)
+		}
+		if shift > 60 {
+			return 0, 0, fmt.Errorf("invalid polyline: value too long at offset %d", i)
+		}
+		c := encoded[i]
+		if c < 63 || c > 127 {
+			return 0, 0, fmt.Errorf("invalid polyline: unexpected character %q at offset %d", c, i)
+		}
+		i++
+		chunk := uint64(c - 63)
+		u |= (chunk & 0x1f) << shift
+		if chunk < 0x20 {
+			break
+		}
+	}
+	value := int64(u >> 1)
+	if u&1 != 0 {
+		value = ^value
+	}
+	return value, i, nil
+}
+
+// Unwrap shifts longitudes by whole turns so that no step between consecutive points
+// exceeds 180°; a line crossing the antimeridian then continues past ±180 instead of
+// jumping across the map.
+func Unwrap(points []Point) []Point {
+	for i := 1; i < len(points); i++ {
+		for points[i].Lng-points[i-1].Lng > 180 {
+			points[i].Lng -= 360
+		}
+		for points[i].Lng-points[i-1].Lng < -180 {
+			points[i].Lng += 360
+		}
+	}
+	return points
+}
+
+// LengthKm returns the great-circle length of a line in kilometres
+func LengthKm(points []Point) float64 {
+	var length float64
+	for i := 1; i < len(points); i++ {
+		length += geocode.DistanceKm(points[i-1].Lat, points[i-1].Lng, points[i].Lat, points[i].Lng)
+	}
+	return length
+}
+
+// maxGreatCircleSegments bounds the number of segments GreatCircle produces
+const maxGreatCircleSegments = 256
+
+// GreatCircle interpolates the shortest great-circle arc from a to b with segments of
+// at most segmentKm. Longitudes are unwrapped so the line stays continuous.
+func GreatCircle(a, b Point, segmentKm float64) []Point {
+	distance := geocode.DistanceKm(a.Lat, a.Lng, b.Lat, b.Lng)
+	segments := int(math.Ceil(distance / segmentKm))
+	if segments < 1 {
+		segments = 1
+	}
+	if segments > maxGreatCircleSegments {
+		segments = maxGreatCircleSegments
+	}
+
+	ax, ay, az := toVector(a)
+	bx, by, bz := toVector(b)
+	angle := math.Acos(math.Max(-1, math.Min(1, ax*bx+ay*by+az*bz)))
+	if angle < 1e-12 || math.Pi-angle < 1e-12 {
+		// Coincident or antipodal ends have no single arc between them
+		return Unwrap([]Point{a, b})
+	}
+	points := make([]Point, 0, segments+1)
+	points = append(points, a)
+	for i := 1; i < segments; i++ {
+		t := float64(i) / float64(segments)
+		// Spherical linear interpolation between the two unit vectors
+		wa := math.Sin((1-t)*angle) / math.Sin(angle)
+		wb := math.Sin(t*angle) / math.Sin(angle)
+		points = append(points, fromVector(wa*ax+wb*bx, wa*ay+wb*by, wa*az+wb*bz))
+	}
+	points = append(points, b)
+	return Unwrap(points)
+}
+
+func toVector(p Point) (float64, float64, float64) {
+	lat := p.Lat * math.Pi / 180
+	lng := p.Lng * math.Pi / 180
+	return math.Cos(lat) * math.Cos(lng), math.Cos(lat) * math.Sin(lng), math.Sin(lat)
+}
+
+func fromVector(x, y, z float64) Point {
+	return Point{
+		Lat: math.Atan2(z, math.Hypot(x, y)) * 180 / math.Pi,
+		Lng: math.Atan2(y, x) * 180 / math.Pi,
+	}
+}
+
+// maxMercatorLatitude is the latitude limit of the Web Mercator projection
+const maxMercatorLatitude = 85.05112878
+
+// Simplify drops the points of a line that lie within one pixel of the simplified
+// line when drawn on 256-pixel Web Mercator tiles at zoom (Douglas–Peucker). The
+// first and last points are always kept.
+func Simplify(points []Point, zoom int) []Point {
+	if len(points) <= 2 {
+		return points
+	}
+	tolerance := 1 / (256 * math.Exp2(float64(zoom)))
+
+	xs := make([]float64, len(points))
+	ys := make([]float64, len(points))
+	for i, p := range points {
+		lat := math.Max(-maxMercatorLatitude, math.Min(maxMercatorLatitude, p.Lat))
+		sinLat := math.Sin(lat * math.Pi / 180)
+		xs[i] = (p.Lng + 180) / 360
+		ys[i] = 0.5 - math.Log((1+sinLat)/(1-sinLat))/(4*math.Pi)
+	}
+
+	keep := make([]bool, len(points))
+	keep[0], keep[len(points)-1] = true, true
+	stack := [][2]int{{0, len(points) - 1}}
+	for len(stack) > 0 {
+		first, last := stack[len(stack)-1][0], stack[len(stack)-1][1]
+		stack = stack[:len(stack)-1]
+
+		farthest, maxDistance := -1, tolerance
+		for i := first + 1; i < last; i++ {
+			d := segmentDistance(xs[i], ys[i], xs[first], ys[first], xs[last], ys[last])
+			if d > maxDistance {
+				farthest, maxDistance = i, d
+			}
+		}
+		if farthest >= 0 {
+			keep[farthest] = true
+			stack = append(stack, [2]int{first, farthest}, [2]int{farthest, last})
+		}
+	}
+
+	simplified := make([]Point, 0, len(points))
+	for i, p := range points {
+		if keep[i] {
+			simplified = append(simplified, p)
+		}
+	}
+	return simplified
+}
+
+// segmentDistance returns the distance from (px, py) to the segment a–b
+func segmentDistance(px, py, ax, ay, bx, by float64) float64 {
+	dx, dy := bx-ax, by-ay
+	if dx == 0 && dy == 0 {
+		return math.Hypot(px-ax, py-ay)
+	}
+	t := ((px-ax)*dx + (py-ay)*dy) / (dx*dx + dy*dy)
+	t = math.Max(0, math.Min(1, t))
+	return math.Hypot(px-(ax+t*dx), py-(ay+t*dy))
+}
+
+// SplitAntimeridian wraps the longitudes of a line into [-180, 180], splitting it
+// where it crosses the antimeridian, for formats that cannot carry unwrapped lines
+func SplitAntimeridian(points []Point) [][]Point {
+	if len(points) == 0 {
+		return nil
+	}
+	wrap := func(lng float64) float64 {
+		return lng - 360*math.Floor((lng+180)/360)
+	}
+	parts := [][]Point{{{Lat: points[0].Lat, Lng: wrap(points[0].Lng)}}}
+	for i := 1; i < len(points); i++ {
+		prev, p := points[i-1], points[i]
+		// Unwrap p next to prev, then cut where the step crosses ±180
+		lng := p.Lng
+		for lng-prev.Lng > 180 {
+			lng -= 360
+		}
+		for lng-prev.Lng < -180 {
+			lng += 360
+		}
+		shift := wrap(prev.Lng) - prev.Lng
+		from, to := prev.Lng+shift, lng+shift
+		if to > 180 || to < -180 {
+			edge := 180.0
+			if to < -180 {
+				edge = -180
+			}
+			f := (edge - from) / (to - from)
+			lat := prev.Lat + f*(p.Lat-prev.Lat)
+			last := &parts[len(parts)-1]
+			*last = append(*last, Point{Lat: lat, Lng: edge})
+			parts = append(parts, []Point{{Lat: lat, Lng: -edge}})
+		}
+		parts[len(parts)-1] = append(parts[len(parts)-1], Point{Lat: p.Lat, Lng: wrap(to)})
+	}
+	return parts
+}
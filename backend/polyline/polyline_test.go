@@ -0,0 +1,108 @@
+package polyline
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	// Example from the algorithm's documentation
+	points := []Point{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}}
+	encoded := Encode(points)
+	assert.Equal(t, "_p~iF~ps|U_ulLnnqC_mqNvxq`@", encoded)
+
+	decoded, err := Decode(encoded)
+	require.NoError(t, err)
+	require.Len(t, decoded, len(points))
+	for i := range points {
+		assert.InDelta(t, points[i].Lat, decoded[i].Lat, 1e-9)
+		assert.InDelta(t, points[i].Lng, decoded[i].Lng, 1e-9)
+	}
+
+	empty, err := Decode("")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	for _, encoded := range []string{"_p~iF", "_p~iF~ps|", "ab\ncd", "~~~~~~~~~~~~~~~~"} {
+		_, err := Decode(encoded)
+		assert.Error(t, err, encoded)
+	}
+}
+
+func TestUnwrap(t *testing.T) {
+	points := Unwrap([]Point{{0, 170}, {0, 179}, {0, -179}, {0, -170}})
+	assert.Equal(t, []Point{{0, 170}, {0, 179}, {0, 181}, {0, 190}}, points)
+}
+
+func TestGreatCircle(t *testing.T) {
+	london := Point{51.47, -0.45}
+	tokyo := Point{35.55, 139.78}
+	arc := GreatCircle(london, tokyo, 100)
+
+	require.Greater(t, len(arc), 90)
+	assert.Equal(t, london, arc[0])
+	assert.Equal(t, tokyo, arc[len(arc)-1])
+	// The arc follows the shortest route over Siberia and is no longer than it
+	highest := 0.0
+	for _, p := range arc {
+		highest = math.Max(highest, p.Lat)
+	}
+	assert.Greater(t, highest, 60.0)
+	direct := LengthKm([]Point{london, tokyo})
+	assert.InDelta(t, direct, LengthKm(arc), direct*1e-6)
+
+	// Pacific crossings continue past the antimeridian
+	pacific := GreatCircle(Point{35.55, 139.78}, Point{37.62, -122.38}, 200)
+	for i := 1; i < len(pacific); i++ {
+		assert.Less(t, math.Abs(pacific[i].Lng-pacific[i-1].Lng), 180.0)
+	}
+	assert.InDelta(t, 237.62, pacific[len(pacific)-1].Lng, 1e-9)
+
+	same := GreatCircle(tokyo, tokyo, 100)
+	assert.Equal(t, []Point{tokyo, tokyo}, same)
+	antipodal := GreatCircle(Point{0, 0}, Point{0, 180}, 100)
+	assert.Equal(t, []Point{{0, 0}, {0, 180}}, antipodal)
+}
+
+func TestSimplify(t *testing.T) {
+	// A zigzag of ~100 m teeth collapses at low zoom and survives at high zoom
+	var line []Point
+	for i := 0; i <= 100; i++ {
+		lat := 0.0
+		if i%2 == 1 {
+			lat = 0.001
+		}
+		line = append(line, Point{lat, float64(i) * 0.001})
+	}
+
+	low := Simplify(line, 5)
+	assert.Equal(t, []Point{line[0], line[len(line)-1]}, low)
+	assert.Len(t, Simplify(line, 18), len(line))
+
+	// Points along a meridian are dropped at any zoom
+	straight := []Point{{0, 10}, {1, 10}, {2, 10}, {3, 10}}
+	assert.Equal(t, []Point{{0, 10}, {3, 10}}, Simplify(straight, 20))
+	assert.Equal(t, straight[:2], Simplify(straight[:2], 0))
+}
+
+func TestSplitAntimeridian(t *testing.T) {
+	parts := SplitAntimeridian([]Point{{0, 170}, {10, 190}, {20, 200}})
+	require.Len(t, parts, 2)
+	assert.Equal(t, []Point{{0, 170}, {5, 180}}, parts[0])
+	assert.Equal(t, []Point{{5, -180}, {10, -170}, {20, -160}}, parts[1])
+
+	// Wrapped input crossing westwards is split the same way
+	parts = SplitAntimeridian([]Point{{0, -170}, {10, 170}})
+	require.Len(t, parts, 2)
+	assert.Equal(t, []Point{{0, -170}, {5, -180}}, parts[0])
+	assert.Equal(t, []Point{{5, 180}, {10, 170}}, parts[1])
+
+	line := []Point{{0, 0}, {1, 1}}
+	assert.Equal(t, [][]Point{line}, SplitAntimeridian(line))
+	assert.Nil(t, SplitAntimeridian(nil))
+}
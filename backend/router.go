@@ -1,13 +1,18 @@
 package backend
 
 import (
+	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"geoalbum/backend/basemap"
 	"geoalbum/backend/controller"
 	"geoalbum/backend/database"
+	"geoalbum/backend/logging"
 	"geoalbum/backend/middleware"
+	"geoalbum/backend/service"
+	"geoalbum/backend/tileproxy"
 )
 
 // Register registers all backend routes and initializes the database
@@ -17,27 +22,70 @@ func Register(r *gin.Engine) {
 		panic("Failed to initialize database: " + err.Error())
 	}
 
+	// Load the offline gazetteer in the background, as a new dataset takes a while to
+	// load and resolve albums against; albums simply stay unresolved if it is unavailable
+	go func() {
+		if err := service.NewGeocodeService().EnsureGazetteer(); err != nil {
+			logging.WithError(err).Error("Failed to load gazetteer")
+		}
+	}()
+
+	// Open offline basemaps; the map falls back to online tiles without them
+	if err := basemap.Initialize(os.Getenv("BASEMAP_DIR")); err != nil {
+		logging.WithError(err).Error("Failed to load offline basemaps")
+	}
+	// Enable the caching tile proxy when providers are configured
+	if err := tileproxy.Initialize(); err != nil {
+		logging.WithError(err).Error("Failed to start tile proxy")
+	}
+
+	csp := middleware.DefaultContentSecurityPolicy()
+	if len(basemap.All()) > 0 {
+		csp = csp.WithOfflineBasemaps(basemap.HasVector(), os.Getenv("BASEMAP_OFFLINE") == "true")
+	}
+
 	// Start rate limiter cleanup routine
 	middleware.CleanupRateLimiters()
 
 	// Add security middleware
-	r.Use(middleware.SecurityHeadersMiddleware())
-	r.Use(middleware.RequestSizeMiddleware(10 << 20)) // 10MB max request size
+	r.Use(middleware.SecurityHeadersMiddleware(csp))
+	// 10MB max request size, except for uploaded Google Takeout exports and account archives
+	r.Use(middleware.RouteRequestSizeMiddleware(10<<20, map[string]int64{
+		"/api/import/takeout": controller.MaxTakeoutUploadSize,
+		"/api/me/import":      controller.MaxAccountUploadSize,
+	}))
 	r.Use(middleware.CORSMiddleware())
 	r.Use(middleware.RequestIDMiddleware())
 	r.Use(middleware.LoggerMiddleware())
 	r.Use(middleware.SecurityValidationMiddleware())
 	
-	// Add rate limiting (100 requests per minute per IP)
-	r.Use(middleware.RateLimitMiddleware(100, 1*time.Minute))
+	// Add rate limiting (100 requests per minute per IP); map tiles are counted apart
+	r.Use(middleware.RouteRateLimitMiddleware(100, 1*time.Minute, map[string]int{
+		"/api/tiles/albums/:z/:x/:y":        controller.TileRequestsPerMinute,
+		"/api/basemap/:name/:z/:x/:y":       controller.TileRequestsPerMinute,
+		"/api/tileproxy/:provider/:z/:x/:y": controller.TileRequestsPerMinute,
+	}))
 
 	// Initialize controllers
 	authController := controller.NewAuthController()
 	albumController := controller.NewAlbumController()
 	photoController := controller.NewPhotoController()
 	pathController := controller.NewPathController()
+	tripController := controller.NewTripController()
+	importController := controller.NewImportController()
+	exportController := controller.NewExportController()
 	securityController := controller.NewSecurityController()
 	healthController := controller.NewHealthController()
+	tagController := controller.NewTagController()
+	savedPlaceController := controller.NewSavedPlaceController()
+	geocodeController := controller.NewGeocodeController()
+	statsController := controller.NewStatsController()
+	timelineController := controller.NewTimelineController()
+	heatmapController := controller.NewHeatmapController()
+	tileController := controller.NewTileController()
+	basemapController := controller.NewBasemapController()
+	tileProxyController := controller.NewTileProxyController()
+	accountController := controller.NewAccountController()
 
 	// API routes
 	api := r.Group("/api")
@@ -61,6 +109,30 @@ func Register(r *gin.Engine) {
 			photoFiles.GET("/:id/file", photoController.ServePhotoFile)
 		}
 
+		// Vector tile routes (support query token for map libraries)
+		tiles := api.Group("/tiles")
+		tiles.Use(middleware.AuthMiddlewareWithQueryToken())
+		{
+			tiles.GET("/albums/:z/:x/:y", tileController.GetAlbumTile)
+		}
+
+		// Offline basemap tiles (support query token for map libraries)
+		basemapTiles := api.Group("/basemap")
+		basemapTiles.Use(middleware.AuthMiddlewareWithQueryToken())
+		{
+			basemapTiles.GET("/:name/:z/:x/:y", basemapController.GetBasemapTile)
+		}
+
+		// Proxied remote basemap tiles (support query token for map libraries)
+		proxiedTiles := api.Group("/tileproxy")
+		proxiedTiles.Use(middleware.AuthMiddlewareWithQueryToken())
+		{
+			proxiedTiles.GET("/:provider/:z/:x/:y", tileProxyController.GetTile)
+		}
+
+		// Account archive downloads (signed, expiring links)
+		api.GET("/me/export/:id/download", accountController.DownloadExport)
+
 		// Protected routes (auth required)
 		protected := api.Group("")
 		protected.Use(middleware.AuthMiddleware())
@@ -72,20 +144,50 @@ func Register(r *gin.Engine) {
 				albums.GET("", albumController.GetAlbums)
 				albums.GET("/:id", albumController.GetAlbum)
 				albums.PUT("/:id", albumController.UpdateAlbum)
+				albums.PATCH("/:id", albumController.UpdateAlbum)
 				albums.DELETE("/:id", albumController.DeleteAlbum)
 				
 				// Photo routes for albums
 				albums.POST("/:id/photos", photoController.UploadPhoto)
 				albums.POST("/:id/photos/multiple", photoController.UploadMultiplePhotos)
 				albums.GET("/:id/photos", photoController.GetAlbumPhotos)
+
+				// Tag routes for albums
+				albums.POST("/:id/tags", tagController.AttachAlbumTags)
+				albums.DELETE("/:id/tags/:tagId", tagController.DetachAlbumTag)
 			}
 
 			// Photo routes
 			photos := protected.Group("/photos")
 			{
+				photos.GET("", photoController.GetPhotos)
+				photos.PATCH("", photoController.UpdatePhotoRatings)
 				photos.GET("/:id", photoController.GetPhoto)
 				photos.DELETE("/:id", photoController.DeletePhoto)
 				photos.PUT("/:id/order", photoController.UpdatePhotoOrder)
+				photos.POST("/:id/tags", tagController.AttachPhotoTags)
+				photos.DELETE("/:id/tags/:tagId", tagController.DetachPhotoTag)
+			}
+
+			// Tag routes
+			tags := protected.Group("/tags")
+			{
+				tags.GET("", tagController.GetTags)
+				tags.POST("", tagController.CreateTag)
+				tags.PUT("/:id", tagController.RenameTag)
+				tags.POST("/:id/merge", tagController.MergeTags)
+				tags.DELETE("/:id", tagController.DeleteTag)
+			}
+
+			// Saved place routes
+			places := protected.Group("/places")
+			{
+				places.GET("", savedPlaceController.GetSavedPlaces)
+				places.POST("", savedPlaceController.CreateSavedPlace)
+				places.GET("/:id", savedPlaceController.GetSavedPlace)
+				places.PUT("/:id", savedPlaceController.UpdateSavedPlace)
+				places.DELETE("/:id", savedPlaceController.DeleteSavedPlace)
+				places.GET("/:id/albums", savedPlaceController.GetAlbumsInPlace)
 			}
 
 			// Path routes
@@ -93,15 +195,66 @@ func Register(r *gin.Engine) {
 			{
 				paths.POST("", pathController.CreatePath)
 				paths.GET("", pathController.GetPaths)
+				paths.POST("/auto", pathController.AutoCreatePaths)
 				paths.GET("/:id", pathController.GetPath)
+				paths.PUT("/:id", pathController.UpdatePath)
+				paths.PUT("/:id/geometry", pathController.SetPathGeometry)
+				paths.DELETE("/:id/geometry", pathController.ClearPathGeometry)
 				paths.DELETE("/:id", pathController.DeletePath)
 			}
 
+			// Trip routes
+			trips := protected.Group("/trips")
+			{
+				trips.POST("", tripController.CreateTrip)
+				trips.GET("", tripController.GetTrips)
+				trips.GET("/:id", tripController.GetTrip)
+				trips.PUT("/:id", tripController.UpdateTrip)
+				trips.DELETE("/:id", tripController.DeleteTrip)
+			}
+
+			// Import routes
+			imports := protected.Group("/import")
+			{
+				imports.POST("/gpx", importController.ImportGPX)
+				imports.POST("/places", importController.ImportPlaces)
+				imports.POST("/takeout", importController.ImportTakeout)
+				imports.GET("/takeout/:id", importController.GetTakeoutJob)
+			}
+
+			// Export routes
+			protected.GET("/export/map", exportController.ExportMap)
+
+			// Account archive routes
+			me := protected.Group("/me")
+			{
+				me.POST("/export", accountController.ExportAccount)
+				me.GET("/export/:id", accountController.GetExportJob)
+				me.POST("/import", accountController.ImportAccount)
+				me.GET("/import/:id", accountController.GetImportJob)
+			}
+
 			// Album-specific path routes (for "next destination" functionality)
 			// These routes are nested under the existing albums/:id routes
 			albums.POST("/:id/next-destination", pathController.SetNextDestination)
 			albums.GET("/:id/next-destination", pathController.GetNextDestination)
 			albums.DELETE("/:id/next-destination", pathController.RemoveNextDestination)
+			albums.GET("/:id/route", pathController.GetRoute)
+
+			// Offline basemaps
+			protected.GET("/basemap", basemapController.GetBasemaps)
+			protected.GET("/tileproxy", tileProxyController.GetProviders)
+			protected.POST("/tileproxy/seed", tileProxyController.SeedTiles)
+			protected.GET("/tileproxy/seed/:id", tileProxyController.GetSeedJob)
+			protected.DELETE("/tileproxy/seed/:id", tileProxyController.CancelSeedJob)
+
+			// Offline geocoding
+			protected.GET("/geocode", geocodeController.Geocode)
+
+			// Library statistics
+			protected.GET("/stats", statsController.GetStats)
+			protected.GET("/timeline", timelineController.GetTimeline)
+			protected.GET("/heatmap", heatmapController.GetHeatmap)
 		}
 
 		// Security endpoints (development only)
@@ -26,6 +26,7 @@ func (a *SQLInjectionAuditor) AuditDAOLayer() []string {
 		&dao.AlbumDAO{},
 		&dao.PhotoDAO{},
 		&dao.PathDAO{},
+		&dao.TagDAO{},
 	}
 	
 	for _, daoInstance := range daoTypes {
@@ -0,0 +1,935 @@
+package service
+
+import (
+	"archive/zip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"geoalbum/backend/archive"
+	"geoalbum/backend/dao"
+	"geoalbum/backend/datum"
+	"geoalbum/backend/logging"
+	"geoalbum/backend/middleware"
+	"geoalbum/backend/model"
+	"geoalbum/backend/polyline"
+)
+
+// accountExportTTL is how long the download link of an account archive is valid;
+// the archive is deleted once it has expired
+const accountExportTTL = 24 * time.Hour
+
+// accountImportRetention is how long finished account imports remain queryable
+const accountImportRetention = 24 * time.Hour
+
+var (
+	// ErrExportInProgress is returned when the user already has an archive being built
+	ErrExportInProgress = errors.New("an account export is already running")
+	// ErrExportNotFound is returned for export jobs that do not exist or belong to
+	// another user, and for archives that are no longer available
+	ErrExportNotFound = errors.New("account export not found")
+	// ErrExportLinkInvalid is returned for download links with a wrong signature
+	ErrExportLinkInvalid = errors.New("invalid download link")
+	// ErrExportLinkExpired is returned for download links past their expiry
+	ErrExportLinkExpired = errors.New("download link has expired")
+)
+
+// accountExportJob is a running or finished account export
+type accountExportJob struct {
+	mu  sync.Mutex
+	job model.AccountExportJob
+}
+
+func (j *accountExportJob) snapshot() model.AccountExportJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.job
+}
+
+func (j *accountExportJob) update(change func(job *model.AccountExportJob)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	change(&j.job)
+}
+
+// accountImportJob is a running or finished account import
+type accountImportJob struct {
+	mu  sync.Mutex
+	job model.AccountImportJob
+}
+
+func (j *accountImportJob) snapshot() model.AccountImportJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job := j.job
+	job.Errors = append([]string(nil), j.job.Errors...)
+	return job
+}
+
+func (j *accountImportJob) update(change func(job *model.AccountImportJob)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	change(&j.job)
+}
+
+// addError records an error about one record, keeping the first MaxImportJobErrors
+func (j *accountImportJob) addError(format string, args ...interface{}) {
+	j.update(func(state *model.AccountImportJob) {
+		if len(state.Errors) < model.MaxImportJobErrors {
+			state.Errors = append(state.Errors, fmt.Sprintf(format, args...))
+		}
+	})
+}
+
+// Account jobs are shared by every AccountService so they can be polled from any request
+var (
+	accountJobsMu      sync.Mutex
+	accountExportJobs  = make(map[string]*accountExportJob)
+	accountImportJobs  = make(map[string]*accountImportJob)
+	accountExportsDir  = filepath.Join("data", "exports")
+	accountLinkKeyOnce sync.Once
+	accountLinkKey     []byte
+)
+
+type AccountService struct {
+	userDAO           *dao.UserDAO
+	albumDAO          *dao.AlbumDAO
+	photoDAO          *dao.PhotoDAO
+	pathDAO           *dao.PathDAO
+	tripDAO           *dao.TripDAO
+	tagDAO            *dao.TagDAO
+	savedPlaceDAO     *dao.SavedPlaceDAO
+	albumService      *AlbumService
+	photoService      *PhotoService
+	pathService       *PathService
+	tripService       *TripService
+	tagService        *TagService
+	savedPlaceService *SavedPlaceService
+}
+
+func NewAccountService() *AccountService {
+	return &AccountService{
+		userDAO:           dao.NewUserDAO(),
+		albumDAO:          dao.NewAlbumDAO(),
+		photoDAO:          dao.NewPhotoDAO(),
+		pathDAO:           dao.NewPathDAO(),
+		tripDAO:           dao.NewTripDAO(),
+		tagDAO:            dao.NewTagDAO(),
+		savedPlaceDAO:     dao.NewSavedPlaceDAO(),
+		albumService:      NewAlbumService(),
+		photoService:      NewPhotoService(),
+		pathService:       NewPathService(),
+		tripService:       NewTripService(),
+		tagService:        NewTagService(),
+		savedPlaceService: NewSavedPlaceService(),
+	}
+}
+
+// StartAccountExport starts building an archive of everything the user keeps: albums,
+// photos with their original files, paths, trips, tags and saved places, in the
+// format of package archive. Once the job completes it carries a download link that
+// is valid for a day.
+func (s *AccountService) StartAccountExport(userID string) (*model.AccountExportJob, error) {
+	pruneAccountJobs()
+
+	accountJobsMu.Lock()
+	for _, job := range accountExportJobs {
+		if state := job.snapshot(); state.UserID == userID && state.Status == model.ExportJobRunning {
+			accountJobsMu.Unlock()
+			return nil, ErrExportInProgress
+		}
+	}
+	job := &accountExportJob{job: model.AccountExportJob{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Status:    model.ExportJobRunning,
+		StartedAt: time.Now().UTC(),
+	}}
+	accountExportJobs[job.job.ID] = job
+	accountJobsMu.Unlock()
+
+	go s.runExport(job)
+	state := job.snapshot()
+	return &state, nil
+}
+
+// GetAccountExport returns the state of one of the user's account exports
+func (s *AccountService) GetAccountExport(id, userID string) (*model.AccountExportJob, error) {
+	accountJobsMu.Lock()
+	job, ok := accountExportJobs[id]
+	accountJobsMu.Unlock()
+	if !ok {
+		return nil, ErrExportNotFound
+	}
+	state := job.snapshot()
+	if state.UserID != userID {
+		return nil, ErrExportNotFound
+	}
+	return &state, nil
+}
+
+// OpenAccountExport checks a download link of an account archive and returns the
+// path of the archive. Links stay valid across restarts until they expire.
+func (s *AccountService) OpenAccountExport(id, expires, signature string) (string, error) {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return "", ErrExportLinkInvalid
+	}
+	expected := exportLinkSignature(id, expiresAt)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return "", ErrExportLinkInvalid
+	}
+	if time.Now().Unix() > expiresAt {
+		return "", ErrExportLinkExpired
+	}
+
+	path := accountExportPath(id)
+	if _, err := os.Stat(path); err != nil {
+		return "", ErrExportNotFound
+	}
+	return path, nil
+}
+
+func (s *AccountService) runExport(job *accountExportJob) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logging.WithField("job_id", job.job.ID).Errorf("Account export panicked: %v", recovered)
+			finishExportJob(job, fmt.Errorf("internal error"))
+		}
+	}()
+
+	path := accountExportPath(job.job.ID)
+	if err := s.writeExport(job, path); err != nil {
+		os.Remove(path + ".part")
+		logging.WithError(err).WithField("job_id", job.job.ID).Error("Failed to export account")
+		finishExportJob(job, err)
+		return
+	}
+	finishExportJob(job, nil)
+}
+
+// writeExport writes the user's archive next to path and moves it there once complete
+func (s *AccountService) writeExport(job *accountExportJob, path string) error {
+	userID := job.job.UserID
+	user, err := s.userDAO.GetByID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return fmt.Errorf("user not found")
+	}
+
+	content := &archive.Archive{Manifest: archive.Manifest{CreatedAt: time.Now().UTC(), Username: user.Username}}
+	albums, err := s.albumDAO.GetByUserID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get albums: %w", err)
+	}
+	for _, album := range albums {
+		// Listings fill in an automatic cover, so the chosen one is read separately
+		stored, err := s.albumDAO.GetByID(album.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get album: %w", err)
+		}
+		content.Albums = append(content.Albums, archive.Album{
+			ID:           album.ID,
+			Title:        html.UnescapeString(album.Title),
+			Description:  html.UnescapeString(album.Description),
+			Latitude:     album.Latitude,
+			Longitude:    album.Longitude,
+			CreatedAt:    album.CreatedAt.UTC(),
+			StartAt:      album.StartAt.UTC(),
+			EndAt:        album.EndAt.UTC(),
+			DatesManual:  album.DatesManual,
+			Timezone:     album.Timezone,
+			ExternalID:   album.ExternalID,
+			CoverPhotoID: stored.CoverPhotoID,
+			Tags:         unescapeAll(album.Tags),
+		})
+	}
+
+	photos, err := s.photoDAO.GetByUserIDFiltered(userID, model.PhotoFilter{})
+	if err != nil {
+		return fmt.Errorf("failed to get photos: %w", err)
+	}
+	sort.SliceStable(photos, func(i, j int) bool {
+		if photos[i].AlbumID != photos[j].AlbumID {
+			return photos[i].AlbumID < photos[j].AlbumID
+		}
+		return photos[i].DisplayOrder < photos[j].DisplayOrder
+	})
+	job.update(func(state *model.AccountExportJob) {
+		state.Albums = len(albums)
+		state.Photos = len(photos)
+	})
+
+	if err := os.MkdirAll(accountExportsDir, 0700); err != nil {
+		return fmt.Errorf("failed to create exports directory: %w", err)
+	}
+	file, err := os.OpenFile(path+".part", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer file.Close()
+	writer := archive.NewWriter(file)
+
+	for _, photo := range photos {
+		record := archive.Photo{
+			ID:           photo.ID,
+			AlbumID:      photo.AlbumID,
+			Filename:     photo.Filename,
+			MimeType:     photo.MimeType,
+			Size:         photo.FileSize,
+			DisplayOrder: photo.DisplayOrder,
+			UploadedAt:   photo.UploadedAt.UTC(),
+			TakenAt:      photo.TakenAt,
+			Rating:       photo.Rating,
+			Favorite:     photo.Favorite,
+			Latitude:     photo.Latitude,
+			Longitude:    photo.Longitude,
+			Description:  html.UnescapeString(photo.Description),
+			Tags:         unescapeAll(photo.Tags),
+		}
+		missing := false
+		if original, err := os.Open(photo.FilePath); err != nil {
+			missing = true
+		} else {
+			err := writer.WritePhoto(&record, original)
+			original.Close()
+			if err != nil {
+				return err
+			}
+		}
+		content.Photos = append(content.Photos, record)
+		job.update(func(state *model.AccountExportJob) {
+			state.Done++
+			if missing {
+				state.MissingFiles++
+			}
+		})
+	}
+
+	if err := s.collectExport(userID, content); err != nil {
+		return err
+	}
+	if err := writer.Close(content); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := os.Rename(path+".part", path); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	job.update(func(state *model.AccountExportJob) { state.Size = info.Size() })
+	return nil
+}
+
+// collectExport adds the user's paths, trips, tags and saved places to an archive
+func (s *AccountService) collectExport(userID string, content *archive.Archive) error {
+	paths, err := s.pathDAO.GetByUserID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get paths: %w", err)
+	}
+	for _, path := range paths {
+		record := archive.Path{
+			ID:              path.ID,
+			FromAlbumID:     path.FromAlbumID,
+			ToAlbumID:       path.ToAlbumID,
+			CreatedAt:       path.CreatedAt.UTC(),
+			TransportMode:   path.TransportMode,
+			DurationMinutes: path.DurationMinutes,
+			DepartAt:        path.DepartAt,
+			ArriveAt:        path.ArriveAt,
+			Notes:           html.UnescapeString(path.Notes),
+		}
+		if path.Geometry != "" {
+			if line, err := polyline.Decode(path.Geometry); err == nil {
+				for _, point := range line {
+					// Stored geometry is unwrapped across the antimeridian
+					lng := point.Lng - 360*math.Floor((point.Lng+180)/360)
+					record.Geometry = append(record.Geometry, [2]float64{lng, point.Lat})
+				}
+			}
+		}
+		content.Paths = append(content.Paths, record)
+	}
+
+	trips, err := s.tripDAO.GetByUserID(userID, "")
+	if err != nil {
+		return fmt.Errorf("failed to get trips: %w", err)
+	}
+	for _, trip := range trips {
+		stops, err := s.tripDAO.GetStops(trip.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get trip stops: %w", err)
+		}
+		record := archive.Trip{
+			ID:          trip.ID,
+			Title:       html.UnescapeString(trip.Title),
+			Description: html.UnescapeString(trip.Description),
+			StartAt:     trip.StartAt,
+			EndAt:       trip.EndAt,
+			CreatedAt:   trip.CreatedAt.UTC(),
+			AlbumIDs:    []string{},
+		}
+		for _, stop := range stops {
+			record.AlbumIDs = append(record.AlbumIDs, stop.AlbumID)
+		}
+		content.Trips = append(content.Trips, record)
+	}
+
+	tags, err := s.tagDAO.GetByUserID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get tags: %w", err)
+	}
+	for _, tag := range tags {
+		content.Tags = append(content.Tags, archive.Tag{Name: html.UnescapeString(tag.Name), CreatedAt: tag.CreatedAt.UTC()})
+	}
+
+	places, err := s.savedPlaceDAO.GetByUserID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get saved places: %w", err)
+	}
+	for _, place := range places {
+		content.SavedPlaces = append(content.SavedPlaces, archive.SavedPlace{
+			Name:      html.UnescapeString(place.Name),
+			Kind:      place.Kind,
+			Latitude:  place.Latitude,
+			Longitude: place.Longitude,
+			RadiusM:   place.RadiusM,
+			Polygon:   place.Polygon,
+			AutoTag:   place.AutoTag,
+			CreatedAt: place.CreatedAt.UTC(),
+		})
+	}
+	return nil
+}
+
+func finishExportJob(job *accountExportJob, err error) {
+	finished := time.Now().UTC()
+	job.update(func(state *model.AccountExportJob) {
+		state.FinishedAt = &finished
+		if err != nil {
+			state.Status = model.ExportJobFailed
+			state.Error = err.Error()
+			return
+		}
+		expires := finished.Add(accountExportTTL).Truncate(time.Second)
+		state.Status = model.ExportJobCompleted
+		state.ExpiresAt = &expires
+		state.DownloadURL = fmt.Sprintf("/api/me/export/%s/download?expires=%d&signature=%s",
+			state.ID, expires.Unix(), exportLinkSignature(state.ID, expires.Unix()))
+	})
+}
+
+// exportLinkSignature signs the download link of an archive. The key is derived from
+// the token secret so that links cannot be forged without it, but a link is not a
+// token and a token is not a link.
+func exportLinkSignature(id string, expires int64) string {
+	accountLinkKeyOnce.Do(func() {
+		key := sha256.Sum256(append([]byte("geoalbum account export\n"), middleware.GetJWTSecret()...))
+		accountLinkKey = key[:]
+	})
+	mac := hmac.New(sha256.New, accountLinkKey)
+	fmt.Fprintf(mac, "%s\n%d", id, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func accountExportPath(id string) string {
+	// Job IDs are UUIDs, but links are user input
+	return filepath.Join(accountExportsDir, filepath.Base(filepath.Clean("/"+id))+".zip")
+}
+
+func unescapeAll(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	unescaped := make([]string, len(values))
+	for i, value := range values {
+		unescaped[i] = html.UnescapeString(value)
+	}
+	return unescaped
+}
+
+// StartAccountImport starts importing an account archive into the user's account.
+// Records are matched against what the account already holds, so importing the same
+// archive again only adds what is missing. The archive file is removed afterwards
+// when removeSource is set.
+func (s *AccountService) StartAccountImport(userID, source string, removeSource bool) (*model.AccountImportJob, error) {
+	remove := func() {
+		if removeSource {
+			os.Remove(source)
+		}
+	}
+	reader, err := zip.OpenReader(source)
+	if err != nil {
+		remove()
+		return nil, archive.ErrNotArchive
+	}
+	content, err := archive.Read(reader)
+	if err != nil {
+		reader.Close()
+		remove()
+		return nil, err
+	}
+
+	pruneAccountJobs()
+	job := &accountImportJob{job: model.AccountImportJob{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Status:    model.ImportJobRunning,
+		Total:     len(content.Photos),
+		StartedAt: time.Now().UTC(),
+	}}
+	accountJobsMu.Lock()
+	accountImportJobs[job.job.ID] = job
+	accountJobsMu.Unlock()
+
+	go func() {
+		defer remove()
+		defer reader.Close()
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				logging.WithField("job_id", job.job.ID).Errorf("Account import panicked: %v", recovered)
+				finishImportJob(job, fmt.Errorf("internal error"))
+			}
+		}()
+		finishImportJob(job, s.importArchive(job, reader, content))
+	}()
+	state := job.snapshot()
+	return &state, nil
+}
+
+// GetAccountImport returns the state of one of the user's account imports
+func (s *AccountService) GetAccountImport(id, userID string) (*model.AccountImportJob, error) {
+	accountJobsMu.Lock()
+	job, ok := accountImportJobs[id]
+	accountJobsMu.Unlock()
+	if !ok {
+		return nil, ErrImportJobNotFound
+	}
+	state := job.snapshot()
+	if state.UserID != userID {
+		return nil, ErrImportJobNotFound
+	}
+	return &state, nil
+}
+
+// importArchive imports tags, albums, photos, paths, trips and saved places in that
+// order, so that records are imported after those they refer to. Saved places come
+// last so that they do not tag the imported albums anew.
+func (s *AccountService) importArchive(job *accountImportJob, reader *zip.ReadCloser, content *archive.Archive) error {
+	userID := job.job.UserID
+
+	for _, tag := range content.Tags {
+		_, err := s.tagService.CreateTag(userID, tag.Name)
+		job.update(func(state *model.AccountImportJob) {
+			switch {
+			case err == nil:
+				state.Tags.Created++
+			case errors.Is(err, ErrTagExists):
+				state.Tags.Existing++
+			default:
+				state.Tags.Failed++
+			}
+		})
+		if err != nil && !errors.Is(err, ErrTagExists) {
+			job.addError("tag %q: %v", tag.Name, err)
+		}
+	}
+
+	albumIDs, err := s.importAlbums(job, content)
+	if err != nil {
+		return err
+	}
+	photoIDs := s.importPhotos(job, reader, content, albumIDs)
+
+	// Albums created by this import get back the cover chosen for them
+	for _, album := range content.Albums {
+		target, ok := albumIDs[album.ID]
+		cover, hasCover := photoIDs[album.CoverPhotoID]
+		if !ok || !target.created || !hasCover {
+			continue
+		}
+		if _, err := s.albumService.UpdateAlbum(target.id, userID, AlbumUpdate{CoverPhotoID: &cover}); err != nil {
+			job.addError("album %q: failed to set cover: %v", album.Title, err)
+		}
+	}
+
+	s.importPaths(job, content, albumIDs)
+	if err := s.importTrips(job, content, albumIDs); err != nil {
+		return err
+	}
+
+	for _, place := range content.SavedPlaces {
+		_, err := s.savedPlaceService.CreateSavedPlace(userID, SavedPlaceInput{
+			Name:      place.Name,
+			Latitude:  place.Latitude,
+			Longitude: place.Longitude,
+			RadiusM:   place.RadiusM,
+			Polygon:   place.Polygon,
+			AutoTag:   place.AutoTag,
+			Datum:     datum.WGS84,
+		})
+		job.update(func(state *model.AccountImportJob) {
+			switch {
+			case err == nil:
+				state.SavedPlaces.Created++
+			case errors.Is(err, ErrSavedPlaceExists):
+				state.SavedPlaces.Existing++
+			default:
+				state.SavedPlaces.Failed++
+			}
+		})
+		if err != nil && !errors.Is(err, ErrSavedPlaceExists) {
+			job.addError("saved place %q: %v", place.Name, err)
+		}
+	}
+	return nil
+}
+
+// importedAlbum is the album an archived album was imported into
+type importedAlbum struct {
+	id      string
+	created bool
+}
+
+// importAlbums finds or creates the album of each archived album. An album matches
+// when it has the archived album's ID, as when an archive is imported back into the
+// account it was made from, or was created from it by an earlier import.
+func (s *AccountService) importAlbums(job *accountImportJob, content *archive.Archive) (map[string]importedAlbum, error) {
+	userID := job.job.UserID
+	albums, err := s.albumDAO.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get albums: %w", err)
+	}
+	existing := make(map[string]string, 2*len(albums))
+	for _, album := range albums {
+		existing[album.ID] = album.ID
+		if album.ExternalID != "" {
+			existing[album.ExternalID] = album.ID
+		}
+	}
+
+	albumIDs := make(map[string]importedAlbum, len(content.Albums))
+	for _, album := range content.Albums {
+		externalID := album.ExternalID
+		if externalID == "" {
+			externalID = "geoalbum:" + album.ID
+		}
+		if id, ok := existing[album.ID]; ok {
+			albumIDs[album.ID] = importedAlbum{id: id}
+		} else if id, ok := existing[externalID]; ok {
+			albumIDs[album.ID] = importedAlbum{id: id}
+		}
+		if _, ok := albumIDs[album.ID]; ok {
+			job.update(func(state *model.AccountImportJob) { state.Albums.Existing++ })
+			continue
+		}
+
+		input := NewAlbum{
+			Title:       fitSanitized(album.Title, 200),
+			Description: fitSanitized(album.Description, 2000),
+			Latitude:    album.Latitude,
+			Longitude:   album.Longitude,
+			CreatedAt:   album.CreatedAt,
+			Timezone:    album.Timezone,
+			Datum:       datum.WGS84,
+			ExternalID:  externalID,
+		}
+		if album.DatesManual {
+			input.StartAt, input.EndAt = &album.StartAt, &album.EndAt
+		}
+		created, err := s.albumService.CreateAlbum(userID, input)
+		if err != nil {
+			job.addError("album %q: %v", album.Title, err)
+			job.update(func(state *model.AccountImportJob) { state.Albums.Failed++ })
+			continue
+		}
+		albumIDs[album.ID] = importedAlbum{id: created.ID, created: true}
+		existing[externalID] = created.ID
+		job.update(func(state *model.AccountImportJob) { state.Albums.Created++ })
+
+		if len(album.Tags) > 0 {
+			if _, err := s.tagService.AttachTagsToAlbum(created.ID, userID, album.Tags); err != nil {
+				job.addError("album %q: failed to tag: %v", album.Title, err)
+			}
+		}
+	}
+	return albumIDs, nil
+}
+
+// importPhotos adds the archived photos to their albums in display order, skipping
+// photos an album already has with the same file name and capture time. It returns
+// the IDs the photos have in the account by their archived ID.
+func (s *AccountService) importPhotos(job *accountImportJob, reader *zip.ReadCloser, content *archive.Archive, albumIDs map[string]importedAlbum) map[string]string {
+	userID := job.job.UserID
+	photos := append([]archive.Photo(nil), content.Photos...)
+	sort.SliceStable(photos, func(i, j int) bool {
+		if photos[i].AlbumID != photos[j].AlbumID {
+			return photos[i].AlbumID < photos[j].AlbumID
+		}
+		return photos[i].DisplayOrder < photos[j].DisplayOrder
+	})
+
+	photoIDs := make(map[string]string, len(photos))
+	existing := make(map[string]map[string]string)
+	for _, photo := range photos {
+		photoID, err := s.importPhoto(reader, userID, photo, albumIDs, existing)
+		job.update(func(state *model.AccountImportJob) {
+			state.Done++
+			switch {
+			case err != nil:
+				state.Photos.Failed++
+			case photoID.existing:
+				state.Photos.Existing++
+			default:
+				state.Photos.Created++
+			}
+		})
+		if err != nil {
+			job.addError("photo %s: %v", photo.Filename, err)
+			continue
+		}
+		photoIDs[photo.ID] = photoID.id
+	}
+	return photoIDs
+}
+
+// importedPhoto is the photo an archived photo was imported as
+type importedPhoto struct {
+	id       string
+	existing bool
+}
+
+func (s *AccountService) importPhoto(reader *zip.ReadCloser, userID string, photo archive.Photo, albumIDs map[string]importedAlbum, existing map[string]map[string]string) (importedPhoto, error) {
+	album, ok := albumIDs[photo.AlbumID]
+	if !ok {
+		return importedPhoto{}, fmt.Errorf("its album was not imported")
+	}
+	inAlbum, ok := existing[album.id]
+	if !ok {
+		inAlbum = make(map[string]string)
+		if !album.created {
+			stored, err := s.photoDAO.GetByAlbumID(album.id)
+			if err != nil {
+				return importedPhoto{}, fmt.Errorf("failed to get photos: %w", err)
+			}
+			for _, p := range stored {
+				inAlbum[takeoutPhotoKey(p.Filename, p.TakenAt)] = p.ID
+			}
+		}
+		existing[album.id] = inAlbum
+	}
+	key := takeoutPhotoKey(photo.Filename, photo.TakenAt)
+	if id, ok := inAlbum[key]; ok {
+		return importedPhoto{id: id, existing: true}, nil
+	}
+	if photo.File == "" {
+		return importedPhoto{}, fmt.Errorf("the archive has no file for it")
+	}
+
+	file, err := reader.Open(photo.File)
+	if err != nil {
+		return importedPhoto{}, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	if info, err := file.Stat(); err == nil && info.Size() > MaxPhotoSize {
+		return importedPhoto{}, fmt.Errorf("photo file is larger than %d MB", MaxPhotoSize>>20)
+	}
+	added, err := s.photoService.AddPhoto(album.id, userID, PhotoFile{
+		Filename:    photo.Filename,
+		MimeType:    photo.MimeType,
+		Content:     file,
+		TakenAt:     photo.TakenAt,
+		Latitude:    photo.Latitude,
+		Longitude:   photo.Longitude,
+		Description: fitSanitized(photo.Description, 2000),
+	})
+	if err != nil {
+		return importedPhoto{}, err
+	}
+	inAlbum[key] = added.ID
+
+	// The rating read from the file may differ from the one given in geoalbum
+	if _, err := s.photoDAO.UpdateRatings(userID, []string{added.ID}, &photo.Rating, &photo.Favorite); err != nil {
+		return importedPhoto{}, err
+	}
+	if len(photo.Tags) > 0 {
+		if _, err := s.tagService.AttachTagsToPhoto(added.ID, userID, photo.Tags); err != nil {
+			return importedPhoto{}, err
+		}
+	}
+	return importedPhoto{id: added.ID}, nil
+}
+
+// importPaths creates the archived paths between imported albums, with their travel
+// details and geometry. Paths the albums already have are left as they are.
+func (s *AccountService) importPaths(job *accountImportJob, content *archive.Archive, albumIDs map[string]importedAlbum) {
+	userID := job.job.UserID
+	for _, path := range content.Paths {
+		from, fromOK := albumIDs[path.FromAlbumID]
+		to, toOK := albumIDs[path.ToAlbumID]
+		if !fromOK || !toOK {
+			job.addError("path %s: its albums were not imported", path.ID)
+			job.update(func(state *model.AccountImportJob) { state.Paths.Failed++ })
+			continue
+		}
+		exists, err := s.pathDAO.CheckPathExists(from.id, to.id, userID)
+		if err == nil && exists {
+			job.update(func(state *model.AccountImportJob) { state.Paths.Existing++ })
+			continue
+		}
+
+		created, err := s.pathService.CreatePath(userID, from.id, to.id, ChainPolicyWarn)
+		if err != nil {
+			job.addError("path %s: %v", path.ID, err)
+			job.update(func(state *model.AccountImportJob) { state.Paths.Failed++ })
+			continue
+		}
+		job.update(func(state *model.AccountImportJob) { state.Paths.Created++ })
+
+		update := PathUpdate{
+			TransportMode:   &path.TransportMode,
+			DurationMinutes: path.DurationMinutes,
+			DepartAt:        path.DepartAt,
+			ArriveAt:        path.ArriveAt,
+			Notes:           &path.Notes,
+		}
+		if _, err := s.pathService.UpdatePath(created.ID, userID, update); err != nil {
+			job.addError("path %s: failed to set travel details: %v", path.ID, err)
+		}
+		if len(path.Geometry) >= 2 {
+			points := make([]polyline.Point, len(path.Geometry))
+			for i, point := range path.Geometry {
+				points[i] = polyline.Point{Lat: point[1], Lng: point[0]}
+			}
+			if _, err := s.pathService.SetPathGeometry(created.ID, userID, points, datum.WGS84); err != nil {
+				job.addError("path %s: failed to set geometry: %v", path.ID, err)
+			}
+		}
+	}
+}
+
+// importTrips creates the archived trips whose albums were all imported, unless the
+// account has a trip with the same title and stops
+func (s *AccountService) importTrips(job *accountImportJob, content *archive.Archive, albumIDs map[string]importedAlbum) error {
+	userID := job.job.UserID
+	trips, err := s.tripDAO.GetByUserID(userID, "")
+	if err != nil {
+		return fmt.Errorf("failed to get trips: %w", err)
+	}
+	existing := make(map[string]bool, len(trips))
+	for _, trip := range trips {
+		stops, err := s.tripDAO.GetStops(trip.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get trip stops: %w", err)
+		}
+		stopIDs := make([]string, len(stops))
+		for i, stop := range stops {
+			stopIDs[i] = stop.AlbumID
+		}
+		existing[tripKey(html.UnescapeString(trip.Title), stopIDs)] = true
+	}
+
+	for _, trip := range content.Trips {
+		stopIDs := make([]string, 0, len(trip.AlbumIDs))
+		for _, albumID := range trip.AlbumIDs {
+			if album, ok := albumIDs[albumID]; ok {
+				stopIDs = append(stopIDs, album.id)
+			}
+		}
+		if len(stopIDs) != len(trip.AlbumIDs) {
+			job.addError("trip %q: its albums were not all imported", trip.Title)
+			job.update(func(state *model.AccountImportJob) { state.Trips.Failed++ })
+			continue
+		}
+		if existing[tripKey(trip.Title, stopIDs)] {
+			job.update(func(state *model.AccountImportJob) { state.Trips.Existing++ })
+			continue
+		}
+
+		title, description := fitSanitized(trip.Title, 200), fitSanitized(trip.Description, 2000)
+		_, err := s.tripService.CreateTrip(userID, TripInput{
+			Title:       &title,
+			Description: &description,
+			StartAt:     trip.StartAt,
+			EndAt:       trip.EndAt,
+			AlbumIDs:    stopIDs,
+		})
+		if err != nil {
+			job.addError("trip %q: %v", trip.Title, err)
+			job.update(func(state *model.AccountImportJob) { state.Trips.Failed++ })
+			continue
+		}
+		existing[tripKey(trip.Title, stopIDs)] = true
+		job.update(func(state *model.AccountImportJob) { state.Trips.Created++ })
+	}
+	return nil
+}
+
+func tripKey(title string, albumIDs []string) string {
+	return fmt.Sprintf("%s|%v", title, albumIDs)
+}
+
+func finishImportJob(job *accountImportJob, err error) {
+	finished := time.Now().UTC()
+	job.update(func(state *model.AccountImportJob) {
+		state.Status = model.ImportJobCompleted
+		if err != nil {
+			state.Status = model.ImportJobFailed
+			state.Error = err.Error()
+		}
+		state.FinishedAt = &finished
+	})
+}
+
+// pruneAccountJobs forgets expired exports and deletes their archives, including
+// archives left behind by a previous run, and forgets old finished imports
+func pruneAccountJobs() {
+	now := time.Now()
+	accountJobsMu.Lock()
+	for id, job := range accountExportJobs {
+		state := job.snapshot()
+		expired := state.ExpiresAt != nil && state.ExpiresAt.Before(now)
+		failed := state.Status == model.ExportJobFailed && state.FinishedAt.Before(now.Add(-accountExportTTL))
+		if expired || failed {
+			delete(accountExportJobs, id)
+		}
+	}
+	for id, job := range accountImportJobs {
+		state := job.snapshot()
+		if state.FinishedAt != nil && state.FinishedAt.Before(now.Add(-accountImportRetention)) {
+			delete(accountImportJobs, id)
+		}
+	}
+	accountJobsMu.Unlock()
+
+	entries, err := os.ReadDir(accountExportsDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		// Archives are written within the hour and expire a day after they are done
+		if err == nil && info.ModTime().Before(now.Add(-accountExportTTL-time.Hour)) {
+			os.Remove(filepath.Join(accountExportsDir, entry.Name()))
+		}
+	}
+}
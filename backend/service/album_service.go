@@ -1,6 +1,7 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -8,29 +9,62 @@ import (
 	"github.com/google/uuid"
 
 	"geoalbum/backend/dao"
+	"geoalbum/backend/datum"
+	"geoalbum/backend/logging"
 	"geoalbum/backend/middleware"
 	"geoalbum/backend/model"
 )
 
+var (
+	// ErrAlbumNotFound is returned for albums that do not exist
+	ErrAlbumNotFound = errors.New("album not found")
+	// ErrAlbumAccessDenied is returned for albums of another user
+	ErrAlbumAccessDenied = errors.New("access denied: album does not belong to user")
+	// ErrInvalidAlbum is matched by the errors returned for invalid album input
+	ErrInvalidAlbum = errors.New("invalid album")
+)
+
+func invalidAlbum(format string, args ...interface{}) error {
+	return invalidInput(ErrInvalidAlbum, fmt.Errorf(format, args...))
+}
+
 type AlbumService struct {
-	albumDAO  *dao.AlbumDAO
-	photoDAO  *dao.PhotoDAO
-	sanitizer *middleware.InputSanitizer
+	albumDAO       *dao.AlbumDAO
+	photoDAO       *dao.PhotoDAO
+	geocodeService *GeocodeService
+	placeService   *SavedPlaceService
+	sanitizer      *middleware.InputSanitizer
 }
 
 func NewAlbumService() *AlbumService {
 	return &AlbumService{
-		albumDAO:  dao.NewAlbumDAO(),
-		photoDAO:  dao.NewPhotoDAO(),
-		sanitizer: middleware.GetInputSanitizer(),
+		albumDAO:       dao.NewAlbumDAO(),
+		photoDAO:       dao.NewPhotoDAO(),
+		geocodeService: NewGeocodeService(),
+		placeService:   NewSavedPlaceService(),
+		sanitizer:      middleware.GetInputSanitizer(),
 	}
 }
 
+// NewAlbum holds the fields used to create an album
+type NewAlbum struct {
+	Title       string
+	Description string
+	Latitude    float64
+	Longitude   float64
+	CreatedAt   time.Time
+	StartAt     *time.Time // optional; when set together with EndAt the range is kept as given
+	EndAt       *time.Time
+	Timezone    string      // IANA name of the album location's time zone; derived from the location when empty
+	Datum       datum.Datum // datum of Latitude/Longitude; stored as WGS-84
+	ExternalID  string      // ID of the imported place the album is created from, unique per user
+}
+
 // CreateAlbum creates a new album
-func (s *AlbumService) CreateAlbum(userID, title, description string, latitude, longitude float64, createdAt time.Time) (*model.Album, error) {
+func (s *AlbumService) CreateAlbum(userID string, input NewAlbum) (*model.Album, error) {
 	// Validate and sanitize input
-	title = s.sanitizer.SanitizeString(title)
-	description = s.sanitizer.SanitizeString(description)
+	title := s.sanitizer.SanitizeString(input.Title)
+	description := s.sanitizer.SanitizeString(input.Description)
 	
 	// Validate input
 	if !s.sanitizer.ValidateAlbumTitle(title) {
@@ -41,15 +75,20 @@ func (s *AlbumService) CreateAlbum(userID, title, description string, latitude,
 		return nil, fmt.Errorf("invalid album description: must be max 2000 characters")
 	}
 	
-	if !s.sanitizer.ValidateCoordinates(latitude, longitude) {
+	if !s.sanitizer.ValidateCoordinates(input.Latitude, input.Longitude) {
 		return nil, fmt.Errorf("invalid coordinates: latitude must be -90 to 90, longitude must be -180 to 180")
 	}
+	latitude, longitude := datum.ToWGS84(input.Latitude, input.Longitude, input.Datum)
 	
 	// Check for SQL injection patterns
 	if s.sanitizer.DetectSQLInjection(title) || s.sanitizer.DetectSQLInjection(description) {
 		return nil, fmt.Errorf("invalid input: contains prohibited characters")
 	}
 
+	if _, err := loadTimezone(input.Timezone); err != nil {
+		return nil, err
+	}
+
 	album := &model.Album{
 		ID:          uuid.New().String(),
 		UserID:      userID,
@@ -57,14 +96,28 @@ func (s *AlbumService) CreateAlbum(userID, title, description string, latitude,
 		Description: description,
 		Latitude:    latitude,
 		Longitude:   longitude,
-		CreatedAt:   createdAt,
+		CreatedAt:   input.CreatedAt,
 		UpdatedAt:   time.Now(),
+		StartAt:     input.CreatedAt,
+		EndAt:       input.CreatedAt,
+		Timezone:    input.Timezone,
+		ExternalID:  input.ExternalID,
+	}
+
+	if input.StartAt != nil || input.EndAt != nil {
+		if err := setManualDates(album, input.StartAt, input.EndAt); err != nil {
+			return nil, err
+		}
 	}
 
+	s.resolvePlace(album)
+
 	if err := s.albumDAO.Create(album); err != nil {
 		return nil, fmt.Errorf("failed to create album: %w", err)
 	}
+	s.placeService.tagAlbumWithPlaces(album)
 
+	localizeAlbum(album)
 	return album, nil
 }
 
@@ -75,34 +128,35 @@ func (s *AlbumService) GetAlbumsByUserID(userID string) ([]model.Album, error) {
 		return nil, fmt.Errorf("failed to get albums: %w", err)
 	}
 
-	// Add photo count for each album
 	for i := range albums {
-		photos, err := s.photoDAO.GetByAlbumID(albums[i].ID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get photo count for album %s: %w", albums[i].ID, err)
-		}
-		albums[i].PhotoCount = len(photos)
+		localizeAlbum(&albums[i])
+	}
+	return albums, nil
+}
+
+// GetAlbumsFiltered retrieves a user's albums matching filter
+func (s *AlbumService) GetAlbumsFiltered(userID string, filter model.AlbumFilter) ([]model.Album, error) {
+	albums, err := s.albumDAO.GetByUserIDFiltered(userID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get albums: %w", err)
 	}
 
+	for i := range albums {
+		localizeAlbum(&albums[i])
+	}
 	return albums, nil
 }
 
-// GetAlbumsByUserIDAndTimeRange retrieves albums for a user within a time range
+// GetAlbumsByUserIDAndTimeRange retrieves albums for a user whose date range overlaps the given window
 func (s *AlbumService) GetAlbumsByUserIDAndTimeRange(userID string, startDate, endDate *time.Time) ([]model.Album, error) {
 	albums, err := s.albumDAO.GetByUserIDAndTimeRange(userID, startDate, endDate)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get albums by time range: %w", err)
 	}
 
-	// Add photo count for each album
 	for i := range albums {
-		photos, err := s.photoDAO.GetByAlbumID(albums[i].ID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get photo count for album %s: %w", albums[i].ID, err)
-		}
-		albums[i].PhotoCount = len(photos)
+		localizeAlbum(&albums[i])
 	}
-
 	return albums, nil
 }
 
@@ -126,48 +180,178 @@ func (s *AlbumService) GetAlbumByID(id, userID string) (*model.Album, error) {
 	}
 	album.Photos = photos
 	album.PhotoCount = len(photos)
+	if album.CoverPhotoID == "" {
+		album.CoverPhotoID = autoCoverPhotoID(photos)
+	}
+	localizeAlbum(album)
 
 	return album, nil
 }
 
-// UpdateAlbum updates an album
-func (s *AlbumService) UpdateAlbum(id, userID, title, description string) (*model.Album, error) {
-	// Validate and sanitize input
-	title = s.sanitizer.SanitizeString(title)
-	description = s.sanitizer.SanitizeString(description)
-	
-	// Validate input
-	if title != "" && !s.sanitizer.ValidateAlbumTitle(title) {
-		return nil, fmt.Errorf("invalid album title: must be 1-200 characters")
+// autoCoverPhotoID picks the cover of an album without an explicit one: the highest
+// rated photo, favorites first, then display order. Mirrors the album listing query.
+func autoCoverPhotoID(photos []model.Photo) string {
+	var cover *model.Photo
+	for i := range photos {
+		photo := &photos[i]
+		if cover == nil || photo.Rating > cover.Rating ||
+			(photo.Rating == cover.Rating && photo.Favorite && !cover.Favorite) {
+			cover = photo
+		}
 	}
-	
-	if !s.sanitizer.ValidateAlbumDescription(description) {
-		return nil, fmt.Errorf("invalid album description: must be max 2000 characters")
+	if cover == nil {
+		return ""
 	}
-	
-	// Check for SQL injection patterns
-	if s.sanitizer.DetectSQLInjection(title) || s.sanitizer.DetectSQLInjection(description) {
-		return nil, fmt.Errorf("invalid input: contains prohibited characters")
+	return cover.ID
+}
+
+// resolvePlace fills in an album's place names, and its time zone when unset, from
+// its coordinates. Geocoding failures are logged and leave the album unresolved.
+func (s *AlbumService) resolvePlace(album *model.Album) {
+	if err := s.geocodeService.ResolveAlbumPlace(album); err != nil {
+		logging.WithError(err).WithField("album_id", album.ID).Warn("Failed to resolve album place")
 	}
+}
+
+// derivedTimezone reports whether an album's time zone is the one its location resolves to
+func (s *AlbumService) derivedTimezone(album *model.Album) bool {
+	if album.Timezone == "" {
+		return false
+	}
+	place, err := s.geocodeService.Reverse(album.Latitude, album.Longitude)
+	return err == nil && place != nil && place.Timezone == album.Timezone
+}
+
+// AlbumUpdate holds the fields of a partial album update; nil fields are left untouched
+type AlbumUpdate struct {
+	Title        *string
+	Description  *string
+	Latitude     *float64
+	Longitude    *float64
+	CreatedAt    *time.Time
+	StartAt      *time.Time
+	EndAt        *time.Time
+	AutoDates    bool        // re-derive the date range from photo capture times
+	Timezone     *string     // empty string re-derives the time zone from the location
+	CoverPhotoID *string     // empty string clears the explicit cover
+	Datum        datum.Datum // datum of Latitude/Longitude; stored as WGS-84
+}
 
+// UpdateAlbum applies a partial update to an album
+func (s *AlbumService) UpdateAlbum(id, userID string, update AlbumUpdate) (*model.Album, error) {
 	// First check if album exists and belongs to user
-	album, err := s.GetAlbumByID(id, userID)
+	album, err := s.albumDAO.GetByID(id)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get album: %w", err)
+	}
+	if album == nil {
+		return nil, ErrAlbumNotFound
+	}
+	if album.UserID != userID {
+		return nil, ErrAlbumAccessDenied
 	}
 
-	// Update fields
-	if title != "" {
+	if update.Title != nil {
+		title := s.sanitizer.SanitizeString(*update.Title)
+		if !s.sanitizer.ValidateAlbumTitle(title) {
+			return nil, invalidAlbum("invalid album title: must be 1-200 characters")
+		}
+		if s.sanitizer.DetectSQLInjection(title) {
+			return nil, invalidAlbum("invalid input: contains prohibited characters")
+		}
 		album.Title = title
 	}
-	album.Description = description
+
+	if update.Description != nil {
+		description := s.sanitizer.SanitizeString(*update.Description)
+		if !s.sanitizer.ValidateAlbumDescription(description) {
+			return nil, invalidAlbum("invalid album description: must be max 2000 characters")
+		}
+		if s.sanitizer.DetectSQLInjection(description) {
+			return nil, invalidAlbum("invalid input: contains prohibited characters")
+		}
+		album.Description = description
+	}
+
+	previous := *album
+	if update.Latitude != nil || update.Longitude != nil {
+		// A single coordinate is combined with the other one as seen in the input datum
+		latitude, longitude := datum.FromWGS84(album.Latitude, album.Longitude, update.Datum)
+		if update.Latitude != nil {
+			latitude = *update.Latitude
+		}
+		if update.Longitude != nil {
+			longitude = *update.Longitude
+		}
+		if !s.sanitizer.ValidateCoordinates(latitude, longitude) {
+			return nil, invalidAlbum("invalid coordinates: latitude must be -90 to 90, longitude must be -180 to 180")
+		}
+		album.Latitude, album.Longitude = datum.ToWGS84(latitude, longitude, update.Datum)
+	}
+	moved := album.Latitude != previous.Latitude || album.Longitude != previous.Longitude
+
+	if update.CreatedAt != nil {
+		if update.CreatedAt.IsZero() {
+			return nil, invalidAlbum("invalid album date: created_at must not be empty")
+		}
+		album.CreatedAt = *update.CreatedAt
+	}
+
+	if update.Timezone != nil {
+		if _, err := loadTimezone(*update.Timezone); err != nil {
+			return nil, invalidInput(ErrInvalidAlbum, err)
+		}
+		album.Timezone = *update.Timezone
+	} else if moved && s.derivedTimezone(&previous) {
+		// The time zone came from the old location; let the new one replace it
+		album.Timezone = ""
+	}
+	if moved || album.Timezone == "" {
+		s.resolvePlace(album)
+	}
+
+	if update.StartAt != nil || update.EndAt != nil {
+		if update.AutoDates {
+			return nil, invalidAlbum("invalid album dates: start_at/end_at cannot be combined with auto_dates")
+		}
+		if err := setManualDates(album, update.StartAt, update.EndAt); err != nil {
+			return nil, invalidInput(ErrInvalidAlbum, err)
+		}
+	} else if update.AutoDates {
+		album.DatesManual = false
+	}
+	if !album.DatesManual {
+		// Keep the range valid until RefreshDateRange re-derives it below
+		album.StartAt = album.CreatedAt
+		album.EndAt = album.CreatedAt
+	}
+
+	if update.CoverPhotoID != nil {
+		if *update.CoverPhotoID != "" {
+			photo, err := s.photoDAO.GetByID(*update.CoverPhotoID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get cover photo: %w", err)
+			}
+			if photo == nil || photo.AlbumID != album.ID {
+				return nil, invalidAlbum("invalid cover photo: photo does not belong to album")
+			}
+		}
+		album.CoverPhotoID = *update.CoverPhotoID
+	}
+
 	album.UpdatedAt = time.Now()
 
 	if err := s.albumDAO.Update(album); err != nil {
 		return nil, fmt.Errorf("failed to update album: %w", err)
 	}
+	if err := s.albumDAO.RefreshDateRange(album.ID); err != nil {
+		return nil, fmt.Errorf("failed to update album: %w", err)
+	}
+	if moved {
+		s.placeService.tagAlbumWithPlaces(album)
+	}
 
-	return album, nil
+	return s.GetAlbumByID(id, userID)
 }
 
 // DeleteAlbum deletes an album and its associated photo files
@@ -191,4 +375,57 @@ func (s *AlbumService) DeleteAlbum(id, userID string) error {
 	}
 
 	return nil
+}
+
+// setManualDates pins an album's date range to the given bounds; a missing bound
+// takes the value of the other one
+func setManualDates(album *model.Album, startAt, endAt *time.Time) error {
+	start, end := album.StartAt, album.EndAt
+	if startAt != nil {
+		start = *startAt
+	}
+	if endAt != nil {
+		end = *endAt
+	}
+	if startAt != nil && endAt == nil && end.Before(start) {
+		end = start
+	}
+	if endAt != nil && startAt == nil && start.After(end) {
+		start = end
+	}
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return fmt.Errorf("invalid album dates: end_at must not be before start_at")
+	}
+
+	album.StartAt = start
+	album.EndAt = end
+	album.DatesManual = true
+	return nil
+}
+
+// loadTimezone validates an IANA time zone name; the empty name means no zone
+func loadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return nil, nil
+	}
+	if name == "Local" {
+		return nil, fmt.Errorf("invalid timezone: %s", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone: %s", name)
+	}
+	return loc, nil
+}
+
+// localizeAlbum expresses an album's dates in the time zone of its location
+// so that clients display local dates
+func localizeAlbum(album *model.Album) {
+	loc, err := loadTimezone(album.Timezone)
+	if err != nil || loc == nil {
+		return
+	}
+	album.CreatedAt = album.CreatedAt.In(loc)
+	album.StartAt = album.StartAt.In(loc)
+	album.EndAt = album.EndAt.In(loc)
 }
\ No newline at end of file
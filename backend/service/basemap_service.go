@@ -0,0 +1,60 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"geoalbum/backend/basemap"
+	"geoalbum/backend/model"
+)
+
+// ErrBasemapNotFound is returned for a basemap name that is not loaded
+var ErrBasemapNotFound = errors.New("basemap not found")
+
+// BasemapTile is an encoded tile of an offline basemap
+type BasemapTile struct {
+	Data        []byte
+	ContentType string
+	Gzipped     bool
+}
+
+type BasemapService struct{}
+
+func NewBasemapService() *BasemapService {
+	return &BasemapService{}
+}
+
+// ListBasemaps returns the offline basemaps loaded from BASEMAP_DIR
+func (s *BasemapService) ListBasemaps() []model.Basemap {
+	sources := basemap.All()
+	basemaps := make([]model.Basemap, len(sources))
+	for i, source := range sources {
+		basemaps[i] = source.Basemap
+	}
+	return basemaps
+}
+
+// GetTile returns tile z/x/y of the named basemap, or nil when the tile set has no
+// tile there
+func (s *BasemapService) GetTile(name string, z, x, y int) (*BasemapTile, error) {
+	source := basemap.Get(name)
+	if source == nil {
+		return nil, ErrBasemapNotFound
+	}
+	if z < source.MinZoom || z > source.MaxZoom {
+		return nil, nil
+	}
+	if n := 1 << uint(z); x >= n || y >= n {
+		return nil, fmt.Errorf("invalid tile: %d/%d/%d", z, x, y)
+	}
+
+	data, err := source.Tile(z, x, y)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	return &BasemapTile{
+		Data:        data,
+		ContentType: source.ContentType(),
+		Gzipped:     basemap.IsGzipped(data),
+	}, nil
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	"geoalbum/backend/database"
+	"geoalbum/backend/logging"
+)
+
+var initTestLogger sync.Once
+
+// setupTestDB points the global database at an empty one private to the test
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	initTestLogger.Do(func() {
+		_ = logging.InitializeGlobalLogger(&logging.LogConfig{Level: logging.ErrorLevel, Format: "text", Output: "stdout"})
+	})
+
+	db, err := sqlx.Open("sqlite", filepath.Join(t.TempDir(), "test.db")+"?_pragma=foreign_keys(1)")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, database.Setup(db))
+}
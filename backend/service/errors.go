@@ -0,0 +1,17 @@
+package service
+
+// invalidInputError describes invalid input; it matches the sentinel for its kind
+// of input, such as ErrInvalidAlbum
+type invalidInputError struct {
+	kind error
+	err  error
+}
+
+func (e *invalidInputError) Error() string { return e.err.Error() }
+
+func (e *invalidInputError) Is(target error) bool { return target == e.kind }
+
+// invalidInput marks err as invalid input of the given kind
+func invalidInput(kind, err error) error {
+	return &invalidInputError{kind: kind, err: err}
+}
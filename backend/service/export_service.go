@@ -0,0 +1,431 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"geoalbum/backend/dao"
+	"geoalbum/backend/geojson"
+	"geoalbum/backend/gpx"
+	"geoalbum/backend/kml"
+	"geoalbum/backend/model"
+	"geoalbum/backend/polyline"
+)
+
+// Map export formats, as accepted by the format query parameter
+const (
+	MapFormatGeoJSON = "geojson"
+	MapFormatKML     = "kml"
+	MapFormatGPX     = "gpx"
+)
+
+type ExportService struct {
+	albumDAO *dao.AlbumDAO
+	pathDAO  *dao.PathDAO
+	tripDAO  *dao.TripDAO
+}
+
+func NewExportService() *ExportService {
+	return &ExportService{
+		albumDAO: dao.NewAlbumDAO(),
+		pathDAO:  dao.NewPathDAO(),
+		tripDAO:  dao.NewTripDAO(),
+	}
+}
+
+// MapExportOptions selects what a map export contains
+type MapExportOptions struct {
+	Format    string
+	StartDate *time.Time // albums whose dates overlap the range, with the paths between them
+	EndDate   *time.Time
+	TripID    string // only this trip, its stops and its legs
+	BaseURL   string // scheme and host that cover photo URLs are made absolute with
+}
+
+// mapExport is the selection of a user's library written by a map export
+type mapExport struct {
+	albums []model.Album
+	paths  []model.Path
+	trips  []exportTrip
+}
+
+// exportTrip is a trip with its stop albums in order and the line along its legs
+type exportTrip struct {
+	trip  model.Trip
+	stops []*model.Album
+	line  []polyline.Point
+}
+
+// ExportMap writes the user's albums as points and their paths and trips as lines in
+// the requested format. Coordinates are always WGS-84. Trips are exported whole when
+// any of their stops is selected.
+func (s *ExportService) ExportMap(userID string, options MapExportOptions, w io.Writer) error {
+	export, err := s.collect(userID, options)
+	if err != nil {
+		return err
+	}
+
+	switch options.Format {
+	case MapFormatKML:
+		return kml.Encode(w, export.kml(options.BaseURL))
+	case MapFormatGPX:
+		return gpx.Encode(w, export.gpx(), "GeoAlbum")
+	default:
+		if err := json.NewEncoder(w).Encode(export.geoJSON(options.BaseURL)); err != nil {
+			return fmt.Errorf("failed to write GeoJSON: %w", err)
+		}
+		return nil
+	}
+}
+
+// collect gathers the albums, paths and trips selected by options
+func (s *ExportService) collect(userID string, options MapExportOptions) (*mapExport, error) {
+	all, err := s.albumDAO.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get albums: %w", err)
+	}
+	albumsByID := make(map[string]*model.Album, len(all))
+	for i := range all {
+		localizeAlbum(&all[i])
+		albumsByID[all[i].ID] = &all[i]
+	}
+
+	var trips []model.Trip
+	selected := make(map[string]bool)
+	if options.TripID != "" {
+		trip, err := s.tripDAO.GetByID(options.TripID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get trip: %w", err)
+		}
+		if trip == nil || trip.UserID != userID {
+			return nil, ErrTripNotFound
+		}
+		trips = []model.Trip{*trip}
+	} else {
+		albums := all
+		if options.StartDate != nil || options.EndDate != nil {
+			if albums, err = s.albumDAO.GetByUserIDAndTimeRange(userID, options.StartDate, options.EndDate); err != nil {
+				return nil, fmt.Errorf("failed to get albums: %w", err)
+			}
+		}
+		for _, album := range albums {
+			selected[album.ID] = true
+		}
+		if trips, err = s.tripDAO.GetByUserID(userID, ""); err != nil {
+			return nil, fmt.Errorf("failed to get trips: %w", err)
+		}
+	}
+
+	paths, err := s.pathDAO.GetByUserIDWithAlbums(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get paths: %w", err)
+	}
+	pathsByEnds := make(map[[2]string]*model.Path, len(paths))
+	for i := range paths {
+		localizeAlbum(paths[i].FromAlbum)
+		localizeAlbum(paths[i].ToAlbum)
+		setPathGeometry(&paths[i])
+		pathsByEnds[[2]string{paths[i].FromAlbumID, paths[i].ToAlbumID}] = &paths[i]
+	}
+
+	export := &mapExport{}
+	legs := make(map[string]bool)
+	for _, trip := range trips {
+		stops, err := s.tripDAO.GetStops(trip.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get trip stops: %w", err)
+		}
+		t := exportTrip{trip: trip}
+		include := options.TripID != ""
+		for _, stop := range stops {
+			if album, ok := albumsByID[stop.AlbumID]; ok {
+				t.stops = append(t.stops, album)
+				include = include || selected[album.ID]
+			}
+		}
+		if !include {
+			continue
+		}
+
+		for i := 1; i < len(t.stops); i++ {
+			from, to := t.stops[i-1], t.stops[i]
+			leg := []polyline.Point{{Lat: from.Latitude, Lng: from.Longitude}, {Lat: to.Latitude, Lng: to.Longitude}}
+			if path, ok := pathsByEnds[[2]string{from.ID, to.ID}]; ok {
+				leg = pathLine(path)
+				legs[path.ID] = true
+			}
+			if len(t.line) > 0 {
+				leg = leg[1:]
+			}
+			t.line = append(t.line, leg...)
+		}
+		t.trip.DistanceKm = roundKm(polyline.LengthKm(t.line))
+		export.trips = append(export.trips, t)
+		if options.TripID != "" {
+			for _, album := range t.stops {
+				selected[album.ID] = true
+			}
+		}
+	}
+
+	for _, album := range all {
+		if selected[album.ID] {
+			export.albums = append(export.albums, album)
+		}
+	}
+	for _, path := range paths {
+		if options.TripID != "" && !legs[path.ID] {
+			continue
+		}
+		if selected[path.FromAlbumID] && selected[path.ToAlbumID] {
+			export.paths = append(export.paths, path)
+		}
+	}
+	return export, nil
+}
+
+// coverURL returns the absolute URL of an album's cover photo, or "" when it has none
+func coverURL(album *model.Album, baseURL string) string {
+	if album.CoverPhotoID == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/api/photos/%s/file", baseURL, album.CoverPhotoID)
+}
+
+// pathName names a path after the albums it joins
+func pathName(path *model.Path) string {
+	return path.FromAlbum.Title + " → " + path.ToAlbum.Title
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func (export *mapExport) geoJSON(baseURL string) *geojson.FeatureCollection {
+	fc := geojson.NewFeatureCollection()
+	for i := range export.albums {
+		album := &export.albums[i]
+		properties := map[string]interface{}{
+			"kind":        "album",
+			"title":       album.Title,
+			"description": album.Description,
+			"start_at":    formatTime(&album.StartAt),
+			"end_at":      formatTime(&album.EndAt),
+			"photo_count": album.PhotoCount,
+		}
+		if cover := coverURL(album, baseURL); cover != "" {
+			properties["cover_url"] = cover
+		}
+		if album.Country != "" {
+			properties["country"] = album.Country
+		}
+		if album.City != "" {
+			properties["city"] = album.City
+		}
+		fc.Add(album.ID, geojson.NewPoint(album.Latitude, album.Longitude), properties)
+	}
+	for i := range export.paths {
+		path := &export.paths[i]
+		properties := map[string]interface{}{
+			"kind":           "path",
+			"title":          pathName(path),
+			"from_album_id":  path.FromAlbumID,
+			"to_album_id":    path.ToAlbumID,
+			"transport_mode": path.TransportMode,
+			"distance_km":    path.DistanceKm,
+			"depart_at":      formatTime(path.DepartAt),
+			"arrive_at":      formatTime(path.ArriveAt),
+			"notes":          path.Notes,
+			"interpolated":   path.Interpolated,
+		}
+		if path.DurationMinutes != nil {
+			properties["duration_minutes"] = *path.DurationMinutes
+		}
+		fc.Add(path.ID, geojson.NewLine(pathLine(path)), properties)
+	}
+	for _, t := range export.trips {
+		albumIDs := make([]string, len(t.stops))
+		for i, album := range t.stops {
+			albumIDs[i] = album.ID
+		}
+		properties := map[string]interface{}{
+			"kind":        "trip",
+			"title":       t.trip.Title,
+			"description": t.trip.Description,
+			"start_at":    formatTime(t.trip.StartAt),
+			"end_at":      formatTime(t.trip.EndAt),
+			"stop_count":  len(t.stops),
+			"distance_km": t.trip.DistanceKm,
+			"album_ids":   albumIDs,
+		}
+		var geometry *geojson.Geometry
+		if len(t.line) >= 2 {
+			geometry = geojson.NewLine(t.line)
+		} else if len(t.stops) == 1 {
+			geometry = geojson.NewPoint(t.stops[0].Latitude, t.stops[0].Longitude)
+		}
+		fc.Add(t.trip.ID, geometry, properties)
+	}
+	return fc
+}
+
+// Line colours of paths by transport mode, as KML aabbggrr
+var transportColors = map[string]string{
+	model.TransportWalk:   "ff3c9d2e",
+	model.TransportCar:    "ff2b6be6",
+	model.TransportTrain:  "ffb05a8e",
+	model.TransportFlight: "ffd98a2b",
+	model.TransportFerry:  "ffa3a317",
+}
+
+const (
+	albumIconHref  = "https://maps.google.com/mapfiles/kml/paddle/red-circle.png"
+	pathColor      = "ff7f7f7f"
+	tripColor      = "ff0080ff"
+	tripLineWidth  = 4
+	pathLineWidth  = 2.5
+	albumIconScale = 1.1
+)
+
+func (export *mapExport) kml(baseURL string) *kml.KML {
+	doc := kml.Document{
+		Name: "GeoAlbum",
+		Styles: []kml.Style{
+			{ID: "album", IconStyle: &kml.IconStyle{Scale: albumIconScale, Icon: &kml.Icon{Href: albumIconHref}}},
+			{ID: "path", LineStyle: &kml.LineStyle{Color: pathColor, Width: pathLineWidth}},
+			{ID: "trip", LineStyle: &kml.LineStyle{Color: tripColor, Width: tripLineWidth}},
+		},
+	}
+	for _, mode := range model.TransportModes {
+		doc.Styles = append(doc.Styles, kml.Style{
+			ID:        "path-" + mode,
+			LineStyle: &kml.LineStyle{Color: transportColors[mode], Width: pathLineWidth},
+		})
+	}
+
+	albums := kml.Folder{Name: "Albums"}
+	for i := range export.albums {
+		albums.Placemarks = append(albums.Placemarks, albumPlacemark(&export.albums[i], baseURL))
+	}
+	paths := kml.Folder{Name: "Paths"}
+	for i := range export.paths {
+		paths.Placemarks = append(paths.Placemarks, pathPlacemark(&export.paths[i]))
+	}
+	trips := kml.Folder{Name: "Trips"}
+	for _, t := range export.trips {
+		folder := kml.Folder{Name: t.trip.Title, Description: t.trip.Description}
+		if len(t.line) >= 2 {
+			line := kml.Placemark{
+				ID:          t.trip.ID,
+				Name:        t.trip.Title,
+				Description: t.trip.Description,
+				StyleURL:    "#trip",
+			}
+			if t.trip.StartAt != nil || t.trip.EndAt != nil {
+				line.TimeSpan = &kml.TimeSpan{Begin: formatTime(t.trip.StartAt), End: formatTime(t.trip.EndAt)}
+			}
+			line.SetLine(t.line)
+			line.AddData("stop_count", strconv.Itoa(len(t.stops)))
+			line.AddData("distance_km", strconv.FormatFloat(t.trip.DistanceKm, 'f', -1, 64))
+			folder.Placemarks = append(folder.Placemarks, line)
+		}
+		for i, album := range t.stops {
+			stop := albumPlacemark(album, baseURL)
+			// Placemark IDs must be unique, so stops name their album in data instead
+			stop.ID = ""
+			stop.Name = fmt.Sprintf("%d. %s", i+1, album.Title)
+			stop.AddData("album_id", album.ID)
+			folder.Placemarks = append(folder.Placemarks, stop)
+		}
+		trips.Folders = append(trips.Folders, folder)
+	}
+
+	doc.Folders = []kml.Folder{albums, paths, trips}
+	return &kml.KML{Document: doc}
+}
+
+func albumPlacemark(album *model.Album, baseURL string) kml.Placemark {
+	var description strings.Builder
+	if cover := coverURL(album, baseURL); cover != "" {
+		fmt.Fprintf(&description, `<img src="%s" width="240"/>`, html.EscapeString(cover))
+	}
+	if album.Description != "" {
+		// Descriptions are stored HTML-escaped already
+		fmt.Fprintf(&description, "<p>%s</p>", html.EscapeString(html.UnescapeString(album.Description)))
+	}
+	fmt.Fprintf(&description, "<p>%d photos</p>", album.PhotoCount)
+
+	placemark := kml.Placemark{
+		ID:          album.ID,
+		Name:        album.Title,
+		Description: description.String(),
+		StyleURL:    "#album",
+		Point:       kml.NewPoint(album.Latitude, album.Longitude),
+	}
+	if !album.StartAt.IsZero() {
+		placemark.TimeSpan = &kml.TimeSpan{Begin: formatTime(&album.StartAt), End: formatTime(&album.EndAt)}
+	}
+	placemark.AddData("photo_count", strconv.Itoa(album.PhotoCount))
+	if cover := coverURL(album, baseURL); cover != "" {
+		placemark.AddData("cover_url", cover)
+	}
+	return placemark
+}
+
+func pathPlacemark(path *model.Path) kml.Placemark {
+	style := "#path"
+	if path.TransportMode != "" {
+		style = "#path-" + path.TransportMode
+	}
+	placemark := kml.Placemark{
+		ID:          path.ID,
+		Name:        pathName(path),
+		Description: path.Notes,
+		StyleURL:    style,
+	}
+	if path.DepartAt != nil || path.ArriveAt != nil {
+		placemark.TimeSpan = &kml.TimeSpan{Begin: formatTime(path.DepartAt), End: formatTime(path.ArriveAt)}
+	}
+	placemark.SetLine(pathLine(path))
+	if path.TransportMode != "" {
+		placemark.AddData("transport_mode", path.TransportMode)
+	}
+	placemark.AddData("distance_km", strconv.FormatFloat(path.DistanceKm, 'f', -1, 64))
+	return placemark
+}
+
+func (export *mapExport) gpx() *gpx.File {
+	file := &gpx.File{}
+	for _, album := range export.albums {
+		waypoint := gpx.Point{Lat: album.Latitude, Lng: album.Longitude, Name: album.Title, Description: album.Description}
+		if !album.StartAt.IsZero() {
+			start := album.StartAt
+			waypoint.Time = &start
+		}
+		file.Waypoints = append(file.Waypoints, waypoint)
+	}
+	for i := range export.paths {
+		path := &export.paths[i]
+		track := gpx.Line{Name: pathName(path)}
+		for _, p := range pathLine(path) {
+			track.Points = append(track.Points, gpx.Point{Lat: p.Lat, Lng: p.Lng})
+		}
+		file.Tracks = append(file.Tracks, track)
+	}
+	for _, t := range export.trips {
+		route := gpx.Line{Name: t.trip.Title}
+		for _, album := range t.stops {
+			route.Points = append(route.Points, gpx.Point{Lat: album.Latitude, Lng: album.Longitude, Name: album.Title})
+		}
+		file.Routes = append(file.Routes, route)
+	}
+	return file
+}
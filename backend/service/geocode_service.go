@@ -0,0 +1,241 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"geoalbum/backend/dao"
+	"geoalbum/backend/geocode"
+	"geoalbum/backend/logging"
+	"geoalbum/backend/model"
+)
+
+// maxReverseDistanceKm bounds how far the nearest gazetteer place may be from a
+// point for reverse geocoding to attribute the point to it
+const maxReverseDistanceKm = 250
+
+type GeocodeService struct {
+	gazetteerDAO *dao.GazetteerDAO
+	albumDAO     *dao.AlbumDAO
+}
+
+func NewGeocodeService() *GeocodeService {
+	return &GeocodeService{
+		gazetteerDAO: dao.NewGazetteerDAO(),
+		albumDAO:     dao.NewAlbumDAO(),
+	}
+}
+
+// EnsureGazetteer loads the gazetteer into the database if the configured dataset
+// differs from the loaded one, then re-resolves the places of all albums.
+// GEONAMES_DIR selects a GeoNames dump; otherwise the bundled gazetteer is used.
+func (s *GeocodeService) EnsureGazetteer() error {
+	dataset, err := geocode.Load(os.Getenv("GEONAMES_DIR"))
+	if err != nil {
+		return fmt.Errorf("failed to load gazetteer dataset: %w", err)
+	}
+
+	loaded, err := s.gazetteerDAO.GetVersion()
+	if err != nil {
+		return err
+	}
+	if loaded == dataset.Version {
+		return nil
+	}
+
+	if err := s.gazetteerDAO.ReplaceAll(dataset.Places, dataset.Version); err != nil {
+		return err
+	}
+	logging.WithFields(map[string]interface{}{
+		"version": dataset.Version,
+		"places":  len(dataset.Places),
+	}).Info("Gazetteer loaded")
+
+	return s.BackfillAlbumPlaces()
+}
+
+// BackfillAlbumPlaces resolves the place names of every album. Album time zones
+// that are already set are kept.
+func (s *GeocodeService) BackfillAlbumPlaces() error {
+	albums, err := s.albumDAO.GetAllLocations()
+	if err != nil {
+		return err
+	}
+
+	for i := range albums {
+		album := &albums[i]
+		if err := s.ResolveAlbumPlace(album); err != nil {
+			return err
+		}
+		if err := s.albumDAO.UpdatePlace(album); err != nil {
+			return err
+		}
+	}
+
+	logging.WithField("album_count", len(albums)).Info("Album places backfilled")
+	return nil
+}
+
+// ResolveAlbumPlace sets an album's country, region and city from its coordinates,
+// clearing them when no place is near. An empty time zone is filled in from the place.
+func (s *GeocodeService) ResolveAlbumPlace(album *model.Album) error {
+	place, err := s.Reverse(album.Latitude, album.Longitude)
+	if err != nil {
+		return err
+	}
+
+	if place == nil {
+		album.CountryCode, album.Country, album.Region, album.City = "", "", "", ""
+		return nil
+	}
+
+	album.CountryCode = place.CountryCode
+	album.Country = place.Country
+	album.Region = place.Region
+	album.City = place.Name
+	if album.Timezone == "" {
+		album.Timezone = place.Timezone
+	}
+	return nil
+}
+
+// Reverse returns the gazetteer place nearest to a point, or nil when none lies
+// within maxReverseDistanceKm
+func (s *GeocodeService) Reverse(lat, lng float64) (*model.GazetteerPlace, error) {
+	latDelta, lngDelta := geocode.BoundingBox(lat, maxReverseDistanceKm)
+	minLng, maxLng := wrapLongitude(lng-lngDelta), wrapLongitude(lng+lngDelta)
+	if lngDelta >= 180 {
+		minLng, maxLng = -180, 180
+	}
+
+	places, err := s.gazetteerDAO.GetWithin(lat-latDelta, lat+latDelta, minLng, maxLng)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reverse geocode: %w", err)
+	}
+
+	var nearest *model.GazetteerPlace
+	nearestDistance := math.Inf(1)
+	for i := range places {
+		distance := geocode.DistanceKm(lat, lng, places[i].Latitude, places[i].Longitude)
+		if distance < nearestDistance {
+			nearest, nearestDistance = &places[i], distance
+		}
+	}
+	if nearestDistance > maxReverseDistanceKm {
+		return nil, nil
+	}
+	return nearest, nil
+}
+
+// geocodeCandidateLimit bounds how many name matches are ranked per query
+const geocodeCandidateLimit = 200
+
+// GeocodeQuery is a forward geocoding request
+type GeocodeQuery struct {
+	Text     string
+	Limit    int
+	Lang     string             // "zh" for Chinese display names, otherwise English
+	Viewport *model.BoundingBox // optional; places in or near it rank higher
+}
+
+// Search returns gazetteer places whose name in any language starts with the query
+// text. Results are ranked by population, exact name matches and, when a viewport is
+// given, closeness to it.
+func (s *GeocodeService) Search(query GeocodeQuery) ([]model.GeocodeResult, error) {
+	text := strings.TrimSpace(query.Text)
+	if text == "" {
+		return []model.GeocodeResult{}, nil
+	}
+
+	results, err := s.gazetteerDAO.SearchByName(text, nil, geocodeCandidateLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to geocode: %w", err)
+	}
+	if query.Viewport != nil {
+		// Small places in view would not make it among the most populous matches
+		// worldwide, so they are looked up on their own
+		inView, err := s.gazetteerDAO.SearchByName(text, query.Viewport, geocodeCandidateLimit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to geocode: %w", err)
+		}
+		seen := make(map[int64]bool, len(results))
+		for _, result := range results {
+			seen[result.ID] = true
+		}
+		for _, result := range inView {
+			if !seen[result.ID] {
+				results = append(results, result)
+			}
+		}
+	}
+
+	for i := range results {
+		result := &results[i]
+		result.Score = geocodeScore(result, text, query.Viewport)
+		result.DisplayName = displayName(&result.GazetteerPlace, query.Lang)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	if query.Limit > 0 && len(results) > query.Limit {
+		results = results[:query.Limit]
+	}
+	return results, nil
+}
+
+// geocodeScore ranks a match. Population contributes up to ~7 points (log10), an
+// exact name match 3, and a viewport up to 4: full marks inside it, decaying with
+// distance from its centre relative to its size.
+func geocodeScore(result *model.GeocodeResult, text string, viewport *model.BoundingBox) float64 {
+	score := math.Log10(float64(result.Population) + 10)
+	if strings.EqualFold(result.MatchedName, text) {
+		score += 3
+	}
+
+	if viewport != nil {
+		if viewport.Contains(result.Latitude, result.Longitude) {
+			score += 4
+		} else {
+			centerLat, centerLng := viewport.Center()
+			size := geocode.DistanceKm(viewport.MinLat, viewport.MinLng, viewport.MaxLat, viewport.MaxLng)
+			distance := geocode.DistanceKm(centerLat, centerLng, result.Latitude, result.Longitude)
+			score += 4 * math.Exp(-distance/math.Max(size, 1))
+		}
+	}
+	return score
+}
+
+// displayName formats "city, region, country" in the requested language
+func displayName(place *model.GazetteerPlace, lang string) string {
+	if lang == "zh" {
+		name, country := place.NameZh, place.CountryZh
+		if name == "" {
+			name = place.Name
+		}
+		if country == "" {
+			country = place.Country
+		}
+		if country == "" || country == name {
+			return name
+		}
+		return name + ", " + country
+	}
+
+	parts := []string{place.Name}
+	if place.Region != "" && place.Region != place.Name {
+		parts = append(parts, place.Region)
+	}
+	if place.Country != "" && place.Country != place.Name {
+		parts = append(parts, place.Country)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// wrapLongitude maps a longitude onto [-180, 180]; infinities become NaN
+func wrapLongitude(lng float64) float64 {
+	return math.Remainder(lng, 360)
+}
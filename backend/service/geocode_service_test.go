@@ -0,0 +1,129 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"geoalbum/backend/dao"
+	"geoalbum/backend/model"
+)
+
+// setupGazetteerDB opens an isolated database holding places as the gazetteer
+func setupGazetteerDB(t *testing.T, places []model.GazetteerPlace) {
+	t.Helper()
+	setupTestDB(t)
+	require.NoError(t, dao.NewGazetteerDAO().ReplaceAll(places, "test"))
+}
+
+func resultNames(results []model.GeocodeResult) []string {
+	names := make([]string, len(results))
+	for i, result := range results {
+		names[i] = result.Name
+	}
+	return names
+}
+
+func TestSearchRanking(t *testing.T) {
+	var places []model.GazetteerPlace
+	// More large places sharing the prefix than are ranked per query
+	for i := 0; i < geocodeCandidateLimit+50; i++ {
+		places = append(places, model.GazetteerPlace{
+			Name: fmt.Sprintf("Springfield %03d", i), Latitude: 39.8, Longitude: -89.6,
+			CountryCode: "US", Population: int64(100000 + i),
+		})
+	}
+	places = append(places,
+		model.GazetteerPlace{Name: "Springvale", Latitude: -37.95, Longitude: 145.15, CountryCode: "AU", Population: 500},
+		model.GazetteerPlace{Name: "Spring", Latitude: 30.08, Longitude: -95.42, CountryCode: "US", Population: 50},
+		model.GazetteerPlace{Name: "Vienna", Latitude: 48.21, Longitude: 16.37, CountryCode: "AT", Population: 1900000,
+			AlternateNames: []string{"Viena", "Vienne", "Vienna International Centre"}},
+	)
+	setupGazetteerDB(t, places)
+	s := NewGeocodeService()
+
+	// Without a viewport the small town is outranked by the larger places
+	results, err := s.Search(GeocodeQuery{Text: "spring"})
+	require.NoError(t, err)
+	assert.NotContains(t, resultNames(results), "Springvale")
+	// but an exact name match is always a candidate
+	assert.Contains(t, resultNames(results), "Spring")
+
+	// A viewport over Melbourne brings the town in view back, at the top
+	melbourne := &model.BoundingBox{MinLat: -38.2, MinLng: 144.6, MaxLat: -37.6, MaxLng: 145.4}
+	results, err = s.Search(GeocodeQuery{Text: "spring", Limit: 5, Viewport: melbourne})
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+	assert.Equal(t, "Springvale", results[0].Name)
+
+	// The best matching name is an exact one, else the primary name
+	results, err = s.Search(GeocodeQuery{Text: "vien"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "Vienna", results[0].MatchedName)
+	results, err = s.Search(GeocodeQuery{Text: "VIENNE"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "Vienne", results[0].MatchedName)
+	results, err = s.Search(GeocodeQuery{Text: "viena"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "Viena", results[0].MatchedName)
+}
+
+func TestReverse(t *testing.T) {
+	setupGazetteerDB(t, []model.GazetteerPlace{
+		{Name: "Suva", Latitude: -18.1416, Longitude: 178.4419, CountryCode: "FJ", Population: 93970},
+		{Name: "Waiyevo", Latitude: -16.79, Longitude: -179.98, CountryCode: "FJ", Population: 1000},
+		{Name: "Isolated", Latitude: 0, Longitude: -150, CountryCode: "XX", Population: 10},
+	})
+	s := NewGeocodeService()
+
+	tests := []struct {
+		name     string
+		lat, lng float64
+		want     string // "" for no place
+	}{
+		{"near a place", -18.0, 178.5, "Suva"},
+		// Taveuni straddles the antimeridian; its nearest place is on the other side
+		{"west of the antimeridian", -16.8, 179.97, "Waiyevo"},
+		{"east of the antimeridian", -16.5, -179.5, "Waiyevo"},
+		{"just within range", 2.2, -150, "Isolated"},
+		{"just out of range", 2.3, -150, ""},
+		{"open ocean", -40, -130, ""},
+		{"near the pole", 89.9, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			place, err := s.Reverse(tt.lat, tt.lng)
+			require.NoError(t, err)
+			if tt.want == "" {
+				assert.Nil(t, place)
+				return
+			}
+			require.NotNil(t, place)
+			assert.Equal(t, tt.want, place.Name)
+		})
+	}
+}
+
+func TestReverseHighLatitude(t *testing.T) {
+	setupGazetteerDB(t, []model.GazetteerPlace{
+		{Name: "Ny-Ålesund", Latitude: 78.92, Longitude: 11.93, CountryCode: "SJ"},
+		{Name: "Camp", Latitude: 88.8, Longitude: -60, CountryCode: "CA"},
+	})
+	s := NewGeocodeService()
+
+	place, err := s.Reverse(79, 22)
+	require.NoError(t, err)
+	require.NotNil(t, place)
+	assert.Equal(t, "Ny-Ålesund", place.Name)
+
+	// Some 220 km away across the pole, on the opposite meridian
+	place, err = s.Reverse(89.2, 120)
+	require.NoError(t, err)
+	require.NotNil(t, place)
+	assert.Equal(t, "Camp", place.Name)
+}
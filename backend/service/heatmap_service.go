@@ -0,0 +1,89 @@
+package service
+
+import (
+	"fmt"
+	"math"
+
+	"geoalbum/backend/dao"
+	"geoalbum/backend/model"
+)
+
+// heatmapCellSizePx is the grid cell size in screen pixels at the requested zoom
+const heatmapCellSizePx = 32
+
+// maxMercatorLatitude is the latitude limit of the Web Mercator projection
+const maxMercatorLatitude = 85.05112878
+
+type HeatmapService struct {
+	photoDAO *dao.PhotoDAO
+}
+
+func NewHeatmapService() *HeatmapService {
+	return &HeatmapService{
+		photoDAO: dao.NewPhotoDAO(),
+	}
+}
+
+// GetHeatmap aggregates a user's photo locations inside bbox (everywhere when nil)
+// into Web Mercator grid cells of heatmapCellSizePx at zoom. Each cell is reported
+// at the weighted centroid of its photos.
+func (s *HeatmapService) GetHeatmap(userID string, bbox *model.BoundingBox, zoom int) (*model.Heatmap, error) {
+	if zoom < 0 || zoom > 22 {
+		return nil, fmt.Errorf("invalid zoom: must be 0-22")
+	}
+
+	locations, err := s.photoDAO.GetLocationWeights(userID, bbox)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get heatmap: %w", err)
+	}
+
+	type cellKey struct{ x, y int64 }
+	type cell struct {
+		latSum, lngSum float64
+		weight         int
+	}
+	cells := make(map[cellKey]*cell)
+	var order []cellKey
+
+	worldSize := 256 * math.Exp2(float64(zoom))
+	for _, location := range locations {
+		x, y := mercatorPixel(location.Latitude, location.Longitude, worldSize)
+		key := cellKey{int64(x / heatmapCellSizePx), int64(y / heatmapCellSizePx)}
+		c := cells[key]
+		if c == nil {
+			c = &cell{}
+			cells[key] = c
+			order = append(order, key)
+		}
+		c.latSum += location.Latitude * float64(location.Weight)
+		c.lngSum += location.Longitude * float64(location.Weight)
+		c.weight += location.Weight
+	}
+
+	heatmap := &model.Heatmap{
+		Zoom:       zoom,
+		CellSizePx: heatmapCellSizePx,
+		Points:     make([]model.HeatmapPoint, 0, len(cells)),
+	}
+	for _, key := range order {
+		c := cells[key]
+		heatmap.Points = append(heatmap.Points, model.HeatmapPoint{
+			Latitude:  c.latSum / float64(c.weight),
+			Longitude: c.lngSum / float64(c.weight),
+			Weight:    c.weight,
+		})
+		if c.weight > heatmap.MaxWeight {
+			heatmap.MaxWeight = c.weight
+		}
+	}
+	return heatmap, nil
+}
+
+// mercatorPixel projects a point onto a Web Mercator world worldSize pixels wide
+func mercatorPixel(lat, lng, worldSize float64) (float64, float64) {
+	lat = math.Max(-maxMercatorLatitude, math.Min(maxMercatorLatitude, lat))
+	sinLat := math.Sin(lat * math.Pi / 180)
+	x := (lng + 180) / 360 * worldSize
+	y := (0.5 - math.Log((1+sinLat)/(1-sinLat))/(4*math.Pi)) * worldSize
+	return x, y
+}
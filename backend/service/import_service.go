@@ -0,0 +1,664 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"geoalbum/backend/dao"
+	"geoalbum/backend/datum"
+	"geoalbum/backend/geocode"
+	"geoalbum/backend/geojson"
+	"geoalbum/backend/gpx"
+	"geoalbum/backend/kml"
+	"geoalbum/backend/model"
+	"geoalbum/backend/polyline"
+)
+
+type ImportService struct {
+	albumDAO     *dao.AlbumDAO
+	pathDAO      *dao.PathDAO
+	photoDAO     *dao.PhotoDAO
+	albumService *AlbumService
+	pathService  *PathService
+}
+
+func NewImportService() *ImportService {
+	return &ImportService{
+		albumDAO:     dao.NewAlbumDAO(),
+		pathDAO:      dao.NewPathDAO(),
+		photoDAO:     dao.NewPhotoDAO(),
+		albumService: NewAlbumService(),
+		pathService:  NewPathService(),
+	}
+}
+
+// GPXImportOptions controls how a GPX file is matched against the user's library
+type GPXImportOptions struct {
+	MatchRadiusM float64       // waypoints and track points this close to an album belong to it
+	CameraOffset time.Duration // camera clock minus GPS time; subtracted from capture times
+	MaxGap       time.Duration // photos are only placed between track points at most this far apart
+	CreateAlbums bool          // create albums for waypoints that match none
+	Geotag       bool          // place photos without a GPS position along the tracks
+}
+
+// ImportGPX imports a GPX file. Waypoints become albums unless one already lies within
+// the match radius. Tracks and routes are split where they pass albums and each
+// stretch between two albums becomes the geometry of the path joining them, created
+// if needed; tracks are applied after routes so recorded geometry wins. Photos
+// without a GPS position are then placed along the tracks by capture time.
+func (s *ImportService) ImportGPX(userID string, r io.Reader, options GPXImportOptions) (*model.GPXImportResult, error) {
+	file, err := gpx.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	albums, err := s.albumDAO.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get albums: %w", err)
+	}
+	index := newAlbumIndex(albums)
+
+	result := &model.GPXImportResult{
+		Waypoints:       len(file.Waypoints),
+		Routes:          len(file.Routes),
+		Tracks:          len(file.Tracks),
+		AlbumsCreated:   []model.Album{},
+		AlbumsMatched:   []model.ImportMatch{},
+		Paths:           []model.ImportedPath{},
+		PhotosGeotagged: []model.GeotaggedPhoto{},
+	}
+	for _, track := range file.Tracks {
+		result.TrackPoints += len(track.Points)
+	}
+
+	for i, waypoint := range file.Waypoints {
+		name := waypoint.Name
+		if name == "" {
+			name = fmt.Sprintf("Waypoint %d", i+1)
+		}
+		if album, distance := index.nearest(waypoint.Lat, waypoint.Lng, options.MatchRadiusM); album != nil {
+			result.AlbumsMatched = append(result.AlbumsMatched, model.ImportMatch{
+				Name:      name,
+				AlbumID:   album.ID,
+				DistanceM: math.Round(distance),
+			})
+			continue
+		}
+		if !options.CreateAlbums {
+			continue
+		}
+
+		createdAt := time.Now()
+		if waypoint.Time != nil {
+			createdAt = *waypoint.Time
+		}
+		album, err := s.albumService.CreateAlbum(userID, NewAlbum{
+			Title:       name,
+			Description: waypoint.Description,
+			Latitude:    waypoint.Lat,
+			Longitude:   waypoint.Lng,
+			CreatedAt:   createdAt,
+			Datum:       datum.WGS84,
+		})
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("waypoint %q: %v", name, err))
+			continue
+		}
+		result.AlbumsCreated = append(result.AlbumsCreated, *album)
+		index.add(*album)
+	}
+
+	for _, route := range file.Routes {
+		s.importLine(userID, route, "route", index, options.MatchRadiusM, result)
+	}
+	for _, track := range file.Tracks {
+		s.importLine(userID, track, "track", index, options.MatchRadiusM, result)
+	}
+
+	if options.Geotag {
+		if err := s.geotagPhotos(userID, gpx.NewTimeline(file.Tracks), options, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// importLine turns each stretch of line between two different albums into the
+// geometry of the path joining them
+func (s *ImportService) importLine(userID string, line gpx.Line, source string, index *albumIndex, radiusM float64, result *model.GPXImportResult) {
+	// A visit is a run of consecutive points near the same album
+	type visit struct {
+		albumID     string
+		first, last int
+	}
+	var visits []visit
+	for i, p := range line.Points {
+		album, _ := index.nearest(p.Lat, p.Lng, radiusM)
+		if album == nil {
+			continue
+		}
+		if n := len(visits); n > 0 && visits[n-1].albumID == album.ID {
+			visits[n-1].last = i
+			continue
+		}
+		visits = append(visits, visit{albumID: album.ID, first: i, last: i})
+	}
+
+	for i := 1; i < len(visits); i++ {
+		from, to := visits[i-1], visits[i]
+		points := make([]polyline.Point, 0, to.first-from.last+1)
+		for _, p := range line.Points[from.last : to.first+1] {
+			points = append(points, polyline.Point{Lat: p.Lat, Lng: p.Lng})
+		}
+
+		path, created, err := s.findOrCreatePath(userID, from.albumID, to.albumID, &result.Warnings)
+		if err == nil {
+			_, err = s.pathService.SetPathGeometry(path.ID, userID, points, datum.WGS84)
+		}
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s %q: %v", source, line.Name, err))
+			continue
+		}
+		result.Paths = append(result.Paths, model.ImportedPath{
+			PathID:      path.ID,
+			FromAlbumID: from.albumID,
+			ToAlbumID:   to.albumID,
+			Source:      source,
+			Name:        line.Name,
+			Points:      len(points),
+			Created:     created,
+		})
+	}
+}
+
+// findOrCreatePath returns the path from one album to another, creating it when
+// there is none; chain warnings of a new path are appended to warnings
+func (s *ImportService) findOrCreatePath(userID, fromAlbumID, toAlbumID string, warnings *[]string) (*model.Path, bool, error) {
+	paths, err := s.pathDAO.GetByFromAlbumID(fromAlbumID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get paths: %w", err)
+	}
+	for i := range paths {
+		if paths[i].ToAlbumID == toAlbumID && paths[i].UserID == userID {
+			return &paths[i], false, nil
+		}
+	}
+
+	path, err := s.pathService.CreatePath(userID, fromAlbumID, toAlbumID, ChainPolicyWarn)
+	if err != nil {
+		return nil, false, err
+	}
+	*warnings = append(*warnings, path.Warnings...)
+	return path, true, nil
+}
+
+// geotagPhotos places the user's photos without a GPS position along the timeline
+func (s *ImportService) geotagPhotos(userID string, timeline gpx.Timeline, options GPXImportOptions, result *model.GPXImportResult) error {
+	photos, err := s.photoDAO.GetUnlocatedByUserID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get photos: %w", err)
+	}
+	result.PhotosUnlocated = len(photos)
+
+	var located []model.Photo
+	for _, photo := range photos {
+		lat, lng, ok := timeline.Locate(photo.TakenAt.Add(-options.CameraOffset), options.MaxGap)
+		if !ok {
+			continue
+		}
+		photo.Latitude, photo.Longitude = &lat, &lng
+		located = append(located, photo)
+		result.PhotosGeotagged = append(result.PhotosGeotagged, model.GeotaggedPhoto{
+			PhotoID:   photo.ID,
+			AlbumID:   photo.AlbumID,
+			Latitude:  lat,
+			Longitude: lng,
+		})
+	}
+	if len(located) == 0 {
+		return nil
+	}
+	if err := s.photoDAO.UpdateLocations(located); err != nil {
+		return fmt.Errorf("failed to geotag photos: %w", err)
+	}
+	return nil
+}
+
+// albumIndex finds the album nearest to a point, keeping albums sorted by latitude
+// so that only those in the point's latitude band are measured
+type albumIndex struct {
+	albums []model.Album
+}
+
+func newAlbumIndex(albums []model.Album) *albumIndex {
+	index := &albumIndex{albums: append([]model.Album(nil), albums...)}
+	sort.Slice(index.albums, func(i, j int) bool { return index.albums[i].Latitude < index.albums[j].Latitude })
+	return index
+}
+
+func (index *albumIndex) add(album model.Album) {
+	i := sort.Search(len(index.albums), func(i int) bool { return index.albums[i].Latitude >= album.Latitude })
+	index.albums = append(index.albums, model.Album{})
+	copy(index.albums[i+1:], index.albums[i:])
+	index.albums[i] = album
+}
+
+// nearest returns the album nearest to a point within radiusM metres, and its distance
+func (index *albumIndex) nearest(lat, lng, radiusM float64) (*model.Album, float64) {
+	latDelta, _ := geocode.BoundingBox(lat, radiusM/1000)
+	i := sort.Search(len(index.albums), func(i int) bool { return index.albums[i].Latitude >= lat-latDelta })
+
+	var nearest *model.Album
+	best := radiusM
+	for ; i < len(index.albums) && index.albums[i].Latitude <= lat+latDelta; i++ {
+		album := &index.albums[i]
+		distance := geocode.DistanceKm(lat, lng, album.Latitude, album.Longitude) * 1000
+		if distance <= best {
+			nearest, best = album, distance
+		}
+	}
+	return nearest, best
+}
+
+// Place file formats accepted by ImportPlaces
+const (
+	PlaceFormatGeoJSON = "geojson"
+	PlaceFormatKML     = "kml"
+)
+
+// ErrInvalidPlaceFile is matched by the errors returned for place files that cannot
+// be read as GeoJSON or KML
+var ErrInvalidPlaceFile = errors.New("invalid place file")
+
+// maxPlaceFeatures bounds the number of features of a single place import
+const maxPlaceFeatures = 10000
+
+// maxExternalIDLength bounds the length of the external ID stored on albums
+const maxExternalIDLength = 200
+
+// PlaceImportOptions controls how a GeoJSON or KML file of places is imported
+type PlaceImportOptions struct {
+	Format       string      // PlaceFormatGeoJSON or PlaceFormatKML; detected from the content when empty
+	Datum        datum.Datum // datum of the file's coordinates
+	MatchRadiusM float64     // line ends this close to an imported point are joined to its album
+	CreatePaths  bool        // create paths from lines between imported points
+}
+
+// placeFeature is a GeoJSON feature or KML placemark reduced to what an import uses
+type placeFeature struct {
+	externalID  string
+	name        string
+	description string
+	startAt     *time.Time
+	endAt       *time.Time
+	trip        bool // a trip line exported by this app, which is not a single path
+	geometry    string
+	point       *polyline.Point
+	lines       [][]polyline.Point
+	err         error
+}
+
+// ImportPlaces imports a GeoJSON or KML file of places. Points become albums, keyed by
+// the feature ID so that importing the same file again finds the albums it created
+// instead of duplicating them; features without an ID are keyed by name and position.
+// Lines whose ends lie on two imported points become the geometry of the path between
+// their albums. Each feature is reported on, and features that fail do not stop the
+// import.
+//
+// The import is not a transaction: albums and paths are created one at a time and kept
+// when a later feature fails. Importing the file again completes it, as the features
+// already imported are found by their ID.
+func (s *ImportService) ImportPlaces(userID string, r io.Reader, options PlaceImportOptions) (*model.PlaceImportResult, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	format := options.Format
+	if format == "" {
+		format = detectPlaceFormat(data)
+	}
+
+	var features []placeFeature
+	switch format {
+	case PlaceFormatGeoJSON:
+		features, err = geoJSONPlaces(data)
+	case PlaceFormatKML:
+		features, err = kmlPlaces(data)
+	default:
+		return nil, invalidInput(ErrInvalidPlaceFile, fmt.Errorf("unrecognised file format: expected GeoJSON or KML"))
+	}
+	if err != nil {
+		return nil, invalidInput(ErrInvalidPlaceFile, err)
+	}
+	if len(features) > maxPlaceFeatures {
+		return nil, invalidInput(ErrInvalidPlaceFile, fmt.Errorf("too many features: %d, at most %d per import", len(features), maxPlaceFeatures))
+	}
+
+	albums, err := s.albumDAO.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get albums: %w", err)
+	}
+	albumsByExternalID := make(map[string]*model.Album, len(albums))
+	for i := range albums {
+		albumsByExternalID[albums[i].ID] = &albums[i]
+	}
+	for i := range albums {
+		if albums[i].ExternalID != "" {
+			albumsByExternalID[albums[i].ExternalID] = &albums[i]
+		}
+	}
+
+	result := &model.PlaceImportResult{
+		Format:   format,
+		Features: len(features),
+		Results:  make([]model.PlaceFeatureResult, len(features)),
+	}
+	for i, feature := range features {
+		result.Results[i] = model.PlaceFeatureResult{
+			Index:      i,
+			ExternalID: feature.externalID,
+			Name:       feature.name,
+			Geometry:   feature.geometry,
+		}
+	}
+
+	// Points first, so lines can join points that come after them in the file
+	imported := newAlbumIndex(nil)
+	for i, feature := range features {
+		if feature.err != nil || feature.point == nil {
+			continue
+		}
+		report := &result.Results[i]
+		if album, ok := albumsByExternalID[feature.externalID]; ok {
+			report.Status = model.ImportStatusExisting
+			report.AlbumID = album.ID
+			imported.add(*album)
+			continue
+		}
+
+		title := feature.name
+		if title == "" {
+			title = fmt.Sprintf("Place %d", i+1)
+		}
+		input := NewAlbum{
+			Title:       title,
+			Description: feature.description,
+			Latitude:    feature.point.Lat,
+			Longitude:   feature.point.Lng,
+			CreatedAt:   time.Now(),
+			Datum:       options.Datum,
+			ExternalID:  feature.externalID,
+		}
+		if feature.startAt != nil {
+			input.CreatedAt = *feature.startAt
+			input.StartAt, input.EndAt = feature.startAt, feature.endAt
+		}
+		album, err := s.albumService.CreateAlbum(userID, input)
+		if err != nil {
+			report.Status, report.Error = model.ImportStatusFailed, err.Error()
+			continue
+		}
+		report.Status = model.ImportStatusCreated
+		report.AlbumID = album.ID
+		albumsByExternalID[feature.externalID] = album
+		imported.add(*album)
+	}
+
+	for i, feature := range features {
+		report := &result.Results[i]
+		switch {
+		case feature.err != nil:
+			report.Status, report.Error = model.ImportStatusFailed, feature.err.Error()
+		case feature.point != nil:
+			// Handled above
+		case feature.lines != nil:
+			s.importPlaceLine(userID, feature, imported, options, report, result)
+		case feature.geometry == "":
+			report.Status, report.Error = model.ImportStatusSkipped, "feature has no point or line geometry"
+		default:
+			report.Status, report.Error = model.ImportStatusSkipped, fmt.Sprintf("unsupported geometry %s", feature.geometry)
+		}
+
+		switch {
+		case report.Status == model.ImportStatusCreated && report.AlbumID != "":
+			result.AlbumsCreated++
+		case report.Status == model.ImportStatusExisting && report.AlbumID != "":
+			result.AlbumsExisting++
+		case report.Status == model.ImportStatusCreated:
+			result.PathsCreated++
+		case report.Status == model.ImportStatusExisting:
+			result.PathsExisting++
+		case report.Status == model.ImportStatusSkipped:
+			result.Skipped++
+		case report.Status == model.ImportStatusFailed:
+			result.Failed++
+		}
+	}
+	return result, nil
+}
+
+// importPlaceLine makes a line the geometry of the path between the imported points
+// at its two ends, creating the path if needed
+func (s *ImportService) importPlaceLine(userID string, feature placeFeature, imported *albumIndex, options PlaceImportOptions, report *model.PlaceFeatureResult, result *model.PlaceImportResult) {
+	if !options.CreatePaths {
+		report.Status, report.Error = model.ImportStatusSkipped, "path creation is disabled"
+		return
+	}
+	if feature.trip {
+		report.Status, report.Error = model.ImportStatusSkipped, "trip lines are not imported as paths"
+		return
+	}
+
+	// Parts of a line split at the antimeridian are joined back together
+	var line []polyline.Point
+	for _, part := range feature.lines {
+		line = append(line, part...)
+	}
+	endpoint := func(p polyline.Point) *model.Album {
+		lat, lng := datum.ToWGS84(p.Lat, p.Lng, options.Datum)
+		album, _ := imported.nearest(lat, lng, options.MatchRadiusM)
+		return album
+	}
+	from, to := endpoint(line[0]), endpoint(line[len(line)-1])
+	if from == nil || to == nil {
+		report.Status, report.Error = model.ImportStatusSkipped, "line ends do not match imported points"
+		return
+	}
+	if from.ID == to.ID {
+		report.Status, report.Error = model.ImportStatusSkipped, "line starts and ends at the same point"
+		return
+	}
+
+	path, created, err := s.findOrCreatePath(userID, from.ID, to.ID, &result.Warnings)
+	if err == nil {
+		_, err = s.pathService.SetPathGeometry(path.ID, userID, line, options.Datum)
+	}
+	if err != nil {
+		report.Status, report.Error = model.ImportStatusFailed, err.Error()
+		return
+	}
+	report.Status = model.ImportStatusExisting
+	if created {
+		report.Status = model.ImportStatusCreated
+	}
+	report.PathID = path.ID
+}
+
+// detectPlaceFormat tells GeoJSON from KML by the first character of the content
+func detectPlaceFormat(data []byte) string {
+	content := strings.TrimLeft(strings.TrimPrefix(string(data), "\ufeff"), " \t\r\n")
+	switch {
+	case strings.HasPrefix(content, "{"):
+		return PlaceFormatGeoJSON
+	case strings.HasPrefix(content, "<"):
+		return PlaceFormatKML
+	}
+	return ""
+}
+
+func geoJSONPlaces(data []byte) ([]placeFeature, error) {
+	decoded, err := geojson.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	features := make([]placeFeature, len(decoded))
+	for i, f := range decoded {
+		feature := placeFeature{
+			externalID:  string(f.ID),
+			name:        stringProperty(f.Properties, "name", "title", "Name", "NAME"),
+			description: stringProperty(f.Properties, "description", "desc", "Description"),
+			trip:        stringProperty(f.Properties, "kind") == "trip",
+		}
+		if feature.externalID == "" {
+			feature.externalID = stringProperty(f.Properties, "external_id", "id")
+		}
+		feature.startAt, feature.endAt, feature.err = parsePlaceTimes(
+			stringProperty(f.Properties, "start_at", "time", "timestamp", "date", "begin"),
+			stringProperty(f.Properties, "end_at", "end"),
+		)
+
+		if f.Geometry != nil {
+			feature.geometry = f.Geometry.Type
+		}
+		if f.Geometry != nil && feature.err == nil {
+			switch f.Geometry.Type {
+			case "Point":
+				var point polyline.Point
+				if point, feature.err = f.Geometry.Point(); feature.err == nil {
+					feature.point = &point
+				}
+			case "LineString", "MultiLineString":
+				feature.lines, feature.err = f.Geometry.Lines()
+			}
+		}
+		features[i] = finishPlace(feature)
+	}
+	return features, nil
+}
+
+func kmlPlaces(data []byte) ([]placeFeature, error) {
+	placemarks, err := kml.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	features := make([]placeFeature, len(placemarks))
+	for i := range placemarks {
+		p := &placemarks[i]
+		feature := placeFeature{
+			externalID:  p.ID,
+			name:        strings.TrimSpace(p.Name),
+			description: strings.TrimSpace(p.Description),
+			trip:        p.StyleURL == "#trip",
+		}
+		if feature.externalID == "" {
+			// Trip stops exported by this app carry the ID of their album
+			feature.externalID = firstNonEmpty(p.Data("external_id"), p.Data("album_id"))
+		}
+		switch {
+		case p.TimeStamp != nil:
+			feature.startAt, feature.endAt, feature.err = parsePlaceTimes(p.TimeStamp.When, "")
+		case p.TimeSpan != nil:
+			feature.startAt, feature.endAt, feature.err = parsePlaceTimes(p.TimeSpan.Begin, p.TimeSpan.End)
+		}
+
+		if feature.err == nil {
+			var point polyline.Point
+			var ok bool
+			if point, ok, feature.err = p.Position(); ok {
+				feature.geometry, feature.point = "Point", &point
+			} else if feature.err == nil {
+				if feature.lines, feature.err = p.Lines(); len(feature.lines) > 0 {
+					feature.geometry = "LineString"
+				}
+			}
+			if feature.geometry == "" && feature.err == nil && (p.Point != nil || p.MultiGeometry != nil) {
+				feature.geometry = "MultiGeometry"
+			}
+		}
+		features[i] = finishPlace(feature)
+	}
+	return features, nil
+}
+
+// finishPlace derives the external ID of a point without one from its name and
+// position, and checks the ID's length
+func finishPlace(feature placeFeature) placeFeature {
+	if feature.err != nil || feature.point == nil {
+		return feature
+	}
+	if feature.externalID == "" {
+		key := fmt.Sprintf("%s|%.6f|%.6f", feature.name, feature.point.Lat, feature.point.Lng)
+		sum := sha256.Sum256([]byte(key))
+		feature.externalID = "sha256:" + hex.EncodeToString(sum[:16])
+	}
+	if len(feature.externalID) > maxExternalIDLength {
+		feature.err = fmt.Errorf("feature ID is longer than %d characters", maxExternalIDLength)
+	}
+	return feature
+}
+
+// stringProperty returns the first of the named properties that is set, as text
+func stringProperty(properties map[string]interface{}, names ...string) string {
+	for _, name := range names {
+		switch value := properties[name].(type) {
+		case string:
+			if value = strings.TrimSpace(value); value != "" {
+				return value
+			}
+		case float64:
+			return strconv.FormatFloat(value, 'f', -1, 64)
+		}
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// placeTimeLayouts are the time formats accepted in imported places
+var placeTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// parsePlaceTimes parses the optional start and end time of a place
+func parsePlaceTimes(start, end string) (*time.Time, *time.Time, error) {
+	parse := func(value string) (*time.Time, error) {
+		if value == "" {
+			return nil, nil
+		}
+		for _, layout := range placeTimeLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return &t, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid time %q", value)
+	}
+	startAt, err := parse(strings.TrimSpace(start))
+	if err != nil {
+		return nil, nil, err
+	}
+	endAt, err := parse(strings.TrimSpace(end))
+	if err != nil {
+		return nil, nil, err
+	}
+	if startAt == nil {
+		// An end alone is taken as the place's single time
+		startAt, endAt = endAt, nil
+	}
+	return startAt, endAt, nil
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+	"modernc.org/sqlite"
+
+	"geoalbum/backend/dao"
+	"geoalbum/backend/database"
+	"geoalbum/backend/logging"
+	"geoalbum/backend/model"
+)
+
+// queryCount counts statements prepared through the countingDriver
+var queryCount atomic.Int64
+
+var registerCountingDriver sync.Once
+
+// countingDriver wraps the SQLite driver and counts every statement sent to it.
+// Its connections only implement driver.Conn, so database/sql routes all
+// queries and execs through Prepare.
+type countingDriver struct {
+	sqlite.Driver
+}
+
+func (d *countingDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &countingConn{conn: conn}, nil
+}
+
+type countingConn struct {
+	conn driver.Conn
+}
+
+func (c *countingConn) Prepare(query string) (driver.Stmt, error) {
+	queryCount.Add(1)
+	return c.conn.Prepare(query)
+}
+
+func (c *countingConn) Close() error { return c.conn.Close() }
+
+func (c *countingConn) Begin() (driver.Tx, error) { return c.conn.Begin() }
+
+// setupListingDB opens an isolated database and seeds one user with the given
+// number of albums, each with two photos, chained together by paths
+func setupListingDB(tb testing.TB, albumCount int) string {
+	tb.Helper()
+
+	registerCountingDriver.Do(func() {
+		sql.Register("sqlite-counting", &countingDriver{})
+		_ = logging.InitializeGlobalLogger(&logging.LogConfig{
+			Level:  logging.ErrorLevel,
+			Format: "text",
+			Output: "stdout",
+		})
+	})
+
+	dsn := filepath.Join(tb.TempDir(), "bench.db") + "?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)"
+	db, err := sqlx.Open("sqlite-counting", dsn)
+	require.NoError(tb, err)
+	db.SetMaxOpenConns(1)
+	tb.Cleanup(func() { db.Close() })
+	require.NoError(tb, database.Setup(db))
+
+	userID := uuid.New().String()
+	now := time.Now()
+	require.NoError(tb, dao.NewUserDAO().Create(&model.User{
+		ID: userID, Username: "bench_" + userID[:8], PasswordHash: "x", CreatedAt: now, UpdatedAt: now,
+	}))
+
+	albumDAO := dao.NewAlbumDAO()
+	photoDAO := dao.NewPhotoDAO()
+	pathDAO := dao.NewPathDAO()
+	var previousID string
+	for i := 0; i < albumCount; i++ {
+		album := &model.Album{
+			ID:        uuid.New().String(),
+			UserID:    userID,
+			Title:     fmt.Sprintf("Album %d", i),
+			Latitude:  float64(i%90) / 2,
+			Longitude: float64(i%180) / 2,
+			CreatedAt: now.Add(time.Duration(i) * time.Hour),
+			UpdatedAt: now,
+		}
+		require.NoError(tb, albumDAO.Create(album))
+		for j := 0; j < 2; j++ {
+			require.NoError(tb, photoDAO.Create(&model.Photo{
+				ID: uuid.New().String(), AlbumID: album.ID, Filename: "p.jpg", FilePath: "p.jpg",
+				FileSize: 1, MimeType: "image/jpeg", DisplayOrder: j, UploadedAt: now,
+			}))
+		}
+		if previousID != "" {
+			require.NoError(tb, pathDAO.Create(&model.Path{
+				ID: uuid.New().String(), UserID: userID, FromAlbumID: previousID, ToAlbumID: album.ID, CreatedAt: now,
+			}))
+		}
+		previousID = album.ID
+	}
+
+	return userID
+}
+
+var listingSizes = []int{10, 100, 1000}
+
+func TestListingQueryCountIsFlat(t *testing.T) {
+	for _, n := range listingSizes {
+		t.Run(fmt.Sprintf("albums=%d", n), func(t *testing.T) {
+			userID := setupListingDB(t, n)
+
+			queryCount.Store(0)
+			albums, err := NewAlbumService().GetAlbumsByUserID(userID)
+			require.NoError(t, err)
+			require.Len(t, albums, n)
+			require.Equal(t, int64(1), queryCount.Load())
+			for _, album := range albums {
+				require.Equal(t, 2, album.PhotoCount)
+				require.NotEmpty(t, album.CoverPhotoID)
+			}
+
+			queryCount.Store(0)
+			paths, err := NewPathService().GetPathsByUserID(userID)
+			require.NoError(t, err)
+			require.Len(t, paths, n-1)
+			require.Equal(t, int64(1), queryCount.Load())
+			for _, path := range paths {
+				require.NotNil(t, path.FromAlbum)
+				require.NotNil(t, path.ToAlbum)
+				require.Equal(t, path.FromAlbumID, path.FromAlbum.ID)
+				require.Equal(t, path.ToAlbumID, path.ToAlbum.ID)
+			}
+		})
+	}
+}
+
+func BenchmarkGetAlbumsByUserID(b *testing.B) {
+	for _, n := range listingSizes {
+		b.Run(fmt.Sprintf("albums=%d", n), func(b *testing.B) {
+			userID := setupListingDB(b, n)
+			svc := NewAlbumService()
+
+			queryCount.Store(0)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := svc.GetAlbumsByUserID(userID); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(queryCount.Load())/float64(b.N), "queries/op")
+		})
+	}
+}
+
+func BenchmarkGetPathsByUserID(b *testing.B) {
+	for _, n := range listingSizes {
+		b.Run(fmt.Sprintf("paths=%d", n-1), func(b *testing.B) {
+			userID := setupListingDB(b, n)
+			svc := NewPathService()
+
+			queryCount.Store(0)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := svc.GetPathsByUserID(userID); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(queryCount.Load())/float64(b.N), "queries/op")
+		})
+	}
+}
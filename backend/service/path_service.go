@@ -1,29 +1,88 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 
 	"geoalbum/backend/dao"
+	"geoalbum/backend/datum"
+	"geoalbum/backend/middleware"
 	"geoalbum/backend/model"
+	"geoalbum/backend/polyline"
 )
 
+// ChainPolicy decides what happens when a new path closes a loop or forks a chain
+// of next destinations
+type ChainPolicy string
+
+const (
+	ChainPolicyWarn   ChainPolicy = "warn"   // create the path and report the problems
+	ChainPolicyReject ChainPolicy = "reject" // refuse the path
+)
+
+// ErrChainConflict is returned under ChainPolicyReject when a path would close a
+// loop or fork a chain
+var ErrChainConflict = errors.New("path conflicts with existing chain")
+
+// DefaultRouteDepth is the number of hops walked in each direction unless asked otherwise
+const DefaultRouteDepth = 100
+
+// maxPathDurationMinutes bounds a path's travel duration (one year)
+const maxPathDurationMinutes = 366 * 24 * 60
+
+// maxPathGeometryPoints bounds the number of points in a path's geometry
+const maxPathGeometryPoints = 50000
+
+// flightSegmentKm is the segment length of great circles drawn for flights
+const flightSegmentKm = 100
+
 type PathService struct {
-	pathDAO  *dao.PathDAO
-	albumDAO *dao.AlbumDAO
+	pathDAO   *dao.PathDAO
+	albumDAO  *dao.AlbumDAO
+	sanitizer *middleware.InputSanitizer
 }
 
 func NewPathService() *PathService {
 	return &PathService{
-		pathDAO:  dao.NewPathDAO(),
-		albumDAO: dao.NewAlbumDAO(),
+		pathDAO:   dao.NewPathDAO(),
+		albumDAO:  dao.NewAlbumDAO(),
+		sanitizer: middleware.GetInputSanitizer(),
+	}
+}
+
+// PathUpdate holds a path's travel details; nil fields are left unchanged
+type PathUpdate struct {
+	TransportMode   *string // "" clears the mode
+	DurationMinutes *int
+	DepartAt        *time.Time
+	ArriveAt        *time.Time
+	Notes           *string
+	ClearSchedule   bool // remove DurationMinutes, DepartAt and ArriveAt
+}
+
+// CreatePath creates a new path between two albums. Loops and forks it introduces
+// are handled according to policy.
+func (s *PathService) CreatePath(userID, fromAlbumID, toAlbumID string, policy ChainPolicy) (*model.Path, error) {
+	path, err := s.newPath(userID, fromAlbumID, toAlbumID, false, policy)
+	if err != nil {
+		return nil, err
 	}
+
+	if err := s.pathDAO.Create(path); err != nil {
+		return nil, fmt.Errorf("failed to create path: %w", err)
+	}
+	return path, nil
 }
 
-// CreatePath creates a new path between two albums
-func (s *PathService) CreatePath(userID, fromAlbumID, toAlbumID string) (*model.Path, error) {
+// newPath validates a path between two of the user's albums and builds it, with
+// album details for the response, without saving it. Unless the path replaces
+// those leaving fromAlbumID, it must not exist yet.
+func (s *PathService) newPath(userID, fromAlbumID, toAlbumID string, replacing bool, policy ChainPolicy) (*model.Path, error) {
 	// Validate that both albums exist and belong to the user
 	fromAlbum, err := s.albumDAO.GetByID(fromAlbumID)
 	if err != nil {
@@ -48,15 +107,21 @@ func (s *PathService) CreatePath(userID, fromAlbumID, toAlbumID string) (*model.
 	}
 
 	// Check if path already exists
-	exists, err := s.pathDAO.CheckPathExists(fromAlbumID, toAlbumID, userID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check path existence: %w", err)
+	if !replacing {
+		exists, err := s.pathDAO.CheckPathExists(fromAlbumID, toAlbumID, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check path existence: %w", err)
+		}
+		if exists {
+			return nil, fmt.Errorf("path already exists between these albums")
+		}
 	}
-	if exists {
-		return nil, fmt.Errorf("path already exists between these albums")
+
+	warnings, err := s.checkChain(userID, fromAlbumID, toAlbumID, replacing, policy)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create the path
 	path := &model.Path{
 		ID:          uuid.New().String(),
 		UserID:      userID,
@@ -65,37 +130,26 @@ func (s *PathService) CreatePath(userID, fromAlbumID, toAlbumID string) (*model.
 		CreatedAt:   time.Now(),
 	}
 
-	if err := s.pathDAO.Create(path); err != nil {
-		return nil, fmt.Errorf("failed to create path: %w", err)
-	}
-
 	// Load album details for response
 	path.FromAlbum = fromAlbum
 	path.ToAlbum = toAlbum
+	path.Warnings = warnings
+	setPathGeometry(path)
 
 	return path, nil
 }
 
 // GetPathsByUserID retrieves all paths for a user with album details
 func (s *PathService) GetPathsByUserID(userID string) ([]model.Path, error) {
-	paths, err := s.pathDAO.GetByUserID(userID)
+	paths, err := s.pathDAO.GetByUserIDWithAlbums(userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get paths: %w", err)
 	}
 
-	// Load album details for each path
 	for i := range paths {
-		fromAlbum, err := s.albumDAO.GetByID(paths[i].FromAlbumID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get from album for path %s: %w", paths[i].ID, err)
-		}
-		paths[i].FromAlbum = fromAlbum
-
-		toAlbum, err := s.albumDAO.GetByID(paths[i].ToAlbumID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get to album for path %s: %w", paths[i].ID, err)
-		}
-		paths[i].ToAlbum = toAlbum
+		localizeAlbum(paths[i].FromAlbum)
+		localizeAlbum(paths[i].ToAlbum)
+		setPathGeometry(&paths[i])
 	}
 
 	return paths, nil
@@ -103,7 +157,7 @@ func (s *PathService) GetPathsByUserID(userID string) ([]model.Path, error) {
 
 // GetPathByID retrieves a path by ID and ensures it belongs to the user
 func (s *PathService) GetPathByID(id, userID string) (*model.Path, error) {
-	path, err := s.pathDAO.GetByID(id)
+	path, err := s.pathDAO.GetByIDWithAlbums(id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get path: %w", err)
 	}
@@ -114,22 +168,153 @@ func (s *PathService) GetPathByID(id, userID string) (*model.Path, error) {
 		return nil, fmt.Errorf("access denied: path does not belong to user")
 	}
 
-	// Load album details
-	fromAlbum, err := s.albumDAO.GetByID(path.FromAlbumID)
+	localizeAlbum(path.FromAlbum)
+	localizeAlbum(path.ToAlbum)
+	setPathGeometry(path)
+	return path, nil
+}
+
+// UpdatePath updates a path's transport mode, schedule and notes
+func (s *PathService) UpdatePath(id, userID string, update PathUpdate) (*model.Path, error) {
+	path, err := s.getStoredPath(id, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get from album: %w", err)
+		return nil, err
 	}
-	path.FromAlbum = fromAlbum
 
-	toAlbum, err := s.albumDAO.GetByID(path.ToAlbumID)
+	if update.TransportMode != nil {
+		mode := strings.ToLower(strings.TrimSpace(*update.TransportMode))
+		if mode != "" && !containsFold(model.TransportModes, mode) {
+			return nil, fmt.Errorf("invalid transport mode: must be one of %s", strings.Join(model.TransportModes, ", "))
+		}
+		path.TransportMode = mode
+	}
+
+	if update.Notes != nil {
+		notes := s.sanitizer.SanitizeString(*update.Notes)
+		if !s.sanitizer.ValidateAlbumDescription(notes) {
+			return nil, fmt.Errorf("invalid path notes: must be max 2000 characters")
+		}
+		if s.sanitizer.DetectSQLInjection(notes) {
+			return nil, fmt.Errorf("invalid input: contains prohibited characters")
+		}
+		path.Notes = notes
+	}
+
+	if update.ClearSchedule {
+		if update.DurationMinutes != nil || update.DepartAt != nil || update.ArriveAt != nil {
+			return nil, fmt.Errorf("invalid path schedule: duration_minutes/depart_at/arrive_at cannot be combined with clear_schedule")
+		}
+		path.DurationMinutes, path.DepartAt, path.ArriveAt = nil, nil, nil
+	}
+	if update.DurationMinutes != nil {
+		if *update.DurationMinutes < 0 || *update.DurationMinutes > maxPathDurationMinutes {
+			return nil, fmt.Errorf("invalid path duration: must be 0-%d minutes", maxPathDurationMinutes)
+		}
+		path.DurationMinutes = update.DurationMinutes
+	}
+	if update.DepartAt != nil {
+		path.DepartAt = update.DepartAt
+	}
+	if update.ArriveAt != nil {
+		path.ArriveAt = update.ArriveAt
+	}
+	if path.DepartAt != nil && path.ArriveAt != nil && path.ArriveAt.Before(*path.DepartAt) {
+		return nil, fmt.Errorf("invalid path schedule: arrive_at must not be before depart_at")
+	}
+
+	if err := s.pathDAO.Update(path); err != nil {
+		return nil, fmt.Errorf("failed to update path: %w", err)
+	}
+	setPathGeometry(path)
+	return path, nil
+}
+
+// SetPathGeometry replaces a path's geometry with points given in datum d. No points
+// removes it, so the path is drawn straight again (or as a great circle for flights).
+func (s *PathService) SetPathGeometry(id, userID string, points []polyline.Point, d datum.Datum) (*model.Path, error) {
+	path, err := s.getStoredPath(id, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get to album: %w", err)
+		return nil, err
 	}
-	path.ToAlbum = toAlbum
 
+	if len(points) == 1 || len(points) > maxPathGeometryPoints {
+		return nil, fmt.Errorf("invalid path geometry: must have 2-%d points", maxPathGeometryPoints)
+	}
+	// Longitudes are wrapped first, so geometry as returned, unwrapped across the
+	// antimeridian, can be sent back
+	line := make([]polyline.Point, len(points))
+	for i, p := range points {
+		lng := wrapLongitude(p.Lng)
+		if !s.sanitizer.ValidateCoordinates(p.Lat, lng) {
+			return nil, fmt.Errorf("invalid path geometry: point %d is out of range", i)
+		}
+		line[i].Lat, line[i].Lng = datum.ToWGS84(p.Lat, lng, d)
+	}
+	path.Geometry = ""
+	if len(line) > 0 {
+		path.Geometry = polyline.Encode(polyline.Unwrap(line))
+	}
+
+	if err := s.pathDAO.Update(path); err != nil {
+		return nil, fmt.Errorf("failed to update path geometry: %w", err)
+	}
+	setPathGeometry(path)
 	return path, nil
 }
 
+// getStoredPath retrieves an owned path as stored, without interpolated geometry,
+// so that it can be written back
+func (s *PathService) getStoredPath(id, userID string) (*model.Path, error) {
+	path, err := s.GetPathByID(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if path.Interpolated {
+		path.Geometry, path.Interpolated = "", false
+	}
+	return path, nil
+}
+
+// SimplifyPathGeometry simplifies a path's geometry for display at a zoom level
+func SimplifyPathGeometry(path *model.Path, zoom int) {
+	if path.Geometry == "" {
+		return
+	}
+	if line, err := polyline.Decode(path.Geometry); err == nil {
+		path.Geometry = polyline.Encode(polyline.Simplify(line, zoom))
+	}
+}
+
+// setPathGeometry fills in a path's distance, measured along its geometry when it has
+// one, and gives flights without geometry a great circle
+func setPathGeometry(path *model.Path) {
+	if path.FromAlbum == nil || path.ToAlbum == nil {
+		return
+	}
+	line := pathLine(path)
+	if path.Geometry == "" && path.TransportMode == model.TransportFlight {
+		path.Geometry = polyline.Encode(line)
+		path.Interpolated = true
+	}
+	path.DistanceKm = roundKm(polyline.LengthKm(line))
+}
+
+// pathLine returns the line a path is drawn along in WGS-84: its geometry, a great
+// circle for flights, or else a straight segment between its albums
+func pathLine(path *model.Path) []polyline.Point {
+	if path.Geometry != "" {
+		if line, err := polyline.Decode(path.Geometry); err == nil && len(line) >= 2 {
+			return line
+		}
+	}
+	from := polyline.Point{Lat: path.FromAlbum.Latitude, Lng: path.FromAlbum.Longitude}
+	to := polyline.Point{Lat: path.ToAlbum.Latitude, Lng: path.ToAlbum.Longitude}
+	if path.TransportMode == model.TransportFlight {
+		return polyline.GreatCircle(from, to, flightSegmentKm)
+	}
+	return []polyline.Point{from, to}
+}
+
 // DeletePath deletes a path
 func (s *PathService) DeletePath(id, userID string) error {
 	// First check if path exists and belongs to user
@@ -147,14 +332,17 @@ func (s *PathService) DeletePath(id, userID string) error {
 
 // SetNextDestination sets or updates the "next destination" for an album
 // This replaces any existing path from the album
-func (s *PathService) SetNextDestination(userID, fromAlbumID, toAlbumID string) (*model.Path, error) {
-	// First delete any existing paths from this album
-	if err := s.pathDAO.DeleteByFromAlbumID(fromAlbumID, userID); err != nil {
-		return nil, fmt.Errorf("failed to delete existing paths: %w", err)
+func (s *PathService) SetNextDestination(userID, fromAlbumID, toAlbumID string, policy ChainPolicy) (*model.Path, error) {
+	path, err := s.newPath(userID, fromAlbumID, toAlbumID, true, policy)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create new path
-	return s.CreatePath(userID, fromAlbumID, toAlbumID)
+	// Swap the existing paths from this album for the new one in one transaction
+	if err := s.pathDAO.ReplaceFromAlbum(path); err != nil {
+		return nil, fmt.Errorf("failed to replace next destination: %w", err)
+	}
+	return path, nil
 }
 
 // GetNextDestination gets the next destination album for a given album
@@ -185,4 +373,318 @@ func (s *PathService) GetNextDestination(fromAlbumID, userID string) (*model.Alb
 // RemoveNextDestination removes the "next destination" for an album
 func (s *PathService) RemoveNextDestination(fromAlbumID, userID string) error {
 	return s.pathDAO.DeleteByFromAlbumID(fromAlbumID, userID)
-}
\ No newline at end of file
+}
+
+// GetRoute walks the chain of paths from an album forward, backward or both ways and
+// returns the albums in travel order. Where several paths leave (or enter) an album the
+// oldest is followed; the walk stops after maxDepth hops in each direction or when it
+// comes back to an album already on the route.
+func (s *PathService) GetRoute(albumID, userID, direction string, maxDepth int) (*model.Route, error) {
+	album, err := s.albumDAO.GetByID(albumID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get album: %w", err)
+	}
+	if album == nil {
+		return nil, fmt.Errorf("album not found")
+	}
+	if album.UserID != userID {
+		return nil, fmt.Errorf("access denied: album does not belong to user")
+	}
+
+	albums, err := s.albumDAO.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get route: %w", err)
+	}
+	albumsByID := make(map[string]model.Album, len(albums))
+	for _, a := range albums {
+		localizeAlbum(&a)
+		albumsByID[a.ID] = a
+	}
+
+	paths, err := s.pathDAO.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get route: %w", err)
+	}
+	sort.SliceStable(paths, func(i, j int) bool { return paths[i].CreatedAt.Before(paths[j].CreatedAt) })
+	next := make(map[string][]string)
+	previous := make(map[string][]string)
+	for _, path := range paths {
+		next[path.FromAlbumID] = append(next[path.FromAlbumID], path.ToAlbumID)
+		previous[path.ToAlbumID] = append(previous[path.ToAlbumID], path.FromAlbumID)
+	}
+
+	route := &model.Route{AlbumID: albumID, Direction: direction}
+	visited := map[string]bool{albumID: true}
+	walk := func(links map[string][]string) []string {
+		var ids []string
+		current := albumID
+		for depth := 0; ; depth++ {
+			candidates := links[current]
+			if len(candidates) == 0 {
+				break
+			}
+			if len(candidates) > 1 {
+				route.Branches = append(route.Branches, current)
+			}
+			if depth == maxDepth {
+				route.Truncated = true
+				break
+			}
+			if visited[candidates[0]] {
+				route.Cycle = true
+				break
+			}
+			current = candidates[0]
+			visited[current] = true
+			ids = append(ids, current)
+		}
+		return ids
+	}
+
+	var forward, backward []string
+	if direction != model.RouteBackward {
+		forward = walk(next)
+	}
+	if direction != model.RouteForward {
+		backward = walk(previous)
+	}
+
+	route.Albums = make([]model.Album, 0, len(backward)+1+len(forward))
+	for i := len(backward) - 1; i >= 0; i-- {
+		route.Albums = append(route.Albums, albumsByID[backward[i]])
+	}
+	route.StartIndex = len(route.Albums)
+	route.Albums = append(route.Albums, albumsByID[albumID])
+	for _, id := range forward {
+		route.Albums = append(route.Albums, albumsByID[id])
+	}
+	return route, nil
+}
+
+// checkChain describes the loop and forks a path from fromAlbumID to toAlbumID would
+// introduce, or returns ErrChainConflict for them under ChainPolicyReject. Existing
+// paths leaving fromAlbumID are ignored when the new path replaces them.
+func (s *PathService) checkChain(userID, fromAlbumID, toAlbumID string, replacing bool, policy ChainPolicy) ([]string, error) {
+	paths, err := s.pathDAO.GetByUserIDWithAlbums(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check path chain: %w", err)
+	}
+
+	titles := map[string]string{fromAlbumID: fromAlbumID, toAlbumID: toAlbumID}
+	for _, path := range paths {
+		titles[path.FromAlbumID] = path.FromAlbum.Title
+		titles[path.ToAlbumID] = path.ToAlbum.Title
+	}
+	problems := chainProblems(paths, titles, fromAlbumID, toAlbumID, replacing)
+	if len(problems) > 0 && policy == ChainPolicyReject {
+		return nil, fmt.Errorf("%w: %s", ErrChainConflict, strings.Join(problems, "; "))
+	}
+	return problems, nil
+}
+
+// chainProblems lists the loop and forks a path from fromAlbumID to toAlbumID would
+// introduce among paths, naming albums by their titles
+func chainProblems(paths []model.Path, titles map[string]string, fromAlbumID, toAlbumID string, replacing bool) []string {
+	next := make(map[string][]string)
+	var problems []string
+	for _, path := range paths {
+		if replacing && path.FromAlbumID == fromAlbumID {
+			continue
+		}
+		if path.FromAlbumID == fromAlbumID && path.ToAlbumID != toAlbumID {
+			problems = append(problems, fmt.Sprintf("album %q already leads to %q, so the chain forks",
+				titles[fromAlbumID], titles[path.ToAlbumID]))
+		}
+		if path.ToAlbumID == toAlbumID && path.FromAlbumID != fromAlbumID {
+			problems = append(problems, fmt.Sprintf("album %q is already reached from %q, so two chains join",
+				titles[toAlbumID], titles[path.FromAlbumID]))
+		}
+		next[path.FromAlbumID] = append(next[path.FromAlbumID], path.ToAlbumID)
+	}
+
+	// The new path closes a loop when fromAlbumID is reachable from toAlbumID
+	parent := map[string]string{toAlbumID: ""}
+	queue := []string{toAlbumID}
+	for len(queue) > 0 && fromAlbumID != toAlbumID {
+		current := queue[0]
+		queue = queue[1:]
+		if current == fromAlbumID {
+			break
+		}
+		for _, n := range next[current] {
+			if _, seen := parent[n]; !seen {
+				parent[n] = current
+				queue = append(queue, n)
+			}
+		}
+	}
+	if _, reached := parent[fromAlbumID]; reached {
+		// Walk back from fromAlbumID to toAlbumID, then list the loop in travel order
+		var chain []string
+		for id := fromAlbumID; id != ""; id = parent[id] {
+			chain = append(chain, titles[id])
+		}
+		stops := []string{titles[fromAlbumID]}
+		for i := len(chain) - 1; i >= 0; i-- {
+			stops = append(stops, chain[i])
+		}
+		problems = append(problems, "the path closes a loop: "+strings.Join(stops, " → "))
+	}
+	return problems
+}
+
+// AutoLinkInput selects the albums to link by date: either AlbumIDs or a date range
+type AutoLinkInput struct {
+	AlbumIDs        []string
+	StartDate       *time.Time
+	EndDate         *time.Time
+	MaxGap          time.Duration // a longer gap between albums starts a new chain; 0 never splits
+	DryRun          bool          // only report the paths that would be created
+	ReplaceExisting bool          // remove other paths leaving linked albums, like SetNextDestination
+	ChainPolicy     ChainPolicy   // how loops and forks the new paths introduce are handled
+}
+
+// AutoLink orders the selected albums by date and links consecutive albums with
+// paths, in one transaction. Loops and forks the new paths introduce are handled
+// according to the input's chain policy; a dry run only reports them.
+func (s *PathService) AutoLink(userID string, input AutoLinkInput) (*model.AutoPathResult, error) {
+	var albums []model.Album
+	var err error
+	switch {
+	case len(input.AlbumIDs) > 0:
+		albums, err = s.albumDAO.GetByUserID(userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get albums: %w", err)
+		}
+		albums, err = selectAlbums(albums, input.AlbumIDs)
+		if err != nil {
+			return nil, err
+		}
+	case input.StartDate != nil || input.EndDate != nil:
+		albums, err = s.albumDAO.GetByUserIDAndTimeRange(userID, input.StartDate, input.EndDate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get albums: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("no albums selected: give album_ids or a date range")
+	}
+
+	sort.SliceStable(albums, func(i, j int) bool {
+		if !albums[i].StartAt.Equal(albums[j].StartAt) {
+			return albums[i].StartAt.Before(albums[j].StartAt)
+		}
+		if !albums[i].EndAt.Equal(albums[j].EndAt) {
+			return albums[i].EndAt.Before(albums[j].EndAt)
+		}
+		return albums[i].ID < albums[j].ID
+	})
+
+	paths, err := s.pathDAO.GetByUserIDWithAlbums(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get paths: %w", err)
+	}
+	existing := make(map[[2]string]string, len(paths))
+	outgoing := make(map[string]int)
+	titles := make(map[string]string)
+	for _, path := range paths {
+		existing[[2]string{path.FromAlbumID, path.ToAlbumID}] = path.ID
+		outgoing[path.FromAlbumID]++
+		titles[path.FromAlbumID] = path.FromAlbum.Title
+		titles[path.ToAlbumID] = path.ToAlbum.Title
+	}
+	for _, album := range albums {
+		titles[album.ID] = album.Title
+	}
+
+	result := &model.AutoPathResult{DryRun: input.DryRun, Chains: [][]string{}, Paths: []model.AutoPath{}}
+	var links []model.Path
+	var conflicts []string
+	for i, album := range albums {
+		gap := time.Duration(0)
+		if i > 0 {
+			gap = album.StartAt.Sub(albums[i-1].EndAt)
+			if gap < 0 {
+				gap = 0
+			}
+		}
+		if i == 0 || (input.MaxGap > 0 && gap > input.MaxGap) {
+			result.Chains = append(result.Chains, []string{album.ID})
+			continue
+		}
+		chain := &result.Chains[len(result.Chains)-1]
+		*chain = append(*chain, album.ID)
+
+		from := albums[i-1].ID
+		proposed := model.AutoPath{FromAlbumID: from, ToAlbumID: album.ID, GapHours: gap.Hours()}
+		link := model.Path{UserID: userID, FromAlbumID: from, ToAlbumID: album.ID, CreatedAt: time.Now()}
+		if pathID, ok := existing[[2]string{from, album.ID}]; ok {
+			link.ID = pathID
+			proposed.PathID = pathID
+			proposed.Existing = true
+			result.Existing++
+			if input.ReplaceExisting {
+				result.Replaced += int64(outgoing[from] - 1)
+			}
+		} else {
+			link.ID = uuid.New().String()
+			if !input.DryRun {
+				proposed.PathID = link.ID
+			}
+			result.Created++
+			if input.ReplaceExisting {
+				result.Replaced += int64(outgoing[from])
+			}
+			// Check against the paths as they will be once the earlier links are made
+			proposed.Warnings = chainProblems(paths, titles, from, album.ID, input.ReplaceExisting)
+			conflicts = append(conflicts, proposed.Warnings...)
+		}
+		if input.ReplaceExisting {
+			kept := paths[:0]
+			for _, path := range paths {
+				if path.FromAlbumID != from || path.ToAlbumID == album.ID {
+					kept = append(kept, path)
+				}
+			}
+			paths = kept
+		}
+		if !proposed.Existing {
+			paths = append(paths, link)
+		}
+		// Existing links are passed on too: they are kept, but still displace other
+		// paths from their album when replacing
+		links = append(links, link)
+		result.Paths = append(result.Paths, proposed)
+	}
+
+	if input.DryRun {
+		return result, nil
+	}
+	if len(conflicts) > 0 && input.ChainPolicy == ChainPolicyReject {
+		return nil, fmt.Errorf("%w: %s", ErrChainConflict, strings.Join(conflicts, "; "))
+	}
+	if result.Replaced, err = s.pathDAO.CreateLinks(links, input.ReplaceExisting); err != nil {
+		return nil, fmt.Errorf("failed to create paths: %w", err)
+	}
+	return result, nil
+}
+
+// selectAlbums picks the albums with the given IDs, failing on any that is unknown
+func selectAlbums(albums []model.Album, ids []string) ([]model.Album, error) {
+	byID := make(map[string]model.Album, len(albums))
+	for _, album := range albums {
+		byID[album.ID] = album
+	}
+	selected := make([]model.Album, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		album, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("album not found: %s", id)
+		}
+		if !seen[id] {
+			seen[id] = true
+			selected = append(selected, album)
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"geoalbum/backend/dao"
+	"geoalbum/backend/database"
+	"geoalbum/backend/datum"
+	"geoalbum/backend/model"
+	"geoalbum/backend/polyline"
+)
+
+// setupAlbums creates a user owning one album per title, a day apart in title
+// order, and returns the user and album IDs
+func setupAlbums(t *testing.T, titles ...string) (string, []string) {
+	t.Helper()
+	setupTestDB(t)
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	user := &model.User{ID: "user", Username: "traveller", PasswordHash: "x", CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, dao.NewUserDAO().Create(user))
+
+	ids := make([]string, len(titles))
+	for i, title := range titles {
+		start := now.AddDate(0, 0, i)
+		album := &model.Album{ID: title, UserID: user.ID, Title: title, CreatedAt: now, UpdatedAt: now,
+			StartAt: start, EndAt: start.Add(6 * time.Hour)}
+		require.NoError(t, dao.NewAlbumDAO().Create(album))
+		ids[i] = album.ID
+	}
+	return user.ID, ids
+}
+
+// destinations lists where each album's paths lead
+func destinations(t *testing.T, userID string) map[string][]string {
+	t.Helper()
+	paths, err := dao.NewPathDAO().GetByUserID(userID)
+	require.NoError(t, err)
+	next := make(map[string][]string)
+	for _, path := range paths {
+		next[path.FromAlbumID] = append(next[path.FromAlbumID], path.ToAlbumID)
+	}
+	return next
+}
+
+func TestSetNextDestination(t *testing.T) {
+	userID, ids := setupAlbums(t, "a", "b", "c", "d")
+	s := NewPathService()
+	a, b, c, d := ids[0], ids[1], ids[2], ids[3]
+
+	_, err := s.CreatePath(userID, a, b, ChainPolicyWarn)
+	require.NoError(t, err)
+	_, err = s.CreatePath(userID, a, c, ChainPolicyWarn)
+	require.NoError(t, err)
+
+	// Both paths from the album are replaced by the new one
+	path, err := s.SetNextDestination(userID, a, d, ChainPolicyWarn)
+	require.NoError(t, err)
+	assert.Empty(t, path.Warnings)
+	assert.Equal(t, map[string][]string{a: {d}}, destinations(t, userID))
+
+	// Setting the same destination again keeps a single path
+	_, err = s.SetNextDestination(userID, a, d, ChainPolicyWarn)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{a: {d}}, destinations(t, userID))
+
+	// A refused path leaves the old destination in place
+	_, err = s.CreatePath(userID, d, b, ChainPolicyWarn)
+	require.NoError(t, err)
+	_, err = s.SetNextDestination(userID, b, a, ChainPolicyReject)
+	assert.ErrorIs(t, err, ErrChainConflict)
+	path, err = s.SetNextDestination(userID, b, a, ChainPolicyWarn)
+	require.NoError(t, err)
+	assert.Len(t, path.Warnings, 1)
+
+	// So does one that fails to be saved
+	_, err = database.DB.Exec(`CREATE TRIGGER fail_insert BEFORE INSERT ON paths
+		WHEN NEW.to_album_id = 'c' BEGIN SELECT RAISE(ABORT, 'insert failed'); END`)
+	require.NoError(t, err)
+	_, err = s.SetNextDestination(userID, a, c, ChainPolicyWarn)
+	require.Error(t, err)
+	assert.Equal(t, map[string][]string{a: {d}, d: {b}, b: {a}}, destinations(t, userID))
+}
+
+func TestAutoLinkChainCheck(t *testing.T) {
+	userID, ids := setupAlbums(t, "a", "b", "c", "d")
+	s := NewPathService()
+	a, b, c, d := ids[0], ids[1], ids[2], ids[3]
+
+	_, err := s.CreatePath(userID, c, a, ChainPolicyWarn)
+	require.NoError(t, err)
+	_, err = s.CreatePath(userID, b, d, ChainPolicyWarn)
+	require.NoError(t, err)
+	input := AutoLinkInput{AlbumIDs: []string{a, b, c}, ChainPolicy: ChainPolicyReject}
+
+	// The links are checked together with the existing paths, and with each other
+	input.DryRun = true
+	result, err := s.AutoLink(userID, input)
+	require.NoError(t, err)
+	require.Len(t, result.Paths, 2)
+	assert.Empty(t, result.Paths[0].Warnings)
+	assert.Equal(t, []string{
+		`album "b" already leads to "d", so the chain forks`,
+		`the path closes a loop: b → c → a → b`,
+	}, result.Paths[1].Warnings)
+
+	// Under the reject policy nothing is linked
+	input.DryRun = false
+	_, err = s.AutoLink(userID, input)
+	assert.ErrorIs(t, err, ErrChainConflict)
+	assert.Equal(t, map[string][]string{c: {a}, b: {d}}, destinations(t, userID))
+
+	// Replacing removes the fork, but not the loop
+	input.ReplaceExisting = true
+	input.DryRun = true
+	result, err = s.AutoLink(userID, input)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`the path closes a loop: b → c → a → b`}, result.Paths[1].Warnings)
+
+	// Under the warn policy the links are made and the problems reported
+	input.ChainPolicy = ChainPolicyWarn
+	input.DryRun = false
+	result, err = s.AutoLink(userID, input)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Created)
+	assert.Len(t, result.Paths[1].Warnings, 1)
+	assert.Equal(t, map[string][]string{a: {b}, b: {c}, c: {a}}, destinations(t, userID))
+}
+
+func TestSetPathGeometryAntimeridian(t *testing.T) {
+	userID, ids := setupAlbums(t, "fiji", "samoa")
+	s := NewPathService()
+	path, err := s.CreatePath(userID, ids[0], ids[1], ChainPolicyWarn)
+	require.NoError(t, err)
+
+	points := []polyline.Point{{Lat: -18.1, Lng: 178.4}, {Lat: -16, Lng: 179.9}, {Lat: -14.3, Lng: -170.7}}
+	path, err = s.SetPathGeometry(path.ID, userID, points, datum.WGS84)
+	require.NoError(t, err)
+	line, err := polyline.Decode(path.Geometry)
+	require.NoError(t, err)
+	require.Len(t, line, 3)
+	assert.InDelta(t, 189.3, line[2].Lng, 1e-5, "unwrapped across the antimeridian")
+
+	// The geometry as returned is accepted back unchanged
+	path, err = s.GetPathByID(path.ID, userID)
+	require.NoError(t, err)
+	returned, err := polyline.Decode(path.Geometry)
+	require.NoError(t, err)
+	path, err = s.SetPathGeometry(path.ID, userID, returned, datum.WGS84)
+	require.NoError(t, err)
+	again, err := polyline.Decode(path.Geometry)
+	require.NoError(t, err)
+	assert.Equal(t, line, again)
+
+	_, err = s.SetPathGeometry(path.ID, userID, []polyline.Point{{Lat: 0, Lng: 0}, {Lat: 91, Lng: 0}}, datum.WGS84)
+	assert.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -13,10 +14,23 @@ import (
 	"github.com/google/uuid"
 
 	"geoalbum/backend/dao"
+	"geoalbum/backend/imagemeta"
+	"geoalbum/backend/logging"
 	"geoalbum/backend/middleware"
 	"geoalbum/backend/model"
 )
 
+var (
+	// ErrPhotoNotFound is returned for photos that do not exist
+	ErrPhotoNotFound = errors.New("photo not found")
+	// ErrInvalidPhoto is matched by the errors returned for invalid photo input
+	ErrInvalidPhoto = errors.New("invalid photo")
+)
+
+func invalidPhoto(format string, args ...interface{}) error {
+	return invalidInput(ErrInvalidPhoto, fmt.Errorf(format, args...))
+}
+
 type PhotoService struct {
 	photoDAO *dao.PhotoDAO
 	albumDAO *dao.AlbumDAO
@@ -29,8 +43,39 @@ func NewPhotoService() *PhotoService {
 	}
 }
 
+// MaxPhotoSize is the largest photo file that can be added to an album, matching the
+// request size limit of uploads so that imports cannot store larger files
+const MaxPhotoSize = 10 << 20
+
+// PhotoFile is the content of a photo added to an album. TakenAt and the position,
+// when set, take precedence over the metadata embedded in the file.
+type PhotoFile struct {
+	Filename    string
+	MimeType    string
+	Content     io.Reader
+	TakenAt     *time.Time
+	Latitude    *float64
+	Longitude   *float64
+	Description string
+}
+
 // UploadPhoto uploads a photo to an album
 func (s *PhotoService) UploadPhoto(albumID, userID string, file *multipart.FileHeader) (*model.Photo, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	defer src.Close()
+
+	return s.AddPhoto(albumID, userID, PhotoFile{
+		Filename: file.Filename,
+		MimeType: file.Header.Get("Content-Type"),
+		Content:  src,
+	})
+}
+
+// AddPhoto stores a photo file in an album
+func (s *PhotoService) AddPhoto(albumID, userID string, file PhotoFile) (*model.Photo, error) {
 	// Verify album exists and belongs to user
 	album, err := s.albumDAO.GetByID(albumID)
 	if err != nil {
@@ -44,10 +89,16 @@ func (s *PhotoService) UploadPhoto(albumID, userID string, file *multipart.FileH
 	}
 
 	// Validate file type
-	if !s.isValidImageType(file.Header.Get("Content-Type")) {
+	if !s.isValidImageType(file.MimeType) {
 		return nil, fmt.Errorf("invalid file type: only JPEG, PNG, and HEIC are supported")
 	}
 
+	sanitizer := middleware.GetInputSanitizer()
+	description := sanitizer.SanitizeString(file.Description)
+	if !sanitizer.ValidateAlbumDescription(description) {
+		return nil, fmt.Errorf("invalid photo description: must be max 2000 characters")
+	}
+
 	// Create user-specific uploads directory if it doesn't exist
 	uploadsDir := filepath.Join("data", "uploads", userID)
 	if err := os.MkdirAll(uploadsDir, 0755); err != nil {
@@ -60,21 +111,32 @@ func (s *PhotoService) UploadPhoto(albumID, userID string, file *multipart.FileH
 	filePath := filepath.Join(uploadsDir, filename)
 
 	// Save file to disk
-	src, err := file.Open()
-	if err != nil {
-		return nil, fmt.Errorf("failed to open uploaded file: %w", err)
-	}
-	defer src.Close()
-
 	dst, err := os.Create(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create file: %w", err)
 	}
 	defer dst.Close()
 
-	if _, err := io.Copy(dst, src); err != nil {
+	size, err := io.Copy(dst, io.LimitReader(file.Content, MaxPhotoSize+1))
+	if err != nil {
+		os.Remove(filePath)
 		return nil, fmt.Errorf("failed to save file: %w", err)
 	}
+	if size > MaxPhotoSize {
+		os.Remove(filePath)
+		return nil, fmt.Errorf("photo file is larger than %d MB", MaxPhotoSize>>20)
+	}
+
+	// Read the capture time, star rating and GPS position from embedded metadata; a
+	// camera clock without a recorded offset is interpreted in the album's time zone
+	meta := s.readMetadata(filePath, album.Timezone)
+	if file.TakenAt != nil {
+		takenAt := file.TakenAt.UTC()
+		meta.takenAt = &takenAt
+	}
+	if file.Latitude != nil && file.Longitude != nil {
+		meta.latitude, meta.longitude = file.Latitude, file.Longitude
+	}
 
 	// Get next display order
 	existingPhotos, err := s.photoDAO.GetByAlbumID(albumID)
@@ -89,10 +151,15 @@ func (s *PhotoService) UploadPhoto(albumID, userID string, file *multipart.FileH
 		AlbumID:      albumID,
 		Filename:     file.Filename,
 		FilePath:     filePath,
-		FileSize:     file.Size,
-		MimeType:     file.Header.Get("Content-Type"),
+		FileSize:     size,
+		MimeType:     file.MimeType,
 		DisplayOrder: displayOrder,
 		UploadedAt:   time.Now(),
+		TakenAt:      meta.takenAt,
+		Rating:       meta.rating,
+		Latitude:     meta.latitude,
+		Longitude:    meta.longitude,
+		Description:  description,
 		URL:          fmt.Sprintf("/api/photos/%s/file", uuid.New().String()),
 	}
 
@@ -105,11 +172,22 @@ func (s *PhotoService) UploadPhoto(albumID, userID string, file *multipart.FileH
 	// Set the correct URL with the photo ID
 	photo.URL = fmt.Sprintf("/api/photos/%s/file", photo.ID)
 
+	if meta.takenAt != nil {
+		if err := s.albumDAO.RefreshDateRange(albumID); err != nil {
+			logging.WithError(err).WithField("album_id", albumID).Warn("Failed to refresh album date range")
+		}
+	}
+
 	return photo, nil
 }
 
 // GetPhotosByAlbumID retrieves all photos for an album
 func (s *PhotoService) GetPhotosByAlbumID(albumID, userID string) ([]model.Photo, error) {
+	return s.GetPhotosByAlbumIDFiltered(albumID, userID, model.PhotoFilter{})
+}
+
+// GetPhotosByAlbumIDFiltered retrieves the photos of an album matching filter
+func (s *PhotoService) GetPhotosByAlbumIDFiltered(albumID, userID string, filter model.PhotoFilter) ([]model.Photo, error) {
 	// Verify album exists and belongs to user
 	album, err := s.albumDAO.GetByID(albumID)
 	if err != nil {
@@ -122,7 +200,7 @@ func (s *PhotoService) GetPhotosByAlbumID(albumID, userID string) ([]model.Photo
 		return nil, fmt.Errorf("access denied: album does not belong to user")
 	}
 
-	photos, err := s.photoDAO.GetByAlbumID(albumID)
+	photos, err := s.photoDAO.GetByAlbumIDFiltered(albumID, userID, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get photos: %w", err)
 	}
@@ -135,6 +213,20 @@ func (s *PhotoService) GetPhotosByAlbumID(albumID, userID string) ([]model.Photo
 	return photos, nil
 }
 
+// GetPhotosByUserIDFiltered retrieves photos across all of a user's albums matching filter
+func (s *PhotoService) GetPhotosByUserIDFiltered(userID string, filter model.PhotoFilter) ([]model.Photo, error) {
+	photos, err := s.photoDAO.GetByUserIDFiltered(userID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get photos: %w", err)
+	}
+
+	for i := range photos {
+		photos[i].URL = fmt.Sprintf("/api/photos/%s/file", photos[i].ID)
+	}
+
+	return photos, nil
+}
+
 // GetPhotoByID retrieves a photo by ID and verifies user access
 func (s *PhotoService) GetPhotoByID(photoID, userID string) (*model.Photo, error) {
 	photo, err := s.photoDAO.GetByID(photoID)
@@ -142,7 +234,7 @@ func (s *PhotoService) GetPhotoByID(photoID, userID string) (*model.Photo, error
 		return nil, fmt.Errorf("failed to get photo: %w", err)
 	}
 	if photo == nil {
-		return nil, fmt.Errorf("photo not found")
+		return nil, ErrPhotoNotFound
 	}
 
 	// Verify album belongs to user
@@ -179,6 +271,12 @@ func (s *PhotoService) DeletePhoto(photoID, userID string) error {
 		return fmt.Errorf("failed to delete photo from database: %w", err)
 	}
 
+	if photo.TakenAt != nil {
+		if err := s.albumDAO.RefreshDateRange(photo.AlbumID); err != nil {
+			logging.WithError(err).WithField("album_id", photo.AlbumID).Warn("Failed to refresh album date range")
+		}
+	}
+
 	return nil
 }
 
@@ -197,6 +295,32 @@ func (s *PhotoService) UpdatePhotoOrder(photoID, userID string, newOrder int) er
 	return nil
 }
 
+// MaxBulkPhotoUpdate caps how many photos one bulk update may touch
+const MaxBulkPhotoUpdate = 500
+
+// UpdatePhotoRatings sets the star rating and/or favorite flag of several photos at once.
+// Photos that do not exist or belong to another user are skipped; the number updated is returned.
+func (s *PhotoService) UpdatePhotoRatings(userID string, photoIDs []string, rating *int, favorite *bool) (int64, error) {
+	if len(photoIDs) == 0 {
+		return 0, invalidPhoto("no photos specified")
+	}
+	if len(photoIDs) > MaxBulkPhotoUpdate {
+		return 0, invalidPhoto("too many photos: at most %d can be updated at once", MaxBulkPhotoUpdate)
+	}
+	if rating == nil && favorite == nil {
+		return 0, invalidPhoto("rating or favorite must be provided")
+	}
+	if rating != nil && (*rating < 0 || *rating > 5) {
+		return 0, invalidPhoto("rating must be between 0 and 5")
+	}
+
+	updated, err := s.photoDAO.UpdateRatings(userID, photoIDs, rating, favorite)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update photo ratings: %w", err)
+	}
+	return updated, nil
+}
+
 // GetPhotoFile returns the file path for serving the photo file
 func (s *PhotoService) GetPhotoFile(photoID, userID string) (string, error) {
 	photo, err := s.GetPhotoByID(photoID, userID)
@@ -212,6 +336,38 @@ func (s *PhotoService) GetPhotoFile(photoID, userID string) (string, error) {
 	return photo.FilePath, nil
 }
 
+// photoMetadata holds the fields read from a photo's embedded metadata
+type photoMetadata struct {
+	takenAt   *time.Time
+	rating    int
+	latitude  *float64
+	longitude *float64
+}
+
+// readMetadata returns the capture time, star rating and GPS position of a saved
+// photo; unknown fields are left empty
+func (s *PhotoService) readMetadata(filePath, timezone string) photoMetadata {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return photoMetadata{}
+	}
+	defer file.Close()
+
+	meta, err := imagemeta.Extract(file)
+	if err != nil {
+		logging.WithError(err).WithField("file", filePath).Debug("Failed to read photo metadata")
+		return photoMetadata{}
+	}
+
+	result := photoMetadata{rating: meta.Rating, latitude: meta.Latitude, longitude: meta.Longitude}
+	loc, _ := loadTimezone(timezone)
+	if takenAt, ok := meta.TakenAt(loc); ok {
+		takenAt = takenAt.UTC()
+		result.takenAt = &takenAt
+	}
+	return result
+}
+
 // isValidImageType checks if the MIME type is supported
 func (s *PhotoService) isValidImageType(mimeType string) bool {
 	validTypes := []string{
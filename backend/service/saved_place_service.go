@@ -0,0 +1,314 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"geoalbum/backend/dao"
+	"geoalbum/backend/datum"
+	"geoalbum/backend/geofence"
+	"geoalbum/backend/logging"
+	"geoalbum/backend/middleware"
+	"geoalbum/backend/model"
+)
+
+// Saved place shape limits
+const (
+	maxPlaceRadiusM       = 500000
+	maxPlacePolygonPoints = 1000
+)
+
+var (
+	// ErrSavedPlaceExists is returned when a place name is already used by the user
+	ErrSavedPlaceExists = errors.New("saved place already exists")
+	// ErrSavedPlaceNotFound is returned for places that do not exist
+	ErrSavedPlaceNotFound = errors.New("saved place not found")
+	// ErrSavedPlaceAccessDenied is returned for places of another user
+	ErrSavedPlaceAccessDenied = errors.New("access denied: saved place does not belong to user")
+	// ErrInvalidSavedPlace is matched by the errors returned for invalid place input
+	ErrInvalidSavedPlace = errors.New("invalid saved place")
+)
+
+func invalidSavedPlace(format string, args ...interface{}) error {
+	return invalidInput(ErrInvalidSavedPlace, fmt.Errorf(format, args...))
+}
+
+type SavedPlaceService struct {
+	savedPlaceDAO *dao.SavedPlaceDAO
+	albumDAO      *dao.AlbumDAO
+	tagService    *TagService
+	sanitizer     *middleware.InputSanitizer
+}
+
+func NewSavedPlaceService() *SavedPlaceService {
+	return &SavedPlaceService{
+		savedPlaceDAO: dao.NewSavedPlaceDAO(),
+		albumDAO:      dao.NewAlbumDAO(),
+		tagService:    NewTagService(),
+		sanitizer:     middleware.GetInputSanitizer(),
+	}
+}
+
+// SavedPlaceInput describes a place: either Latitude, Longitude and RadiusM, or Polygon
+type SavedPlaceInput struct {
+	Name      string
+	Latitude  *float64
+	Longitude *float64
+	RadiusM   *float64
+	Polygon   [][2]float64 // [longitude, latitude] vertices
+	AutoTag   bool         // tag new albums inside the place with its name
+	Datum     datum.Datum  // datum of the coordinates; stored as WGS-84
+}
+
+// CreateSavedPlace creates a named place for a user
+func (s *SavedPlaceService) CreateSavedPlace(userID string, input SavedPlaceInput) (*model.SavedPlace, error) {
+	place := &model.SavedPlace{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		CreatedAt: time.Now(),
+	}
+	if err := s.applyInput(place, input); err != nil {
+		return nil, err
+	}
+	place.UpdatedAt = place.CreatedAt
+
+	if err := s.savedPlaceDAO.Create(place); err != nil {
+		return nil, fmt.Errorf("failed to create saved place: %w", err)
+	}
+	return place, nil
+}
+
+// GetSavedPlaces retrieves a user's places with the number of albums inside each
+func (s *SavedPlaceService) GetSavedPlaces(userID string) ([]model.SavedPlace, error) {
+	places, err := s.savedPlaceDAO.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved places: %w", err)
+	}
+	if len(places) == 0 {
+		return places, nil
+	}
+
+	albums, err := s.albumDAO.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved places: %w", err)
+	}
+	for i := range places {
+		contains := placeContains(&places[i])
+		for _, album := range albums {
+			if contains(album.Latitude, album.Longitude) {
+				places[i].AlbumCount++
+			}
+		}
+	}
+	return places, nil
+}
+
+// GetSavedPlaceByID retrieves a place and ensures it belongs to the user
+func (s *SavedPlaceService) GetSavedPlaceByID(id, userID string) (*model.SavedPlace, error) {
+	place, err := s.savedPlaceDAO.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved place: %w", err)
+	}
+	if place == nil {
+		return nil, ErrSavedPlaceNotFound
+	}
+	if place.UserID != userID {
+		return nil, ErrSavedPlaceAccessDenied
+	}
+	return place, nil
+}
+
+// UpdateSavedPlace replaces a place's name, shape and tagging. Albums already tagged
+// keep their tags.
+func (s *SavedPlaceService) UpdateSavedPlace(id, userID string, input SavedPlaceInput) (*model.SavedPlace, error) {
+	place, err := s.GetSavedPlaceByID(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyInput(place, input); err != nil {
+		return nil, err
+	}
+	place.UpdatedAt = time.Now()
+
+	if err := s.savedPlaceDAO.Update(place); err != nil {
+		return nil, fmt.Errorf("failed to update saved place: %w", err)
+	}
+	return place, nil
+}
+
+// DeleteSavedPlace deletes a place; tags it gave to albums are kept
+func (s *SavedPlaceService) DeleteSavedPlace(id, userID string) error {
+	if _, err := s.GetSavedPlaceByID(id, userID); err != nil {
+		return err
+	}
+	if err := s.savedPlaceDAO.Delete(id, userID); err != nil {
+		return fmt.Errorf("failed to delete saved place: %w", err)
+	}
+	return nil
+}
+
+// GetAlbumsInPlace retrieves the user's albums located inside a place
+func (s *SavedPlaceService) GetAlbumsInPlace(id, userID string) ([]model.Album, error) {
+	place, err := s.GetSavedPlaceByID(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	minLat, minLng, maxLat, maxLng := placeBounds(place)
+	candidates, err := s.albumDAO.GetByUserIDFiltered(userID, model.AlbumFilter{
+		BBox: &model.BoundingBox{MinLat: minLat, MinLng: minLng, MaxLat: maxLat, MaxLng: maxLng},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get albums in saved place: %w", err)
+	}
+
+	contains := placeContains(place)
+	albums := []model.Album{}
+	for _, album := range candidates {
+		if contains(album.Latitude, album.Longitude) {
+			localizeAlbum(&album)
+			albums = append(albums, album)
+		}
+	}
+	return albums, nil
+}
+
+// TagAlbum tags an album with the names of the auto-tagging places it lies in and
+// returns the names added
+func (s *SavedPlaceService) TagAlbum(album *model.Album) ([]string, error) {
+	places, err := s.savedPlaceDAO.GetByUserID(album.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved places: %w", err)
+	}
+
+	var names []string
+	for i := range places {
+		if places[i].AutoTag && placeContains(&places[i])(album.Latitude, album.Longitude) {
+			names = append(names, places[i].Name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	if _, err := s.tagService.AttachTagsToAlbum(album.ID, album.UserID, names); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// tagAlbumWithPlaces applies TagAlbum, logging rather than failing on errors, and
+// records the new tags on the album
+func (s *SavedPlaceService) tagAlbumWithPlaces(album *model.Album) {
+	names, err := s.TagAlbum(album)
+	if err != nil {
+		logging.WithError(err).WithField("album_id", album.ID).Warn("Failed to tag album with saved places")
+		return
+	}
+	for _, name := range names {
+		if !containsFold(album.Tags, name) {
+			album.Tags = append(album.Tags, name)
+		}
+	}
+}
+
+// applyInput validates input and copies it onto place
+func (s *SavedPlaceService) applyInput(place *model.SavedPlace, input SavedPlaceInput) error {
+	name := strings.Join(strings.Fields(s.sanitizer.SanitizeString(input.Name)), " ")
+	if !s.sanitizer.ValidateTagName(name) {
+		return invalidSavedPlace("invalid place name: must be 1-50 characters without commas")
+	}
+	if s.sanitizer.DetectSQLInjection(name) {
+		return invalidSavedPlace("invalid place name: contains prohibited characters")
+	}
+	existing, err := s.savedPlaceDAO.GetByName(place.UserID, name)
+	if err != nil {
+		return fmt.Errorf("failed to check existing saved place: %w", err)
+	}
+	if existing != nil && existing.ID != place.ID {
+		return ErrSavedPlaceExists
+	}
+
+	circle := input.Latitude != nil || input.Longitude != nil || input.RadiusM != nil
+	polygon := len(input.Polygon) > 0
+	switch {
+	case circle && polygon:
+		return invalidSavedPlace("invalid place: give either a centre and radius or a polygon, not both")
+	case circle:
+		if input.Latitude == nil || input.Longitude == nil || input.RadiusM == nil {
+			return invalidSavedPlace("invalid place: latitude, longitude and radius_m are all required")
+		}
+		if !s.sanitizer.ValidateCoordinates(*input.Latitude, *input.Longitude) {
+			return invalidSavedPlace("invalid coordinates: latitude must be -90 to 90, longitude must be -180 to 180")
+		}
+		if *input.RadiusM <= 0 || *input.RadiusM > maxPlaceRadiusM {
+			return invalidSavedPlace("invalid radius: must be greater than 0 and at most %d metres", maxPlaceRadiusM)
+		}
+		latitude, longitude := datum.ToWGS84(*input.Latitude, *input.Longitude, input.Datum)
+		place.Kind = model.SavedPlaceCircle
+		place.Latitude, place.Longitude, place.RadiusM = &latitude, &longitude, input.RadiusM
+		place.Polygon = nil
+	case polygon:
+		ring := input.Polygon
+		if len(ring) > 1 && ring[len(ring)-1] == ring[0] {
+			ring = ring[:len(ring)-1]
+		}
+		if len(ring) < 3 || len(ring) > maxPlacePolygonPoints {
+			return invalidSavedPlace("invalid polygon: must have 3-%d distinct vertices", maxPlacePolygonPoints)
+		}
+		polygon := make([][2]float64, len(ring))
+		for i, vertex := range ring {
+			if !s.sanitizer.ValidateCoordinates(vertex[1], vertex[0]) {
+				return invalidSavedPlace("invalid polygon: vertices are [longitude, latitude] within -180 to 180 and -90 to 90")
+			}
+			latitude, longitude := datum.ToWGS84(vertex[1], vertex[0], input.Datum)
+			polygon[i] = [2]float64{longitude, latitude}
+		}
+		place.Kind = model.SavedPlacePolygon
+		place.Polygon = polygon
+		place.Latitude, place.Longitude, place.RadiusM = nil, nil, nil
+	default:
+		return invalidSavedPlace("invalid place: a centre and radius or a polygon is required")
+	}
+
+	place.Name = name
+	place.AutoTag = input.AutoTag
+	return nil
+}
+
+// placeContains returns the containment test for a place's shape
+func placeContains(place *model.SavedPlace) func(lat, lng float64) bool {
+	if place.Kind == model.SavedPlacePolygon {
+		return geofence.Polygon(place.Polygon).Contains
+	}
+	return placeCircle(place).Contains
+}
+
+// placeBounds returns a box enclosing a place
+func placeBounds(place *model.SavedPlace) (minLat, minLng, maxLat, maxLng float64) {
+	if place.Kind == model.SavedPlacePolygon {
+		return geofence.Polygon(place.Polygon).Bounds()
+	}
+	return placeCircle(place).Bounds()
+}
+
+func placeCircle(place *model.SavedPlace) geofence.Circle {
+	var circle geofence.Circle
+	if place.Latitude != nil && place.Longitude != nil && place.RadiusM != nil {
+		circle = geofence.Circle{Latitude: *place.Latitude, Longitude: *place.Longitude, RadiusM: *place.RadiusM}
+	}
+	return circle
+}
+
+// containsFold reports whether names contains name, ignoring case
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
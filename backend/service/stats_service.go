@@ -0,0 +1,229 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"geoalbum/backend/dao"
+	"geoalbum/backend/model"
+	"geoalbum/backend/polyline"
+)
+
+// unspecifiedTransportMode keys the distance of paths without a transport mode
+const unspecifiedTransportMode = "unspecified"
+
+type StatsService struct {
+	albumDAO *dao.AlbumDAO
+	photoDAO *dao.PhotoDAO
+	pathDAO  *dao.PathDAO
+	userDAO  *dao.UserDAO
+
+	mu    sync.Mutex
+	cache map[string]statsCacheEntry
+}
+
+// statsCacheEntry holds a user's stats computed at a given data version
+type statsCacheEntry struct {
+	version int64
+	stats   *model.Stats
+}
+
+func NewStatsService() *StatsService {
+	return &StatsService{
+		albumDAO: dao.NewAlbumDAO(),
+		photoDAO: dao.NewPhotoDAO(),
+		pathDAO:  dao.NewPathDAO(),
+		userDAO:  dao.NewUserDAO(),
+		cache:    make(map[string]statsCacheEntry),
+	}
+}
+
+// GetStats returns a user's travel statistics. Results are cached until the user's
+// albums, photos or paths change.
+func (s *StatsService) GetStats(userID string) (*model.Stats, error) {
+	version, err := s.userDAO.GetDataVersion(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+
+	s.mu.Lock()
+	entry, ok := s.cache[userID]
+	s.mu.Unlock()
+	if ok && entry.version == version {
+		return entry.stats, nil
+	}
+
+	stats, err := s.computeStats(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[userID] = statsCacheEntry{version: version, stats: stats}
+	s.mu.Unlock()
+	return stats, nil
+}
+
+// computeStats aggregates a user's albums, photos and paths. Calendar years and
+// months are taken in each album's local time zone.
+func (s *StatsService) computeStats(userID string) (*model.Stats, error) {
+	albums, err := s.albumDAO.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+	photos, err := s.photoDAO.GetCaptureTimesByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+	paths, err := s.pathDAO.GetByUserIDWithAlbums(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+
+	stats := &model.Stats{
+		Albums:         len(albums),
+		Photos:         len(photos),
+		Paths:          len(paths),
+		CountryCodes:   []string{},
+		DistanceByMode: map[string]float64{},
+		Years:          []model.YearStats{},
+		GeneratedAt:    time.Now().UTC(),
+	}
+
+	years := make(map[int]*yearAccumulator)
+	year := func(y int) *yearAccumulator {
+		if years[y] == nil {
+			years[y] = &yearAccumulator{stats: model.YearStats{Year: y}, countries: map[string]bool{}, cities: map[string]bool{}}
+		}
+		return years[y]
+	}
+	months := make(map[string]*model.MonthStats)
+	month := func(t time.Time) *model.MonthStats {
+		key := t.Format("2006-01")
+		if months[key] == nil {
+			months[key] = &model.MonthStats{Month: key}
+		}
+		return months[key]
+	}
+
+	countries := make(map[string]bool)
+	cities := make(map[string]bool)
+	albumsByID := make(map[string]*model.Album, len(albums))
+	locations := make(map[string]*time.Location, len(albums))
+	for i := range albums {
+		album := &albums[i]
+		albumsByID[album.ID] = album
+		loc, _ := loadTimezone(album.Timezone)
+		if loc == nil {
+			loc = time.UTC
+		}
+		locations[album.ID] = loc
+
+		start := album.StartAt.In(loc)
+		stats.StorageBytes += album.StorageBytes
+		if stats.FirstTripAt == nil || album.StartAt.Before(*stats.FirstTripAt) {
+			first := start
+			stats.FirstTripAt = &first
+		}
+		if stats.LastTripAt == nil || album.EndAt.After(*stats.LastTripAt) {
+			last := album.EndAt.In(loc)
+			stats.LastTripAt = &last
+		}
+
+		acc := year(start.Year())
+		acc.stats.Albums++
+		acc.stats.StorageBytes += album.StorageBytes
+		month(start).Albums++
+
+		if album.CountryCode != "" {
+			countries[album.CountryCode] = true
+			acc.countries[album.CountryCode] = true
+		}
+		if album.City != "" {
+			key := album.CountryCode + "|" + album.Region + "|" + album.City
+			cities[key] = true
+			acc.cities[key] = true
+		}
+	}
+
+	for _, photo := range photos {
+		album := albumsByID[photo.AlbumID]
+		if album == nil {
+			continue
+		}
+		taken := album.StartAt
+		if photo.TakenAt != nil {
+			taken = *photo.TakenAt
+		}
+		taken = taken.In(locations[album.ID])
+		year(taken.Year()).stats.Photos++
+		month(taken).Photos++
+	}
+
+	for _, path := range paths {
+		if path.FromAlbum == nil || path.ToAlbum == nil {
+			continue
+		}
+		// Measured along the path's geometry when it has one
+		distance := polyline.LengthKm(pathLine(&path))
+		stats.DistanceKm += distance
+		mode := path.TransportMode
+		if mode == "" {
+			mode = unspecifiedTransportMode
+		}
+		stats.DistanceByMode[mode] += distance
+		// A leg counts towards the year it arrives in
+		arrival := path.ToAlbum.StartAt
+		if loc := locations[path.ToAlbumID]; loc != nil {
+			arrival = arrival.In(loc)
+		}
+		year(arrival.Year()).stats.DistanceKm += distance
+	}
+
+	stats.Countries = len(countries)
+	stats.Cities = len(cities)
+	for code := range countries {
+		stats.CountryCodes = append(stats.CountryCodes, code)
+	}
+	sort.Strings(stats.CountryCodes)
+	stats.DistanceKm = roundKm(stats.DistanceKm)
+	for mode, distance := range stats.DistanceByMode {
+		stats.DistanceByMode[mode] = roundKm(distance)
+	}
+
+	for _, acc := range years {
+		acc.stats.Countries = len(acc.countries)
+		acc.stats.Cities = len(acc.cities)
+		acc.stats.DistanceKm = roundKm(acc.stats.DistanceKm)
+		stats.Years = append(stats.Years, acc.stats)
+	}
+	sort.Slice(stats.Years, func(i, j int) bool {
+		return stats.Years[i].Year < stats.Years[j].Year
+	})
+
+	for _, m := range months {
+		busiest := stats.BusiestMonth
+		if busiest == nil || m.Photos > busiest.Photos ||
+			(m.Photos == busiest.Photos && (m.Albums > busiest.Albums ||
+				(m.Albums == busiest.Albums && m.Month < busiest.Month))) {
+			stats.BusiestMonth = m
+		}
+	}
+
+	return stats, nil
+}
+
+// yearAccumulator collects one year's stats along with the distinct places visited
+type yearAccumulator struct {
+	stats     model.YearStats
+	countries map[string]bool
+	cities    map[string]bool
+}
+
+// roundKm rounds a distance to 100 metres
+func roundKm(km float64) float64 {
+	return math.Round(km*10) / 10
+}
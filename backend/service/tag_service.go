@@ -0,0 +1,330 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"geoalbum/backend/dao"
+	"geoalbum/backend/middleware"
+	"geoalbum/backend/model"
+)
+
+var (
+	// ErrTagExists is returned when a tag name is already used by the user
+	ErrTagExists = errors.New("tag already exists")
+	// ErrTagNotFound is returned for tags that do not exist
+	ErrTagNotFound = errors.New("tag not found")
+	// ErrTagAccessDenied is returned for tags of another user
+	ErrTagAccessDenied = errors.New("access denied: tag does not belong to user")
+	// ErrInvalidTag is matched by the errors returned for invalid tag input
+	ErrInvalidTag = errors.New("invalid tag")
+)
+
+func invalidTag(format string, args ...interface{}) error {
+	return invalidInput(ErrInvalidTag, fmt.Errorf(format, args...))
+}
+
+type TagService struct {
+	tagDAO    *dao.TagDAO
+	albumDAO  *dao.AlbumDAO
+	photoDAO  *dao.PhotoDAO
+	sanitizer *middleware.InputSanitizer
+}
+
+func NewTagService() *TagService {
+	return &TagService{
+		tagDAO:    dao.NewTagDAO(),
+		albumDAO:  dao.NewAlbumDAO(),
+		photoDAO:  dao.NewPhotoDAO(),
+		sanitizer: middleware.GetInputSanitizer(),
+	}
+}
+
+// CreateTag creates a new tag for a user
+func (s *TagService) CreateTag(userID, name string) (*model.Tag, error) {
+	name, err := s.sanitizeTagName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.tagDAO.GetByName(userID, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing tag: %w", err)
+	}
+	if existing != nil {
+		return nil, ErrTagExists
+	}
+
+	return s.createTag(userID, name)
+}
+
+// createTag stores a tag whose name has already been sanitized
+func (s *TagService) createTag(userID, name string) (*model.Tag, error) {
+	tag := &model.Tag{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      name,
+		CreatedAt: time.Now(),
+	}
+	if err := s.tagDAO.Create(tag); err != nil {
+		return nil, fmt.Errorf("failed to create tag: %w", err)
+	}
+
+	return tag, nil
+}
+
+// GetTagsByUserID retrieves all tags for a user with usage counts
+func (s *TagService) GetTagsByUserID(userID string) ([]model.Tag, error) {
+	tags, err := s.tagDAO.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+	return tags, nil
+}
+
+// GetTagByID retrieves a tag by ID and ensures it belongs to the user
+func (s *TagService) GetTagByID(id, userID string) (*model.Tag, error) {
+	tag, err := s.tagDAO.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tag: %w", err)
+	}
+	if tag == nil {
+		return nil, ErrTagNotFound
+	}
+	if tag.UserID != userID {
+		return nil, ErrTagAccessDenied
+	}
+	return tag, nil
+}
+
+// RenameTag renames a tag; renaming onto another existing tag is rejected (merge instead)
+func (s *TagService) RenameTag(id, userID, name string) (*model.Tag, error) {
+	if _, err := s.GetTagByID(id, userID); err != nil {
+		return nil, err
+	}
+
+	name, err := s.sanitizeTagName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.tagDAO.GetByName(userID, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing tag: %w", err)
+	}
+	if existing != nil && existing.ID != id {
+		return nil, ErrTagExists
+	}
+
+	if err := s.tagDAO.Rename(id, userID, name); err != nil {
+		return nil, fmt.Errorf("failed to rename tag: %w", err)
+	}
+
+	return s.GetTagByID(id, userID)
+}
+
+// MergeTags folds the source tags into the target tag and deletes the sources
+func (s *TagService) MergeTags(targetID, userID string, sourceIDs []string) (*model.Tag, error) {
+	if _, err := s.GetTagByID(targetID, userID); err != nil {
+		return nil, err
+	}
+
+	var sources []string
+	for _, sourceID := range sourceIDs {
+		if sourceID == targetID {
+			continue
+		}
+		if _, err := s.GetTagByID(sourceID, userID); err != nil {
+			return nil, err
+		}
+		sources = append(sources, sourceID)
+	}
+	if len(sources) == 0 {
+		return nil, invalidTag("no tags to merge")
+	}
+
+	if err := s.tagDAO.Merge(targetID, sources, userID); err != nil {
+		return nil, fmt.Errorf("failed to merge tags: %w", err)
+	}
+
+	return s.GetTagByID(targetID, userID)
+}
+
+// DeleteTag deletes a tag and detaches it everywhere
+func (s *TagService) DeleteTag(id, userID string) error {
+	if _, err := s.GetTagByID(id, userID); err != nil {
+		return err
+	}
+
+	if err := s.tagDAO.Delete(id, userID); err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+	return nil
+}
+
+// AttachTagsToAlbum attaches tags by name to an album, creating missing tags
+func (s *TagService) AttachTagsToAlbum(albumID, userID string, names []string) ([]model.Tag, error) {
+	if err := s.checkAlbumOwner(albumID, userID); err != nil {
+		return nil, err
+	}
+
+	tags, err := s.getOrCreateTags(userID, names)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		if err := s.tagDAO.AttachToAlbum(albumID, tag.ID); err != nil {
+			return nil, fmt.Errorf("failed to attach tag: %w", err)
+		}
+	}
+
+	return s.tagDAO.GetByAlbumID(albumID)
+}
+
+// DetachTagFromAlbum removes a tag from an album
+func (s *TagService) DetachTagFromAlbum(albumID, userID, tagID string) error {
+	if err := s.checkAlbumOwner(albumID, userID); err != nil {
+		return err
+	}
+	if _, err := s.GetTagByID(tagID, userID); err != nil {
+		return err
+	}
+
+	if err := s.tagDAO.DetachFromAlbum(albumID, tagID); err != nil {
+		return fmt.Errorf("failed to detach tag: %w", err)
+	}
+	return nil
+}
+
+// AttachTagsToPhoto attaches tags by name to a photo, creating missing tags
+func (s *TagService) AttachTagsToPhoto(photoID, userID string, names []string) (*model.Photo, error) {
+	if err := s.checkPhotoOwner(photoID, userID); err != nil {
+		return nil, err
+	}
+
+	tags, err := s.getOrCreateTags(userID, names)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		if err := s.tagDAO.AttachToPhoto(photoID, tag.ID); err != nil {
+			return nil, fmt.Errorf("failed to attach tag: %w", err)
+		}
+	}
+
+	photo, err := s.photoDAO.GetByID(photoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get photo: %w", err)
+	}
+	photo.URL = fmt.Sprintf("/api/photos/%s/file", photo.ID)
+	return photo, nil
+}
+
+// DetachTagFromPhoto removes a tag from a photo
+func (s *TagService) DetachTagFromPhoto(photoID, userID, tagID string) error {
+	if err := s.checkPhotoOwner(photoID, userID); err != nil {
+		return err
+	}
+	if _, err := s.GetTagByID(tagID, userID); err != nil {
+		return err
+	}
+
+	if err := s.tagDAO.DetachFromPhoto(photoID, tagID); err != nil {
+		return fmt.Errorf("failed to detach tag: %w", err)
+	}
+	return nil
+}
+
+// ParseTagFilter parses a comma-separated tags= query value into distinct sanitized
+// names. Empty entries are skipped; any invalid name is an error, rather than being
+// dropped and widening the filter.
+func (s *TagService) ParseTagFilter(raw string) ([]string, error) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(raw, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		name, err := s.sanitizeTagName(part)
+		if err != nil {
+			return nil, err
+		}
+		key := strings.ToLower(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// getOrCreateTags resolves tag names to the user's tags, creating missing ones
+func (s *TagService) getOrCreateTags(userID string, names []string) ([]model.Tag, error) {
+	if len(names) == 0 {
+		return nil, invalidTag("no tags given")
+	}
+
+	var tags []model.Tag
+	for _, name := range names {
+		name, err := s.sanitizeTagName(name)
+		if err != nil {
+			return nil, err
+		}
+
+		tag, err := s.tagDAO.GetByName(userID, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tag: %w", err)
+		}
+		if tag == nil {
+			if tag, err = s.createTag(userID, name); err != nil {
+				return nil, err
+			}
+		}
+		tags = append(tags, *tag)
+	}
+	return tags, nil
+}
+
+// sanitizeTagName sanitizes, collapses whitespace and validates a tag name
+func (s *TagService) sanitizeTagName(name string) (string, error) {
+	name = strings.Join(strings.Fields(s.sanitizer.SanitizeString(name)), " ")
+	if !s.sanitizer.ValidateTagName(name) {
+		return "", invalidTag("invalid tag name: must be 1-50 characters without commas")
+	}
+	if s.sanitizer.DetectSQLInjection(name) {
+		return "", invalidTag("invalid tag name: contains prohibited characters")
+	}
+	return name, nil
+}
+
+// checkAlbumOwner ensures an album exists and belongs to the user
+func (s *TagService) checkAlbumOwner(albumID, userID string) error {
+	album, err := s.albumDAO.GetByID(albumID)
+	if err != nil {
+		return fmt.Errorf("failed to get album: %w", err)
+	}
+	if album == nil {
+		return ErrAlbumNotFound
+	}
+	if album.UserID != userID {
+		return ErrAlbumAccessDenied
+	}
+	return nil
+}
+
+// checkPhotoOwner ensures a photo exists and its album belongs to the user
+func (s *TagService) checkPhotoOwner(photoID, userID string) error {
+	photo, err := s.photoDAO.GetByID(photoID)
+	if err != nil {
+		return fmt.Errorf("failed to get photo: %w", err)
+	}
+	if photo == nil {
+		return ErrPhotoNotFound
+	}
+	return s.checkAlbumOwner(photo.AlbumID, userID)
+}
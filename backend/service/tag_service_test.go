@@ -0,0 +1,27 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTagFilter(t *testing.T) {
+	s := NewTagService()
+
+	names, err := s.ParseTagFilter("")
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	names, err = s.ParseTagFilter(" beach ,  road   trip,Beach,,")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"beach", "road trip"}, names)
+
+	// An invalid name fails the whole filter instead of being dropped from it
+	_, err = s.ParseTagFilter("beach," + strings.Repeat("x", 60))
+	assert.ErrorIs(t, err, ErrInvalidTag)
+	_, err = s.ParseTagFilter("beach,'; DROP TABLE tags; --")
+	assert.ErrorIs(t, err, ErrInvalidTag)
+}
@@ -0,0 +1,453 @@
+package service
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"geoalbum/backend/dao"
+	"geoalbum/backend/datum"
+	"geoalbum/backend/logging"
+	"geoalbum/backend/middleware"
+	"geoalbum/backend/model"
+	"geoalbum/backend/takeout"
+)
+
+// Ways of grouping Takeout photos into albums
+const (
+	TakeoutGroupFolder  = "folder"  // one album per Google Photos album
+	TakeoutGroupCluster = "cluster" // albums from photos taken close together
+)
+
+// Default clustering parameters for photos outside Google Photos albums
+const (
+	DefaultTakeoutRadiusKm = 2.0
+	DefaultTakeoutMaxGap   = 24 * time.Hour
+)
+
+// takeoutJobRetention is how long finished Takeout import jobs remain queryable
+const takeoutJobRetention = 24 * time.Hour
+
+// ErrImportJobNotFound is returned for import jobs that do not exist or belong to
+// another user
+var ErrImportJobNotFound = errors.New("import job not found")
+
+// takeoutJob is a running or finished Takeout import
+type takeoutJob struct {
+	mu  sync.Mutex
+	job model.TakeoutImportJob
+}
+
+func (j *takeoutJob) snapshot() model.TakeoutImportJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job := j.job
+	job.Errors = append([]string(nil), j.job.Errors...)
+	return job
+}
+
+// update applies a change to the job's state
+func (j *takeoutJob) update(change func(job *model.TakeoutImportJob)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	change(&j.job)
+}
+
+// addError records a per-photo or per-album error, keeping the first
+// MaxImportJobErrors of them
+func (j *takeoutJob) addError(format string, args ...interface{}) {
+	j.update(func(state *model.TakeoutImportJob) {
+		if len(state.Errors) < model.MaxImportJobErrors {
+			state.Errors = append(state.Errors, fmt.Sprintf(format, args...))
+		}
+	})
+}
+
+// Import jobs are shared by every TakeoutService so they can be polled from any request
+var (
+	takeoutJobsMu sync.Mutex
+	takeoutJobs   = make(map[string]*takeoutJob)
+)
+
+type TakeoutService struct {
+	albumDAO     *dao.AlbumDAO
+	photoDAO     *dao.PhotoDAO
+	albumService *AlbumService
+	photoService *PhotoService
+}
+
+func NewTakeoutService() *TakeoutService {
+	return &TakeoutService{
+		albumDAO:     dao.NewAlbumDAO(),
+		photoDAO:     dao.NewPhotoDAO(),
+		albumService: NewAlbumService(),
+		photoService: NewPhotoService(),
+	}
+}
+
+// TakeoutImportOptions controls how a Takeout export is turned into albums
+type TakeoutImportOptions struct {
+	Group        string        // TakeoutGroupFolder or TakeoutGroupCluster
+	RadiusKm     float64       // photos this close to a cluster's centre join it
+	MaxGap       time.Duration // a cluster ends after this long without photos
+	RemoveSource bool          // delete the export when the job ends, for uploaded files
+}
+
+// StartTakeoutImport starts importing a Google Takeout export, either a ZIP file or
+// an extracted folder, into the user's albums. The returned job can be polled with
+// GetTakeoutJob.
+func (s *TakeoutService) StartTakeoutImport(userID, source string, options TakeoutImportOptions) (*model.TakeoutImportJob, error) {
+	export, err := openTakeout(source, options)
+	if err != nil {
+		return nil, err
+	}
+
+	pruneTakeoutJobs()
+	job := newTakeoutJob(userID, options)
+	takeoutJobsMu.Lock()
+	takeoutJobs[job.job.ID] = job
+	takeoutJobsMu.Unlock()
+
+	go s.run(job, export, takeoutDefaults(options))
+	state := job.snapshot()
+	return &state, nil
+}
+
+// ImportTakeout imports a Google Takeout export and waits for it to finish, calling
+// progress after each photo
+func (s *TakeoutService) ImportTakeout(userID, source string, options TakeoutImportOptions, progress func(model.TakeoutImportJob)) (*model.TakeoutImportJob, error) {
+	export, err := openTakeout(source, options)
+	if err != nil {
+		return nil, err
+	}
+
+	job := newTakeoutJob(userID, options)
+	s.importTakeout(job, export, takeoutDefaults(options), progress)
+	state := job.snapshot()
+	return &state, nil
+}
+
+// GetTakeoutJob returns the state of one of the user's Takeout import jobs
+func (s *TakeoutService) GetTakeoutJob(id, userID string) (*model.TakeoutImportJob, error) {
+	takeoutJobsMu.Lock()
+	job, ok := takeoutJobs[id]
+	takeoutJobsMu.Unlock()
+	if !ok {
+		return nil, ErrImportJobNotFound
+	}
+	state := job.snapshot()
+	if state.UserID != userID {
+		return nil, ErrImportJobNotFound
+	}
+	return &state, nil
+}
+
+func takeoutDefaults(options TakeoutImportOptions) TakeoutImportOptions {
+	if options.Group == "" {
+		options.Group = TakeoutGroupFolder
+	}
+	if options.RadiusKm <= 0 {
+		options.RadiusKm = DefaultTakeoutRadiusKm
+	}
+	if options.MaxGap <= 0 {
+		options.MaxGap = DefaultTakeoutMaxGap
+	}
+	return options
+}
+
+func newTakeoutJob(userID string, options TakeoutImportOptions) *takeoutJob {
+	return &takeoutJob{job: model.TakeoutImportJob{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Status:    model.ImportJobRunning,
+		Group:     takeoutDefaults(options).Group,
+		StartedAt: time.Now().UTC(),
+	}}
+}
+
+func (s *TakeoutService) run(job *takeoutJob, export *takeoutExport, options TakeoutImportOptions) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logging.WithField("job_id", job.job.ID).Errorf("Takeout import panicked: %v", recovered)
+			finishTakeoutJob(job, fmt.Errorf("internal error"))
+		}
+	}()
+	s.importTakeout(job, export, options, nil)
+}
+
+func (s *TakeoutService) importTakeout(job *takeoutJob, export *takeoutExport, options TakeoutImportOptions, progress func(model.TakeoutImportJob)) {
+	defer export.close()
+
+	fsys := export.fsys
+	archive, err := takeout.Scan(fsys)
+	if err != nil {
+		finishTakeoutJob(job, err)
+		return
+	}
+
+	var groups []takeout.Group
+	var unlocated []takeout.Photo
+	if options.Group == TakeoutGroupFolder {
+		groups, unlocated = takeout.ByFolder(archive, options.RadiusKm, options.MaxGap)
+	} else {
+		groups, unlocated = takeout.ByLocation(archive.Distinct(), options.RadiusKm, options.MaxGap)
+	}
+	total := len(unlocated)
+	for _, group := range groups {
+		total += len(group.Photos)
+	}
+	job.update(func(state *model.TakeoutImportJob) {
+		state.Total = total
+		state.Unsupported = len(archive.Unsupported)
+		// Photos that cannot be placed on the map have no album to go into
+		state.Done = len(unlocated)
+		state.Skipped = len(unlocated)
+	})
+	if len(unlocated) > 0 {
+		job.addError("%d photos were skipped because neither they nor the photos taken around them have a location", len(unlocated))
+	}
+	for _, name := range archive.Oversized {
+		job.addError("%s: ignored metadata file larger than %d KB", name, takeout.MaxSidecarSize>>10)
+	}
+
+	userID := job.job.UserID
+	albums, err := s.albumDAO.GetByUserID(userID)
+	if err != nil {
+		finishTakeoutJob(job, fmt.Errorf("failed to get albums: %w", err))
+		return
+	}
+	albumsByExternalID := make(map[string]*model.Album, len(albums))
+	for i := range albums {
+		if albums[i].ExternalID != "" {
+			albumsByExternalID[albums[i].ExternalID] = &albums[i]
+		}
+	}
+
+	for _, group := range groups {
+		s.importGroup(job, fsys, group, albumsByExternalID, progress)
+	}
+	finishTakeoutJob(job, nil)
+}
+
+// importGroup imports the photos of a group into its album, creating the album
+// unless an earlier import of the same export did. Photos already in the album
+// are skipped.
+func (s *TakeoutService) importGroup(job *takeoutJob, fsys fs.FS, group takeout.Group, albumsByExternalID map[string]*model.Album, progress func(model.TakeoutImportJob)) {
+	userID := job.job.UserID
+	externalID := takeoutExternalID(group.Key)
+	album, ok := albumsByExternalID[externalID]
+	existing := make(map[string]bool)
+	if ok {
+		photos, err := s.photoDAO.GetByAlbumID(album.ID)
+		if err != nil {
+			job.addError("album %q: failed to get photos: %v", group.Title, err)
+			job.update(func(state *model.TakeoutImportJob) {
+				state.Done += len(group.Photos)
+				state.Failed += len(group.Photos)
+			})
+			return
+		}
+		for _, photo := range photos {
+			existing[takeoutPhotoKey(photo.Filename, photo.TakenAt)] = true
+		}
+		job.update(func(state *model.TakeoutImportJob) { state.AlbumsReused++ })
+	} else {
+		createdAt := time.Now()
+		if first := firstTakenAt(group.Photos); first != nil {
+			createdAt = *first
+		}
+		created, err := s.albumService.CreateAlbum(userID, NewAlbum{
+			Title:       fitSanitized(group.Title, 200),
+			Description: fitSanitized(group.Description, 2000),
+			Latitude:    group.Lat,
+			Longitude:   group.Lng,
+			CreatedAt:   createdAt,
+			Datum:       datum.WGS84,
+			ExternalID:  externalID,
+		})
+		if err != nil {
+			job.addError("album %q: %v", group.Title, err)
+			job.update(func(state *model.TakeoutImportJob) {
+				state.Done += len(group.Photos)
+				state.Failed += len(group.Photos)
+			})
+			return
+		}
+		album = created
+		albumsByExternalID[externalID] = album
+		job.update(func(state *model.TakeoutImportJob) { state.AlbumsCreated++ })
+	}
+
+	for _, photo := range group.Photos {
+		filename := path.Base(photo.Path)
+		if existing[takeoutPhotoKey(filename, photo.TakenAt)] {
+			job.update(func(state *model.TakeoutImportJob) {
+				state.Done++
+				state.Skipped++
+			})
+		} else if err := s.importPhoto(fsys, album.ID, userID, filename, photo); err != nil {
+			job.addError("%s: %v", photo.Path, err)
+			job.update(func(state *model.TakeoutImportJob) {
+				state.Done++
+				state.Failed++
+			})
+		} else {
+			existing[takeoutPhotoKey(filename, photo.TakenAt)] = true
+			job.update(func(state *model.TakeoutImportJob) {
+				state.Done++
+				state.Imported++
+			})
+		}
+		if progress != nil {
+			progress(job.snapshot())
+		}
+	}
+}
+
+func (s *TakeoutService) importPhoto(fsys fs.FS, albumID, userID, filename string, photo takeout.Photo) error {
+	file, err := fsys.Open(photo.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open photo: %w", err)
+	}
+	defer file.Close()
+	// ZIP entries give their uncompressed size; AddPhoto still stops at the limit
+	// should an entry hold more than it claims
+	if info, err := file.Stat(); err == nil && info.Size() > MaxPhotoSize {
+		return fmt.Errorf("photo file is larger than %d MB", MaxPhotoSize>>20)
+	}
+
+	photoFile := PhotoFile{
+		Filename:    filename,
+		MimeType:    takeout.MediaTypes[strings.ToLower(path.Ext(filename))],
+		Content:     file,
+		TakenAt:     photo.TakenAt,
+		Description: fitSanitized(photo.Description, 2000),
+	}
+	if photo.Located {
+		lat, lng := photo.Lat, photo.Lng
+		photoFile.Latitude, photoFile.Longitude = &lat, &lng
+	}
+	_, err = s.photoService.AddPhoto(albumID, userID, photoFile)
+	return err
+}
+
+// takeoutExport is an opened Takeout export
+type takeoutExport struct {
+	fsys  fs.FS
+	close func()
+}
+
+// openTakeout opens an extracted export folder or a ZIP file as a file system and
+// checks the import options. An uploaded export is removed again when it cannot be
+// imported or once the export is closed.
+func openTakeout(source string, options TakeoutImportOptions) (*takeoutExport, error) {
+	remove := func() {
+		if options.RemoveSource {
+			os.Remove(source)
+		}
+	}
+
+	options = takeoutDefaults(options)
+	if options.Group != TakeoutGroupFolder && options.Group != TakeoutGroupCluster {
+		remove()
+		return nil, fmt.Errorf("invalid grouping %q: must be folder or cluster", options.Group)
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		remove()
+		return nil, fmt.Errorf("failed to open export: %w", err)
+	}
+	if info.IsDir() {
+		return &takeoutExport{fsys: os.DirFS(source), close: remove}, nil
+	}
+	reader, err := zip.OpenReader(source)
+	if err != nil {
+		remove()
+		return nil, fmt.Errorf("failed to open export: not a ZIP file or folder")
+	}
+	return &takeoutExport{fsys: reader, close: func() {
+		reader.Close()
+		remove()
+	}}, nil
+}
+
+// takeoutExternalID keys an album by its group so a repeated import reuses it
+func takeoutExternalID(key string) string {
+	id := "takeout:" + key
+	if len(id) > maxExternalIDLength {
+		sum := sha256.Sum256([]byte(key))
+		id = "takeout:sha256:" + hex.EncodeToString(sum[:16])
+	}
+	return id
+}
+
+// takeoutPhotoKey identifies a photo within an album by file name and capture time
+func takeoutPhotoKey(filename string, takenAt *time.Time) string {
+	if takenAt == nil {
+		return filename
+	}
+	return fmt.Sprintf("%s|%d", filename, takenAt.Unix())
+}
+
+func firstTakenAt(photos []takeout.Photo) *time.Time {
+	var first *time.Time
+	for _, photo := range photos {
+		if photo.TakenAt != nil && (first == nil || photo.TakenAt.Before(*first)) {
+			first = photo.TakenAt
+		}
+	}
+	return first
+}
+
+// fitSanitized shortens text so that it is at most limit bytes once sanitized,
+// as Google Photos allows longer titles and descriptions than albums do
+func fitSanitized(text string, limit int) string {
+	sanitizer := middleware.GetInputSanitizer()
+	text = strings.TrimSpace(text)
+	// Sanitizing never shortens text, so start from its first limit bytes
+	if len(text) > limit {
+		text = text[:limit]
+	}
+	for len(text) > 0 && (!utf8.ValidString(text) || len(sanitizer.SanitizeString(text)) > limit) {
+		_, size := utf8.DecodeLastRuneInString(text)
+		text = text[:len(text)-size]
+	}
+	return strings.TrimSpace(text)
+}
+
+func finishTakeoutJob(job *takeoutJob, err error) {
+	finished := time.Now().UTC()
+	job.update(func(state *model.TakeoutImportJob) {
+		state.Status = model.ImportJobCompleted
+		if err != nil {
+			state.Status = model.ImportJobFailed
+			state.Error = err.Error()
+		}
+		state.FinishedAt = &finished
+	})
+}
+
+// pruneTakeoutJobs forgets import jobs that finished more than takeoutJobRetention ago
+func pruneTakeoutJobs() {
+	cutoff := time.Now().Add(-takeoutJobRetention)
+	takeoutJobsMu.Lock()
+	defer takeoutJobsMu.Unlock()
+	for id, job := range takeoutJobs {
+		state := job.snapshot()
+		if state.FinishedAt != nil && state.FinishedAt.Before(cutoff) {
+			delete(takeoutJobs, id)
+		}
+	}
+}
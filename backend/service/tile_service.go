@@ -0,0 +1,243 @@
+package service
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"sync"
+
+	"geoalbum/backend/dao"
+	"geoalbum/backend/datum"
+	"geoalbum/backend/model"
+	"geoalbum/backend/mvt"
+	"geoalbum/backend/polyline"
+)
+
+// MaxTileZoom is the deepest zoom level album tiles are rendered for
+const MaxTileZoom = 22
+
+// tileBuffer is how far, in tile units, features beyond a tile's edge are still
+// included so that symbols and lines crossing the edge render without seams
+const tileBuffer = 64
+
+// maxCachedTiles bounds the number of encoded tiles kept in memory across all users
+const maxCachedTiles = 4096
+
+type TileService struct {
+	albumDAO *dao.AlbumDAO
+	pathDAO  *dao.PathDAO
+	userDAO  *dao.UserDAO
+
+	mu    sync.Mutex
+	cache map[string]*list.Element
+	lru   *list.List // of *tileCacheEntry, most recently used first
+}
+
+// tileCacheEntry is an encoded tile rendered at a given data version
+type tileCacheEntry struct {
+	key     string
+	version int64
+	data    []byte
+}
+
+func NewTileService() *TileService {
+	return &TileService{
+		albumDAO: dao.NewAlbumDAO(),
+		pathDAO:  dao.NewPathDAO(),
+		userDAO:  dao.NewUserDAO(),
+		cache:    make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// GetAlbumTile returns the vector tile z/x/y of a user's albums, plus their paths when
+// includePaths is set, along with the data version it reflects. Coordinates are placed
+// in datum d, matching basemap tiles drawn in it. Tiles are cached until the user's
+// albums, photos or paths change.
+func (s *TileService) GetAlbumTile(userID string, z, x, y int, includePaths bool, d datum.Datum) ([]byte, int64, error) {
+	if z < 0 || z > MaxTileZoom {
+		return nil, 0, fmt.Errorf("invalid zoom: must be 0-%d", MaxTileZoom)
+	}
+	if n := mvt.TileCount(z); x < 0 || x >= n || y < 0 || y >= n {
+		return nil, 0, fmt.Errorf("invalid tile: %d/%d/%d", z, x, y)
+	}
+
+	version, err := s.userDAO.GetDataVersion(userID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get album tile: %w", err)
+	}
+
+	key := fmt.Sprintf("%s/%d/%d/%d/%t/%s", userID, z, x, y, includePaths, d)
+	if data, ok := s.cached(key, version); ok {
+		return data, version, nil
+	}
+
+	data, err := s.renderAlbumTile(userID, mvt.TileProjection{Z: z, X: x, Y: y, Extent: mvt.DefaultExtent}, includePaths, d)
+	if err != nil {
+		return nil, 0, err
+	}
+	s.store(key, version, data)
+	return data, version, nil
+}
+
+// renderAlbumTile encodes an "albums" point layer and optionally a "paths" line layer
+func (s *TileService) renderAlbumTile(userID string, projection mvt.TileProjection, includePaths bool, d datum.Datum) ([]byte, error) {
+	minLat, minLng, maxLat, maxLng := projection.Bounds(tileBuffer)
+	bounds := &model.BoundingBox{MinLat: minLat, MinLng: minLng, MaxLat: maxLat, MaxLng: maxLng}
+
+	// The tile covers bounds in d; albums are stored in WGS-84
+	query := bounds
+	if d != datum.WGS84 {
+		query = &model.BoundingBox{}
+		query.MinLat, query.MinLng = datum.ToWGS84(minLat, minLng, d)
+		query.MaxLat, query.MaxLng = datum.ToWGS84(maxLat, maxLng, d)
+	}
+	albums, err := s.albumDAO.GetByUserIDFiltered(userID, model.AlbumFilter{BBox: query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get album tile: %w", err)
+	}
+
+	albumLayer := mvt.NewLayer("albums", mvt.DefaultExtent)
+	for _, album := range albums {
+		px, py := projection.Project(datum.FromWGS84(album.Latitude, album.Longitude, d))
+		properties := map[string]interface{}{
+			"id":          album.ID,
+			"title":       album.Title,
+			"photo_count": album.PhotoCount,
+		}
+		if album.CoverPhotoID != "" {
+			properties["cover_photo_id"] = album.CoverPhotoID
+		}
+		albumLayer.AddPoint(mvt.Point{X: int(math.Round(px)), Y: int(math.Round(py))}, properties)
+	}
+
+	tile := &mvt.Tile{Layers: []*mvt.Layer{albumLayer}}
+	if includePaths {
+		pathLayer, err := s.renderPathLayer(userID, projection, bounds, d)
+		if err != nil {
+			return nil, err
+		}
+		tile.Layers = append(tile.Layers, pathLayer)
+	}
+	return tile.Encode(), nil
+}
+
+// renderPathLayer draws each path along its line (see pathLine), simplified for the
+// tile's zoom and clipped to the buffered tile. Lines running past the antimeridian
+// are drawn a second time one turn over, so they show on both sides of it.
+func (s *TileService) renderPathLayer(userID string, projection mvt.TileProjection, bounds *model.BoundingBox, d datum.Datum) (*mvt.Layer, error) {
+	paths, err := s.pathDAO.GetByUserIDWithAlbums(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get album tile: %w", err)
+	}
+
+	layer := mvt.NewLayer("paths", mvt.DefaultExtent)
+	for i := range paths {
+		path := &paths[i]
+		if path.FromAlbum == nil || path.ToAlbum == nil {
+			continue
+		}
+		line := polyline.Simplify(pathLine(path), projection.Z)
+		minLat, minLng := math.Inf(1), math.Inf(1)
+		maxLat, maxLng := math.Inf(-1), math.Inf(-1)
+		for j := range line {
+			line[j].Lat, line[j].Lng = datum.FromWGS84(line[j].Lat, line[j].Lng, d)
+			minLat, maxLat = math.Min(minLat, line[j].Lat), math.Max(maxLat, line[j].Lat)
+			minLng, maxLng = math.Min(minLng, line[j].Lng), math.Max(maxLng, line[j].Lng)
+		}
+
+		properties := map[string]interface{}{
+			"id":             path.ID,
+			"from_album_id":  path.FromAlbumID,
+			"to_album_id":    path.ToAlbumID,
+			"transport_mode": path.TransportMode,
+		}
+		for _, shift := range []float64{0, -360, 360} {
+			if maxLat < bounds.MinLat || minLat > bounds.MaxLat ||
+				maxLng+shift < bounds.MinLng || minLng+shift > bounds.MaxLng {
+				continue
+			}
+			for _, part := range clipLine(projection, line, shift) {
+				layer.AddLineString(part, properties)
+			}
+		}
+	}
+	return layer, nil
+}
+
+// clipLine projects a line, shifted by shift degrees of longitude, into the tile and
+// clips it to the buffered tile, returning the parts that remain inside
+func clipLine(projection mvt.TileProjection, line []polyline.Point, shift float64) [][]mvt.Point {
+	var parts [][]mvt.Point
+	var part []mvt.Point
+	flush := func() {
+		if len(part) >= 2 {
+			parts = append(parts, part)
+		}
+		part = nil
+	}
+
+	low, high := float64(-tileBuffer), float64(projection.Extent+tileBuffer)
+	for i := 1; i < len(line); i++ {
+		ax, ay := projection.Project(line[i-1].Lat, line[i-1].Lng+shift)
+		bx, by := projection.Project(line[i].Lat, line[i].Lng+shift)
+		cax, cay, cbx, cby, ok := mvt.ClipSegment(ax, ay, bx, by, low, high)
+		if !ok {
+			flush()
+			continue
+		}
+		start := mvt.Point{X: int(math.Round(cax)), Y: int(math.Round(cay))}
+		end := mvt.Point{X: int(math.Round(cbx)), Y: int(math.Round(cby))}
+		// A segment entering the tile starts a new part
+		if cax != ax || cay != ay {
+			flush()
+		}
+		if len(part) == 0 || part[len(part)-1] != start {
+			part = append(part, start)
+		}
+		if part[len(part)-1] != end {
+			part = append(part, end)
+		}
+		// A segment leaving the tile ends the part
+		if cbx != bx || cby != by {
+			flush()
+		}
+	}
+	flush()
+	return parts
+}
+
+// cached returns a cached tile if it was rendered at version
+func (s *TileService) cached(key string, version int64) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.cache[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*tileCacheEntry)
+	if entry.version != version {
+		return nil, false
+	}
+	s.lru.MoveToFront(element)
+	return entry.data, true
+}
+
+// store caches a tile, evicting the least recently used tiles beyond maxCachedTiles
+func (s *TileService) store(key string, version int64, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.cache[key]; ok {
+		element.Value = &tileCacheEntry{key: key, version: version, data: data}
+		s.lru.MoveToFront(element)
+		return
+	}
+	s.cache[key] = s.lru.PushFront(&tileCacheEntry{key: key, version: version, data: data})
+	for s.lru.Len() > maxCachedTiles {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.cache, oldest.Value.(*tileCacheEntry).key)
+	}
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"geoalbum/backend/model"
+	"geoalbum/backend/tileproxy"
+)
+
+// ErrTileProxyDisabled is returned when no tile proxy providers are configured
+var ErrTileProxyDisabled = errors.New("tile proxy is not enabled")
+
+type TileProxyService struct{}
+
+func NewTileProxyService() *TileProxyService {
+	return &TileProxyService{}
+}
+
+// ListProviders returns the names of the configured tile providers
+func (s *TileProxyService) ListProviders() []string {
+	proxy := tileproxy.Default()
+	if proxy == nil {
+		return []string{}
+	}
+	return proxy.Providers()
+}
+
+// GetTile returns a tile through the caching proxy
+func (s *TileProxyService) GetTile(ctx context.Context, provider string, z, x, y int) (*tileproxy.Tile, error) {
+	proxy := tileproxy.Default()
+	if proxy == nil {
+		return nil, ErrTileProxyDisabled
+	}
+	return proxy.Get(ctx, provider, z, x, y)
+}
+
+// SeedTiles starts caching a provider's tiles for bbox across a zoom range for a user
+func (s *TileProxyService) SeedTiles(userID, provider string, bbox model.BoundingBox, minZoom, maxZoom int) (*model.TileSeedJob, error) {
+	proxy := tileproxy.Default()
+	if proxy == nil {
+		return nil, ErrTileProxyDisabled
+	}
+	job, err := proxy.Seed(userID, provider, bbox, minZoom, maxZoom)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetSeedJob returns the progress of a user's seed job, or nil if the user has none
+// with that ID
+func (s *TileProxyService) GetSeedJob(id, userID string) (*model.TileSeedJob, error) {
+	proxy := tileproxy.Default()
+	if proxy == nil {
+		return nil, ErrTileProxyDisabled
+	}
+	job, ok := proxy.SeedJob(id, userID)
+	if !ok {
+		return nil, nil
+	}
+	return &job, nil
+}
+
+// CancelSeedJob stops a user's seed job, reporting false if the user has none with
+// that ID
+func (s *TileProxyService) CancelSeedJob(id, userID string) (bool, error) {
+	proxy := tileproxy.Default()
+	if proxy == nil {
+		return false, ErrTileProxyDisabled
+	}
+	return proxy.CancelSeedJob(id, userID), nil
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"geoalbum/backend/dao"
+	"geoalbum/backend/model"
+)
+
+// Timeline granularities
+const (
+	GranularityYear  = "year"
+	GranularityMonth = "month"
+	GranularityDay   = "day"
+)
+
+type TimelineService struct {
+	albumDAO *dao.AlbumDAO
+	photoDAO *dao.PhotoDAO
+}
+
+func NewTimelineService() *TimelineService {
+	return &TimelineService{
+		albumDAO: dao.NewAlbumDAO(),
+		photoDAO: dao.NewPhotoDAO(),
+	}
+}
+
+// GetTimeline buckets a user's photos by capture time, falling back to their album's
+// created_at, with bucket boundaries computed in timezone (UTC when empty). An album
+// counts towards every bucket holding one of its photos, or the bucket of its
+// created_at when it has none. Buckets without albums are omitted.
+func (s *TimelineService) GetTimeline(userID, granularity, timezone string, includeAlbumIDs bool) ([]model.TimelineBucket, error) {
+	loc, err := loadTimezone(timezone)
+	if err != nil {
+		return nil, err
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	if granularity == "" {
+		granularity = GranularityMonth
+	}
+	if granularity != GranularityYear && granularity != GranularityMonth && granularity != GranularityDay {
+		return nil, fmt.Errorf("invalid granularity: %s", granularity)
+	}
+
+	albums, err := s.albumDAO.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get timeline: %w", err)
+	}
+	photos, err := s.photoDAO.GetCaptureTimesByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get timeline: %w", err)
+	}
+
+	buckets := make(map[string]*model.TimelineBucket)
+	bucketAlbums := make(map[string]map[string]bool)
+	bucketFor := func(t time.Time) *model.TimelineBucket {
+		start, end, key := bucketBounds(t.In(loc), granularity)
+		if buckets[key] == nil {
+			buckets[key] = &model.TimelineBucket{Key: key, Start: start, End: end}
+			bucketAlbums[key] = make(map[string]bool)
+		}
+		return buckets[key]
+	}
+	addAlbum := func(bucket *model.TimelineBucket, albumID string) {
+		if !bucketAlbums[bucket.Key][albumID] {
+			bucketAlbums[bucket.Key][albumID] = true
+			bucket.Albums++
+			if includeAlbumIDs {
+				bucket.AlbumIDs = append(bucket.AlbumIDs, albumID)
+			}
+		}
+	}
+
+	createdAt := make(map[string]time.Time, len(albums))
+	for _, album := range albums {
+		createdAt[album.ID] = album.CreatedAt
+	}
+
+	hasPhotos := make(map[string]bool)
+	for _, photo := range photos {
+		created, ok := createdAt[photo.AlbumID]
+		if !ok {
+			continue
+		}
+		taken := created
+		if photo.TakenAt != nil {
+			taken = *photo.TakenAt
+		}
+		bucket := bucketFor(taken)
+		bucket.Photos++
+		addAlbum(bucket, photo.AlbumID)
+		hasPhotos[photo.AlbumID] = true
+	}
+
+	for _, album := range albums {
+		if !hasPhotos[album.ID] {
+			addAlbum(bucketFor(album.CreatedAt), album.ID)
+		}
+	}
+
+	result := make([]model.TimelineBucket, 0, len(buckets))
+	for _, bucket := range buckets {
+		result = append(result, *bucket)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Start.Before(result[j].Start)
+	})
+	return result, nil
+}
+
+// bucketBounds returns the [start, end) bounds and key of the bucket containing t,
+// in t's location
+func bucketBounds(t time.Time, granularity string) (time.Time, time.Time, string) {
+	loc := t.Location()
+	switch granularity {
+	case GranularityYear:
+		start := time.Date(t.Year(), 1, 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(1, 0, 0), start.Format("2006")
+	case GranularityDay:
+		start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 0, 1), start.Format("2006-01-02")
+	default:
+		start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 1, 0), start.Format("2006-01")
+	}
+}
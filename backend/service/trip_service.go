@@ -0,0 +1,297 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"geoalbum/backend/dao"
+	"geoalbum/backend/geocode"
+	"geoalbum/backend/middleware"
+	"geoalbum/backend/model"
+)
+
+// maxTripStops bounds the number of stops of a single trip
+const maxTripStops = 500
+
+var (
+	// ErrTripNotFound is returned when a trip does not exist
+	ErrTripNotFound = errors.New("trip not found")
+	// ErrTripAccessDenied is returned for trips of another user
+	ErrTripAccessDenied = errors.New("access denied: trip does not belong to user")
+	// ErrInvalidTrip is matched by the errors returned for invalid trip input
+	ErrInvalidTrip = errors.New("invalid trip")
+)
+
+func invalidTrip(format string, args ...interface{}) error {
+	return invalidInput(ErrInvalidTrip, fmt.Errorf(format, args...))
+}
+
+type TripService struct {
+	tripDAO   *dao.TripDAO
+	albumDAO  *dao.AlbumDAO
+	pathDAO   *dao.PathDAO
+	sanitizer *middleware.InputSanitizer
+}
+
+func NewTripService() *TripService {
+	return &TripService{
+		tripDAO:   dao.NewTripDAO(),
+		albumDAO:  dao.NewAlbumDAO(),
+		pathDAO:   dao.NewPathDAO(),
+		sanitizer: middleware.GetInputSanitizer(),
+	}
+}
+
+// TripInput holds trip fields; on update nil fields are left unchanged
+type TripInput struct {
+	Title       *string
+	Description *string
+	StartAt     *time.Time
+	EndAt       *time.Time
+	ClearDates  bool     // remove StartAt and EndAt
+	AlbumIDs    []string // stops in visiting order; nil keeps the current stops
+}
+
+// CreateTrip creates a trip visiting the given albums in order. Paths are created
+// between consecutive stops that are not connected yet.
+func (s *TripService) CreateTrip(userID string, input TripInput) (*model.Trip, error) {
+	now := time.Now()
+	trip := &model.Trip{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if input.Title == nil {
+		return nil, invalidTrip("invalid trip title: must be 1-200 characters")
+	}
+	if err := s.applyDetails(trip, input); err != nil {
+		return nil, err
+	}
+
+	stops, legs, err := s.planStops(userID, input.AlbumIDs)
+	if err != nil {
+		return nil, err
+	}
+	trip.Stops = stops
+
+	if err := s.tripDAO.Create(trip, legs); err != nil {
+		return nil, fmt.Errorf("failed to create trip: %w", err)
+	}
+	return s.GetTripByID(trip.ID, userID)
+}
+
+// GetTripsByUserID retrieves a user's trips without their stops, optionally only
+// those stopping at albumID
+func (s *TripService) GetTripsByUserID(userID, albumID string) ([]model.Trip, error) {
+	trips, err := s.tripDAO.GetByUserID(userID, albumID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trips: %w", err)
+	}
+	return trips, nil
+}
+
+// GetTripByID retrieves a trip with its stops and the legs between them, ensuring it
+// belongs to the user
+func (s *TripService) GetTripByID(id, userID string) (*model.Trip, error) {
+	trip, err := s.getOwnedTrip(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	stops, err := s.tripDAO.GetStops(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trip: %w", err)
+	}
+	albums, err := s.albumDAO.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trip: %w", err)
+	}
+	albumsByID := make(map[string]*model.Album, len(albums))
+	for i := range albums {
+		localizeAlbum(&albums[i])
+		albumsByID[albums[i].ID] = &albums[i]
+	}
+	paths, err := s.pathDAO.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trip: %w", err)
+	}
+	pathIDs := make(map[[2]string]string, len(paths))
+	for _, path := range paths {
+		pathIDs[[2]string{path.FromAlbumID, path.ToAlbumID}] = path.ID
+	}
+
+	trip.Stops = stops
+	trip.Legs = []model.TripLeg{}
+	for i := range trip.Stops {
+		if album, ok := albumsByID[trip.Stops[i].AlbumID]; ok {
+			// A copy per stop, as an album may be visited more than once
+			stopAlbum := *album
+			trip.Stops[i].Album = &stopAlbum
+		}
+		if i == 0 {
+			continue
+		}
+		from, to := trip.Stops[i-1], trip.Stops[i]
+		leg := model.TripLeg{
+			FromPosition: from.Position,
+			ToPosition:   to.Position,
+			FromAlbumID:  from.AlbumID,
+			ToAlbumID:    to.AlbumID,
+			PathID:       pathIDs[[2]string{from.AlbumID, to.AlbumID}],
+		}
+		if from.Album != nil && to.Album != nil {
+			leg.DistanceKm = geocode.DistanceKm(from.Album.Latitude, from.Album.Longitude, to.Album.Latitude, to.Album.Longitude)
+		}
+		trip.DistanceKm += leg.DistanceKm
+		trip.Legs = append(trip.Legs, leg)
+	}
+	return trip, nil
+}
+
+// UpdateTrip applies a partial update to a trip; replacing the stops creates any
+// missing paths between them
+func (s *TripService) UpdateTrip(id, userID string, input TripInput) (*model.Trip, error) {
+	trip, err := s.getOwnedTrip(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyDetails(trip, input); err != nil {
+		return nil, err
+	}
+
+	var stops []model.TripStop
+	var legs []model.Path
+	if input.AlbumIDs != nil {
+		if stops, legs, err = s.planStops(userID, input.AlbumIDs); err != nil {
+			return nil, err
+		}
+	}
+	trip.UpdatedAt = time.Now()
+
+	if err := s.tripDAO.Update(trip, stops, legs); err != nil {
+		return nil, fmt.Errorf("failed to update trip: %w", err)
+	}
+	return s.GetTripByID(id, userID)
+}
+
+// DeleteTrip deletes a trip; its albums and the paths between them are kept
+func (s *TripService) DeleteTrip(id, userID string) error {
+	if _, err := s.getOwnedTrip(id, userID); err != nil {
+		return err
+	}
+	if err := s.tripDAO.Delete(id, userID); err != nil {
+		return fmt.Errorf("failed to delete trip: %w", err)
+	}
+	return nil
+}
+
+// getOwnedTrip retrieves a trip without its stops and ensures it belongs to the user
+func (s *TripService) getOwnedTrip(id, userID string) (*model.Trip, error) {
+	trip, err := s.tripDAO.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trip: %w", err)
+	}
+	if trip == nil {
+		return nil, ErrTripNotFound
+	}
+	if trip.UserID != userID {
+		return nil, ErrTripAccessDenied
+	}
+	return trip, nil
+}
+
+// applyDetails validates the title, description and dates of input onto trip
+func (s *TripService) applyDetails(trip *model.Trip, input TripInput) error {
+	if input.Title != nil {
+		title := s.sanitizer.SanitizeString(*input.Title)
+		if !s.sanitizer.ValidateAlbumTitle(title) {
+			return invalidTrip("invalid trip title: must be 1-200 characters")
+		}
+		if s.sanitizer.DetectSQLInjection(title) {
+			return invalidTrip("invalid input: contains prohibited characters")
+		}
+		trip.Title = title
+	}
+
+	if input.Description != nil {
+		description := s.sanitizer.SanitizeString(*input.Description)
+		if !s.sanitizer.ValidateAlbumDescription(description) {
+			return invalidTrip("invalid trip description: must be max 2000 characters")
+		}
+		if s.sanitizer.DetectSQLInjection(description) {
+			return invalidTrip("invalid input: contains prohibited characters")
+		}
+		trip.Description = description
+	}
+
+	if input.ClearDates {
+		if input.StartAt != nil || input.EndAt != nil {
+			return invalidTrip("invalid trip dates: start_at/end_at cannot be combined with clear_dates")
+		}
+		trip.StartAt, trip.EndAt = nil, nil
+	}
+	if input.StartAt != nil {
+		trip.StartAt = input.StartAt
+	}
+	if input.EndAt != nil {
+		trip.EndAt = input.EndAt
+	}
+	if trip.StartAt != nil && trip.EndAt != nil && trip.EndAt.Before(*trip.StartAt) {
+		return invalidTrip("invalid trip dates: end_at must not be before start_at")
+	}
+	return nil
+}
+
+// planStops validates a list of stops and returns them with the paths that still
+// have to be created to connect consecutive stops
+func (s *TripService) planStops(userID string, albumIDs []string) ([]model.TripStop, []model.Path, error) {
+	if len(albumIDs) > maxTripStops {
+		return nil, nil, invalidTrip("invalid trip stops: at most %d stops are allowed", maxTripStops)
+	}
+
+	stops := make([]model.TripStop, 0, len(albumIDs))
+	for i, albumID := range albumIDs {
+		album, err := s.albumDAO.GetByID(albumID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get album: %w", err)
+		}
+		if album == nil {
+			return nil, nil, fmt.Errorf("%w: %s", ErrAlbumNotFound, albumID)
+		}
+		if album.UserID != userID {
+			return nil, nil, ErrAlbumAccessDenied
+		}
+		if i > 0 && albumIDs[i-1] == albumID {
+			return nil, nil, invalidTrip("invalid trip stops: album %s follows itself", albumID)
+		}
+		stops = append(stops, model.TripStop{Position: i, AlbumID: albumID})
+	}
+
+	var legs []model.Path
+	planned := make(map[[2]string]bool)
+	for i := 1; i < len(stops); i++ {
+		pair := [2]string{stops[i-1].AlbumID, stops[i].AlbumID}
+		if planned[pair] {
+			continue
+		}
+		exists, err := s.pathDAO.CheckPathExists(pair[0], pair[1], userID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to check path existence: %w", err)
+		}
+		planned[pair] = true
+		if !exists {
+			legs = append(legs, model.Path{
+				ID:          uuid.New().String(),
+				UserID:      userID,
+				FromAlbumID: pair[0],
+				ToAlbumID:   pair[1],
+				CreatedAt:   time.Now(),
+			})
+		}
+	}
+	return stops, legs, nil
+}
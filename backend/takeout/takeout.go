@@ -0,0 +1,452 @@
+// Package takeout reads Google Photos exports made with Google Takeout: media files
+// with the JSON sidecars that carry their capture time, location and description,
+// organised into album folders and "Photos from <year>" folders.
+package takeout
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"geoalbum/backend/geocode"
+)
+
+// MediaTypes maps the extensions of the photo files that are imported to their MIME type
+var MediaTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".heic": "image/heic",
+	".heif": "image/heif",
+}
+
+// Photo is a photo file of an export with the metadata of its sidecar
+type Photo struct {
+	Path        string // slash-separated path within the export
+	Folder      string // path of the folder holding the photo
+	Title       string // original file name
+	Description string
+	TakenAt     *time.Time
+	Lat, Lng    float64
+	Located     bool
+}
+
+// Archive is the content of an export
+type Archive struct {
+	Photos      []Photo
+	Albums      map[string]Album // album folders by path
+	Unsupported []string         // media files that are not imported, such as videos
+	Oversized   []string         // metadata files ignored for being larger than MaxSidecarSize
+}
+
+// Album is the metadata of an album folder
+type Album struct {
+	Title       string
+	Description string
+}
+
+// sidecar is the JSON metadata Google Photos writes next to each media file
+type sidecar struct {
+	Title          string `json:"title"`
+	Description    string `json:"description"`
+	PhotoTakenTime struct {
+		Timestamp string `json:"timestamp"`
+	} `json:"photoTakenTime"`
+	GeoData     geoData `json:"geoData"`
+	GeoDataExif geoData `json:"geoDataExif"`
+}
+
+type geoData struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// known reports whether a position was recorded; Google writes 0, 0 for none
+func (g geoData) known() bool {
+	return (g.Latitude != 0 || g.Longitude != 0) &&
+		g.Latitude >= -90 && g.Latitude <= 90 && g.Longitude >= -180 && g.Longitude <= 180
+}
+
+var (
+	// yearFolder matches the folders holding every photo of a year, which are not albums
+	yearFolder = regexp.MustCompile(`^Photos from \d{4}$`)
+	// duplicateName matches the "(n)" Google appends to clashing file names
+	duplicateName = regexp.MustCompile(`^(.*)\((\d+)\)(\.[^.]*)$`)
+	// editedName matches edited copies, which share the sidecar of the original
+	editedName = regexp.MustCompile(`^(.*)-edited(\.[^.]*)$`)
+)
+
+// sidecarSuffix is inserted before .json by newer exports
+const sidecarSuffix = ".supplemental-metadata"
+
+// truncatedSidecarLength is the length from which sidecar names may have been cut short
+const truncatedSidecarLength = 46
+
+// MaxSidecarSize bounds the JSON metadata files that are read; real ones are a few KB
+const MaxSidecarSize = 1 << 20
+
+// errSidecarTooLarge is returned for metadata files larger than MaxSidecarSize
+var errSidecarTooLarge = errors.New("metadata file is too large")
+
+// Scan reads an export, which may be the extracted folder or the ZIP file opened
+// as a file system. Photos without a sidecar are kept without metadata.
+func Scan(fsys fs.FS) (*Archive, error) {
+	archive := &Archive{Albums: make(map[string]Album)}
+	folders := make(map[string][]string)
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			folders[path.Dir(name)] = append(folders[path.Dir(name)], path.Base(name))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export: %w", err)
+	}
+
+	names := make([]string, 0, len(folders))
+	for folder := range folders {
+		names = append(names, folder)
+	}
+	sort.Strings(names)
+	for _, folder := range names {
+		if err := archive.scanFolder(fsys, folder, folders[folder]); err != nil {
+			return nil, err
+		}
+	}
+	return archive, nil
+}
+
+func (archive *Archive) scanFolder(fsys fs.FS, folder string, files []string) error {
+	sort.Strings(files)
+	sidecars := make(map[string]bool)
+	var media []string
+	for _, name := range files {
+		ext := strings.ToLower(path.Ext(name))
+		switch {
+		case name == "metadata.json":
+		case ext == ".json":
+			sidecars[name] = true
+		case MediaTypes[ext] != "":
+			media = append(media, name)
+		case ext == ".mp4" || ext == ".mov" || ext == ".gif" || ext == ".webp" || ext == ".avi" || ext == ".3gp":
+			archive.Unsupported = append(archive.Unsupported, path.Join(folder, name))
+		}
+	}
+	if len(media) == 0 {
+		return nil
+	}
+
+	if !yearFolder.MatchString(path.Base(folder)) && folder != "." {
+		album := Album{Title: path.Base(folder)}
+		var meta sidecar
+		name := path.Join(folder, "metadata.json")
+		if err := readJSON(fsys, name, &meta); err == nil && meta.Title != "" {
+			album.Title, album.Description = meta.Title, meta.Description
+		} else if errors.Is(err, errSidecarTooLarge) {
+			archive.Oversized = append(archive.Oversized, name)
+		}
+		archive.Albums[folder] = album
+	}
+
+	for _, name := range media {
+		photo := Photo{Path: path.Join(folder, name), Folder: folder, Title: name}
+		if sidecarName := matchSidecar(name, sidecars); sidecarName != "" {
+			var meta sidecar
+			name := path.Join(folder, sidecarName)
+			if err := readJSON(fsys, name, &meta); err == nil {
+				meta.apply(&photo)
+			} else if errors.Is(err, errSidecarTooLarge) {
+				archive.Oversized = append(archive.Oversized, name)
+			}
+		}
+		archive.Photos = append(archive.Photos, photo)
+	}
+	return nil
+}
+
+func (meta *sidecar) apply(photo *Photo) {
+	if meta.Title != "" {
+		photo.Title = meta.Title
+	}
+	photo.Description = strings.TrimSpace(meta.Description)
+	if seconds, err := strconv.ParseInt(meta.PhotoTakenTime.Timestamp, 10, 64); err == nil && seconds > 0 {
+		takenAt := time.Unix(seconds, 0).UTC()
+		photo.TakenAt = &takenAt
+	}
+	// geoData holds the location as edited in Google Photos; geoDataExif the camera's
+	for _, geo := range []geoData{meta.GeoData, meta.GeoDataExif} {
+		if geo.known() {
+			photo.Lat, photo.Lng, photo.Located = geo.Latitude, geo.Longitude, true
+			break
+		}
+	}
+}
+
+// matchSidecar finds the sidecar of a media file among the JSON files of its folder.
+// Google names it after the media file, with the "(n)" of duplicates moved to the end,
+// sharing it between a photo and its edited copy, and truncating long names.
+func matchSidecar(name string, sidecars map[string]bool) string {
+	var candidates []string
+	add := func(name, duplicate string) {
+		candidates = append(candidates,
+			name+duplicate+".json",
+			name+sidecarSuffix+duplicate+".json",
+		)
+	}
+	add(name, "")
+	if m := duplicateName.FindStringSubmatch(name); m != nil {
+		add(m[1]+m[3], "("+m[2]+")")
+	}
+	if m := editedName.FindStringSubmatch(name); m != nil {
+		add(m[1]+m[2], "")
+	}
+	for _, candidate := range candidates {
+		if sidecars[candidate] {
+			return candidate
+		}
+	}
+
+	full := name + sidecarSuffix
+	if m := editedName.FindStringSubmatch(name); m != nil {
+		full = m[1] + m[2] + sidecarSuffix
+	}
+	best := ""
+	for candidate := range sidecars {
+		if len(candidate) < truncatedSidecarLength {
+			continue
+		}
+		stem := strings.TrimSuffix(candidate, ".json")
+		if strings.HasPrefix(full, stem) && len(stem) > len(best)-len(".json") {
+			best = candidate
+		}
+	}
+	return best
+}
+
+// readJSON decodes a metadata file, refusing files larger than MaxSidecarSize before
+// inflating them
+func readJSON(fsys fs.FS, name string, v interface{}) error {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() > MaxSidecarSize {
+		return errSidecarTooLarge
+	}
+	// The size of an archive entry is only what its header claims
+	data, err := io.ReadAll(io.LimitReader(file, MaxSidecarSize+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > MaxSidecarSize {
+		return errSidecarTooLarge
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Group is a set of photos imported into one album
+type Group struct {
+	Key         string // identifies the group across imports of the same export
+	Title       string
+	Description string
+	Photos      []Photo
+	Lat, Lng    float64 // centre of the located photos
+}
+
+// ByFolder groups the photos of album folders by folder. Photos that are only in
+// year folders are grouped by location as in ByLocation; copies of album photos in
+// year folders are dropped. Photos of folders where none is located are returned
+// ungrouped.
+func ByFolder(archive *Archive, radiusKm float64, maxGap time.Duration) ([]Group, []Photo) {
+	byFolder := make(map[string][]Photo)
+	var loose []Photo
+	for _, photo := range archive.Distinct() {
+		if _, ok := archive.Albums[photo.Folder]; ok {
+			byFolder[photo.Folder] = append(byFolder[photo.Folder], photo)
+		} else {
+			loose = append(loose, photo)
+		}
+	}
+
+	folders := make([]string, 0, len(byFolder))
+	for folder := range byFolder {
+		folders = append(folders, folder)
+	}
+	sort.Strings(folders)
+
+	var groups []Group
+	var ungrouped []Photo
+	for _, folder := range folders {
+		photos := byFolder[folder]
+		lat, lng, ok := centre(photos)
+		if !ok {
+			ungrouped = append(ungrouped, photos...)
+			continue
+		}
+		album := archive.Albums[folder]
+		groups = append(groups, Group{
+			Key:         "folder:" + folder,
+			Title:       album.Title,
+			Description: album.Description,
+			Photos:      photos,
+			Lat:         lat,
+			Lng:         lng,
+		})
+	}
+
+	clustered, unlocated := ByLocation(loose, radiusKm, maxGap)
+	return append(groups, clustered...), append(ungrouped, unlocated...)
+}
+
+// ByLocation groups photos in capture order into runs taken within radiusKm of the
+// run's centre with at most maxGap between consecutive photos. Unlocated photos join
+// the run they were taken in; those taken outside any run are returned ungrouped.
+func ByLocation(photos []Photo, radiusKm float64, maxGap time.Duration) ([]Group, []Photo) {
+	sorted := append([]Photo(nil), photos...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].TakenAt, sorted[j].TakenAt
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return a.Before(*b)
+	})
+
+	var groups []Group
+	var ungrouped, pending []Photo
+	var current *Group
+	var last *time.Time
+	var sum position
+	for _, photo := range sorted {
+		inTime := current != nil && photo.TakenAt != nil && last != nil && photo.TakenAt.Sub(*last) <= maxGap
+		if !photo.Located {
+			if inTime {
+				current.Photos = append(current.Photos, photo)
+				last = photo.TakenAt
+			} else {
+				pending = append(pending, photo)
+			}
+			continue
+		}
+		if inTime && geocode.DistanceKm(current.Lat, current.Lng, photo.Lat, photo.Lng) <= radiusKm {
+			current.Photos = append(current.Photos, photo)
+			sum.add(photo)
+			current.Lat, current.Lng, _ = sum.mean()
+			last = photo.TakenAt
+			continue
+		}
+
+		ungrouped = append(ungrouped, pending...)
+		pending = nil
+		groups = append(groups, Group{Key: "cluster:" + photo.Path, Photos: []Photo{photo}, Lat: photo.Lat, Lng: photo.Lng})
+		current = &groups[len(groups)-1]
+		last = photo.TakenAt
+		sum = position{}
+		sum.add(photo)
+	}
+	ungrouped = append(ungrouped, pending...)
+
+	for i := range groups {
+		groups[i].Title = dateTitle(groups[i].Photos)
+	}
+	return groups, ungrouped
+}
+
+// Distinct returns the photos of the export leaving out the copies in year folders
+// of photos that are in an album
+func (archive *Archive) Distinct() []Photo {
+	inAlbum := make(map[string]bool)
+	for _, photo := range archive.Photos {
+		if _, ok := archive.Albums[photo.Folder]; ok {
+			inAlbum[photo.identity()] = true
+		}
+	}
+	var photos []Photo
+	for _, photo := range archive.Photos {
+		if _, ok := archive.Albums[photo.Folder]; ok || !inAlbum[photo.identity()] {
+			photos = append(photos, photo)
+		}
+	}
+	return photos
+}
+
+// identity tells copies of a photo in different folders apart from other photos
+func (photo Photo) identity() string {
+	if photo.TakenAt == nil {
+		return photo.Title
+	}
+	return photo.Title + "|" + strconv.FormatInt(photo.TakenAt.Unix(), 10)
+}
+
+// centre returns the mean position of the located photos
+func centre(photos []Photo) (float64, float64, bool) {
+	var sum position
+	for _, photo := range photos {
+		sum.add(photo)
+	}
+	return sum.mean()
+}
+
+// position accumulates photo positions, averaging longitudes on the unit circle so
+// that places either side of the antimeridian average to it rather than to 0
+type position struct {
+	lat, x, y float64
+	n         int
+}
+
+func (p *position) add(photo Photo) {
+	if !photo.Located {
+		return
+	}
+	p.lat += photo.Lat
+	p.x += math.Cos(photo.Lng * math.Pi / 180)
+	p.y += math.Sin(photo.Lng * math.Pi / 180)
+	p.n++
+}
+
+func (p *position) mean() (float64, float64, bool) {
+	if p.n == 0 {
+		return 0, 0, false
+	}
+	return p.lat / float64(p.n), math.Atan2(p.y, p.x) * 180 / math.Pi, true
+}
+
+// dateTitle names a group after the dates its photos were taken
+func dateTitle(photos []Photo) string {
+	var first, last *time.Time
+	for _, photo := range photos {
+		if photo.TakenAt == nil {
+			continue
+		}
+		if first == nil || photo.TakenAt.Before(*first) {
+			first = photo.TakenAt
+		}
+		if last == nil || photo.TakenAt.After(*last) {
+			last = photo.TakenAt
+		}
+	}
+	if first == nil {
+		return "Imported photos"
+	}
+	from, to := first.Format("2006-01-02"), last.Format("2006-01-02")
+	if from == to {
+		return from
+	}
+	return from + " – " + to
+}
@@ -0,0 +1,173 @@
+package takeout
+
+import (
+	"archive/zip"
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScan(t *testing.T) {
+	fsys := fstest.MapFS{
+		"Takeout/Google Photos/Kyoto/metadata.json": {Data: []byte(`{"title": "Kyoto trip", "description": "Temples"}`)},
+		"Takeout/Google Photos/Kyoto/IMG_0001.JPG":  {Data: []byte("jpeg")},
+		"Takeout/Google Photos/Kyoto/IMG_0001.JPG.json": {Data: []byte(`{
+  "title": "IMG_0001.JPG", "description": "Fushimi Inari",
+  "photoTakenTime": {"timestamp": "1556870400"},
+  "geoData": {"latitude": 0.0, "longitude": 0.0},
+  "geoDataExif": {"latitude": 34.9671, "longitude": 135.7727}
+}`)},
+		"Takeout/Google Photos/Kyoto/IMG_0002(1).jpg":                                                {Data: []byte("jpeg")},
+		"Takeout/Google Photos/Kyoto/IMG_0002.jpg.supplemental-metadata(1).json":                     {Data: []byte(`{"title": "IMG_0002.jpg", "photoTakenTime": {"timestamp": "1556874000"}, "geoData": {"latitude": 35.0116, "longitude": 135.7681}}`)},
+		"Takeout/Google Photos/Kyoto/IMG_0003-edited.jpg":                                            {Data: []byte("jpeg")},
+		"Takeout/Google Photos/Kyoto/IMG_0003.jpg.json":                                              {Data: []byte(`{"title": "IMG_0003.jpg", "photoTakenTime": {"timestamp": "1556877600"}}`)},
+		"Takeout/Google Photos/Kyoto/clip.mp4":                                                       {Data: []byte("mp4")},
+		"Takeout/Google Photos/Photos from 2019/PXL_20190503_101010123_very_long_name.jpg":           {Data: []byte("jpeg")},
+		"Takeout/Google Photos/Photos from 2019/PXL_20190503_101010123_very_long_name.jpg.supp.json": {Data: []byte(`{"title": "PXL_20190503_101010123_very_long_name.jpg", "photoTakenTime": {"timestamp": "1556878210"}}`)},
+		"Takeout/Google Photos/Photos from 2019/no-sidecar.png":                                      {Data: []byte("png")},
+	}
+
+	archive, err := Scan(fsys)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]Album{
+		"Takeout/Google Photos/Kyoto": {Title: "Kyoto trip", Description: "Temples"},
+	}, archive.Albums)
+	assert.Equal(t, []string{"Takeout/Google Photos/Kyoto/clip.mp4"}, archive.Unsupported)
+	require.Len(t, archive.Photos, 5)
+
+	photos := make(map[string]Photo)
+	for _, photo := range archive.Photos {
+		photos[photo.Path[len("Takeout/Google Photos/"):]] = photo
+	}
+
+	first := photos["Kyoto/IMG_0001.JPG"]
+	assert.Equal(t, "Fushimi Inari", first.Description)
+	require.NotNil(t, first.TakenAt)
+	assert.Equal(t, time.Date(2019, 5, 3, 8, 0, 0, 0, time.UTC), *first.TakenAt)
+	assert.True(t, first.Located, "falls back to geoDataExif")
+	assert.Equal(t, 34.9671, first.Lat)
+
+	duplicate := photos["Kyoto/IMG_0002(1).jpg"]
+	assert.True(t, duplicate.Located)
+	assert.Equal(t, 35.0116, duplicate.Lat)
+
+	edited := photos["Kyoto/IMG_0003-edited.jpg"]
+	require.NotNil(t, edited.TakenAt)
+	assert.False(t, edited.Located)
+
+	truncated := photos["Photos from 2019/PXL_20190503_101010123_very_long_name.jpg"]
+	require.NotNil(t, truncated.TakenAt)
+	assert.Equal(t, int64(1556878210), truncated.TakenAt.Unix())
+
+	bare := photos["Photos from 2019/no-sidecar.png"]
+	assert.Nil(t, bare.TakenAt)
+	assert.Equal(t, "no-sidecar.png", bare.Title)
+}
+
+func photoAt(path string, hour int, lat, lng float64) Photo {
+	takenAt := time.Date(2019, 5, 3, 0, 0, 0, 0, time.UTC).Add(time.Duration(hour) * time.Hour)
+	return Photo{Path: path, Folder: "Photos from 2019", Title: path, TakenAt: &takenAt, Lat: lat, Lng: lng, Located: true}
+}
+
+func TestByLocation(t *testing.T) {
+	unlocated := photoAt("e.jpg", 3, 0, 0)
+	unlocated.Located = false
+	stray := photoAt("z.jpg", 200, 0, 0)
+	stray.Located = false
+
+	photos := []Photo{
+		photoAt("c.jpg", 30, 35.0116, 135.7681), // next day, still Kyoto
+		photoAt("a.jpg", 0, 34.9671, 135.7727),
+		photoAt("b.jpg", 2, 34.9700, 135.7750),
+		unlocated,
+		photoAt("d.jpg", 100, 35.6812, 139.7671), // Tokyo, days later
+		stray,
+	}
+	groups, ungrouped := ByLocation(photos, 10, 36*time.Hour)
+
+	require.Len(t, groups, 2)
+	assert.Equal(t, "cluster:a.jpg", groups[0].Key)
+	assert.Equal(t, "2019-05-03 – 2019-05-04", groups[0].Title)
+	var paths []string
+	for _, photo := range groups[0].Photos {
+		paths = append(paths, photo.Path)
+	}
+	assert.Equal(t, []string{"a.jpg", "b.jpg", "e.jpg", "c.jpg"}, paths)
+	assert.InDelta(t, 34.98, groups[0].Lat, 0.01)
+
+	assert.Equal(t, "2019-05-07", groups[1].Title)
+	assert.Len(t, groups[1].Photos, 1)
+	assert.Equal(t, []Photo{stray}, ungrouped)
+}
+
+func TestByLocationAntimeridian(t *testing.T) {
+	groups, _ := ByLocation([]Photo{
+		photoAt("a.jpg", 0, -16.5, 179.9),
+		photoAt("b.jpg", 1, -16.5, -179.9),
+	}, 50, time.Hour)
+
+	require.Len(t, groups, 1)
+	assert.InDelta(t, 180, math.Abs(groups[0].Lng), 0.01)
+}
+
+func TestByFolder(t *testing.T) {
+	inAlbum := photoAt("Kyoto/a.jpg", 0, 34.9671, 135.7727)
+	inAlbum.Folder = "Kyoto"
+	copyInYear := photoAt("Photos from 2019/a.jpg", 0, 34.9671, 135.7727)
+	copyInYear.Title = inAlbum.Title
+	noPlace := photoAt("Empty/b.jpg", 0, 0, 0)
+	noPlace.Folder, noPlace.Located = "Empty", false
+
+	archive := &Archive{
+		Photos: []Photo{inAlbum, copyInYear, noPlace, photoAt("Photos from 2019/c.jpg", 50, 48.8566, 2.3522)},
+		Albums: map[string]Album{"Kyoto": {Title: "Kyoto trip"}, "Empty": {Title: "Empty"}},
+	}
+	groups, ungrouped := ByFolder(archive, 2, 24*time.Hour)
+
+	require.Len(t, groups, 2)
+	assert.Equal(t, "folder:Kyoto", groups[0].Key)
+	assert.Equal(t, "Kyoto trip", groups[0].Title)
+	assert.Equal(t, []Photo{inAlbum}, groups[0].Photos)
+	assert.Equal(t, "cluster:Photos from 2019/c.jpg", groups[1].Key)
+	assert.Equal(t, []Photo{noPlace}, ungrouped)
+}
+
+func TestScanOversizedSidecar(t *testing.T) {
+	// Highly compressible metadata, as a ZIP entry a few KB in size
+	padding := strings.Repeat(" ", MaxSidecarSize)
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"Kyoto/metadata.json":     `{"title": "Kyoto trip"` + padding + `}`,
+		"Kyoto/IMG_0001.jpg":      "jpeg",
+		"Kyoto/IMG_0001.jpg.json": `{"title": "IMG_0001.jpg", "photoTakenTime": {"timestamp": "1556870400"}` + padding + `}`,
+		"Kyoto/IMG_0002.jpg":      "jpeg",
+		"Kyoto/IMG_0002.jpg.json": `{"title": "IMG_0002.jpg", "photoTakenTime": {"timestamp": "1556874000"}}`,
+	} {
+		f, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = f.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	require.Less(t, buf.Len(), MaxSidecarSize/10)
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	archive, err := Scan(reader)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"Kyoto/metadata.json", "Kyoto/IMG_0001.jpg.json"}, archive.Oversized)
+	assert.Equal(t, map[string]Album{"Kyoto": {Title: "Kyoto"}}, archive.Albums)
+	require.Len(t, archive.Photos, 2)
+	assert.Nil(t, archive.Photos[0].TakenAt, "oversized sidecar is ignored")
+	require.NotNil(t, archive.Photos[1].TakenAt)
+	assert.Equal(t, int64(1556874000), archive.Photos[1].TakenAt.Unix())
+}
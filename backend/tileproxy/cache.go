@@ -0,0 +1,226 @@
+package tileproxy
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tileFileExt marks cache files; each holds a JSON metadata line followed by the tile
+const tileFileExt = ".tile"
+
+// tempFilePattern names the files tiles are written to before being moved in place
+const tempFilePattern = ".tmp-*"
+
+// tileMeta is what is remembered about a cached tile's upstream response
+type tileMeta struct {
+	ContentType  string    `json:"content_type"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Expires      time.Time `json:"expires"`
+}
+
+// cachedTile is a tile read from the disk cache
+type cachedTile struct {
+	meta tileMeta
+	data []byte
+}
+
+// cacheEntry is a cached tile in the LRU index
+type cacheEntry struct {
+	key  string
+	size int64
+}
+
+// diskCache stores tiles as files and evicts the least recently used ones once the
+// total size exceeds maxBytes. Access times are recorded in file modification
+// times so that the eviction order survives restarts.
+type diskCache struct {
+	dir      string
+	maxBytes int64
+
+	mu    sync.Mutex
+	index map[string]*list.Element
+	lru   *list.List // of *cacheEntry, most recently used first
+	size  int64
+}
+
+// openDiskCache indexes the tiles already in dir
+func openDiskCache(dir string, maxBytes int64) (*diskCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create tile cache directory: %w", err)
+	}
+
+	type found struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	var files []found
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if temp, _ := filepath.Match(tempFilePattern, d.Name()); temp {
+			// Leftover temporary file from an interrupted write
+			return os.Remove(path)
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if !strings.HasSuffix(rel, tileFileExt) {
+			// Not ours; the directory may be shared, so it is left alone
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(strings.TrimSuffix(rel, tileFileExt))
+		files = append(files, found{key: key, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to index tile cache: %w", err)
+	}
+
+	cache := &diskCache{dir: dir, maxBytes: maxBytes, index: make(map[string]*list.Element), lru: list.New()}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+	for _, file := range files {
+		cache.index[file.key] = cache.lru.PushBack(&cacheEntry{key: file.key, size: file.size})
+		cache.size += file.size
+	}
+	cache.mu.Lock()
+	cache.evictLocked()
+	cache.mu.Unlock()
+	return cache, nil
+}
+
+func (c *diskCache) path(key string) string {
+	return filepath.Join(c.dir, filepath.FromSlash(key)+tileFileExt)
+}
+
+// get reads a cached tile and marks it as recently used; it returns nil when the
+// tile is not cached
+func (c *diskCache) get(key string) (*cachedTile, error) {
+	c.mu.Lock()
+	element, ok := c.index[key]
+	if ok {
+		c.lru.MoveToFront(element)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, nil
+	}
+
+	path := c.path(key)
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			c.remove(key)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read cached tile: %w", err)
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+
+	header, data, ok := bytes.Cut(raw, []byte("\n"))
+	var meta tileMeta
+	if !ok || json.Unmarshal(header, &meta) != nil {
+		c.remove(key)
+		return nil, nil
+	}
+	return &cachedTile{meta: meta, data: data}, nil
+}
+
+// put writes a tile, replacing any cached copy, then evicts tiles beyond the size limit
+func (c *diskCache) put(key string, tile *cachedTile) error {
+	header, err := json.Marshal(tile.meta)
+	if err != nil {
+		return fmt.Errorf("failed to encode tile metadata: %w", err)
+	}
+
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create tile cache directory: %w", err)
+	}
+	// Write to a temporary file first so readers never see a partial tile
+	tmp, err := os.CreateTemp(filepath.Dir(path), tempFilePattern)
+	if err != nil {
+		return fmt.Errorf("failed to write cached tile: %w", err)
+	}
+	writer := bufio.NewWriter(tmp)
+	writer.Write(header)
+	writer.WriteByte('\n')
+	writer.Write(tile.data)
+	err = writer.Flush()
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cached tile: %w", err)
+	}
+
+	size := int64(len(header) + 1 + len(tile.data))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.index[key]; ok {
+		entry := element.Value.(*cacheEntry)
+		c.size += size - entry.size
+		entry.size = size
+		c.lru.MoveToFront(element)
+	} else {
+		c.index[key] = c.lru.PushFront(&cacheEntry{key: key, size: size})
+		c.size += size
+	}
+	c.evictLocked()
+	return nil
+}
+
+// remove deletes a cached tile
+func (c *diskCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.index[key]; ok {
+		c.removeLocked(element)
+	}
+}
+
+// evictLocked removes least recently used tiles until the cache fits maxBytes
+func (c *diskCache) evictLocked() {
+	for c.size > c.maxBytes && c.lru.Len() > 0 {
+		c.removeLocked(c.lru.Back())
+	}
+}
+
+func (c *diskCache) removeLocked(element *list.Element) {
+	entry := element.Value.(*cacheEntry)
+	c.lru.Remove(element)
+	delete(c.index, entry.key)
+	c.size -= entry.size
+	os.Remove(c.path(entry.key))
+}
+
+// stats returns the number of cached tiles and their total size in bytes
+func (c *diskCache) stats() (int, int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len(), c.size
+}
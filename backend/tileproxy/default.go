@@ -0,0 +1,69 @@
+package tileproxy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"geoalbum/backend/logging"
+)
+
+// defaultCacheMB is the tile cache size limit when TILE_PROXY_CACHE_MB is unset
+const defaultCacheMB = 1024
+
+var (
+	defaultMu    sync.RWMutex
+	defaultProxy *Proxy
+)
+
+// Initialize sets up the proxy from the environment: TILE_PROXY_PROVIDERS lists the
+// providers (see ParseProviders), TILE_PROXY_CACHE_DIR the cache directory (default
+// data/tilecache) and TILE_PROXY_CACHE_MB its size limit. Without providers the
+// proxy stays disabled.
+func Initialize() error {
+	providers, err := ParseProviders(os.Getenv("TILE_PROXY_PROVIDERS"))
+	if err != nil {
+		return err
+	}
+	if len(providers) == 0 {
+		return nil
+	}
+
+	cacheDir := os.Getenv("TILE_PROXY_CACHE_DIR")
+	if cacheDir == "" {
+		cacheDir = filepath.Join("data", "tilecache")
+	}
+	cacheMB := int64(defaultCacheMB)
+	if value := os.Getenv("TILE_PROXY_CACHE_MB"); value != "" {
+		cacheMB, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || cacheMB <= 0 {
+			return fmt.Errorf("invalid TILE_PROXY_CACHE_MB %q", value)
+		}
+	}
+
+	proxy, err := New(Config{Providers: providers, CacheDir: cacheDir, MaxCacheBytes: cacheMB << 20})
+	if err != nil {
+		return err
+	}
+	defaultMu.Lock()
+	defaultProxy = proxy
+	defaultMu.Unlock()
+
+	tiles, size := proxy.CacheStats()
+	logging.WithFields(map[string]interface{}{
+		"providers":   proxy.Providers(),
+		"cache_dir":   cacheDir,
+		"cache_tiles": tiles,
+		"cache_bytes": size,
+	}).Info("Tile proxy enabled")
+	return nil
+}
+
+// Default returns the proxy configured by Initialize, or nil when it is disabled
+func Default() *Proxy {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultProxy
+}
@@ -0,0 +1,80 @@
+package tileproxy
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// providerNamePattern restricts provider names to safe URL and directory names
+var providerNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// Provider is an upstream tile server
+type Provider struct {
+	Name string
+	// URLTemplate contains {z}, {x} and {y}, and optionally {s} for a subdomain
+	URLTemplate string
+	Subdomains  []string
+}
+
+// ParseProviders parses a whitespace-separated list of "name=template" entries. A
+// template may end in "|abc" to list the subdomains substituted for {s}; they
+// default to a, b and c.
+func ParseProviders(spec string) ([]Provider, error) {
+	var providers []Provider
+	seen := make(map[string]bool)
+	for _, entry := range strings.Fields(spec) {
+		name, template, ok := strings.Cut(entry, "=")
+		if !ok || !providerNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid tile provider %q: expected name=template", entry)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate tile provider %q", name)
+		}
+		seen[name] = true
+
+		provider := Provider{Name: name, URLTemplate: template, Subdomains: []string{"a", "b", "c"}}
+		if i := strings.LastIndexByte(template, '|'); i >= 0 {
+			provider.URLTemplate = template[:i]
+			provider.Subdomains = strings.Split(template[i+1:], "")
+		}
+		if err := provider.validate(); err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+func (p Provider) validate() error {
+	if !providerNamePattern.MatchString(p.Name) {
+		return fmt.Errorf("invalid tile provider name %q", p.Name)
+	}
+	if !strings.HasPrefix(p.URLTemplate, "http://") && !strings.HasPrefix(p.URLTemplate, "https://") {
+		return fmt.Errorf("tile provider %s: template must be an http(s) URL", p.Name)
+	}
+	for _, placeholder := range []string{"{z}", "{x}", "{y}"} {
+		if !strings.Contains(p.URLTemplate, placeholder) {
+			return fmt.Errorf("tile provider %s: template lacks %s", p.Name, placeholder)
+		}
+	}
+	if strings.Contains(p.URLTemplate, "{s}") && len(p.Subdomains) == 0 {
+		return fmt.Errorf("tile provider %s: template uses {s} without subdomains", p.Name)
+	}
+	return nil
+}
+
+// URL returns the upstream URL of tile z/x/y. The subdomain is chosen from the tile
+// position so that a tile always maps to the same host.
+func (p Provider) URL(z, x, y int) string {
+	replacements := []string{
+		"{z}", strconv.Itoa(z),
+		"{x}", strconv.Itoa(x),
+		"{y}", strconv.Itoa(y),
+	}
+	if len(p.Subdomains) > 0 {
+		replacements = append(replacements, "{s}", p.Subdomains[(x+y)%len(p.Subdomains)])
+	}
+	return strings.NewReplacer(replacements...).Replace(p.URLTemplate)
+}
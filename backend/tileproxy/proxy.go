@@ -0,0 +1,318 @@
+// Package tileproxy fetches map tiles from remote tile servers on behalf of clients
+// and keeps them in a size-limited disk cache, so that tiles seen once, or seeded in
+// advance, remain available offline.
+package tileproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxZoom is the deepest zoom level the proxy accepts
+const MaxZoom = 22
+
+// maxTileBytes bounds the size of a single upstream tile
+const maxTileBytes = 4 << 20
+
+// maxUpstreamRequests bounds concurrent requests to the tile servers
+const maxUpstreamRequests = 8
+
+var (
+	// ErrUnknownProvider is returned for a provider name that is not configured
+	ErrUnknownProvider = errors.New("unknown tile provider")
+	// ErrTileNotFound is returned when the tile server has no such tile
+	ErrTileNotFound = errors.New("tile not found")
+)
+
+// Cache outcomes reported with a tile
+const (
+	CacheHit         = "HIT"
+	CacheMiss        = "MISS"
+	CacheRevalidated = "REVALIDATED"
+	CacheStale       = "STALE" // expired, served because the tile server failed
+)
+
+// Config configures a Proxy
+type Config struct {
+	Providers     []Provider
+	CacheDir      string
+	MaxCacheBytes int64
+	// DefaultTTL applies to tiles whose response carries no freshness information
+	DefaultTTL time.Duration
+	// UserAgent identifies the proxy to tile servers, which often require one
+	UserAgent string
+	// Client performs upstream requests; http.DefaultClient with a timeout when nil
+	Client *http.Client
+}
+
+// Proxy serves tiles from its cache, fetching them from the tile servers when missing
+// or expired
+type Proxy struct {
+	providers  map[string]Provider
+	cache      *diskCache
+	client     *http.Client
+	userAgent  string
+	defaultTTL time.Duration
+	upstream   chan struct{}
+	now        func() time.Time
+
+	jobsMu sync.Mutex
+	jobs   map[string]*seedJob
+}
+
+// Tile is a tile served by the proxy
+type Tile struct {
+	Data        []byte
+	ContentType string
+	Expires     time.Time
+	Cache       string
+}
+
+// New creates a proxy, indexing any tiles already in the cache directory
+func New(config Config) (*Proxy, error) {
+	if len(config.Providers) == 0 {
+		return nil, fmt.Errorf("no tile providers configured")
+	}
+	providers := make(map[string]Provider, len(config.Providers))
+	for _, provider := range config.Providers {
+		if err := provider.validate(); err != nil {
+			return nil, err
+		}
+		providers[provider.Name] = provider
+	}
+
+	cache, err := openDiskCache(config.CacheDir, config.MaxCacheBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	client := config.Client
+	if client == nil {
+		client = &http.Client{Timeout: 20 * time.Second}
+	}
+	defaultTTL := config.DefaultTTL
+	if defaultTTL <= 0 {
+		defaultTTL = 24 * time.Hour
+	}
+	userAgent := config.UserAgent
+	if userAgent == "" {
+		userAgent = "geoalbum-tileproxy/1.0"
+	}
+
+	return &Proxy{
+		providers:  providers,
+		cache:      cache,
+		client:     client,
+		userAgent:  userAgent,
+		defaultTTL: defaultTTL,
+		upstream:   make(chan struct{}, maxUpstreamRequests),
+		now:        time.Now,
+		jobs:       make(map[string]*seedJob),
+	}, nil
+}
+
+// Providers returns the configured provider names in order
+func (p *Proxy) Providers() []string {
+	names := make([]string, 0, len(p.providers))
+	for name := range p.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CacheStats returns the number of cached tiles and their total size in bytes
+func (p *Proxy) CacheStats() (int, int64) {
+	return p.cache.stats()
+}
+
+// Get returns tile z/x/y of a provider. A fresh cached tile is served directly; an
+// expired one is revalidated with the tile server and, if the server cannot be
+// reached, served stale.
+func (p *Proxy) Get(ctx context.Context, providerName string, z, x, y int) (*Tile, error) {
+	provider, ok := p.providers[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	if z < 0 || z > MaxZoom || x < 0 || y < 0 || x >= 1<<uint(z) || y >= 1<<uint(z) {
+		return nil, fmt.Errorf("invalid tile: %d/%d/%d", z, x, y)
+	}
+
+	key := fmt.Sprintf("%s/%d/%d/%d", provider.Name, z, x, y)
+	cached, err := p.cache.get(key)
+	if err != nil {
+		return nil, err
+	}
+	now := p.now()
+	if cached != nil && now.Before(cached.meta.Expires) {
+		return &Tile{Data: cached.data, ContentType: cached.meta.ContentType, Expires: cached.meta.Expires, Cache: CacheHit}, nil
+	}
+
+	response, err := p.fetch(ctx, provider.URL(z, x, y), cached)
+	if err != nil {
+		if cached != nil {
+			return &Tile{Data: cached.data, ContentType: cached.meta.ContentType, Cache: CacheStale}, nil
+		}
+		return nil, err
+	}
+
+	expires, store := freshness(response.header, now, p.defaultTTL)
+	switch response.status {
+	case http.StatusNotModified:
+		if cached == nil {
+			return nil, fmt.Errorf("tile server responded not modified to an unconditional request")
+		}
+		cached.meta.Expires = expires
+		if etag := response.header.Get("ETag"); etag != "" {
+			cached.meta.ETag = etag
+		}
+		if err := p.cache.put(key, cached); err != nil {
+			return nil, err
+		}
+		return &Tile{Data: cached.data, ContentType: cached.meta.ContentType, Expires: expires, Cache: CacheRevalidated}, nil
+
+	case http.StatusOK:
+		tile := &cachedTile{
+			meta: tileMeta{
+				ContentType:  response.header.Get("Content-Type"),
+				ETag:         response.header.Get("ETag"),
+				LastModified: response.header.Get("Last-Modified"),
+				Expires:      expires,
+			},
+			data: response.body,
+		}
+		if store {
+			if err := p.cache.put(key, tile); err != nil {
+				return nil, err
+			}
+		} else if cached != nil {
+			p.cache.remove(key)
+			expires = time.Time{}
+		} else {
+			expires = time.Time{}
+		}
+		return &Tile{Data: tile.data, ContentType: tile.meta.ContentType, Expires: expires, Cache: CacheMiss}, nil
+
+	case http.StatusNotFound, http.StatusNoContent:
+		return nil, ErrTileNotFound
+	}
+
+	if cached != nil {
+		return &Tile{Data: cached.data, ContentType: cached.meta.ContentType, Cache: CacheStale}, nil
+	}
+	return nil, fmt.Errorf("tile server responded with status %d", response.status)
+}
+
+// upstreamResponse is the part of a tile server response the proxy uses
+type upstreamResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// fetch requests a tile, conditionally when a cached copy exists
+func (p *Proxy) fetch(ctx context.Context, url string, cached *cachedTile) (*upstreamResponse, error) {
+	select {
+	case p.upstream <- struct{}{}:
+		defer func() { <-p.upstream }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tile request: %w", err)
+	}
+	request.Header.Set("User-Agent", p.userAgent)
+	if cached != nil {
+		if cached.meta.ETag != "" {
+			request.Header.Set("If-None-Match", cached.meta.ETag)
+		}
+		if cached.meta.LastModified != "" {
+			request.Header.Set("If-Modified-Since", cached.meta.LastModified)
+		}
+	}
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tile: %w", err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxTileBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tile: %w", err)
+	}
+	if len(body) > maxTileBytes {
+		return nil, fmt.Errorf("tile exceeds %d bytes", maxTileBytes)
+	}
+	return &upstreamResponse{status: response.StatusCode, header: response.Header, body: body}, nil
+}
+
+// freshness derives when a response expires from its Cache-Control, Age and Expires
+// headers, falling back to defaultTTL. store is false when the response must not be
+// kept by a shared cache. no-cache responses are stored but expire immediately, so
+// they are revalidated on every use.
+func freshness(header http.Header, now time.Time, defaultTTL time.Duration) (expires time.Time, store bool) {
+	directives := parseCacheControl(header.Get("Cache-Control"))
+	if _, ok := directives["no-store"]; ok {
+		return now, false
+	}
+	if _, ok := directives["private"]; ok {
+		return now, false
+	}
+	if _, ok := directives["no-cache"]; ok {
+		return now, true
+	}
+
+	for _, name := range []string{"s-maxage", "max-age"} {
+		value, ok := directives[name]
+		if !ok {
+			continue
+		}
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		if age, err := strconv.ParseInt(header.Get("Age"), 10, 64); err == nil && age > 0 {
+			seconds -= age
+		}
+		return now.Add(time.Duration(seconds) * time.Second), true
+	}
+
+	if value := header.Get("Expires"); value != "" {
+		expires, err := http.ParseTime(value)
+		if err != nil {
+			// An invalid Expires means already expired
+			return now, true
+		}
+		if date, err := http.ParseTime(header.Get("Date")); err == nil {
+			// Interpret relative to the server's clock
+			return now.Add(expires.Sub(date)), true
+		}
+		return expires, true
+	}
+
+	return now.Add(defaultTTL), true
+}
+
+// parseCacheControl splits a Cache-Control header into lower-cased directives
+func parseCacheControl(value string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name == "" {
+			continue
+		}
+		directives[strings.ToLower(name)] = strings.Trim(arg, `"`)
+	}
+	return directives
+}
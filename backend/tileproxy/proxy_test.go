@@ -0,0 +1,404 @@
+package tileproxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"geoalbum/backend/model"
+)
+
+// upstream is a stand-in tile server. Tiles are "tile z/x/y" and carry an ETag;
+// the cache headers and failures it responds with can be changed during a test.
+type upstream struct {
+	server   *httptest.Server
+	requests atomic.Int64
+
+	mu           sync.Mutex
+	cacheControl string
+	status       int
+	missing      map[string]bool
+	hold         chan struct{} // when set, responses wait until it is closed
+}
+
+func newUpstream(t *testing.T) *upstream {
+	u := &upstream{cacheControl: "max-age=60", status: http.StatusOK, missing: map[string]bool{}}
+	u.server = httptest.NewServer(http.HandlerFunc(u.serve))
+	t.Cleanup(u.server.Close)
+	return u
+}
+
+func (u *upstream) serve(w http.ResponseWriter, r *http.Request) {
+	u.requests.Add(1)
+	u.mu.Lock()
+	cacheControl, status, missing, hold := u.cacheControl, u.status, u.missing[r.URL.Path], u.hold
+	u.mu.Unlock()
+
+	if hold != nil {
+		select {
+		case <-hold:
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+		return
+	}
+	if missing {
+		http.NotFound(w, r)
+		return
+	}
+	etag := `"` + r.URL.Path + `"`
+	if cacheControl != "" {
+		w.Header().Set("Cache-Control", cacheControl)
+	}
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	fmt.Fprintf(w, "tile %s", strings.TrimPrefix(r.URL.Path, "/"))
+}
+
+func (u *upstream) set(cacheControl string, status int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.cacheControl, u.status = cacheControl, status
+}
+
+// clock is a settable time source for expiry tests
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestProxy(t *testing.T, u *upstream, dir string, maxBytes int64) (*Proxy, *clock) {
+	t.Helper()
+	proxy, err := New(Config{
+		Providers:     []Provider{{Name: "test", URLTemplate: u.server.URL + "/{z}/{x}/{y}"}},
+		CacheDir:      dir,
+		MaxCacheBytes: maxBytes,
+	})
+	require.NoError(t, err)
+	c := &clock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	proxy.now = c.Now
+	return proxy, c
+}
+
+func TestGetCachesTile(t *testing.T) {
+	u := newUpstream(t)
+	proxy, _ := newTestProxy(t, u, t.TempDir(), 1<<20)
+
+	tile, err := proxy.Get(context.Background(), "test", 3, 4, 5)
+	require.NoError(t, err)
+	assert.Equal(t, CacheMiss, tile.Cache)
+	assert.Equal(t, "tile 3/4/5", string(tile.Data))
+	assert.Equal(t, "image/png", tile.ContentType)
+
+	tile, err = proxy.Get(context.Background(), "test", 3, 4, 5)
+	require.NoError(t, err)
+	assert.Equal(t, CacheHit, tile.Cache)
+	assert.Equal(t, "tile 3/4/5", string(tile.Data))
+	assert.EqualValues(t, 1, u.requests.Load())
+}
+
+func TestGetRevalidatesExpiredTile(t *testing.T) {
+	u := newUpstream(t)
+	proxy, clock := newTestProxy(t, u, t.TempDir(), 1<<20)
+
+	_, err := proxy.Get(context.Background(), "test", 1, 0, 1)
+	require.NoError(t, err)
+
+	clock.Advance(61 * time.Second)
+	tile, err := proxy.Get(context.Background(), "test", 1, 0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, CacheRevalidated, tile.Cache)
+	assert.Equal(t, "tile 1/0/1", string(tile.Data))
+	assert.EqualValues(t, 2, u.requests.Load())
+
+	// Revalidation renews freshness
+	tile, err = proxy.Get(context.Background(), "test", 1, 0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, CacheHit, tile.Cache)
+	assert.EqualValues(t, 2, u.requests.Load())
+}
+
+func TestGetHonoursNoStore(t *testing.T) {
+	u := newUpstream(t)
+	u.set("no-store", http.StatusOK)
+	proxy, _ := newTestProxy(t, u, t.TempDir(), 1<<20)
+
+	for i := 0; i < 2; i++ {
+		tile, err := proxy.Get(context.Background(), "test", 0, 0, 0)
+		require.NoError(t, err)
+		assert.Equal(t, CacheMiss, tile.Cache)
+	}
+	assert.EqualValues(t, 2, u.requests.Load())
+	tiles, _ := proxy.CacheStats()
+	assert.Zero(t, tiles)
+}
+
+func TestGetServesStaleTileWhenUpstreamFails(t *testing.T) {
+	u := newUpstream(t)
+	proxy, clock := newTestProxy(t, u, t.TempDir(), 1<<20)
+
+	_, err := proxy.Get(context.Background(), "test", 2, 1, 1)
+	require.NoError(t, err)
+
+	clock.Advance(time.Hour)
+	u.set("max-age=60", http.StatusServiceUnavailable)
+	tile, err := proxy.Get(context.Background(), "test", 2, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, CacheStale, tile.Cache)
+	assert.Equal(t, "tile 2/1/1", string(tile.Data))
+
+	// Without a cached copy the failure is reported
+	_, err = proxy.Get(context.Background(), "test", 2, 2, 2)
+	assert.Error(t, err)
+
+	// An unreachable server behaves the same
+	u.server.Close()
+	tile, err = proxy.Get(context.Background(), "test", 2, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, CacheStale, tile.Cache)
+}
+
+func TestGetReportsMissingTilesAndProviders(t *testing.T) {
+	u := newUpstream(t)
+	u.missing["/5/1/1"] = true
+	proxy, _ := newTestProxy(t, u, t.TempDir(), 1<<20)
+
+	_, err := proxy.Get(context.Background(), "test", 5, 1, 1)
+	assert.ErrorIs(t, err, ErrTileNotFound)
+
+	_, err = proxy.Get(context.Background(), "other", 5, 1, 1)
+	assert.ErrorIs(t, err, ErrUnknownProvider)
+
+	_, err = proxy.Get(context.Background(), "test", 1, 2, 0)
+	assert.Error(t, err)
+}
+
+func TestCacheEvictsLeastRecentlyUsedTiles(t *testing.T) {
+	u := newUpstream(t)
+	// Room for two tiles of this size, not three
+	proxy, _ := newTestProxy(t, u, t.TempDir(), 250)
+
+	get := func(x int) string {
+		tile, err := proxy.Get(context.Background(), "test", 4, x, 0)
+		require.NoError(t, err)
+		return tile.Cache
+	}
+
+	assert.Equal(t, CacheMiss, get(1))
+	assert.Equal(t, CacheMiss, get(2))
+	assert.Equal(t, CacheHit, get(1))
+	assert.Equal(t, CacheMiss, get(3)) // evicts 2, the least recently used
+
+	tiles, size := proxy.CacheStats()
+	assert.Equal(t, 2, tiles)
+	assert.LessOrEqual(t, size, int64(250))
+	assert.Equal(t, CacheHit, get(1))
+	assert.Equal(t, CacheHit, get(3))
+	assert.Equal(t, CacheMiss, get(2))
+}
+
+func TestCacheSurvivesRestart(t *testing.T) {
+	u := newUpstream(t)
+	dir := t.TempDir()
+	proxy, _ := newTestProxy(t, u, dir, 1<<20)
+	_, err := proxy.Get(context.Background(), "test", 6, 7, 8)
+	require.NoError(t, err)
+
+	restarted, _ := newTestProxy(t, u, dir, 1<<20)
+	tiles, _ := restarted.CacheStats()
+	assert.Equal(t, 1, tiles)
+	tile, err := restarted.Get(context.Background(), "test", 6, 7, 8)
+	require.NoError(t, err)
+	assert.Equal(t, CacheHit, tile.Cache)
+	assert.EqualValues(t, 1, u.requests.Load())
+}
+
+func TestCacheKeepsForeignFiles(t *testing.T) {
+	u := newUpstream(t)
+	dir := t.TempDir()
+	foreign := filepath.Join(dir, "geoalbum.db")
+	require.NoError(t, os.WriteFile(foreign, []byte("not a tile"), 0644))
+	leftover := filepath.Join(dir, ".tmp-123456")
+	require.NoError(t, os.WriteFile(leftover, []byte("partial"), 0644))
+
+	proxy, _ := newTestProxy(t, u, dir, 1<<20)
+	tiles, _ := proxy.CacheStats()
+	assert.Equal(t, 0, tiles)
+	assert.FileExists(t, foreign)
+	assert.NoFileExists(t, leftover)
+}
+
+func TestSeedCachesTileRange(t *testing.T) {
+	u := newUpstream(t)
+	u.missing["/2/3/1"] = true
+	proxy, _ := newTestProxy(t, u, t.TempDir(), 1<<20)
+
+	// Kyoto to Tokyo lies within a single tile at zoom 0 to 2
+	bbox := model.BoundingBox{MinLat: 34.9, MinLng: 135.7, MaxLat: 35.8, MaxLng: 139.8}
+	job, err := proxy.Seed("user", "test", bbox, 0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 3, job.Total)
+
+	require.Eventually(t, func() bool {
+		state, ok := proxy.SeedJob(job.ID, "user")
+		return ok && state.Status == model.SeedJobCompleted
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The tile the server lacks counts as done, not failed
+	state, _ := proxy.SeedJob(job.ID, "user")
+	assert.Equal(t, 3, state.Done)
+	assert.Zero(t, state.Failed)
+	assert.NotNil(t, state.FinishedAt)
+
+	tile, err := proxy.Get(context.Background(), "test", 1, 1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, CacheHit, tile.Cache)
+}
+
+func TestSeedRejectsOversizedRanges(t *testing.T) {
+	u := newUpstream(t)
+	proxy, _ := newTestProxy(t, u, t.TempDir(), 1<<20)
+
+	world := model.BoundingBox{MinLat: -85, MinLng: -180, MaxLat: 85, MaxLng: 180}
+	_, err := proxy.Seed("user", "test", world, 0, 12)
+	assert.ErrorIs(t, err, ErrSeedTooLarge)
+
+	_, err = proxy.Seed("user", "test", world, 3, 2)
+	assert.Error(t, err)
+}
+
+func TestSeedJobsPerUser(t *testing.T) {
+	u := newUpstream(t)
+	u.hold = make(chan struct{})
+	proxy, _ := newTestProxy(t, u, t.TempDir(), 1<<20)
+	bbox := model.BoundingBox{MinLat: 34.9, MinLng: 135.7, MaxLat: 35.8, MaxLng: 139.8}
+
+	job, err := proxy.Seed("user", "test", bbox, 0, 2)
+	require.NoError(t, err)
+
+	// Jobs are only visible to their owner
+	_, ok := proxy.SeedJob(job.ID, "other")
+	assert.False(t, ok)
+	assert.False(t, proxy.CancelSeedJob(job.ID, "other"))
+	_, ok = proxy.SeedJob("unknown", "user")
+	assert.False(t, ok)
+
+	// A user runs one job at a time, and only so many run at once
+	_, err = proxy.Seed("user", "test", bbox, 0, 2)
+	assert.ErrorIs(t, err, ErrSeedInProgress)
+	for i := 1; i < MaxRunningSeeds; i++ {
+		_, err = proxy.Seed(fmt.Sprintf("user%d", i), "test", bbox, 0, 2)
+		require.NoError(t, err)
+	}
+	_, err = proxy.Seed("late", "test", bbox, 0, 2)
+	assert.ErrorIs(t, err, ErrSeedCapacity)
+
+	// A cancelled job stops without counting the interrupted tiles as failed
+	assert.True(t, proxy.CancelSeedJob(job.ID, "user"))
+	require.Eventually(t, func() bool {
+		state, _ := proxy.SeedJob(job.ID, "user")
+		return state.Status == model.SeedJobCancelled
+	}, 5*time.Second, 10*time.Millisecond)
+	state, _ := proxy.SeedJob(job.ID, "user")
+	assert.Zero(t, state.Done)
+	assert.Zero(t, state.Failed)
+	assert.NotNil(t, state.FinishedAt)
+
+	// which frees the user to start another
+	close(u.hold)
+	_, err = proxy.Seed("user", "test", bbox, 0, 2)
+	assert.NoError(t, err)
+}
+
+func TestSeedRangesCrossingAntimeridian(t *testing.T) {
+	// Fiji, from 177°E to 178°W
+	bbox := model.BoundingBox{MinLat: -19, MinLng: 177, MaxLat: -16, MaxLng: -178}
+	ranges, total := seedRanges(bbox, 4, 4)
+	require.Len(t, ranges, 2)
+	assert.Equal(t, tileRange{z: 4, minX: 15, maxX: 15, minY: 8, maxY: 8}, ranges[0])
+	assert.Equal(t, tileRange{z: 4, minX: 0, maxX: 0, minY: 8, maxY: 8}, ranges[1])
+	assert.Equal(t, 2, total)
+}
+
+func TestFreshness(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	tests := []struct {
+		name    string
+		header  http.Header
+		expires time.Time
+		store   bool
+	}{
+		{"default", http.Header{}, now.Add(day), true},
+		{"max-age", http.Header{"Cache-Control": {"public, max-age=3600"}}, now.Add(time.Hour), true},
+		{"age", http.Header{"Cache-Control": {"max-age=3600"}, "Age": {"600"}}, now.Add(50 * time.Minute), true},
+		{"s-maxage wins", http.Header{"Cache-Control": {"max-age=60, s-maxage=120"}}, now.Add(2 * time.Minute), true},
+		{"no-cache", http.Header{"Cache-Control": {"no-cache"}}, now, true},
+		{"no-store", http.Header{"Cache-Control": {"no-store"}}, now, false},
+		{"private", http.Header{"Cache-Control": {"private, max-age=60"}}, now, false},
+		{"expires", http.Header{
+			"Date":    {"Wed, 01 May 2024 10:00:00 GMT"},
+			"Expires": {"Wed, 01 May 2024 13:00:00 GMT"},
+		}, now.Add(3 * time.Hour), true},
+		{"invalid expires", http.Header{"Expires": {"0"}}, now, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expires, store := freshness(tt.header, now, day)
+			assert.Equal(t, tt.expires, expires)
+			assert.Equal(t, tt.store, store)
+		})
+	}
+}
+
+func TestParseProviders(t *testing.T) {
+	providers, err := ParseProviders(`osm=https://{s}.tile.openstreetmap.org/{z}/{x}/{y}.png
+		amap=https://webrd0{s}.is.autonavi.com/appmaptile?style=7&x={x}&y={y}&z={z}|1234`)
+	require.NoError(t, err)
+	require.Len(t, providers, 2)
+
+	assert.Equal(t, "https://b.tile.openstreetmap.org/3/1/0.png", providers[0].URL(3, 1, 0))
+	assert.Equal(t, "https://webrd03.is.autonavi.com/appmaptile?style=7&x=1&y=1&z=2", providers[1].URL(2, 1, 1))
+
+	for _, spec := range []string{
+		"osm",
+		"bad/name=https://example.com/{z}/{x}/{y}",
+		"osm=ftp://example.com/{z}/{x}/{y}",
+		"osm=https://example.com/{z}/{x}",
+		"osm=https://example.com/{z}/{x}/{y} osm=https://example.com/{z}/{x}/{y}",
+	} {
+		_, err := ParseProviders(spec)
+		assert.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,239 @@
+package tileproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"geoalbum/backend/model"
+)
+
+// MaxSeedTiles bounds the number of tiles one seed job may fetch
+const MaxSeedTiles = 50000
+
+// MaxRunningSeeds bounds the seed jobs running at once across all users
+const MaxRunningSeeds = 4
+
+// seedWorkers is the number of tiles a seed job fetches concurrently
+const seedWorkers = 4
+
+var (
+	// ErrSeedTooLarge is returned when a seed range covers more than MaxSeedTiles tiles
+	ErrSeedTooLarge = errors.New("seed range has too many tiles")
+	// ErrSeedInProgress is returned when the user already has a seed job running
+	ErrSeedInProgress = errors.New("a seed job is already running")
+	// ErrSeedCapacity is returned when MaxRunningSeeds seed jobs are already running
+	ErrSeedCapacity = errors.New("too many seed jobs are running")
+)
+
+// seedJob is a running or finished seed job
+type seedJob struct {
+	mu     sync.Mutex
+	job    model.TileSeedJob
+	cancel context.CancelFunc
+}
+
+func (j *seedJob) snapshot() model.TileSeedJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.job
+}
+
+// tileRange is an inclusive rectangle of tiles at one zoom level
+type tileRange struct {
+	z, minX, maxX, minY, maxY int
+}
+
+func (r tileRange) count() int {
+	return (r.maxX - r.minX + 1) * (r.maxY - r.minY + 1)
+}
+
+// Seed starts fetching every tile of a provider that intersects bbox at zoom levels
+// minZoom to maxZoom into the cache for a user. Tiles already cached and fresh are
+// not fetched again. A user runs one seed job at a time, and at most MaxRunningSeeds
+// run at once. The returned job can be polled with SeedJob.
+func (p *Proxy) Seed(userID, providerName string, bbox model.BoundingBox, minZoom, maxZoom int) (model.TileSeedJob, error) {
+	if _, ok := p.providers[providerName]; !ok {
+		return model.TileSeedJob{}, ErrUnknownProvider
+	}
+	if minZoom < 0 || maxZoom > MaxZoom || minZoom > maxZoom {
+		return model.TileSeedJob{}, fmt.Errorf("invalid zoom range %d-%d", minZoom, maxZoom)
+	}
+
+	ranges, total := seedRanges(bbox, minZoom, maxZoom)
+	if total > MaxSeedTiles {
+		return model.TileSeedJob{}, fmt.Errorf("%w: %d tiles, at most %d allowed", ErrSeedTooLarge, total, MaxSeedTiles)
+	}
+
+	p.pruneSeedJobs()
+	p.jobsMu.Lock()
+	running := 0
+	for _, other := range p.jobs {
+		state := other.snapshot()
+		if state.Status != model.SeedJobRunning {
+			continue
+		}
+		if state.UserID == userID {
+			p.jobsMu.Unlock()
+			return model.TileSeedJob{}, ErrSeedInProgress
+		}
+		running++
+	}
+	if running >= MaxRunningSeeds {
+		p.jobsMu.Unlock()
+		return model.TileSeedJob{}, ErrSeedCapacity
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &seedJob{cancel: cancel, job: model.TileSeedJob{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Provider:  providerName,
+		BBox:      bbox,
+		MinZoom:   minZoom,
+		MaxZoom:   maxZoom,
+		Status:    model.SeedJobRunning,
+		Total:     total,
+		StartedAt: p.now().UTC(),
+	}}
+	p.jobs[job.job.ID] = job
+	p.jobsMu.Unlock()
+
+	go p.runSeed(ctx, job, ranges)
+	return job.snapshot(), nil
+}
+
+// SeedJob returns the state of a user's seed job, or false if the user has none with
+// that ID
+func (p *Proxy) SeedJob(id, userID string) (model.TileSeedJob, bool) {
+	job, ok := p.seedJob(id, userID)
+	if !ok {
+		return model.TileSeedJob{}, false
+	}
+	return job.snapshot(), true
+}
+
+// CancelSeedJob stops a user's seed job, keeping the tiles fetched so far. It returns
+// false if the user has no seed job with that ID; finished jobs are left as they are.
+func (p *Proxy) CancelSeedJob(id, userID string) bool {
+	job, ok := p.seedJob(id, userID)
+	if ok {
+		job.cancel()
+	}
+	return ok
+}
+
+func (p *Proxy) seedJob(id, userID string) (*seedJob, bool) {
+	p.jobsMu.Lock()
+	job, ok := p.jobs[id]
+	p.jobsMu.Unlock()
+	if !ok || job.snapshot().UserID != userID {
+		return nil, false
+	}
+	return job, true
+}
+
+func (p *Proxy) runSeed(ctx context.Context, job *seedJob, ranges []tileRange) {
+	tiles := make(chan [3]int)
+	var wg sync.WaitGroup
+	for i := 0; i < seedWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for tile := range tiles {
+				_, err := p.Get(ctx, job.job.Provider, tile[0], tile[1], tile[2])
+				if ctx.Err() != nil {
+					continue
+				}
+				job.mu.Lock()
+				job.job.Done++
+				// Tiles the server does not have are not failures
+				if err != nil && !errors.Is(err, ErrTileNotFound) {
+					job.job.Failed++
+				}
+				job.mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, r := range ranges {
+		for x := r.minX; x <= r.maxX; x++ {
+			for y := r.minY; y <= r.maxY; y++ {
+				select {
+				case tiles <- [3]int{r.z, x, y}:
+				case <-ctx.Done():
+					break feed
+				}
+			}
+		}
+	}
+	close(tiles)
+	wg.Wait()
+
+	status := model.SeedJobCompleted
+	if ctx.Err() != nil {
+		status = model.SeedJobCancelled
+	}
+	job.cancel()
+	finished := p.now().UTC()
+	job.mu.Lock()
+	job.job.Status = status
+	job.job.FinishedAt = &finished
+	job.mu.Unlock()
+}
+
+// seedRanges lists the tile ranges covering bbox at each zoom level along with their
+// total tile count. A bbox crossing the antimeridian is split in two.
+func seedRanges(bbox model.BoundingBox, minZoom, maxZoom int) ([]tileRange, int) {
+	spans := [][2]float64{{bbox.MinLng, bbox.MaxLng}}
+	if bbox.MinLng > bbox.MaxLng {
+		spans = [][2]float64{{bbox.MinLng, 180}, {-180, bbox.MaxLng}}
+	}
+
+	var ranges []tileRange
+	total := 0
+	for z := minZoom; z <= maxZoom; z++ {
+		for _, span := range spans {
+			minX, minY := tileAt(bbox.MaxLat, span[0], z)
+			maxX, maxY := tileAt(bbox.MinLat, span[1], z)
+			r := tileRange{z: z, minX: minX, maxX: maxX, minY: minY, maxY: maxY}
+			ranges = append(ranges, r)
+			total += r.count()
+		}
+	}
+	return ranges, total
+}
+
+// tileAt returns the tile containing a point at zoom z
+func tileAt(lat, lng float64, z int) (int, int) {
+	n := 1 << uint(z)
+	lat = math.Max(-85.05112878, math.Min(85.05112878, lat))
+	sinLat := math.Sin(lat * math.Pi / 180)
+	x := int(math.Floor((lng + 180) / 360 * float64(n)))
+	y := int(math.Floor((0.5 - math.Log((1+sinLat)/(1-sinLat))/(4*math.Pi)) * float64(n)))
+	clamp := func(v int) int {
+		return max(0, min(n-1, v))
+	}
+	return clamp(x), clamp(y)
+}
+
+// seedJobRetention is how long finished seed jobs remain queryable
+const seedJobRetention = 24 * time.Hour
+
+// pruneSeedJobs forgets seed jobs that finished more than seedJobRetention ago
+func (p *Proxy) pruneSeedJobs() {
+	cutoff := p.now().Add(-seedJobRetention)
+	p.jobsMu.Lock()
+	defer p.jobsMu.Unlock()
+	for id, job := range p.jobs {
+		state := job.snapshot()
+		if state.FinishedAt != nil && state.FinishedAt.Before(cutoff) {
+			delete(p.jobs, id)
+		}
+	}
+}
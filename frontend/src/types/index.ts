@@ -16,7 +16,19 @@ export interface Album {
   longitude: number;
   created_at: string;
   updated_at: string;
+  start_at?: string;
+  end_at?: string;
+  dates_manual?: boolean;
+  timezone?: string;
+  country_code?: string;
+  country?: string;
+  region?: string;
+  city?: string;
   photo_count?: number;
+  cover_photo_id?: string;
+  external_id?: string;
+  storage_bytes?: number;
+  tags?: string[];
   photos?: Photo[];
 }
 
@@ -29,6 +41,22 @@ export interface Photo {
   mime_type: string;
   display_order: number;
   uploaded_at: string;
+  taken_at?: string;
+  rating?: number;
+  favorite?: boolean;
+  latitude?: number;
+  longitude?: number;
+  description?: string;
+  tags?: string[];
+}
+
+export interface Tag {
+  id: string;
+  user_id: string;
+  name: string;
+  created_at: string;
+  album_count: number;
+  photo_count: number;
 }
 
 export interface Path {
@@ -37,10 +65,21 @@ export interface Path {
   from_album_id: string;
   to_album_id: string;
   created_at: string;
+  transport_mode: TransportMode | '';
+  distance_km: number;
+  duration_minutes?: number;
+  depart_at?: string;
+  arrive_at?: string;
+  notes: string;
+  geometry?: string; // encoded polyline
+  interpolated?: boolean;
   from_album?: Album;
   to_album?: Album;
+  warnings?: string[];
 }
 
+export type TransportMode = 'walk' | 'car' | 'train' | 'flight' | 'ferry';
+
 // API request/response types
 export interface LoginRequest {
   username: string;
@@ -63,11 +102,22 @@ export interface CreateAlbumRequest {
   latitude: number;
   longitude: number;
   created_at: string;
+  start_at?: string;
+  end_at?: string;
+  timezone?: string;
 }
 
 export interface UpdateAlbumRequest {
   title?: string;
   description?: string;
+  latitude?: number;
+  longitude?: number;
+  created_at?: string;
+  start_at?: string;
+  end_at?: string;
+  auto_dates?: boolean;
+  timezone?: string;
+  cover_photo_id?: string;
 }
 
 export interface CreatePathRequest {
@@ -141,4 +191,291 @@ export interface AlbumPanelProps {
   onSetNextDestination: (destinationId: string) => void;
   allAlbums?: Album[];
   className?: string;
-}
\ No newline at end of file
+}
+export interface GeocodeResult {
+  id: number;
+  name: string;
+  name_zh?: string;
+  latitude: number;
+  longitude: number;
+  country_code: string;
+  country: string;
+  country_zh?: string;
+  region?: string;
+  population: number;
+  timezone?: string;
+  display_name: string;
+  matched_name: string;
+  score: number;
+}
+
+export interface YearStats {
+  year: number;
+  albums: number;
+  photos: number;
+  storage_bytes: number;
+  countries: number;
+  cities: number;
+  distance_km: number;
+}
+
+export interface Stats {
+  albums: number;
+  photos: number;
+  storage_bytes: number;
+  countries: number;
+  cities: number;
+  country_codes: string[];
+  paths: number;
+  distance_km: number;
+  distance_by_mode: Record<string, number>;
+  first_trip_at?: string;
+  last_trip_at?: string;
+  busiest_month?: { month: string; albums: number; photos: number };
+  years: YearStats[];
+  generated_at: string;
+}
+
+export interface TimelineBucket {
+  key: string;
+  start: string;
+  end: string;
+  albums: number;
+  photos: number;
+  album_ids?: string[];
+}
+
+export interface HeatmapPoint {
+  latitude: number;
+  longitude: number;
+  weight: number;
+}
+
+export interface Heatmap {
+  zoom: number;
+  cell_size_px: number;
+  max_weight: number;
+  points: HeatmapPoint[];
+}
+
+export interface Basemap {
+  name: string;
+  title: string;
+  description?: string;
+  attribution?: string;
+  type: 'raster' | 'vector';
+  format: string;
+  min_zoom: number;
+  max_zoom: number;
+  bounds?: { min_lat: number; min_lng: number; max_lat: number; max_lng: number };
+  center?: number[];
+  vector_layers?: string[];
+  tile_url: string;
+}
+
+export interface TileSeedJob {
+  id: string;
+  provider: string;
+  bbox: { min_lat: number; min_lng: number; max_lat: number; max_lng: number };
+  min_zoom: number;
+  max_zoom: number;
+  status: 'running' | 'completed';
+  total: number;
+  done: number;
+  failed: number;
+  started_at: string;
+  finished_at?: string;
+}
+
+export interface SavedPlace {
+  id: string;
+  user_id: string;
+  name: string;
+  kind: 'circle' | 'polygon';
+  latitude?: number;
+  longitude?: number;
+  radius_m?: number;
+  polygon?: [number, number][];
+  auto_tag: boolean;
+  created_at: string;
+  updated_at: string;
+  album_count: number;
+}
+
+// Datum of coordinates sent to or requested from the API (crs=); stored as wgs84
+export type CRS = 'wgs84' | 'gcj02' | 'bd09';
+
+export interface TripStop {
+  position: number;
+  album_id: string;
+  album?: Album;
+}
+
+export interface TripLeg {
+  from_position: number;
+  to_position: number;
+  from_album_id: string;
+  to_album_id: string;
+  path_id?: string;
+  distance_km: number;
+}
+
+export interface Trip {
+  id: string;
+  user_id: string;
+  title: string;
+  description: string;
+  start_at?: string;
+  end_at?: string;
+  created_at: string;
+  updated_at: string;
+  stop_count: number;
+  distance_km?: number;
+  stops?: TripStop[];
+  legs?: TripLeg[];
+}
+
+export interface Route {
+  album_id: string;
+  direction: 'forward' | 'backward' | 'both';
+  albums: Album[];
+  start_index: number;
+  cycle: boolean;
+  truncated: boolean;
+  branch_album_ids?: string[];
+}
+
+export interface AutoPath {
+  from_album_id: string;
+  to_album_id: string;
+  path_id?: string;
+  existing: boolean;
+  gap_hours: number;
+}
+
+export interface AutoPathResult {
+  dry_run: boolean;
+  chains: string[][];
+  paths: AutoPath[];
+  created: number;
+  existing: number;
+  replaced: number;
+}
+
+export interface ImportMatch {
+  name: string;
+  album_id: string;
+  distance_m: number;
+}
+
+export interface ImportedPath {
+  path_id: string;
+  from_album_id: string;
+  to_album_id: string;
+  source: 'track' | 'route';
+  name?: string;
+  points: number;
+  created: boolean;
+}
+
+export interface GeotaggedPhoto {
+  photo_id: string;
+  album_id: string;
+  latitude: number;
+  longitude: number;
+}
+
+export interface GPXImportResult {
+  waypoints: number;
+  routes: number;
+  tracks: number;
+  track_points: number;
+  albums_created: Album[];
+  albums_matched: ImportMatch[];
+  paths: ImportedPath[];
+  photos_unlocated: number;
+  photos_geotagged: GeotaggedPhoto[];
+  warnings?: string[];
+}
+
+export type PlaceImportStatus = 'created' | 'existing' | 'skipped' | 'failed';
+
+export interface PlaceFeatureResult {
+  index: number;
+  external_id?: string;
+  name?: string;
+  geometry?: string;
+  status: PlaceImportStatus;
+  album_id?: string;
+  path_id?: string;
+  error?: string;
+}
+
+export interface PlaceImportResult {
+  format: 'geojson' | 'kml';
+  features: number;
+  albums_created: number;
+  albums_existing: number;
+  paths_created: number;
+  paths_existing: number;
+  skipped: number;
+  failed: number;
+  results: PlaceFeatureResult[];
+  warnings?: string[];
+}
+
+export interface TakeoutImportJob {
+  id: string;
+  status: 'running' | 'completed' | 'failed';
+  group: 'folder' | 'cluster';
+  total: number;
+  done: number;
+  imported: number;
+  skipped: number;
+  failed: number;
+  unsupported: number;
+  albums_created: number;
+  albums_reused: number;
+  errors?: string[];
+  error?: string;
+  started_at: string;
+  finished_at?: string;
+}
+
+export interface AccountExportJob {
+  id: string;
+  status: 'running' | 'completed' | 'failed';
+  albums: number;
+  photos: number;
+  done: number;
+  missing_files: number;
+  size?: number;
+  error?: string;
+  started_at: string;
+  finished_at?: string;
+  download_url?: string;
+  expires_at?: string;
+}
+
+export interface ImportCounts {
+  created: number;
+  existing: number;
+  failed: number;
+}
+
+export interface AccountImportJob {
+  id: string;
+  status: 'running' | 'completed' | 'failed';
+  total: number;
+  done: number;
+  albums: ImportCounts;
+  photos: ImportCounts;
+  paths: ImportCounts;
+  trips: ImportCounts;
+  tags: ImportCounts;
+  saved_places: ImportCounts;
+  errors?: string[];
+  error?: string;
+  started_at: string;
+  finished_at?: string;
+}
@@ -9,6 +9,7 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	_ "time/tzdata" // album time zones must resolve on hosts without a zoneinfo database
 
 	"github.com/gin-gonic/gin"
 
@@ -184,6 +185,11 @@ func main() {
 		panic("Failed to initialize logging system: " + err.Error())
 	}
 
+	// Command-line tools share the server's data directory and logging
+	if len(os.Args) > 1 && os.Args[1] == "import-takeout" {
+		os.Exit(backend.RunTakeoutImport(os.Args[2:]))
+	}
+
 	logging.Info("Starting GeoAlbum server")
 
 	// Initialize MIME types for better static file serving